@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/agent"
+	"github.com/haasonsaas/macos-persist-scan/internal/sinks"
+	"github.com/spf13/cobra"
+)
+
+func installAgentCmd() *cobra.Command {
+	var label string
+	var system bool
+	var interval time.Duration
+	var program string
+	var logPath string
+	var allowUnsigned bool
+	var noLoad bool
+	var printOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "install-agent",
+		Short: "Install a launchd job that scans on a schedule",
+		Long: `Write and load a launchd job that runs "scan --quiet" every --interval
+and sends findings at or above --alert-threshold to each --sink.
+
+By default the job is a LaunchAgent in your session. With --system it is a
+LaunchDaemon running as root, which covers every local account; this
+requires sudo. The scanner binary must be validly code signed unless
+--allow-unsigned is given, since an unsigned background job is exactly
+what the scanner flags.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// launchd jobs get no environment to read sink keys from
+			for _, spec := range sinkSpecs {
+				if variables := sinks.Credentials(spec); variables != "" {
+					return fmt.Errorf("sink %q reads %s from the environment, which the installed job does not have; scan from a job you manage that sets them",
+						spec, variables)
+				}
+			}
+			if _, _, err := parseSinks(); err != nil {
+				return err
+			}
+			if system && os.Geteuid() != 0 && !printOnly {
+				return fmt.Errorf("--system requires root")
+			}
+
+			if program == "" {
+				exe, err := os.Executable()
+				if err != nil {
+					return err
+				}
+				if program, err = filepath.EvalSymlinks(exe); err != nil {
+					return err
+				}
+			}
+			if logPath == "" {
+				logPath = filepath.Join(stateDir(), "agent.log")
+				if system {
+					logPath = "/var/log/macos-persist-scan.log"
+				}
+			}
+
+			scanArgs := []string{"scan", "--quiet", "--alert-threshold", alertThreshold}
+			for _, spec := range sinkSpecs {
+				scanArgs = append(scanArgs, "--sink", spec)
+			}
+			if configPath != "" {
+				abs, err := filepath.Abs(configPath)
+				if err != nil {
+					return err
+				}
+				scanArgs = append(scanArgs, "--config", abs)
+			}
+
+			a := &agent.Agent{
+				Label:    label,
+				System:   system,
+				Program:  program,
+				Args:     scanArgs,
+				Interval: interval,
+				LogPath:  logPath,
+			}
+
+			out := cmd.OutOrStdout()
+			if printOnly {
+				data, err := a.Plist()
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(data))
+				return nil
+			}
+
+			if !allowUnsigned {
+				if err := a.VerifyProgram(); err != nil {
+					return fmt.Errorf("%w (use --allow-unsigned to install anyway)", err)
+				}
+			}
+
+			path, err := a.Install(!noLoad)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Installed %s\nScanning every %s; output is logged to %s\n", path, interval, logPath)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&label, "label", agent.DefaultLabel, "launchd label of the job")
+	flags.BoolVar(&system, "system", false, "Install a LaunchDaemon running as root instead of a LaunchAgent")
+	flags.DurationVar(&interval, "interval", 6*time.Hour, "Time between scans")
+	flags.StringArrayVar(&sinkSpecs, "sink", nil, "Send findings to this sink (webhook=URL, slack=URL, teams=URL, pagerduty, opsgenie, github=OWNER/REPO, jira=URL/PROJECT, syslog, syslog=udp://host:port); repeatable")
+	flags.StringVar(&alertThreshold, "alert-threshold", "high", "Minimum risk level sent to sinks (info, low, medium, high, critical)")
+	flags.StringVar(&program, "program", "", "Scanner binary the job runs (default: this executable)")
+	flags.StringVar(&logPath, "log", "", "File receiving the job's output (default ~/.macos-persist-scan/agent.log, or /var/log/macos-persist-scan.log with --system)")
+	flags.BoolVar(&allowUnsigned, "allow-unsigned", false, "Install even if the scanner binary is not code signed")
+	flags.BoolVar(&noLoad, "no-load", false, "Write the plist without loading it")
+	flags.BoolVar(&printOnly, "print", false, "Print the job plist instead of installing it")
+
+	return cmd
+}
+
+func uninstallAgentCmd() *cobra.Command {
+	var label string
+	var system bool
+
+	cmd := &cobra.Command{
+		Use:   "uninstall-agent",
+		Short: "Unload and remove the scheduled scan job",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a := &agent.Agent{Label: label, System: system}
+			path, err := a.Uninstall()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&label, "label", agent.DefaultLabel, "launchd label of the job")
+	cmd.Flags().BoolVar(&system, "system", false, "Remove the LaunchDaemon instead of the LaunchAgent")
+
+	return cmd
+}
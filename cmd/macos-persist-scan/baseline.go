@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/baseline"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func baselineCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "baseline",
+		Short: "Manage the manifest of stock macOS items",
+	}
+
+	var (
+		manifestPath string
+		release      string
+	)
+	buildCmd := &cobra.Command{
+		Use:   "build <result.json>...",
+		Short: "Record the stock items of a clean macOS install in a baseline manifest",
+		Long: `Import JSON scan results of clean macOS installs into a baseline
+manifest. Each result's launchd jobs and periodic scripts are recorded as
+the stock items of its macOS release, by path and by the cdhash of the
+program or the SHA-256 of the file, replacing what the manifest held for
+that release.
+
+Scan a freshly installed system with no third-party software, as root so
+every job is read. Publish the manifest in a rule bundle's "baseline" field
+with a higher version than the bundled one and scans classify exact
+matches as Info.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifest := baseline.Bundled()
+			if data, err := os.ReadFile(manifestPath); err == nil {
+				if manifest, err = baseline.Parse(data); err != nil {
+					return err
+				}
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+
+			for _, path := range args {
+				result, err := scanner.LoadResult(path)
+				if err != nil {
+					return err
+				}
+				major := release
+				if major == "" {
+					major = baseline.Major(result.OSVersion)
+				}
+				if major == "" {
+					return fmt.Errorf("%s does not record its macOS version; set --release", path)
+				}
+				count := manifest.Add(major, result)
+				fmt.Fprintf(cmd.OutOrStdout(), "macOS %s: %d stock items from %s\n", major, count, path)
+			}
+
+			manifest.Version++
+			manifest.Published = time.Now().UTC()
+			data, err := json.MarshalIndent(manifest, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(manifestPath, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("writing baseline manifest: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Baseline manifest %s: version %d, %d releases\n", manifestPath, manifest.Version, len(manifest.Releases))
+			return nil
+		},
+	}
+	buildCmd.Flags().StringVar(&manifestPath, "manifest", "baseline.json", "Baseline manifest to create or update")
+	buildCmd.Flags().StringVar(&release, "release", "", "macOS major version the results were scanned on (default: as recorded in each result)")
+
+	cmd.AddCommand(buildCmd)
+	return cmd
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+// Shell completion scripts come from cobra's built-in completion command;
+// these functions supply the values it cannot know.
+
+// completeMechanisms completes the mechanism names items can have,
+// described by the scanner that reports them where it is the scanner's
+// own.
+func completeMechanisms(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	descriptions := make(map[scanner.MechanismType]string)
+	for _, s := range persistscan.Scanners() {
+		if d, ok := s.(scanner.Describer); ok {
+			descriptions[s.Type()] = d.Info().Description
+		}
+	}
+	var names []string
+	for _, mechanism := range scanner.Mechanisms {
+		name := string(mechanism)
+		if description := descriptions[mechanism]; description != "" {
+			name += "\t" + description
+		}
+		names = append(names, name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFormats completes output format names. After "format=" the rest
+// is a file path, left to the shell.
+func completeFormats(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if strings.Contains(toComplete, "=") {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	var names []string
+	for _, format := range output.Formats {
+		names = append(names, string(format))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFindingIDs returns a completion function for finding IDs, taken
+// from the --results file if one was given and otherwise from the most
+// recent scan in the history database. Each ID is described by its risk
+// and label. With single set, only the first argument is completed.
+func completeFindingIDs(resultsPath *string, single bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if single && len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var result *scanner.ScanResult
+		var err error
+		if *resultsPath != "" {
+			result, err = scanner.LoadResult(*resultsPath)
+		} else {
+			result, err = latestScan()
+		}
+		if err != nil || result == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var ids []string
+		for _, item := range result.Items {
+			if strings.HasPrefix(item.ID, toComplete) {
+				ids = append(ids, fmt.Sprintf("%s\t%s %s", item.ID, item.Risk.Level, item.Label))
+			}
+		}
+		return ids, cobra.ShellCompDirectiveNoFileComp
+	}
+}
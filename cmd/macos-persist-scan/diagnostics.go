@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// printDiagnostics writes, for --verbose, where the scan spent its time and
+// which locations it did not fully examine, so a slow or thin scan can be
+// traced to the component responsible.
+func printDiagnostics(out io.Writer, result *scanner.ScanResult) {
+	fmt.Fprintf(out, "\nScan diagnostics (%s total):\n", result.Duration.Round(time.Millisecond))
+
+	for _, kind := range []string{scanner.TimingScanner, scanner.TimingStage, scanner.TimingHeuristic} {
+		var timings []scanner.Timing
+		for _, t := range result.Timings {
+			if t.Kind == kind {
+				timings = append(timings, t)
+			}
+		}
+		if len(timings) == 0 {
+			continue
+		}
+		// Slowest first
+		sort.SliceStable(timings, func(i, j int) bool {
+			return timings[i].Duration > timings[j].Duration
+		})
+
+		fmt.Fprintln(out)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "  %sS\tDURATION\tITEMS\n", strings.ToUpper(kind))
+		for _, t := range timings {
+			fmt.Fprintf(w, "  %s\t%s\t%d\n", t.Name, t.Duration.Round(time.Microsecond), t.Items)
+		}
+		w.Flush()
+	}
+
+	var skipped []scanner.CoverageEntry
+	for _, entry := range result.Coverage {
+		if entry.Status != scanner.CoverageFull {
+			skipped = append(skipped, entry)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintln(out)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  COVERAGE\tMECHANISM\tDETAIL\tLOCATION")
+		for _, entry := range skipped {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", entry.Status, entry.Mechanism, entry.Detail, entry.Location)
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/diff"
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func diffCmd() *cobra.Command {
+	var format string
+	var exitCode bool
+
+	cmd := &cobra.Command{
+		Use:   "diff <old.json> <new.json>",
+		Short: "Show persistence items added, removed, or changed between two scans",
+		Long: `Compare two JSON scan results and list the persistence items that were
+added, removed, or changed, with the changed fields highlighted.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown diff format %q (valid: table, json)", format)
+			}
+
+			oldResult, err := scanner.LoadResult(args[0])
+			if err != nil {
+				return err
+			}
+			newResult, err := scanner.LoadResult(args[1])
+			if err != nil {
+				return err
+			}
+
+			result := diff.Compare(oldResult, newResult)
+			formatter := &output.DiffFormatter{JSON: format == "json"}
+			data, err := formatter.Format(result)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(cmd.OutOrStdout(), string(data))
+
+			if exitCode && !result.Empty() {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().BoolVar(&exitCode, "exit-code", false, "Exit with status 1 when the scans differ")
+
+	return cmd
+}
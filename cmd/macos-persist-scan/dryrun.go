@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
+)
+
+// dryRunFootprint adds what the scan command itself reads, writes, and
+// contacts to the footprint of the scan.
+func dryRunFootprint(specs []output.Spec) *persistscan.Footprint {
+	footprint := persistscan.DryRun(scanOptions())
+
+	var cli []persistscan.Access
+	if configPath != "" {
+		cli = append(cli, persistscan.Access{Kind: persistscan.AccessFile, Target: configPath, Purpose: "configuration"})
+	}
+	if templatePath != "" {
+		cli = append(cli, persistscan.Access{Kind: persistscan.AccessFile, Target: templatePath, Purpose: "output template"})
+	}
+	for _, path := range []string{historyPath, dbPath} {
+		if path != "" {
+			cli = append(cli, persistscan.Access{Kind: persistscan.AccessDatabase, Target: path, Purpose: "scan history; the previous scan is the baseline for sinks", Write: true})
+		}
+	}
+	for _, spec := range specs {
+		path := spec.Path
+		if path == "" {
+			path = outputFile
+		}
+		if path != "" {
+			cli = append(cli, persistscan.Access{Kind: persistscan.AccessFile, Target: path, Purpose: string(spec.Format) + " output", Write: true})
+		}
+	}
+	if summaryFile != "" {
+		cli = append(cli, persistscan.Access{Kind: persistscan.AccessFile, Target: summaryFile, Purpose: "run summary", Write: true})
+	}
+	for _, spec := range sinkSpecs {
+		cli = append(cli, persistscan.Access{Kind: persistscan.AccessNetwork, Target: sinkTarget(spec), Purpose: "findings at or above " + alertThreshold})
+	}
+	footprint.Accesses = append(footprint.Accesses, cli...)
+	return footprint
+}
+
+// sinkTarget names where a --sink value sends findings without repeating
+// the keys or URL paths that authenticate it.
+func sinkTarget(spec string) string {
+	kind, target, _ := strings.Cut(spec, "=")
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		return kind + " " + u.Scheme + "://" + u.Host
+	}
+	if kind == "github" {
+		return kind + " " + target
+	}
+	return kind
+}
+
+// printDryRun writes the footprint as JSON if that is the primary output
+// format and as text otherwise.
+func printDryRun(out io.Writer, footprint *persistscan.Footprint, specs []output.Spec) error {
+	for _, spec := range specs {
+		if spec.Path == "" && spec.Format == output.FormatterJSON {
+			data, err := json.MarshalIndent(footprint, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, string(data))
+			return nil
+		}
+	}
+
+	fmt.Fprintln(out, "Dry run: nothing below was read, run, written, or contacted.")
+	if footprint.Root != "" {
+		fmt.Fprintf(out, "Paths are relative to the offline root %s.\n", footprint.Root)
+	}
+	for _, info := range footprint.Collectors {
+		fmt.Fprintf(out, "\n%s: %s\n", info.Mechanism, info.Description)
+		for _, section := range []struct {
+			name  string
+			lines []string
+		}{
+			{"Paths", info.Paths},
+			{"Commands", info.Commands},
+		} {
+			if len(section.lines) == 0 {
+				continue
+			}
+			fmt.Fprintf(out, "  %s:\n", section.name)
+			for _, line := range section.lines {
+				fmt.Fprintf(out, "    %s\n", line)
+			}
+		}
+	}
+
+	fmt.Fprintln(out, "\nFor each item found, and once per scan:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, access := range footprint.Accesses {
+		mode := "read"
+		switch {
+		case access.Kind == persistscan.AccessCommand:
+			mode = "run"
+		case access.Kind == persistscan.AccessNetwork:
+			mode = "contact"
+		case access.Write:
+			mode = "write"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", access.Kind, mode, access.Target, access.Purpose)
+	}
+	return w.Flush()
+}
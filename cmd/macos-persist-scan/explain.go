@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/explain"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func explainCmd() *cobra.Command {
+	var resultsPath string
+
+	cmd := &cobra.Command{
+		Use:   "explain <item-id-or-path>",
+		Short: "Print a full breakdown of one finding",
+		Long: `Print everything known about one finding: every heuristic's score and
+reasoning, file hashes, the program's code signature and certificate
+chain, the raw plist, and suggested next steps.
+
+The item is looked up in --results, or else in the most recent scan in the
+history database, or else in a fresh scan.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeFindingIDs(&resultsPath, true),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+
+			var result *scanner.ScanResult
+			switch {
+			case resultsPath != "":
+				result, err = scanner.LoadResult(resultsPath)
+			default:
+				result, err = latestScan()
+				if err == nil && result == nil {
+					result, err = performScan(context.Background())
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			item, err := selectItem(result, args[0])
+			if err != nil {
+				return err
+			}
+
+			// Read evidence from the same disk the scan looked at
+			sysroot.Root = ""
+			if result.Root != "" {
+				if info, err := os.Stat(result.Root); err == nil && info.IsDir() {
+					sysroot.Root = result.Root
+				}
+			}
+
+			explain.Render(cmd.OutOrStdout(), item, explain.Gather(item))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&resultsPath, "results", "r", "", "Look the item up in this JSON scan result")
+	addScanFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/fleet"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func fleetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fleet",
+		Short: "Manage fleet prevalence baselines",
+	}
+
+	var dbPath string
+	buildCmd := &cobra.Command{
+		Use:   "build <result.json>...",
+		Short: "Build or extend a prevalence database from per-host JSON results",
+		Long: `Import JSON scan results collected from many hosts into a prevalence
+database. Pass the database to "scan --fleet-db" to score items that appear
+on very few machines in the fleet.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db := fleet.NewDatabase()
+			if _, err := os.Stat(dbPath); err == nil {
+				if db, err = fleet.Load(dbPath); err != nil {
+					return err
+				}
+			}
+
+			for _, path := range args {
+				result, err := scanner.LoadResult(path)
+				if err != nil {
+					return err
+				}
+
+				host := result.Hostname
+				if host == "" {
+					host = path
+				}
+				if !db.Add(host, result) {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %s: host %s already imported\n", path, host)
+				}
+			}
+
+			if err := db.Save(dbPath); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Prevalence database %s: %d hosts, %d distinct items\n",
+				dbPath, db.TotalHosts(), len(db.Items))
+			return nil
+		},
+	}
+	buildCmd.Flags().StringVar(&dbPath, "db", "fleet-prevalence.json", "Prevalence database to create or update")
+
+	cmd.AddCommand(buildCmd)
+	return cmd
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/pkg/diff"
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/haasonsaas/macos-persist-scan/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+func historyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Browse and compare previous scans",
+		Long: `Every scan is recorded in a local history database
+(~/.macos-persist-scan/history.db by default; see --history). These
+commands list, show, and compare recorded scans, and report when an item
+first appeared.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.Root().PersistentPreRunE(cmd, args); err != nil {
+				return err
+			}
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("history") && cfg.Scan.History != "" {
+				historyPath = cfg.Scan.History
+			}
+			if historyPath == "" {
+				return fmt.Errorf("no history database (--history is empty)")
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringVar(&historyPath, "history", defaultHistoryPath(), "History database to read")
+
+	cmd.AddCommand(historyListCmd())
+	cmd.AddCommand(historyShowCmd())
+	cmd.AddCommand(historyDiffCmd())
+	cmd.AddCommand(historyItemCmd())
+
+	return cmd
+}
+
+func historyListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List recorded scans, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := store.Open(historyPath)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			scans, err := db.List()
+			if err != nil {
+				return err
+			}
+			printScans(cmd, scans)
+			return nil
+		},
+	}
+}
+
+func historyShowCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "show <scan-id>",
+		Short: "Print a recorded scan",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, err := output.ParseSpec(format)
+			if err != nil {
+				return err
+			}
+
+			results, err := loadScans(args)
+			if err != nil {
+				return err
+			}
+			return writeOutputs(results[0], []output.Spec{spec})
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json, sarif, cef, ecs, ndjson, csv, summary, bodyfile, timesketch)")
+	cmd.RegisterFlagCompletionFunc("output", completeFormats)
+
+	return cmd
+}
+
+func historyDiffCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "diff <old-scan-id> <new-scan-id>",
+		Short: "Show items added, removed, or changed between two recorded scans",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown diff format %q (valid: table, json)", format)
+			}
+
+			results, err := loadScans(args)
+			if err != nil {
+				return err
+			}
+
+			formatter := &output.DiffFormatter{JSON: format == "json"}
+			data, err := formatter.Format(diff.Compare(results[0], results[1]))
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(cmd.OutOrStdout(), string(data))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+func historyItemCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "item <item-id>",
+		Short: "List the recorded scans containing an item",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := store.Open(historyPath)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			scans, err := db.ItemScans(args[0])
+			if err != nil {
+				return err
+			}
+			if len(scans) == 0 {
+				return fmt.Errorf("item %s does not appear in any recorded scan", args[0])
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "First seen: scan %d at %s\n", scans[0].ID, scans[0].StartTime.Local().Format("2006-01-02 15:04:05"))
+			last := scans[len(scans)-1]
+			fmt.Fprintf(out, "Last seen:  scan %d at %s\n\n", last.ID, last.StartTime.Local().Format("2006-01-02 15:04:05"))
+			printScans(cmd, scans)
+			return nil
+		},
+	}
+}
+
+// loadScans loads the recorded scans with the given IDs.
+func loadScans(ids []string) ([]*scanner.ScanResult, error) {
+	db, err := store.Open(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var results []*scanner.ScanResult
+	for _, arg := range ids {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scan ID %q", arg)
+		}
+		result, err := db.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// latestScan returns the most recent scan in the history database, or nil
+// if nothing has been recorded.
+func latestScan() (*scanner.ScanResult, error) {
+	if historyPath == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(historyPath); err != nil {
+		return nil, nil
+	}
+
+	db, err := store.Open(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	scans, err := db.List()
+	if err != nil || len(scans) == 0 {
+		return nil, err
+	}
+	return db.Load(scans[0].ID)
+}
+
+func printScans(cmd *cobra.Command, scans []store.ScanInfo) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tHOST\tITEMS\tDURATION")
+	for _, scan := range scans {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n", scan.ID, scan.StartTime.Local().Format("2006-01-02 15:04:05"), scan.Hostname, scan.TotalItems, scan.Duration)
+	}
+	w.Flush()
+}
+
+// defaultHistoryPath is where scans are recorded unless --history says
+// otherwise.
+func defaultHistoryPath() string {
+	return filepath.Join(stateDir(), "history.db")
+}
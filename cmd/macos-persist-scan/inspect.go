@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/explain"
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
+	"github.com/spf13/cobra"
+)
+
+func inspectCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "inspect <path>",
+		Short: "Assess a single plist, script, or binary without scanning the system",
+		Long: `Parse, fingerprint, and run every heuristic against one file, such as a
+suspicious sample received from elsewhere, and print the verdict.
+
+A launchd job definition is assessed as a LaunchDaemon if it is in a
+LaunchDaemons directory and as a LaunchAgent otherwise. Any other file is
+assessed as the program of a LaunchAgent.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+
+			var spec output.Spec
+			if format != "explain" {
+				if spec, err = output.ParseSpec(format); err != nil {
+					return err
+				}
+			}
+
+			result, err := persistscan.Inspect(args[0], scanOptions())
+			if err != nil {
+				return err
+			}
+
+			if format != "explain" {
+				return writeOutputs(result, []output.Spec{spec})
+			}
+			item := &result.Items[0]
+			explain.Render(cmd.OutOrStdout(), item, explain.Gather(item))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", "explain", "Output format (explain, or any scan output format such as json or sarif)")
+	addScanFlags(cmd.Flags())
+
+	return cmd
+}
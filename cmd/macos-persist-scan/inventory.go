@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
+	"github.com/spf13/cobra"
+)
+
+func inventoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "List every persistence item without risk scoring",
+		Long: `Collect the complete persistence inventory and print it without running
+any heuristics, code-signing checks, or suppressions. This is faster than a
+scan and suits configuration management and compliance reporting, which
+need the list rather than a verdict. The exit code is always 0.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+			if !cmd.Flags().Changed("output") {
+				outputFormats = []string{"json"}
+			}
+
+			specs, err := parseOutputSpecs(outputFormats)
+			if err != nil {
+				return err
+			}
+			if err := loadOutputKeys(specs); err != nil {
+				return err
+			}
+
+			opts := scanOptions()
+			opts.Inventory = true
+			opts.Suppressions = ""
+			result, err := persistscan.Scan(context.Background(), opts)
+			if err != nil {
+				return err
+			}
+
+			return writeOutputs(result, specs)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringArrayVarP(&outputFormats, "output", "o", []string{"json"}, "Output format (json, table, ecs, bodyfile, timesketch, template); repeat as format=path to also write files")
+	flags.StringVar(&outputFile, "output-file", "", "Write the primary output to this file instead of stdout")
+	flags.StringVar(&signKeyPath, "sign-key", "", "Sign each output file with this Ed25519 private key, writing a detached signature to file.sig")
+	flags.StringArrayVar(&encryptTo, "encrypt-to", nil, "Encrypt each output file to this age recipient (age1...) or recipients file, writing file.age; repeatable")
+	flags.StringSliceVar(&outputFields, "fields", nil, "Limit JSON and NDJSON items to these dotted fields (e.g. id,label,risk.level,program_file.sha256)")
+	flags.StringVar(&rootPath, "root", "", "Inventory an offline system mounted at this path instead of the running one")
+	flags.BoolVar(&noContent, "no-content", false, "Replace file contents and script bodies with hashes and short excerpts")
+	flags.BoolVarP(&parallel, "parallel", "p", true, "Run scanners in parallel")
+	flags.IntVar(&concurrency, "concurrency", 0, "Maximum scanners, and directories per scanner, processed at once (0 = number of CPUs)")
+	flags.DurationVar(&scanTimeout, "timeout", 60*time.Second, "Give up on any scanner that runs longer than this (0 disables)")
+	flags.DurationVar(&commandTimeout, "command-timeout", 30*time.Second, "Kill external commands that run longer than this (0 disables)")
+
+	return cmd
+}
@@ -2,21 +2,89 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/haasonsaas/macos-persist-scan/internal/collectors"
-	"github.com/haasonsaas/macos-persist-scan/internal/heuristics"
+	"github.com/fatih/color"
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sinks"
 	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
 	"github.com/haasonsaas/macos-persist-scan/pkg/risk"
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/haasonsaas/macos-persist-scan/pkg/store"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
-	outputFormat string
-	parallel     bool
-	verbose      bool
+	outputFormats   []string
+	outputFile      string
+	parallel        bool
+	verbose         bool
+	configPath      string
+	fleetDBPath     string
+	certAgeDays     int
+	scoringModel    string
+	prettyJSON      = true
+	dbPath          string
+	templatePath    string
+	quiet           bool
+	summaryOnly     bool
+	tableColumns    []string
+	tableLayout     string
+	tableSort       string
+	tableWidth      int
+	noContent       bool
+	tableGroupBy    string
+	maxItems        int
+	tablePage       int
+	scanTimeout     time.Duration
+	commandTimeout  time.Duration
+	sigTimeout      time.Duration
+	totalTimeout    time.Duration
+	collectTimeout  time.Duration
+	assessTimeout   time.Duration
+	scannerTimeouts map[string]time.Duration
+	concurrency     int
+	signingCache    string
+	scanCache       string
+	noExec          bool
+	offline         bool
+	suppressions    string
+	historyPath     string
+	rulesPath       string
+	rulesKey        string
+	jamfURL         string
+	disabledHeurs   []string
+	heurConfidence  confidenceFlag
+	riskThresholds  scanner.RiskThresholds
+	outputFields    []string
+	signKeyPath     string
+	encryptTo       []string
+	mechanismFilter []string
+	labelFilter     string
+	pathFilters     []string
+	userFilters     []string
+	sinkSpecs       []string
+	alertThreshold  string
+	rootPath        string
+	logLevel        string
+	logFormat       string
+	noColor         bool
+	summaryFile     string
+	dryRun          bool
 )
 
 func main() {
@@ -25,10 +93,25 @@ func main() {
 		Short: "Scan macOS for persistence mechanisms",
 		Long: `A security tool that discovers, analyzes, and reports on macOS persistence 
 mechanisms to help identify potentially malicious software installations.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if verbose && !cmd.Flags().Changed("log-level") {
+				logLevel = "info"
+			}
+			// Color is already off when stdout is not a terminal or
+			// NO_COLOR is set
+			if noColor {
+				color.NoColor = true
+			}
+			return logging.Setup(os.Stderr, logLevel, logFormat)
+		},
 	}
 
 	// Global flags
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to TOML configuration file")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "Minimum level of log messages written to stderr (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log message format (text, json)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also off when NO_COLOR is set or output is not a terminal)")
 
 	// Scan command
 	scanCmd := &cobra.Command{
@@ -38,11 +121,57 @@ mechanisms to help identify potentially malicious software installations.`,
 		RunE:  runScan,
 	}
 	
-	scanCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, sarif)")
-	scanCmd.Flags().BoolVarP(&parallel, "parallel", "p", true, "Run scanners in parallel")
+	scanCmd.Flags().StringArrayVarP(&outputFormats, "output", "o", []string{"table"}, "Output format (table, json, sarif, cef, ecs, ndjson, csv, summary, bodyfile, timesketch, template); repeat as format=path to also write files")
+	scanCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print only a one-line summary; rely on the exit code for results")
+	scanCmd.Flags().BoolVar(&summaryOnly, "summary", false, "Print risk counts by mechanism instead of per-item rows")
+	scanCmd.Flags().StringVar(&summaryFile, "summary-file", "", "Also write risk counts, errors, duration, and the exit code to this JSON file")
+	scanCmd.Flags().StringSliceVar(&tableColumns, "columns", nil, "Table columns to show (risk, score, mechanism, attack, label, path, program, args, user, modified, notes)")
+	scanCmd.Flags().StringVar(&tableLayout, "layout", "standard", "Table layout (compact, standard, wide)")
+	scanCmd.Flags().StringVar(&tableSort, "sort", "risk", "Table sort key (risk, mechanism, mtime)")
+	scanCmd.Flags().StringVar(&tableGroupBy, "group-by", "", "Split the table into sections (mechanism)")
+	scanCmd.Flags().IntVar(&tableWidth, "max-width", 0, "Truncate table cells to this many characters (0 = no limit)")
+	scanCmd.Flags().IntVar(&maxItems, "max-items", 0, "Show at most this many table rows, noting how many were omitted (0 = no limit)")
+	scanCmd.Flags().IntVar(&tablePage, "page", 1, "Which page of --max-items rows the table shows")
+	scanCmd.Flags().StringVar(&outputFile, "output-file", "", "Write the primary output to this file instead of stdout")
+	scanCmd.Flags().StringVar(&signKeyPath, "sign-key", "", "Sign each output file with this Ed25519 private key, writing a detached signature to file.sig")
+	scanCmd.Flags().StringArrayVar(&encryptTo, "encrypt-to", nil, "Encrypt each output file to this age recipient (age1...) or recipients file, writing file.age; repeatable")
+	scanCmd.Flags().StringSliceVar(&outputFields, "fields", nil, "Limit JSON and NDJSON items to these dotted fields (e.g. id,label,risk.level,program_file.sha256)")
+	scanCmd.Flags().StringVar(&templatePath, "template", "", "Go text/template file for the template output format")
+	scanCmd.Flags().StringArrayVar(&sinkSpecs, "sink", nil, "Send findings at or above --alert-threshold to this sink (webhook=URL, slack=URL, teams=URL, pagerduty, opsgenie, github=OWNER/REPO, jira=URL/PROJECT, syslog, syslog=udp://host:port); repeatable")
+	scanCmd.Flags().StringSliceVar(&mechanismFilter, "mechanism", nil, "Report only items of these mechanisms (e.g. LaunchDaemon,LoginItem); repeatable")
+	scanCmd.Flags().StringVar(&labelFilter, "label", "", "Report only items whose label matches this regular expression")
+	scanCmd.Flags().StringArrayVar(&pathFilters, "path", nil, "Report only items whose path or program matches this glob; repeatable")
+	scanCmd.Flags().StringSliceVar(&userFilters, "user", nil, "Report only items belonging to these users; repeatable")
+	scanCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List every path, command, database, and service the scan would touch, without scanning")
+	scanCmd.Flags().StringVar(&alertThreshold, "alert-threshold", "high", "Minimum risk level sent to sinks (info, low, medium, high, critical)")
+	addScanFlags(scanCmd.Flags())
+	scanCmd.RegisterFlagCompletionFunc("mechanism", completeMechanisms)
+	scanCmd.RegisterFlagCompletionFunc("output", completeFormats)
 
 	// Add commands
 	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(fleetCmd())
+	rootCmd.AddCommand(baselineCmd())
+	rootCmd.AddCommand(diffCmd())
+	rootCmd.AddCommand(mergeCmd())
+	rootCmd.AddCommand(timelineCmd())
+	rootCmd.AddCommand(verifyCmd())
+	rootCmd.AddCommand(historyCmd())
+	rootCmd.AddCommand(explainCmd())
+	rootCmd.AddCommand(inspectCmd())
+	rootCmd.AddCommand(listScannersCmd())
+	rootCmd.AddCommand(preflightCmd())
+	rootCmd.AddCommand(inventoryCmd())
+	rootCmd.AddCommand(timeMachineCmd())
+	rootCmd.AddCommand(snapshotDiffCmd())
+	rootCmd.AddCommand(rescoreCmd())
+	rootCmd.AddCommand(serveCmd())
+	rootCmd.AddCommand(remediateCmd())
+	rootCmd.AddCommand(tuiCmd())
+	rootCmd.AddCommand(triageCmd())
+	rootCmd.AddCommand(updateRulesCmd())
+	rootCmd.AddCommand(installAgentCmd())
+	rootCmd.AddCommand(uninstallAgentCmd())
 	rootCmd.AddCommand(versionCmd())
 
 	if err := rootCmd.Execute(); err != nil {
@@ -51,75 +180,468 @@ mechanisms to help identify potentially malicious software installations.`,
 	}
 }
 
-func runScan(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+// addScanFlags registers the flags that control how a scan is performed,
+// shared by every command that runs scans.
+func addScanFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&rootPath, "root", "", "Scan an offline system mounted at this path instead of the running one")
+	flags.BoolVar(&noExec, "no-exec", false, "Collect only from files, without running crontab, osascript, dscl, system_profiler, nvram, kmutil, or systemextensionsctl")
+	flags.BoolVar(&noContent, "no-content", false, "Replace file contents and script bodies with hashes and short excerpts")
+	flags.StringVar(&dbPath, "db", "", "Also record the scan in this SQLite database")
+	flags.StringVar(&historyPath, "history", defaultHistoryPath(), "Record the scan in this history database (empty disables)")
+	flags.BoolVarP(&parallel, "parallel", "p", true, "Run scanners in parallel")
+	flags.IntVar(&concurrency, "concurrency", 0, "Maximum scanners, directories per scanner, and signature checks processed at once (0 = number of CPUs)")
+	flags.DurationVar(&scanTimeout, "timeout", 60*time.Second, "Give up on any scanner that runs longer than this (0 disables)")
+	flags.StringVar(&signingCache, "signing-cache", "", "Persist codesign and spctl results in this file between runs")
+	flags.StringVar(&scanCache, "scan-cache", "", "Reuse assessments of unchanged items from this file between runs")
+	flags.DurationVar(&commandTimeout, "command-timeout", 30*time.Second, "Kill external commands that run longer than this (0 disables)")
+	flags.DurationVar(&sigTimeout, "signature-timeout", 10*time.Second, "Record a program's signature check as timed out after this long (0 disables)")
+	flags.DurationVar(&totalTimeout, "total-timeout", 0, "Stop the whole scan after this long, reporting what was completed (0 disables)")
+	flags.DurationVar(&collectTimeout, "collect-timeout", 0, "Stop collection after this long and assess what was collected (0 disables)")
+	flags.DurationVar(&assessTimeout, "assess-timeout", 0, "Stop hashing, enrichment, and scoring after this long (0 disables)")
+	flags.StringVar(&suppressions, "suppressions", filepath.Join(stateDir(), "suppressions.json"), "Hide items listed in this suppression file")
+	flags.StringVar(&fleetDBPath, "fleet-db", "", "Fleet prevalence database for rarity scoring")
+	flags.StringVar(&rulesPath, "rules", defaultRulesPath(), "Rule bundle installed by update-rules (empty disables)")
+	flags.StringVar(&rulesKey, "rules-public-key", "", "Base64 Ed25519 public key the rule bundle's signature is checked with")
+	flags.StringVar(&jamfURL, "jamf", "", "Flag configuration profiles and login items this Jamf Pro server does not manage on this Mac (credentials from JAMF_CLIENT_ID/JAMF_CLIENT_SECRET or JAMF_USER/JAMF_PASSWORD)")
+	flags.IntVar(&certAgeDays, "cert-age-days", 30, "Flag Developer ID certificates issued within this many days")
+	flags.StringVar(&scoringModel, "scoring-model", risk.ModelWeightedAverage, "Risk scoring model (weighted-average, max-score, bayesian)")
+	addHeuristicFlags(flags)
+	flags.BoolVar(&offline, "offline", false, "Stay off the network: skip Gatekeeper assessments and the Jamf Pro cross-check")
+}
+
+// addHeuristicFlags registers the flags that turn individual heuristics off
+// or override their confidence.
+func addHeuristicFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&disabledHeurs, "disable-heuristic", nil, "Do not run these heuristics (e.g. name_entropy,dock_anomaly); repeatable")
+	flags.Var(&heurConfidence, "heuristic-confidence", "Give a heuristic's results this confidence from 0 to 1 (e.g. certificate_age=0.5); repeatable")
+}
+
+func runScan(cmd *cobra.Command, args []string) (err error) {
+	// An interrupt stops the scan but still reports what was collected; a
+	// second one exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Every return writes the summary, failures with exit code 1; os.Exit
+	// skips this, so a nonzero scan exit code writes its own
+	var result *scanner.ScanResult
+	defer func() {
+		if dryRun {
+			return
+		}
+		code := 0
+		if err != nil {
+			code = 1
+		}
+		writeSummaryFile(result, code, err)
+	}()
 
-	// Initialize scanners
-	scanners := []scanner.Scanner{
-		collectors.NewLaunchAgentScanner(),
-		collectors.NewLaunchDaemonScanner(),
-		collectors.NewLoginItemsScanner(),
-		collectors.NewConfigProfilesScanner(),
-		collectors.NewCronScanner(),
-		collectors.NewPeriodicScanner(),
-		collectors.NewLoginHooksScanner(),
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
 	}
+	applyConfig(cmd, cfg)
 
-	// Initialize heuristics
-	heuristicsList := []risk.Heuristic{
-		heuristics.NewSignatureHeuristic(),
-		heuristics.NewPathHeuristic(),
-		heuristics.NewBehaviorHeuristic(),
-		heuristics.NewEntropyHeuristic(),
+	if quiet && summaryOnly {
+		return fmt.Errorf("--quiet and --summary cannot be used together")
 	}
 
-	// Create risk engine
-	riskEngine := risk.NewEngine(heuristicsList)
+	specs, err := parseOutputSpecs(outputFormats)
+	if err != nil {
+		return err
+	}
+	if err := loadOutputKeys(specs); err != nil {
+		return err
+	}
+	if quiet || summaryOnly {
+		specs = withSummaryPrimary(specs)
+	}
+	if err := newTableFormatter().Validate(); err != nil {
+		return err
+	}
+	alertSinks, threshold, err := parseSinks()
+	if err != nil {
+		return err
+	}
+	filter, err := parseFilter()
+	if err != nil {
+		return err
+	}
 
-	// Create orchestrator
-	orchestrator := scanner.NewOrchestrator(scanners, parallel)
+	if dryRun {
+		return printDryRun(cmd.OutOrStdout(), dryRunFootprint(specs), specs)
+	}
 
 	// Run scan
-	if verbose {
+	if verbose && !quiet {
 		fmt.Println("Starting scan...")
 	}
 
-	result, err := orchestrator.RunScan(ctx)
+	result, err = performScan(ctx)
 	if err != nil {
-		return fmt.Errorf("scan failed: %w", err)
+		return err
 	}
 
-	// Assess risk for each item
-	for i := range result.Items {
-		result.Items[i].Risk = riskEngine.AssessRisk(&result.Items[i])
+	// Filters narrow what is reported; history still records every item,
+	// so a targeted check does not show the rest as removed
+	full := result
+	if !filter.Empty() {
+		result = full.Select(filter)
 	}
 
-	// Format output
-	formatter := output.GetFormatter(output.FormatterType(outputFormat))
-	if jsonFormatter, ok := formatter.(*output.JSONFormatter); ok && outputFormat == "json" {
-		jsonFormatter.Pretty = true
+	// Format and write output
+	if err := writeOutputs(result, specs); err != nil {
+		return err
 	}
 
-	outputData, err := formatter.Format(result)
+	// The previous scan is the baseline paging sinks compare against
+	baseline, err := latestScan()
 	if err != nil {
-		return fmt.Errorf("failed to format output: %w", err)
+		logging.Warn("loading baseline scan", err)
+	}
+	if baseline != nil && baseline.Root != result.Root {
+		baseline = nil
 	}
 
-	// Write output
-	fmt.Print(string(outputData))
+	// A partial scan would show every missing item as removed in history
+	if result.Partial {
+		slog.Warn("scan interrupted; not recording partial results")
+	} else if err := saveResult(full); err != nil {
+		return err
+	}
 
-	// Set exit code based on findings
-	if result.RiskSummary[scanner.RiskCritical] > 0 {
-		os.Exit(3)
-	} else if result.RiskSummary[scanner.RiskHigh] > 0 {
-		os.Exit(2)
-	} else if result.RiskSummary[scanner.RiskMedium] > 0 {
-		os.Exit(1)
+	if err := sinks.Notify(alertSinks, plaintextResult(result), threshold, baseline); err != nil {
+		logging.Warn("sending alerts", err)
+	}
+
+	// Diagnostics go to stderr so they never mix with formatted output
+	if verbose && !quiet {
+		printDiagnostics(os.Stderr, full)
+	}
+
+	if code := exitCode(result); code != 0 {
+		writeSummaryFile(result, code, nil)
+		os.Exit(code)
+	}
+
+	return nil
+}
+
+// exitCode reports a scan's outcome: 4 if it was interrupted, otherwise
+// 3, 2, or 1 for the highest of critical, high, or medium risk found.
+func exitCode(result *scanner.ScanResult) int {
+	switch {
+	case result.Partial:
+		return 4
+	case result.RiskSummary[scanner.RiskCritical] > 0:
+		return 3
+	case result.RiskSummary[scanner.RiskHigh] > 0:
+		return 2
+	case result.RiskSummary[scanner.RiskMedium] > 0:
+		return 1
+	}
+	return 0
+}
+
+// writeSummaryFile writes the --summary-file record of a scan, if one was
+// requested. Failing to write it does not fail the scan.
+func writeSummaryFile(result *scanner.ScanResult, code int, scanErr error) {
+	if summaryFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(output.NewRunSummary(result, code, scanErr), "", "  ")
+	if err == nil {
+		err = os.WriteFile(summaryFile, append(data, '\n'), 0600)
+	}
+	if err != nil {
+		logging.Warn("writing summary file", err, "path", summaryFile)
+	}
+}
+
+// performScan runs a scan configured by the scan flags and returns the
+// assessed result.
+func performScan(ctx context.Context) (*scanner.ScanResult, error) {
+	return persistscan.Scan(ctx, scanOptions())
+}
+
+// scanOptions converts the scan flags into library options.
+func scanOptions() persistscan.Options {
+	opts := persistscan.Options{
+		Parallel:            parallel,
+		Concurrency:         concurrency,
+		Timeout:             scanTimeout,
+		ScannerTimeouts:     make(map[scanner.MechanismType]time.Duration),
+		CommandTimeout:      commandTimeout,
+		SignatureTimeout:    sigTimeout,
+		TotalTimeout:        totalTimeout,
+		CollectTimeout:      collectTimeout,
+		AssessTimeout:       assessTimeout,
+		Root:                rootPath,
+		NoExec:              noExec,
+		ScoringModel:        scoringModel,
+		RiskThresholds:      riskThresholds,
+		CertificateAge:      time.Duration(certAgeDays) * 24 * time.Hour,
+		FleetDB:             fleetDBPath,
+		SigningCache:        signingCache,
+		ScanCache:           scanCache,
+		Suppressions:        suppressions,
+		Rules:               rulesPath,
+		RulesKey:            rulesKey,
+		Jamf:                jamfURL,
+		NoContent:           noContent,
+		DisabledHeuristics:  disabledHeurs,
+		HeuristicConfidence: heurConfidence,
+		Offline:             offline,
+	}
+	for mechanism, timeout := range scannerTimeouts {
+		opts.ScannerTimeouts[scanner.MechanismType(mechanism)] = timeout
+	}
+	return opts
+}
+
+// confidenceFlag collects heuristic=confidence overrides.
+type confidenceFlag map[string]float64
+
+func (f *confidenceFlag) String() string {
+	var overrides []string
+	for name, confidence := range *f {
+		overrides = append(overrides, fmt.Sprintf("%s=%g", name, confidence))
+	}
+	sort.Strings(overrides)
+	return strings.Join(overrides, ",")
+}
+
+func (f *confidenceFlag) Set(value string) error {
+	for _, override := range strings.Split(value, ",") {
+		name, number, ok := strings.Cut(override, "=")
+		if !ok {
+			return fmt.Errorf("%q is not heuristic=confidence", override)
+		}
+		confidence, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not heuristic=confidence", override)
+		}
+		if *f == nil {
+			*f = make(confidenceFlag)
+		}
+		(*f)[strings.TrimSpace(name)] = confidence
+	}
+	return nil
+}
+
+func (f *confidenceFlag) Type() string {
+	return "heuristic=confidence"
+}
+
+// applySuppressions drops items listed in the --suppressions file from
+// result.
+func applySuppressions(result *scanner.ScanResult) error {
+	return persistscan.ApplySuppressions(result, suppressions)
+}
+
+// saveResult records result in the --db results store and the --history
+// database, if set. Those databases are not encrypted, so with
+// --encrypt-to the contents of files and scripts are redacted from what
+// they record, as with --no-content.
+func saveResult(result *scanner.ScanResult) error {
+	result = plaintextResult(result)
+
+	paths := []string{dbPath}
+	if historyPath != dbPath {
+		paths = append(paths, historyPath)
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := saveTo(path, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plaintextResult returns result as it may leave the process unencrypted:
+// with --encrypt-to, a copy with file and script contents redacted, as
+// --no-content does.
+func plaintextResult(result *scanner.ScanResult) *scanner.ScanResult {
+	if len(recipients) == 0 {
+		return result
+	}
+	redacted := *result
+	redacted.Items = make([]scanner.PersistenceItem, len(result.Items))
+	for i := range result.Items {
+		redacted.Items[i] = result.Items[i].Clone()
+	}
+	redacted.RedactContent()
+	return &redacted
+}
+
+func saveTo(path string, result *scanner.ScanResult) error {
+	// Results can include script contents and usernames
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600); err == nil {
+		f.Close()
+	}
+
+	db, err := store.Open(path)
+	if err != nil {
+		return err
 	}
+	defer db.Close()
 
+	if _, err := db.Save(result); err != nil {
+		return fmt.Errorf("saving scan to %s: %w", path, err)
+	}
 	return nil
 }
 
+// parseSinks validates the --sink and --alert-threshold flags.
+func parseSinks() ([]sinks.Sink, scanner.RiskLevel, error) {
+	threshold, err := scanner.ParseRiskLevel(alertThreshold)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result []sinks.Sink
+	for _, spec := range sinkSpecs {
+		sink, err := sinks.Parse(spec)
+		if err != nil {
+			return nil, "", err
+		}
+		result = append(result, sink)
+	}
+	return result, threshold, nil
+}
+
+// parseFilter builds the item filter from the --mechanism, --label,
+// --path, and --user flags.
+func parseFilter() (*scanner.Filter, error) {
+	filter := &scanner.Filter{
+		Mechanisms: mechanismFilter,
+		Paths:      pathFilters,
+		Users:      userFilters,
+	}
+
+	for _, m := range mechanismFilter {
+		known := false
+		for _, mechanism := range scanner.Mechanisms {
+			if strings.EqualFold(m, string(mechanism)) {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown mechanism %q (see list-scanners)", m)
+		}
+	}
+	for _, pattern := range pathFilters {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid --path pattern %q: %w", pattern, err)
+		}
+	}
+	if labelFilter != "" {
+		var err error
+		if filter.Label, err = regexp.Compile(labelFilter); err != nil {
+			return nil, fmt.Errorf("invalid --label expression: %w", err)
+		}
+	}
+
+	return filter, nil
+}
+
+// applyConfig copies configuration file values into any flags the user did
+// not set explicitly on the command line.
+func applyConfig(cmd *cobra.Command, cfg *config.Config) {
+	flags := cmd.Flags()
+	if !flags.Changed("parallel") {
+		parallel = cfg.Scan.Parallel
+	}
+	if !flags.Changed("output") {
+		outputFormats = []string{cfg.Output.Format}
+		if flags.Changed("template") {
+			outputFormats = []string{string(output.FormatterTemplate)}
+		}
+	}
+	if !flags.Changed("signing-cache") {
+		signingCache = cfg.Scan.SigningCache
+	}
+	if !flags.Changed("no-exec") && cfg.Scan.NoExec {
+		noExec = true
+	}
+	if !flags.Changed("offline") && cfg.Scan.Offline {
+		offline = true
+	}
+	if !flags.Changed("scan-cache") {
+		scanCache = cfg.Scan.ScanCache
+	}
+	if !flags.Changed("suppressions") && cfg.Scan.Suppressions != "" {
+		suppressions = cfg.Scan.Suppressions
+	}
+	if !flags.Changed("rules") && cfg.Rules.Path != "" {
+		rulesPath = cfg.Rules.Path
+	}
+	if !flags.Changed("rules-public-key") && cfg.Rules.PublicKey != "" {
+		rulesKey = cfg.Rules.PublicKey
+	}
+	if !flags.Changed("jamf") && cfg.Jamf.URL != "" {
+		jamfURL = cfg.Jamf.URL
+	}
+	if !flags.Changed("history") && cfg.Scan.History != "" {
+		historyPath = cfg.Scan.History
+	}
+	if !flags.Changed("concurrency") {
+		concurrency = cfg.Scan.Concurrency
+	}
+	if !flags.Changed("timeout") {
+		scanTimeout = time.Duration(cfg.Scan.Timeout) * time.Second
+	}
+	if !flags.Changed("command-timeout") {
+		commandTimeout = time.Duration(cfg.Scan.CommandTimeout) * time.Second
+	}
+	if !flags.Changed("signature-timeout") {
+		sigTimeout = time.Duration(cfg.Scan.SignatureTimeout) * time.Second
+	}
+	if !flags.Changed("total-timeout") {
+		totalTimeout = time.Duration(cfg.Scan.TotalTimeout) * time.Second
+	}
+	if !flags.Changed("collect-timeout") {
+		collectTimeout = time.Duration(cfg.Scan.CollectTimeout) * time.Second
+	}
+	if !flags.Changed("assess-timeout") {
+		assessTimeout = time.Duration(cfg.Scan.AssessTimeout) * time.Second
+	}
+	scannerTimeouts = make(map[string]time.Duration)
+	for mechanism, seconds := range cfg.Scan.ScannerTimeouts {
+		scannerTimeouts[mechanism] = time.Duration(seconds) * time.Second
+	}
+	if !flags.Changed("scoring-model") {
+		scoringModel = cfg.Risk.Model
+	}
+	if !flags.Changed("disable-heuristic") {
+		disabledHeurs = cfg.Risk.DisabledHeuristics
+	}
+	if !flags.Changed("heuristic-confidence") {
+		heurConfidence = cfg.Risk.HeuristicConfidence
+	}
+	riskThresholds = scanner.RiskThresholds(cfg.Risk.Thresholds)
+	if !flags.Changed("no-content") {
+		noContent = cfg.Output.NoContent
+	}
+	if !flags.Changed("sign-key") && cfg.Output.SignKey != "" {
+		signKeyPath = cfg.Output.SignKey
+	}
+	if !flags.Changed("encrypt-to") && len(cfg.Output.EncryptTo) > 0 {
+		encryptTo = cfg.Output.EncryptTo
+	}
+	prettyJSON = cfg.Output.PrettyJSON
+}
+
 func versionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/merge"
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func mergeCmd() *cobra.Command {
+	var (
+		format  string
+		minRisk string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "merge <result.json>...",
+		Short: "Consolidate per-host JSON results into one fleet report",
+		Long: `Combine JSON scan results from many hosts into one report, listing each
+finding with the hosts that have it and each host with its findings.
+
+Every finding carries its fleet prevalence: how many of the merged hosts
+have it, counted the way "fleet build" and the rarity heuristic count
+them, so the same agent installed for different users is one finding.
+Findings below --min-risk are left out of both lists but still counted.
+Of several results from the same host, only the newest scan is merged.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown merge format %q (valid: table, json)", format)
+			}
+			level, err := scanner.ParseRiskLevel(minRisk)
+			if err != nil {
+				return err
+			}
+
+			merger := merge.New()
+			// The file each host's newest result came from
+			paths := make(map[string]string)
+			for _, path := range args {
+				result, err := scanner.LoadResult(path)
+				if err != nil {
+					return err
+				}
+
+				host := result.Hostname
+				if host == "" {
+					host = path
+				}
+				skipped, kept := path, paths[host]
+				if merger.Add(host, result) {
+					skipped, kept = paths[host], path
+					paths[host] = path
+				}
+				if skipped != "" {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %s: keeping the newest scan of host %s, in %s\n", skipped, host, kept)
+				}
+			}
+
+			formatter := &output.MergeFormatter{JSON: format == "json"}
+			data, err := formatter.Format(merger.Report(level))
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(cmd.OutOrStdout(), string(data))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().StringVar(&minRisk, "min-risk", "low", "Lowest risk level to list (info, low, medium, high, critical)")
+
+	return cmd
+}
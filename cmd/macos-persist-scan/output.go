@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"github.com/fatih/color"
+	"github.com/haasonsaas/macos-persist-scan/internal/encrypt"
+	"github.com/haasonsaas/macos-persist-scan/internal/signing"
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// parseOutputSpecs validates the --output values. At most one output may go
+// to the primary destination; the rest must name their own file.
+func parseOutputSpecs(values []string) ([]output.Spec, error) {
+	var specs []output.Spec
+	primary := 0
+
+	for _, value := range values {
+		spec, err := output.ParseSpec(value)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Path == "" {
+			primary++
+		}
+		specs = append(specs, spec)
+	}
+
+	if primary > 1 {
+		return nil, fmt.Errorf("only one output format may be written to stdout or --output-file; use format=path for the others")
+	}
+
+	return specs, nil
+}
+
+// withSummaryPrimary replaces the primary output with the summary format,
+// keeping any outputs that are written to their own files.
+func withSummaryPrimary(specs []output.Spec) []output.Spec {
+	summary := []output.Spec{{Format: output.FormatterSummary}}
+	for _, spec := range specs {
+		if spec.Path != "" {
+			summary = append(summary, spec)
+		}
+	}
+	return summary
+}
+
+var (
+	// signingKey signs every output written to a file when --sign-key is
+	// set.
+	signingKey ed25519.PrivateKey
+	// recipients are who every output written to a file is encrypted to
+	// when --encrypt-to is set.
+	recipients []age.Recipient
+)
+
+// loadOutputKeys reads the --sign-key key and the --encrypt-to recipients,
+// if set, and checks that specs write at least one output to a file for
+// them to apply to. It runs before the scan, so a bad key does not waste
+// one.
+func loadOutputKeys(specs []output.Spec) error {
+	if signKeyPath == "" && len(encryptTo) == 0 {
+		return nil
+	}
+	if signKeyPath != "" {
+		data, err := os.ReadFile(signKeyPath)
+		if err != nil {
+			return fmt.Errorf("reading signing key: %w", err)
+		}
+		if signingKey, err = signing.ParsePrivateKey(data); err != nil {
+			return err
+		}
+	}
+	if len(encryptTo) > 0 {
+		var err error
+		if recipients, err = encrypt.ParseRecipients(encryptTo); err != nil {
+			return err
+		}
+	}
+	for _, spec := range specs {
+		if spec.Path != "" || outputFile != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("--sign-key and --encrypt-to apply to output files; write the results with -o format=path or --output-file")
+}
+
+// writeOutputs renders result in every requested format.
+func writeOutputs(result *scanner.ScanResult, specs []output.Spec) error {
+	for _, spec := range specs {
+		if err := writeOutput(result, spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeOutput(result *scanner.ScanResult, spec output.Spec) error {
+	formatter, err := newFormatter(spec.Format)
+	if err != nil {
+		return err
+	}
+	switch f := formatter.(type) {
+	case *output.JSONFormatter:
+		f.Pretty = prettyJSON
+		f.Fields = outputFields
+	case *output.NDJSONFormatter:
+		f.Fields = outputFields
+	}
+
+	path := spec.Path
+	if path == "" {
+		path = outputFile
+	}
+
+	// Files are never colored, even when stdout is a terminal
+	if path != "" {
+		if len(recipients) > 0 {
+			path += encrypt.Extension
+		}
+		saved := color.NoColor
+		color.NoColor = true
+		defer func() { color.NoColor = saved }()
+	}
+
+	// Line-oriented formats are written item by item rather than
+	// rendered in memory first, which matters for very large scans
+	if stream, ok := formatter.(output.StreamFormatter); ok {
+		if err := writeStream(stream, result, path); err != nil {
+			return fmt.Errorf("writing %s output: %w", spec.Format, err)
+		}
+		return signOutput(path)
+	}
+
+	data, err := formatter.Format(result)
+	if err != nil {
+		return fmt.Errorf("failed to format %s output: %w", spec.Format, err)
+	}
+
+	if path == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+
+	out, err := createOutput(path)
+	if err == nil {
+		if _, err = out.Write(data); err != nil {
+			out.Close()
+		} else {
+			err = out.Close()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s output to %s: %w", spec.Format, path, err)
+	}
+	return signOutput(path)
+}
+
+// createOutput creates the output file at path, encrypted to the
+// --encrypt-to recipients if any are set.
+func createOutput(path string) (io.WriteCloser, error) {
+	if len(recipients) > 0 {
+		return encrypt.Create(path, recipients)
+	}
+	// Results can include script contents and usernames
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+}
+
+// signOutput writes a detached signature of the output file at path when
+// --sign-key is set. Output to stdout is not signed; encrypted output is
+// signed as written, so it can be verified without decrypting it.
+func signOutput(path string) error {
+	if signingKey == nil || path == "" {
+		return nil
+	}
+	return signing.SignFile(path, signingKey)
+}
+
+func writeStream(formatter output.StreamFormatter, result *scanner.ScanResult, path string) error {
+	if path == "" {
+		out := bufio.NewWriter(os.Stdout)
+		if err := formatter.FormatStream(out, result, output.SliceItems(result.Items)); err != nil {
+			return err
+		}
+		return out.Flush()
+	}
+
+	file, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(file)
+	if err := formatter.FormatStream(out, result, output.SliceItems(result.Items)); err != nil {
+		file.Close()
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func newFormatter(format output.FormatterType) (output.Formatter, error) {
+	if format == output.FormatterTemplate {
+		if templatePath == "" {
+			return nil, fmt.Errorf("the template output format requires --template")
+		}
+		return output.NewTemplateFormatter(templatePath)
+	}
+	if format == output.FormatterSummary {
+		return &output.SummaryFormatter{Quiet: quiet}, nil
+	}
+	if format == output.FormatterTable {
+		return newTableFormatter(), nil
+	}
+	return output.GetFormatter(format), nil
+}
+
+func newTableFormatter() *output.TableFormatter {
+	return &output.TableFormatter{
+		Columns:  tableColumns,
+		Layout:   tableLayout,
+		SortBy:   tableSort,
+		MaxWidth: tableWidth,
+		GroupBy:  tableGroupBy,
+		MaxItems: maxItems,
+		Page:     tablePage,
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/preflight"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
+	"github.com/spf13/cobra"
+)
+
+func preflightCmd() *cobra.Command {
+	var format string
+	var root string
+
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check whether a scan will have full coverage",
+		Long: `Check whether this process runs as root and has Full Disk Access, and
+list exactly which mechanisms a scan would see only partially without them.
+Every path each collector reads is probed.
+
+Exits 0 when coverage is complete and 1 otherwise.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown format %q (valid: table, json)", format)
+			}
+			sysroot.Root = root
+
+			report := preflight.Run(persistscan.Scanners())
+
+			out := cmd.OutOrStdout()
+			if format == "json" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(data))
+			} else {
+				printPreflight(out, report)
+			}
+
+			// Incomplete coverage is a result, not a usage mistake
+			if !report.Complete() {
+				cmd.SilenceUsage = true
+				return errors.New("coverage is incomplete")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().StringVar(&root, "root", "", "Check access to an offline system mounted at this path")
+
+	return cmd
+}
+
+func printPreflight(out io.Writer, report *preflight.Report) {
+	yesNo := map[bool]string{true: "yes", false: "no"}
+	fmt.Fprintf(out, "Running as root:  %s\n", yesNo[report.Root])
+	fmt.Fprintf(out, "Full Disk Access: %s\n\n", report.FullDiskAccess)
+
+	for _, m := range report.Mechanisms {
+		if m.Complete() {
+			fmt.Fprintf(out, "%s: complete\n", m.Mechanism)
+			continue
+		}
+		fmt.Fprintf(out, "%s: incomplete\n", m.Mechanism)
+		for _, path := range m.Denied {
+			fmt.Fprintf(out, "  cannot read %s\n", path)
+		}
+		for _, privilege := range m.Missing {
+			fmt.Fprintf(out, "  needs %s\n", privilege)
+		}
+	}
+
+	if report.FullDiskAccess == preflight.AccessDenied {
+		fmt.Fprintln(out, "\nGrant Full Disk Access to the terminal or binary in System Settings > Privacy & Security.")
+	}
+}
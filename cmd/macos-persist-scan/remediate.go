@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/remediate"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func remediateCmd() *cobra.Command {
+	var action string
+	var resultsPath string
+	var quarantineDir string
+	var undoLog string
+	var apply bool
+	var assumeYes bool
+
+	cmd := &cobra.Command{
+		Use:   "remediate <item-id-or-path>...",
+		Short: "Unload, disable, or quarantine persistence items",
+		Long: `Respond to findings by unloading a launchd job (launchctl bootout),
+disabling it (launchctl disable), or moving its file into quarantine.
+
+Items are selected by ID or path from --results, or from a fresh scan.
+Without --apply only a preview of the changes is printed. Applied
+remediations are recorded in an undo log; see "remediate undo".`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeFindingIDs(&resultsPath, false),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+
+			var result *scanner.ScanResult
+			if resultsPath != "" {
+				result, err = scanner.LoadResult(resultsPath)
+			} else {
+				result, err = performScan(context.Background())
+			}
+			if err != nil {
+				return err
+			}
+			if result.Root != "" {
+				return fmt.Errorf("scan of offline root %s cannot be remediated", result.Root)
+			}
+
+			var plans []*remediate.Plan
+			for _, arg := range args {
+				item, err := selectItem(result, arg)
+				if err != nil {
+					return err
+				}
+				plan, err := remediate.NewPlan(*item, remediate.Action(action), quarantineDir)
+				if err != nil {
+					return err
+				}
+				plans = append(plans, plan)
+			}
+
+			out := cmd.OutOrStdout()
+			for _, plan := range plans {
+				fmt.Fprintf(out, "%s %s (%s)\n", plan.Action, plan.Item.Label, plan.Item.Path)
+				for _, line := range plan.Describe() {
+					fmt.Fprintf(out, "  %s\n", line)
+				}
+			}
+
+			if !apply {
+				fmt.Fprintln(out, "\nDry run: no changes made. Re-run with --apply to make these changes.")
+				return nil
+			}
+			if !assumeYes && !confirm(cmd.InOrStdin(), out, fmt.Sprintf("Apply %d remediation(s)?", len(plans))) {
+				fmt.Fprintln(out, "Aborted.")
+				return nil
+			}
+
+			for _, plan := range plans {
+				entry, err := plan.Execute()
+				if err != nil {
+					return fmt.Errorf("remediating %s: %w", plan.Item.Path, err)
+				}
+				if err := remediate.AppendLog(undoLog, entry); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "Applied %s to %s (undo with: remediate undo %s)\n", plan.Action, plan.Item.Path, entry.ID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&action, "action", "", "Remediation to apply (unload, disable, quarantine)")
+	cmd.Flags().StringVarP(&resultsPath, "results", "r", "", "Select items from this JSON scan result instead of scanning")
+	cmd.Flags().StringVar(&quarantineDir, "quarantine-dir", filepath.Join(stateDir(), "quarantine"), "Directory quarantined files are moved into")
+	cmd.PersistentFlags().StringVar(&undoLog, "undo-log", filepath.Join(stateDir(), "remediation.log"), "Log of applied remediations")
+	cmd.Flags().BoolVar(&apply, "apply", false, "Make the changes instead of previewing them")
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Do not ask for confirmation (requires --apply)")
+	cmd.MarkFlagRequired("action")
+	addScanFlags(cmd.Flags())
+
+	cmd.AddCommand(remediateUndoCmd(&undoLog))
+	cmd.AddCommand(remediateLogCmd(&undoLog))
+	return cmd
+}
+
+func remediateUndoCmd(undoLog *string) *cobra.Command {
+	var assumeYes bool
+
+	cmd := &cobra.Command{
+		Use:   "undo <remediation-id>",
+		Short: "Reverse a remediation recorded in the undo log",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := remediate.ReadLog(*undoLog)
+			if err != nil {
+				return err
+			}
+
+			var entry *remediate.LogEntry
+			for i := range entries {
+				if entries[i].ID == args[0] {
+					entry = &entries[i]
+				}
+			}
+			if entry == nil {
+				return fmt.Errorf("remediation %s not found in %s", args[0], *undoLog)
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Undo %s of %s (%s)\n", entry.Action, entry.Label, entry.Path)
+			if entry.QuarantinedAt != "" {
+				fmt.Fprintf(out, "  move: %s -> %s\n", entry.QuarantinedAt, entry.Path)
+			}
+			for _, c := range entry.Undo {
+				fmt.Fprintf(out, "  run: %s\n", strings.Join(c, " "))
+			}
+			if !assumeYes && !confirm(cmd.InOrStdin(), out, "Proceed?") {
+				fmt.Fprintln(out, "Aborted.")
+				return nil
+			}
+
+			if err := remediate.Undo(entry); err != nil {
+				return err
+			}
+			return remediate.MarkUndone(*undoLog, entry.ID)
+		},
+	}
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Do not ask for confirmation")
+
+	return cmd
+}
+
+func remediateLogCmd(undoLog *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "log",
+		Short: "List applied remediations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := remediate.ReadLog(*undoLog)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for _, entry := range entries {
+				status := ""
+				if entry.UndoneAt != nil {
+					status = " (undone)"
+				}
+				fmt.Fprintf(out, "%s  %s  %-10s %s%s\n", entry.ID, entry.Time.Format("2006-01-02 15:04"), entry.Action, entry.Path, status)
+			}
+			return nil
+		},
+	}
+}
+
+// selectItem finds the single item whose ID or path is ref.
+func selectItem(result *scanner.ScanResult, ref string) (*scanner.PersistenceItem, error) {
+	var matches []*scanner.PersistenceItem
+	for i := range result.Items {
+		if result.Items[i].ID == ref || result.Items[i].Path == ref {
+			matches = append(matches, &result.Items[i])
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no item with ID or path %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%q matches %d items; use the item ID", ref, len(matches))
+	}
+}
+
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// stateDir is where the tool keeps local state such as the undo log.
+func stateDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".macos-persist-scan"
+	}
+	return filepath.Join(home, ".macos-persist-scan")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
+	"github.com/haasonsaas/macos-persist-scan/pkg/risk"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func rescoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rescore <results.json>",
+		Short: "Re-run risk scoring over a saved JSON scan result",
+		Long: `Re-run the risk heuristics over a result saved with -o json, without
+reading the system it came from. Use it to re-analyze results collected on
+air-gapped or customer machines with a different scoring model, fleet
+database, or suppression file.
+
+Code-signing and Gatekeeper facts recorded at scan time are reused. The
+certificate age and bundle integrity checks need the original files, so
+their recorded results are kept as they were.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+
+			specs, err := parseOutputSpecs(outputFormats)
+			if err != nil {
+				return err
+			}
+			if err := loadOutputKeys(specs); err != nil {
+				return err
+			}
+
+			saved, err := scanner.LoadResult(args[0])
+			if err != nil {
+				return err
+			}
+
+			result, err := persistscan.Rescore(saved, scanOptions())
+			if err != nil {
+				return err
+			}
+			if noContent {
+				result.RedactContent()
+			}
+
+			return writeOutputs(result, specs)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringArrayVarP(&outputFormats, "output", "o", []string{"table"}, "Output format (table, json, sarif, cef, ecs, ndjson, csv, summary, bodyfile, timesketch, template); repeat as format=path to also write files")
+	cmd.RegisterFlagCompletionFunc("output", completeFormats)
+	flags.StringVar(&outputFile, "output-file", "", "Write the primary output to this file instead of stdout")
+	flags.StringVar(&signKeyPath, "sign-key", "", "Sign each output file with this Ed25519 private key, writing a detached signature to file.sig")
+	flags.StringArrayVar(&encryptTo, "encrypt-to", nil, "Encrypt each output file to this age recipient (age1...) or recipients file, writing file.age; repeatable")
+	flags.StringSliceVar(&outputFields, "fields", nil, "Limit JSON and NDJSON items to these dotted fields (e.g. id,label,risk.level,program_file.sha256)")
+	flags.StringVar(&templatePath, "template", "", "Go text/template file for the template output format")
+	flags.BoolVar(&noContent, "no-content", false, "Replace file contents and script bodies with hashes and short excerpts")
+	flags.StringVar(&suppressions, "suppressions", filepath.Join(stateDir(), "suppressions.json"), "Hide items listed in this suppression file")
+	flags.StringVar(&fleetDBPath, "fleet-db", "", "Fleet prevalence database for rarity scoring")
+	flags.StringVar(&rulesPath, "rules", defaultRulesPath(), "Rule bundle installed by update-rules (empty disables)")
+	flags.StringVar(&rulesKey, "rules-public-key", "", "Base64 Ed25519 public key the rule bundle's signature is checked with")
+	flags.StringVar(&scoringModel, "scoring-model", risk.ModelWeightedAverage, "Risk scoring model (weighted-average, max-score, bayesian)")
+	addHeuristicFlags(flags)
+
+	return cmd
+}
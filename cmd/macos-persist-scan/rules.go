@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/rules"
+	"github.com/spf13/cobra"
+)
+
+func updateRulesCmd() *cobra.Command {
+	var (
+		url        string
+		publicKey  string
+		pinVersion int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "update-rules",
+		Short: "Fetch and install a signed rule bundle",
+		Long: `Download a rule bundle of indicators of compromise and allowlisted
+software, verify its Ed25519 signature, and install it for later scans.
+
+The bundle is fetched from --url and its base64 signature from the same URL
+with .sig appended. A bundle older than the installed one is refused unless
+--pin-version names it, and a pinned update installs only that version.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if !flags.Changed("url") {
+				url = cfg.Rules.URL
+			}
+			if !flags.Changed("public-key") {
+				publicKey = cfg.Rules.PublicKey
+			}
+			if !flags.Changed("pin-version") {
+				pinVersion = cfg.Rules.PinVersion
+			}
+			if !flags.Changed("rules") && cfg.Rules.Path != "" {
+				rulesPath = cfg.Rules.Path
+			}
+
+			if url == "" {
+				return fmt.Errorf("no bundle URL: set --url or rules.url in the config file")
+			}
+			if publicKey == "" {
+				return fmt.Errorf("no public key: set --public-key or rules.public_key in the config file")
+			}
+			key, err := rules.ParsePublicKey(publicKey)
+			if err != nil {
+				return err
+			}
+
+			bundle, err := rules.Update(url, key, pinVersion, rulesPath)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Installed rule bundle version %d to %s\n", bundle.Version, rulesPath)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&url, "url", "", "URL of the rule bundle")
+	cmd.Flags().StringVar(&publicKey, "public-key", "", "Base64 Ed25519 public key the bundle must be signed with")
+	cmd.Flags().IntVar(&pinVersion, "pin-version", 0, "Install exactly this bundle version")
+	cmd.Flags().StringVar(&rulesPath, "rules", defaultRulesPath(), "Where to install the bundle")
+
+	return cmd
+}
+
+// defaultRulesPath is where update-rules installs bundles and scans read
+// them from.
+func defaultRulesPath() string {
+	return filepath.Join(stateDir(), "rules.json")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func listScannersCmd() *cobra.Command {
+	var format string
+	var root string
+
+	cmd := &cobra.Command{
+		Use:   "list-scanners",
+		Short: "List the collectors a scan runs and what each one reads",
+		Long: `List every collector with its mechanism, the exact paths and commands it
+reads, and the privileges it needs for full coverage. Per-user paths depend
+on who runs the command: as root every local account is listed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown format %q (valid: table, json)", format)
+			}
+			sysroot.Root = root
+
+			var infos []scanner.ScannerInfo
+			for _, s := range persistscan.Scanners() {
+				info := scanner.ScannerInfo{Mechanism: s.Type()}
+				if d, ok := s.(scanner.Describer); ok {
+					info = d.Info()
+				}
+				infos = append(infos, info)
+			}
+
+			out := cmd.OutOrStdout()
+			if format == "json" {
+				data, err := json.MarshalIndent(infos, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(data))
+				return nil
+			}
+
+			for i, info := range infos {
+				if i > 0 {
+					fmt.Fprintln(out)
+				}
+				fmt.Fprintf(out, "%s: %s\n", info.Mechanism, info.Description)
+				for _, section := range []struct {
+					name  string
+					lines []string
+				}{
+					{"Paths", info.Paths},
+					{"Commands", info.Commands},
+					{"Privileges", info.Privileges},
+				} {
+					if len(section.lines) == 0 {
+						continue
+					}
+					fmt.Fprintf(out, "  %s:\n", section.name)
+					for _, line := range section.lines {
+						fmt.Fprintf(out, "    %s\n", line)
+					}
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().StringVar(&root, "root", "", "List paths for an offline system mounted at this path")
+
+	return cmd
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/server"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+const tokenEnv = "MACOS_PERSIST_SCAN_TOKEN"
+
+func serveCmd() *cobra.Command {
+	var listenAddr string
+	var socketPath string
+	var token string
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run as a daemon exposing a local REST API",
+		Long: `Serve a token-authenticated HTTP API for triggering scans and querying
+results. The API listens on a loopback address or a unix socket only.
+
+Requests must send "Authorization: Bearer <token>". The token is read from
+--token or $` + tokenEnv + `; if neither is set a random token is generated and
+printed to stderr.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+
+			if token == "" {
+				token = os.Getenv(tokenEnv)
+			}
+			if token == "" {
+				if token, err = randomToken(); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "API token: %s\n", token)
+			}
+
+			listener, err := listen(listenAddr, socketPath)
+			if err != nil {
+				return err
+			}
+
+			srv := server.New(func(ctx context.Context) (*scanner.ScanResult, error) {
+				result, err := performScan(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return result, saveResult(result)
+			}, token)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if interval > 0 {
+				go scheduleScans(ctx, srv, interval)
+			}
+
+			httpServer := &http.Server{
+				Handler:           srv.Handler(),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				httpServer.Shutdown(shutdownCtx)
+			}()
+
+			fmt.Fprintf(os.Stderr, "Listening on %s\n", listener.Addr())
+			if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&listenAddr, "listen", "127.0.0.1:7731", "Loopback address to listen on")
+	cmd.Flags().StringVar(&socketPath, "socket", "", "Listen on this unix socket instead of a TCP address")
+	cmd.Flags().StringVar(&token, "token", "", "Bearer token required by the API (default $"+tokenEnv+" or random)")
+	cmd.Flags().DurationVar(&interval, "interval", 0, "Also scan on this interval, e.g. 1h (0 disables)")
+	addScanFlags(cmd.Flags())
+
+	return cmd
+}
+
+// listen opens the unix socket if one was given, otherwise the TCP address,
+// which must be a loopback address.
+func listen(addr, socketPath string) (net.Listener, error) {
+	if socketPath != "" {
+		// Remove a stale socket left by an earlier run
+		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		listener, err := net.Listen("unix", socketPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(socketPath, 0600); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		return listener, nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("refusing to listen on non-loopback address %q", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+func scheduleScans(ctx context.Context, srv *server.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, _, err := srv.RunScan(ctx); err != nil {
+			logging.Warn("scheduled scan failed", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating API token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/timemachine"
+	"github.com/haasonsaas/macos-persist-scan/pkg/diff"
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+// snapshotChange is the difference between two consecutive points in time.
+type snapshotChange struct {
+	From string       `json:"from"`
+	To   string       `json:"to"`
+	Diff *diff.Result `json:"diff"`
+}
+
+func snapshotDiffCmd() *cobra.Command {
+	var format string
+	var volume string
+	var live bool
+
+	cmd := &cobra.Command{
+		Use:   "snapshot-diff",
+		Short: "Show persistence items that appeared or changed between APFS local snapshots",
+		Long: `Mount every Time Machine local snapshot of the data volume read-only,
+scan each one, and list the persistence items added, removed, or changed
+between consecutive snapshots. This attributes an item to a point in time
+without an external baseline. With --live the newest snapshot is also
+compared with the running system.
+
+Mounting snapshots requires root. Each snapshot is collected like
+"inventory --root", without risk scoring. A snapshot of the data volume
+holds /etc and /var under /private, where they are read from, and lacks
+the System volume, so items under /System, /bin, /sbin, and /usr (other
+than /usr/local) are left out of every scan compared.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown format %q (valid: table, json)", format)
+			}
+
+			snapshots, err := timemachine.LocalSnapshots(volume)
+			if err != nil {
+				return err
+			}
+
+			var names []string
+			var results []*scanner.ScanResult
+			for _, snapshot := range snapshots {
+				result, err := scanSnapshot(volume, snapshot)
+				if err != nil {
+					return err
+				}
+				names = append(names, snapshot.Name)
+				results = append(results, result)
+			}
+			if live {
+				opts := scanOptions()
+				opts.Inventory = true
+				opts.Suppressions = ""
+				result, err := persistscan.Scan(context.Background(), opts)
+				if err != nil {
+					return err
+				}
+				names = append(names, "live system")
+				results = append(results, result)
+			}
+
+			for _, result := range results {
+				timemachine.DropSystemVolume(result)
+			}
+
+			var changes []snapshotChange
+			for i := 1; i < len(results); i++ {
+				changes = append(changes, snapshotChange{
+					From: names[i-1],
+					To:   names[i],
+					Diff: diff.Compare(results[i-1], results[i]),
+				})
+			}
+
+			out := cmd.OutOrStdout()
+			if format == "json" {
+				data, err := json.MarshalIndent(changes, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(data))
+				return nil
+			}
+
+			if len(changes) == 0 {
+				fmt.Fprintf(out, "Only one snapshot (%s); nothing to compare\n", names[0])
+				return nil
+			}
+			formatter := &output.DiffFormatter{}
+			for i, change := range changes {
+				if i > 0 {
+					fmt.Fprintln(out)
+				}
+				fmt.Fprintf(out, "== %s -> %s\n", change.From, change.To)
+				data, err := formatter.Format(change.Diff)
+				if err != nil {
+					return err
+				}
+				fmt.Fprint(out, string(data))
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+	flags.StringVar(&volume, "volume", timemachine.DataVolume, "APFS volume whose local snapshots are compared")
+	flags.BoolVar(&live, "live", false, "Also compare the newest snapshot with the running system")
+	flags.BoolVarP(&parallel, "parallel", "p", true, "Run scanners in parallel")
+	flags.IntVar(&concurrency, "concurrency", 0, "Maximum scanners, directories per scanner, and signature checks processed at once (0 = number of CPUs)")
+	flags.DurationVar(&scanTimeout, "timeout", 60*time.Second, "Give up on any scanner that runs longer than this (0 disables)")
+
+	return cmd
+}
+
+// scanSnapshot mounts snapshot, collects its persistence items, and
+// unmounts it again.
+func scanSnapshot(volume string, snapshot timemachine.Snapshot) (*scanner.ScanResult, error) {
+	dir, unmount, err := timemachine.Mount(volume, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := unmount(); err != nil {
+			logging.Warn("unmounting snapshot", err, "snapshot", snapshot.Name)
+		}
+	}()
+
+	opts := scanOptions()
+	opts.Root = dir
+	opts.Inventory = true
+	opts.Suppressions = ""
+	result, err := persistscan.Scan(context.Background(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("scanning snapshot %s: %w", snapshot.Name, err)
+	}
+	// Report when the snapshot was taken rather than when it was scanned
+	result.StartTime = snapshot.Time
+	result.Root = ""
+	return result, nil
+}
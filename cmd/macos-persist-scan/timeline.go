@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func timelineCmd() *cobra.Command {
+	var (
+		resultsPath string
+		format      string
+		since       string
+		until       string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "timeline",
+		Short: "List every item's timestamps in chronological order",
+		Long: `Flatten the timestamps of every collected item into one chronological
+timeline: file creation and modification times, configuration profile
+install dates, program birth times, and the quarantine times macOS records
+when a file is downloaded. Reading the timeline oldest first shows the
+order persistence was installed in, and which items arrived together.
+
+Items are read from --results, or else from the most recent scan in the
+history database, or else from a fresh scan. -o json writes the events as
+Timesketch JSONL, as the timesketch output format does.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown timeline format %q (valid: table, json)", format)
+			}
+			formatter := &output.TimelineFormatter{Text: format == "table"}
+			var err error
+			if formatter.Since, err = parseTimelineTime(since, false); err != nil {
+				return fmt.Errorf("invalid --since %q: use YYYY-MM-DD or RFC 3339", since)
+			}
+			if formatter.Until, err = parseTimelineTime(until, true); err != nil {
+				return fmt.Errorf("invalid --until %q: use YYYY-MM-DD or RFC 3339", until)
+			}
+
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+
+			var result *scanner.ScanResult
+			switch {
+			case resultsPath != "":
+				result, err = scanner.LoadResult(resultsPath)
+			default:
+				result, err = latestScan()
+				if err == nil && result == nil {
+					result, err = performScan(context.Background())
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			data, err := formatter.Format(result)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(cmd.OutOrStdout(), string(data))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&resultsPath, "results", "r", "", "Build the timeline from this JSON scan result")
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().StringVar(&since, "since", "", "Leave out events before this time (YYYY-MM-DD or RFC 3339)")
+	cmd.Flags().StringVar(&until, "until", "", "Leave out events after this time (YYYY-MM-DD or RFC 3339)")
+	addScanFlags(cmd.Flags())
+
+	return cmd
+}
+
+// parseTimelineTime reads an RFC 3339 time or a UTC date, taken as the
+// start of the day, or its end when endOfDay is set so that --until
+// includes the day given. An empty value is the zero time, which sets no
+// limit.
+func parseTimelineTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
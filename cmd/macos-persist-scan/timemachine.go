@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/timemachine"
+	"github.com/haasonsaas/macos-persist-scan/pkg/persistscan"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func timeMachineCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "timemachine [destination]",
+		Short: "Scan Time Machine backups to find when items first appeared",
+		Long: `Scan the persistence locations inside every Time Machine backup and
+report, for each item found, the first and last backup containing it. Use
+it to establish when a malicious item was installed.
+
+The destination may be a machine directory in Backups.backupdb, an APFS
+backup destination, or a single backup. Without one, the backups tmutil
+lists for this Mac are scanned. Each backup is collected like "inventory
+--root", without risk scoring.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown format %q (valid: table, json)", format)
+			}
+
+			var dest string
+			if len(args) > 0 {
+				dest = args[0]
+			}
+			backups, err := timemachine.List(dest)
+			if err != nil {
+				return err
+			}
+
+			results := make([]*scanner.ScanResult, len(backups))
+			for i, backup := range backups {
+				opts := scanOptions()
+				opts.Root = backup.Root
+				opts.Inventory = true
+				opts.Suppressions = ""
+				if results[i], err = persistscan.Scan(context.Background(), opts); err != nil {
+					return fmt.Errorf("scanning backup %s: %w", backup.Path, err)
+				}
+				timemachine.DropSystemVolume(results[i])
+			}
+			sightings := timemachine.Sightings(backups, results)
+
+			out := cmd.OutOrStdout()
+			if format == "json" {
+				data, err := json.MarshalIndent(struct {
+					Backups []timemachine.Backup   `json:"backups"`
+					Items   []timemachine.Sighting `json:"items"`
+				}{backups, sightings}, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(data))
+				return nil
+			}
+
+			fmt.Fprintf(out, "Scanned %d backups from %s to %s\n\n", len(backups), backups[0].Time.Format("2006-01-02 15:04"), backups[len(backups)-1].Time.Format("2006-01-02 15:04"))
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "FIRST SEEN\tLAST SEEN\tBACKUPS\tMECHANISM\tLABEL\tPATH")
+			for _, s := range sightings {
+				backupsCol := fmt.Sprintf("%d/%d", s.Backups, len(backups))
+				if s.Gaps {
+					backupsCol += " (gaps)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.FirstSeen.Format("2006-01-02 15:04"), s.LastSeen.Format("2006-01-02 15:04"), backupsCol, s.Mechanism, s.Label, s.Path)
+			}
+			return w.Flush()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+	flags.BoolVarP(&parallel, "parallel", "p", true, "Run scanners in parallel")
+	flags.IntVar(&concurrency, "concurrency", 0, "Maximum scanners, directories per scanner, and signature checks processed at once (0 = number of CPUs)")
+	flags.DurationVar(&scanTimeout, "timeout", 60*time.Second, "Give up on any scanner that runs longer than this (0 disables)")
+
+	return cmd
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/suppress"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func triageCmd() *cobra.Command {
+	var resultsPath string
+	var minRisk string
+	var defaultExpiry string
+
+	cmd := &cobra.Command{
+		Use:   "triage",
+		Short: "Review untriaged findings and record decisions in the suppression file",
+		Long: `Walk through findings that have no entry in the suppression file, most
+severe first, and record a decision on each:
+
+  expected     known-good software; hidden from later scans
+  suppress     accepted noise; hidden from later scans
+  investigate  kept in scan results, but not asked about again
+
+Each decision is written to --suppressions as it is made, with a
+justification and an expiry, after which the item is reported again.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+
+			threshold, err := scanner.ParseRiskLevel(minRisk)
+			if err != nil {
+				return err
+			}
+			if _, err := parseExpiry(defaultExpiry, time.Now()); err != nil {
+				return err
+			}
+
+			var result *scanner.ScanResult
+			if resultsPath != "" {
+				result, err = scanner.LoadResult(resultsPath)
+				if err == nil {
+					sysroot.Root = result.Root
+					err = applySuppressions(result)
+				}
+			} else {
+				result, err = performScan(context.Background())
+			}
+			if err != nil {
+				return err
+			}
+
+			list, err := suppress.Load(suppressions)
+			if err != nil {
+				return err
+			}
+
+			var pending []*scanner.PersistenceItem
+			for i := range result.Items {
+				item := &result.Items[i]
+				if item.Risk.Level.Rank() >= threshold.Rank() && list.Match(item) == nil {
+					pending = append(pending, item)
+				}
+			}
+			sort.SliceStable(pending, func(i, j int) bool {
+				return pending[i].Risk.Score > pending[j].Risk.Score
+			})
+
+			out := cmd.OutOrStdout()
+			if len(pending) == 0 {
+				fmt.Fprintf(out, "No untriaged findings at or above %s.\n", threshold)
+				return nil
+			}
+
+			t := &triage{
+				in:            bufio.NewReader(cmd.InOrStdin()),
+				out:           out,
+				list:          list,
+				defaultExpiry: defaultExpiry,
+				counts:        make(map[string]int),
+			}
+			for i, item := range pending {
+				quit, err := t.review(item, i+1, len(pending))
+				if err != nil {
+					return err
+				}
+				if quit {
+					break
+				}
+			}
+
+			fmt.Fprintf(out, "\n%d expected, %d suppressed, %d to investigate, %d skipped; decisions saved to %s\n",
+				t.counts[suppress.StatusExpected], t.counts[suppress.StatusSuppressed], t.counts[suppress.StatusInvestigate], t.counts["skip"], suppressions)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&resultsPath, "results", "r", "", "Triage this JSON scan result instead of scanning")
+	cmd.Flags().StringVar(&minRisk, "min-risk", "low", "Only review findings at or above this risk level (info, low, medium, high, critical)")
+	cmd.Flags().StringVar(&defaultExpiry, "expires", "90d", "Default expiry offered for decisions (e.g. 30d, 12h, 2027-01-31, never)")
+	addScanFlags(cmd.Flags())
+
+	return cmd
+}
+
+// triage asks the operator for a decision on each finding and saves it to
+// the suppression file as it goes.
+type triage struct {
+	in            *bufio.Reader
+	out           io.Writer
+	list          *suppress.List
+	defaultExpiry string
+	counts        map[string]int
+}
+
+// review shows one finding and records the decision on it. It reports
+// whether the operator asked to stop.
+func (t *triage) review(item *scanner.PersistenceItem, n, total int) (bool, error) {
+	fmt.Fprintf(t.out, "\n[%d/%d] %s (%.2f)  %s  %s\n", n, total, item.Risk.Level, item.Risk.Score, item.Mechanism, item.Label)
+	fmt.Fprintf(t.out, "  ID:      %s\n", item.ID)
+	fmt.Fprintf(t.out, "  Path:    %s\n", item.Path)
+	if item.Program != "" {
+		fmt.Fprintf(t.out, "  Program: %s\n", strings.TrimSpace(item.Program+" "+strings.Join(item.ProgramArgs, " ")))
+	}
+	if sig := item.CodeSignature; sig != nil && sig.Signed {
+		fmt.Fprintf(t.out, "  Signed:  %s\n", strings.TrimSpace(sig.Identifier+" "+sig.TeamID))
+	}
+	for _, reason := range item.Risk.Reasons {
+		fmt.Fprintf(t.out, "  - %s\n", reason)
+	}
+
+	var status string
+	for status == "" {
+		answer, err := t.ask("[e]xpected, [s]uppress, [i]nvestigate, s[k]ip, [q]uit: ")
+		if err != nil {
+			return true, nil
+		}
+		switch strings.ToLower(answer) {
+		case "e", "expected":
+			status = suppress.StatusExpected
+		case "s", "suppress":
+			status = suppress.StatusSuppressed
+		case "i", "investigate":
+			status = suppress.StatusInvestigate
+		case "k", "skip", "":
+			t.counts["skip"]++
+			return false, nil
+		case "q", "quit":
+			return true, nil
+		}
+	}
+
+	var reason string
+	for reason == "" {
+		answer, err := t.ask("Justification: ")
+		if err != nil {
+			return true, nil
+		}
+		reason = answer
+	}
+
+	var expires *time.Time
+	for {
+		answer, err := t.ask(fmt.Sprintf("Expires [%s]: ", t.defaultExpiry))
+		if err != nil {
+			return true, nil
+		}
+		if answer == "" {
+			answer = t.defaultExpiry
+		}
+		if expires, err = parseExpiry(answer, time.Now()); err == nil {
+			break
+		}
+		fmt.Fprintln(t.out, err)
+	}
+
+	t.list.Record(item, status, reason, expires)
+	if err := t.list.Save(suppressions); err != nil {
+		return true, err
+	}
+	t.counts[status]++
+	return false, nil
+}
+
+// ask prints a prompt and returns the trimmed answer. It fails at the end
+// of input.
+func (t *triage) ask(prompt string) (string, error) {
+	fmt.Fprint(t.out, prompt)
+	line, err := t.in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(t.out)
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// parseExpiry converts "never", a number of days such as "30d", a Go
+// duration, or a date into an expiry time; "never" is nil.
+func parseExpiry(s string, now time.Time) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "never") {
+		return nil, nil
+	}
+
+	var expires time.Time
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid expiry %q", s)
+		}
+		expires = now.AddDate(0, 0, n)
+	} else if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		expires = now.Add(d)
+	} else if date, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil && date.After(now) {
+		expires = date
+	} else {
+		return nil, fmt.Errorf("invalid expiry %q: use a number of days (30d), a duration (12h), a future date (2006-01-02), or never", s)
+	}
+	return &expires, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/config"
+	"github.com/haasonsaas/macos-persist-scan/internal/suppress"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/internal/tui"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+func tuiCmd() *cobra.Command {
+	var resultsPath string
+	var exportDir string
+	var markedPath string
+
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Triage findings interactively",
+		Long: `Browse findings in a terminal interface with a filterable list and a
+detail pane showing each item's heuristics and raw plist.
+
+Keys: / filter, s suppress (written to --suppressions), m mark for
+remediation, e export the item as JSON, q quit. Marked items are saved to
+--marked on exit for use with "remediate --results".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			applyConfig(cmd, cfg)
+
+			var result *scanner.ScanResult
+			if resultsPath != "" {
+				result, err = scanner.LoadResult(resultsPath)
+				if err == nil {
+					sysroot.Root = result.Root
+					err = applySuppressions(result)
+				}
+			} else {
+				result, err = performScan(context.Background())
+			}
+			if err != nil {
+				return err
+			}
+
+			list, err := suppress.Load(suppressions)
+			if err != nil {
+				return err
+			}
+
+			app := tui.New(result, tui.Options{
+				Suppressions:     list,
+				SuppressionsPath: suppressions,
+				ExportDir:        exportDir,
+			})
+			if err := app.Run(); err != nil {
+				return err
+			}
+
+			marked := app.Marked()
+			if len(marked) == 0 {
+				return nil
+			}
+			if err := writeMarked(markedPath, result, marked); err != nil {
+				return err
+			}
+			fmt.Printf("%d item(s) marked for remediation saved to %s\n", len(marked), markedPath)
+			fmt.Printf("Preview with: macos-persist-scan remediate --results %s --action <unload|disable|quarantine> <path>\n", markedPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&resultsPath, "results", "r", "", "Triage this JSON scan result instead of scanning")
+	cmd.Flags().StringVar(&exportDir, "export-dir", ".", "Directory exported items are written to")
+	cmd.Flags().StringVar(&markedPath, "marked", filepath.Join(stateDir(), "marked.json"), "Where items marked for remediation are saved")
+	addScanFlags(cmd.Flags())
+
+	return cmd
+}
+
+// writeMarked saves the marked items as a scan result.
+func writeMarked(path string, result *scanner.ScanResult, marked []scanner.PersistenceItem) error {
+	out := *result
+	out.Items = marked
+	out.Suppressed = 0
+	out.Summarize()
+
+	data, err := json.MarshalIndent(&out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
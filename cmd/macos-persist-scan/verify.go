@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/signing"
+	"github.com/spf13/cobra"
+)
+
+func verifyCmd() *cobra.Command {
+	var (
+		publicKey     string
+		signaturePath string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "verify <result-file>",
+		Short: "Check the signature of a scan result written with --sign-key",
+		Long: `Verify that a result file written by scan, rescore, or inventory with
+--sign-key has not changed since it was signed. The detached signature is
+read from the file's path with .sig appended, and must have been made by
+the private key matching --public-key.
+
+The signature also covers its trusted comment, which records when the
+result was signed, on which host, and under which file name. Keep the
+public key apart from the machines being scanned: a result from a
+compromised host proves only that it came from whoever held the key.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if publicKey == "" {
+				return fmt.Errorf("no public key: set --public-key")
+			}
+			keyData := []byte(publicKey)
+			if data, err := os.ReadFile(publicKey); err == nil {
+				keyData = data
+			}
+			key, err := signing.ParsePublicKey(keyData)
+			if err != nil {
+				return err
+			}
+
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			if signaturePath == "" {
+				signaturePath = args[0] + ".sig"
+			}
+			signature, err := os.ReadFile(signaturePath)
+			if err != nil {
+				return fmt.Errorf("reading signature: %w", err)
+			}
+
+			verified, err := signing.Verify(data, signature, key)
+			if err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Good signature on %s from key %s\n", args[0], verified.KeyID)
+			fmt.Fprintf(cmd.OutOrStdout(), "Trusted comment: %s\n", verified.Comment)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&publicKey, "public-key", "", "Ed25519 public key the result must be signed with: a PEM or base64 key file, or the base64 key itself")
+	cmd.Flags().StringVar(&signaturePath, "signature", "", "Detached signature file (default: the result path with .sig appended)")
+
+	return cmd
+}
@@ -0,0 +1,144 @@
+// Package agent installs the scanner as a launchd job that scans on a
+// schedule.
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"howett.net/plist"
+)
+
+// DefaultLabel is the launchd label used unless another is given.
+const DefaultLabel = "com.github.haasonsaas.macos-persist-scan"
+
+// Agent describes the scheduled scan job.
+type Agent struct {
+	Label string
+	// System installs a LaunchDaemon running as root instead of a
+	// LaunchAgent in the invoking user's session.
+	System bool
+	// Program is the scanner binary and Args the arguments it is run with.
+	Program  string
+	Args     []string
+	Interval time.Duration
+	// LogPath receives the job's stdout and stderr.
+	LogPath string
+}
+
+type launchdJob struct {
+	Label             string   `plist:"Label"`
+	ProgramArguments  []string `plist:"ProgramArguments"`
+	StartInterval     int      `plist:"StartInterval"`
+	RunAtLoad         bool     `plist:"RunAtLoad"`
+	ProcessType       string   `plist:"ProcessType"`
+	LowPriorityIO     bool     `plist:"LowPriorityIO"`
+	StandardOutPath   string   `plist:"StandardOutPath,omitempty"`
+	StandardErrorPath string   `plist:"StandardErrorPath,omitempty"`
+}
+
+// Path returns where the job's plist is installed.
+func (a *Agent) Path() (string, error) {
+	if a.System {
+		return filepath.Join("/Library/LaunchDaemons", a.Label+".plist"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library/LaunchAgents", a.Label+".plist"), nil
+}
+
+// Plist renders the launchd job definition.
+func (a *Agent) Plist() ([]byte, error) {
+	if a.Interval < time.Minute {
+		return nil, fmt.Errorf("scan interval %s is shorter than a minute", a.Interval)
+	}
+
+	job := launchdJob{
+		Label:             a.Label,
+		ProgramArguments:  append([]string{a.Program}, a.Args...),
+		StartInterval:     int(a.Interval.Seconds()),
+		RunAtLoad:         true,
+		ProcessType:       "Background",
+		LowPriorityIO:     true,
+		StandardOutPath:   a.LogPath,
+		StandardErrorPath: a.LogPath,
+	}
+	return plist.MarshalIndent(job, plist.XMLFormat, "\t")
+}
+
+// VerifyProgram checks that the scanner binary carries a valid code
+// signature, so the installed job does not itself look like an unsigned
+// persistence item.
+func (a *Agent) VerifyProgram() error {
+	output, err := command.CombinedOutput("codesign", "--verify", "--strict", a.Program)
+	if err != nil {
+		return fmt.Errorf("%s is not validly signed: %s", a.Program, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Install writes the plist and loads the job, replacing any job already
+// loaded under the same label.
+func (a *Agent) Install(load bool) (string, error) {
+	data, err := a.Plist()
+	if err != nil {
+		return "", err
+	}
+	path, err := a.Path()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if a.LogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(a.LogPath), 0700); err != nil {
+			return "", err
+		}
+	}
+	// launchd refuses job definitions that are group- or world-writable
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	if !load {
+		return path, nil
+	}
+	domain := a.domain()
+	command.Run("launchctl", "bootout", domain+"/"+a.Label)
+	if output, err := command.CombinedOutput("launchctl", "bootstrap", domain, path); err != nil {
+		return path, fmt.Errorf("launchctl bootstrap %s %s: %s", domain, path, strings.TrimSpace(string(output)))
+	}
+	return path, nil
+}
+
+// Uninstall unloads the job and removes its plist.
+func (a *Agent) Uninstall() (string, error) {
+	path, err := a.Path()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no agent installed at %s", path)
+	}
+
+	command.Run("launchctl", "bootout", a.domain()+"/"+a.Label)
+	if err := os.Remove(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (a *Agent) domain() string {
+	if a.System {
+		return "system"
+	}
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
@@ -0,0 +1,205 @@
+// Package baseline identifies the launchd jobs and periodic scripts macOS
+// itself installs. A manifest lists each release's stock items by path and
+// by the cdhash of their program or the SHA-256 of their file; items that
+// match exactly are classified as Info so they do not bury real findings.
+// A manifest is bundled with the binary, and a rule bundle can carry a
+// newer one.
+package baseline
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+//go:embed manifest.json
+var bundled []byte
+
+// Manifest is one published version of the stock item lists.
+type Manifest struct {
+	// Version increases with every release; a rule bundle's manifest
+	// replaces the bundled one only if it is newer.
+	Version   int       `json:"version"`
+	Published time.Time `json:"published"`
+	// Releases maps a macOS major version, such as "15", to the items a
+	// clean install of it has.
+	Releases map[string][]Entry `json:"releases"`
+}
+
+// Entry is one stock item. An item matches if its path is Path and every
+// hash the entry sets matches.
+type Entry struct {
+	Path string `json:"path"`
+	// CDHash is the code directory hash of the item's program.
+	CDHash string `json:"cdhash,omitempty"`
+	// SHA256 is the hash of the file at Path.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Bundled returns the manifest built into the binary.
+func Bundled() *Manifest {
+	manifest, err := Parse(bundled)
+	if err != nil {
+		panic(err)
+	}
+	return manifest
+}
+
+// Parse decodes a manifest.
+func Parse(data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("parsing baseline manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Newer returns m, or other if it is a newer version.
+func (m *Manifest) Newer(other *Manifest) *Manifest {
+	if other != nil && other.Version > m.Version {
+		return other
+	}
+	return m
+}
+
+// Match returns the release whose stock items item exactly matches, or "".
+// Items are matched against the release of osVersion, or every release
+// when the scanned system's version is unknown.
+func (m *Manifest) Match(item *scanner.PersistenceItem, osVersion string) string {
+	releases := make([]string, 0, len(m.Releases))
+	if major := Major(osVersion); major != "" {
+		releases = append(releases, major)
+	} else {
+		for release := range m.Releases {
+			releases = append(releases, release)
+		}
+		sort.Strings(releases)
+	}
+
+	for _, release := range releases {
+		for _, entry := range m.Releases[release] {
+			if entry.matches(item) {
+				return release
+			}
+		}
+	}
+	return ""
+}
+
+// Covers reports whether m lists stock items for the release of osVersion,
+// or for any release when the version is unknown.
+func (m *Manifest) Covers(osVersion string) bool {
+	if major := Major(osVersion); major != "" {
+		return len(m.Releases[major]) > 0
+	}
+	for _, entries := range m.Releases {
+		if len(entries) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Entry) matches(item *scanner.PersistenceItem) bool {
+	if item.Path != e.Path || (e.CDHash == "" && e.SHA256 == "") {
+		return false
+	}
+	// A stock plist can point at a replaced program, so a launchd job
+	// only matches by its program's cdhash
+	if launchdMechanisms[item.Mechanism] && e.CDHash == "" {
+		return false
+	}
+	if e.CDHash != "" && (item.CodeSignature == nil || !strings.EqualFold(item.CodeSignature.CDHash, e.CDHash)) {
+		return false
+	}
+	if e.SHA256 != "" && (item.ConfigFile == nil || !strings.EqualFold(item.ConfigFile.SHA256, e.SHA256)) {
+		return false
+	}
+	return true
+}
+
+// stockMechanisms are the mechanisms whose stock items a manifest lists.
+var stockMechanisms = map[scanner.MechanismType]bool{
+	scanner.MechanismLaunchAgent:    true,
+	scanner.MechanismLaunchDaemon:   true,
+	scanner.MechanismPeriodicScript: true,
+}
+
+// launchdMechanisms are the stock mechanisms whose entries need a cdhash.
+var launchdMechanisms = map[scanner.MechanismType]bool{
+	scanner.MechanismLaunchAgent:  true,
+	scanner.MechanismLaunchDaemon: true,
+}
+
+// Add records the launchd jobs and periodic scripts of result, a scan of a
+// clean install of release, as that release's stock items, replacing what
+// was recorded for it. Launchd jobs without a cdhash and periodic scripts
+// without a file hash cannot be matched exactly and are left out. It
+// returns how many entries were recorded.
+func (m *Manifest) Add(release string, result *scanner.ScanResult) int {
+	seen := make(map[Entry]bool)
+	var entries []Entry
+	for i := range result.Items {
+		item := &result.Items[i]
+		if !stockMechanisms[item.Mechanism] {
+			continue
+		}
+		entry := Entry{Path: item.Path}
+		if sig := item.CodeSignature; sig != nil && sig.Signed {
+			entry.CDHash = sig.CDHash
+		}
+		if item.ConfigFile != nil {
+			entry.SHA256 = item.ConfigFile.SHA256
+		}
+		if entry.Path == "" || (entry.CDHash == "" && entry.SHA256 == "") || (launchdMechanisms[item.Mechanism] && entry.CDHash == "") || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	if m.Releases == nil {
+		m.Releases = make(map[string][]Entry)
+	}
+	m.Releases[release] = entries
+	return len(entries)
+}
+
+// Stage classifies the items matching m as Info after risk scoring, with
+// the matched release as the first reason.
+func Stage(m *Manifest, osVersion string) scanner.Stage {
+	return scanner.NewStage("baseline", func(items []scanner.PersistenceItem) {
+		// The bundled manifest is empty until built from clean installs;
+		// only a manifest missing the scanned release is worth a warning
+		if len(m.Releases) == 0 {
+			slog.Debug("OS baseline manifest is empty", "manifest_version", m.Version)
+		} else if !m.Covers(osVersion) {
+			slog.Warn("no OS baseline for this macOS release; stock items are scored like any other",
+				"os_version", osVersion, "manifest_version", m.Version)
+		}
+		for i := range items {
+			release := m.Match(&items[i], osVersion)
+			if release == "" {
+				continue
+			}
+			risk := &items[i].Risk
+			risk.Level = scanner.RiskInfo
+			risk.Score = 0
+			risk.Baseline = release
+			risk.Reasons = append([]string{fmt.Sprintf("Matches OS baseline for macOS %s", release)}, risk.Reasons...)
+		}
+	})
+}
+
+// Major returns the major version of a macOS version such as "15.1".
+func Major(version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
@@ -0,0 +1,280 @@
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+const (
+	dslocalGroups    = "/var/db/dslocal/nodes/Default/groups"
+	loginwindowPrefs = "/Library/Preferences/com.apple.loginwindow.plist"
+)
+
+// localAccount is an account record from the local directory node.
+type localAccount struct {
+	Name  string
+	UID   string
+	Shell string
+	Home  string
+	// Hidden is set by the IsHidden attribute, which keeps the account off
+	// the login window and out of System Settings.
+	Hidden bool
+	// Created is the account's creationTime password policy, or zero.
+	Created time.Time
+	// Record is the account's plist in the local node, or "" when it was
+	// read with dscl.
+	Record string
+}
+
+// localAccounts reads the local accounts straight from the local directory
+// node, which needs root on a live system, or asks Directory Services.
+func localAccounts() []localAccount {
+	accounts := dslocalAccounts()
+	if len(accounts) == 0 && canExec() {
+		accounts = dsclAccounts()
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Name < accounts[j].Name })
+	return accounts
+}
+
+func dslocalAccounts() []localAccount {
+	entries, err := readDir(dslocalUsers)
+	if err != nil {
+		return nil
+	}
+
+	var accounts []localAccount
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".plist") {
+			continue
+		}
+		path := filepath.Join(dslocalUsers, entry.Name())
+		data, err := readFile(path)
+		if err != nil {
+			continue
+		}
+
+		var record struct {
+			Name     []string `plist:"name"`
+			UID      []string `plist:"uid"`
+			Shell    []string `plist:"shell"`
+			Home     []string `plist:"home"`
+			IsHidden []string `plist:"IsHidden"`
+			// passwordpolicyoptions holds a plist of its own
+			Policy [][]byte `plist:"passwordpolicyoptions"`
+		}
+		if _, err := plist.Unmarshal(data, &record); err != nil || len(record.Name) == 0 {
+			continue
+		}
+		account := localAccount{
+			Name:   record.Name[0],
+			UID:    first(record.UID),
+			Shell:  first(record.Shell),
+			Home:   first(record.Home),
+			Hidden: first(record.IsHidden) == "1",
+			Record: path,
+		}
+		if len(record.Policy) > 0 {
+			var policy struct {
+				CreationTime time.Time `plist:"creationTime"`
+			}
+			if _, err := plist.Unmarshal(record.Policy[0], &policy); err == nil {
+				account.Created = policy.CreationTime
+			}
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts
+}
+
+// dsclAccounts reads every local account with dscl -readall, whose output
+// separates records with "-" lines and wraps long values onto indented
+// lines.
+func dsclAccounts() []localAccount {
+	output, err := command.Output("dscl", ".", "-readall", "/Users", "RecordName", "UniqueID", "UserShell", "NFSHomeDirectory", "IsHidden")
+	if err != nil {
+		return nil
+	}
+
+	var accounts []localAccount
+	attrs := make(map[string]string)
+	var key string
+	flush := func() {
+		if names := strings.Fields(attrs["RecordName"]); len(names) > 0 {
+			accounts = append(accounts, localAccount{
+				Name:   names[0],
+				UID:    attrs["UniqueID"],
+				Shell:  attrs["UserShell"],
+				Home:   attrs["NFSHomeDirectory"],
+				Hidden: attrs["IsHidden"] == "1",
+			})
+		}
+		attrs = make(map[string]string)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case line == "-":
+			flush()
+		case strings.HasPrefix(line, " ") && key != "":
+			attrs[key] = strings.TrimSpace(attrs[key] + " " + strings.TrimSpace(line))
+		default:
+			var value string
+			key, value, _ = strings.Cut(line, ":")
+			attrs[key] = strings.TrimSpace(value)
+		}
+	}
+	flush()
+
+	return accounts
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// adminMembers returns the accounts in the admin group.
+func adminMembers() map[string]bool {
+	members := make(map[string]bool)
+	if data, err := readFile(filepath.Join(dslocalGroups, "admin.plist")); err == nil {
+		var group struct {
+			Users []string `plist:"users"`
+		}
+		if _, err := plist.Unmarshal(data, &group); err == nil {
+			for _, name := range group.Users {
+				members[name] = true
+			}
+			return members
+		}
+	}
+
+	if canExec() {
+		if output, err := command.Output("dscl", ".", "-read", "/Groups/admin", "GroupMembership"); err == nil {
+			_, list, _ := strings.Cut(string(output), ":")
+			for _, name := range strings.Fields(list) {
+				members[name] = true
+			}
+		}
+	}
+	return members
+}
+
+// AccountScanner reports local accounts that can log in or hold privileges:
+// every account not reserved for a service, and service accounts with UID
+// 0 or in the admin group. Hiding an account, reusing UID 0, or adding an
+// admin gives lasting access that no file-based collector shows.
+type AccountScanner struct{}
+
+func NewAccountScanner() *AccountScanner {
+	return &AccountScanner{}
+}
+
+func (s *AccountScanner) Type() scanner.MechanismType {
+	return scanner.MechanismLocalAccount
+}
+
+// Info reports the account and group records read.
+func (s *AccountScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Local accounts: UID, hidden status, admin membership, and creation time",
+		Paths:       []string{dslocalUsers, dslocalGroups, loginwindowPrefs},
+		Commands: []string{
+			"dscl . -readall /Users, when the account records cannot be read directly (running system only)",
+			"dscl . -read /Groups/admin GroupMembership, when the group records cannot be read directly (running system only)",
+		},
+		Privileges: []string{"root to read the account records directly and see creation times"},
+	}
+}
+
+func (s *AccountScanner) Scan() ([]scanner.PersistenceItem, error) {
+	accounts := localAccounts()
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+	admins := adminMembers()
+
+	// The login window can also hide accounts by name or every account
+	// with a UID below 500
+	var loginwindow struct {
+		HiddenUsers []string `plist:"HiddenUsersList"`
+		Hide500     bool     `plist:"Hide500Users"`
+	}
+	if data, err := readFile(loginwindowPrefs); err == nil {
+		if _, err := plist.Unmarshal(data, &loginwindow); err != nil {
+			logging.Warn("parsing login window preferences", err, "scanner", s.Type(), "path", loginwindowPrefs)
+		}
+	}
+	hiddenByName := make(map[string]bool)
+	for _, name := range loginwindow.HiddenUsers {
+		hiddenByName[name] = true
+	}
+
+	var items []scanner.PersistenceItem
+	for _, account := range accounts {
+		uid, err := strconv.Atoi(account.UID)
+		if err != nil {
+			uid = -1
+		}
+		if strings.HasPrefix(account.Name, "_") && uid != 0 && !admins[account.Name] {
+			continue
+		}
+
+		var hiddenBy []string
+		if account.Hidden {
+			hiddenBy = append(hiddenBy, "IsHidden")
+		}
+		if hiddenByName[account.Name] {
+			hiddenBy = append(hiddenBy, "HiddenUsersList")
+		}
+		if loginwindow.Hide500 && uid >= 0 && uid < 500 {
+			hiddenBy = append(hiddenBy, "Hide500Users")
+		}
+
+		item := scanner.PersistenceItem{
+			Mechanism: scanner.MechanismLocalAccount,
+			Label:     "Account: " + account.Name,
+			Path:      account.Record,
+			User:      account.Name,
+			CreatedAt: account.Created,
+			RawData: map[string]interface{}{
+				"description": fmt.Sprintf("Local account %s (UID %s)", account.Name, account.UID),
+				"uid":         uid,
+				"shell":       account.Shell,
+				"home":        account.Home,
+				"admin":       admins[account.Name],
+				"no_login":    account.Shell == "" || noLoginShells[account.Shell],
+			},
+		}
+		if item.Path == "" {
+			item.Path = "dscl . -read /Users/" + account.Name
+		}
+		if len(hiddenBy) > 0 {
+			item.RawData["hidden_by"] = hiddenBy
+		}
+		if account.Record != "" {
+			// The record's birth time is no creation time: opendirectoryd
+			// rewrites the file on changes such as a new password
+			if info, err := os.Stat(sysroot.Path(account.Record)); err == nil {
+				item.ModifiedAt = info.ModTime()
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,136 @@
+//go:build darwin
+
+package collectors
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// kauth_filesec and ACE constants from <sys/kauth.h>.
+const (
+	filesecMagic = 0x012cc16d
+	filesecNoACL = 0xffffffff
+
+	aceKindMask  = 0xf
+	acePermit    = 1
+	aceDeny      = 2
+	aceInherited = 1 << 4
+)
+
+// aceRights names the KAUTH_VNODE_* rights bits the way ls -le prints them.
+var aceRights = []struct {
+	bit  uint32
+	name string
+}{
+	{1 << 1, "read"},
+	{1 << 2, "write"},
+	{1 << 3, "execute"},
+	{1 << 4, "delete"},
+	{1 << 5, "append"},
+	{1 << 6, "delete_child"},
+	{1 << 7, "readattr"},
+	{1 << 8, "writeattr"},
+	{1 << 9, "readextattr"},
+	{1 << 10, "writeextattr"},
+	{1 << 11, "readsecurity"},
+	{1 << 12, "writesecurity"},
+	{1 << 13, "chown"},
+}
+
+// fileACL returns the entries of the access control list of the file at
+// path, a path on this host, formatted like ls -le.
+func fileACL(path string) ([]string, error) {
+	attrs := unix.Attrlist{
+		Bitmapcount: unix.ATTR_BIT_MAP_COUNT,
+		Commonattr:  unix.ATTR_CMN_EXTENDED_SECURITY,
+	}
+	buf := make([]byte, 4096)
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_GETATTRLIST,
+		uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&attrs)),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	// The buffer holds its length, then an attrreference to the
+	// kauth_filesec: an offset relative to the reference and a length
+	if binary.LittleEndian.Uint32(buf) < 12 {
+		return nil, nil
+	}
+	offset := 4 + int(int32(binary.LittleEndian.Uint32(buf[4:])))
+	length := int(binary.LittleEndian.Uint32(buf[8:]))
+	if length == 0 || offset+length > len(buf) {
+		return nil, nil
+	}
+	return parseFilesec(buf[offset : offset+length])
+}
+
+// parseFilesec decodes a host-order kauth_filesec: magic, owner and group
+// GUIDs, then a kauth_acl of entry count, flags, and entries of a GUID,
+// flags, and rights.
+func parseFilesec(data []byte) ([]string, error) {
+	if len(data) < 44 || binary.LittleEndian.Uint32(data) != filesecMagic {
+		return nil, fmt.Errorf("unrecognized file security descriptor")
+	}
+	count := binary.LittleEndian.Uint32(data[36:])
+	if count == filesecNoACL {
+		return nil, nil
+	}
+
+	var entries []string
+	for i := 0; i < int(count); i++ {
+		ace := data[44+i*24:]
+		if len(ace) < 24 {
+			break
+		}
+		flags := binary.LittleEndian.Uint32(ace[16:])
+		rights := binary.LittleEndian.Uint32(ace[20:])
+
+		kind := "allow"
+		switch flags & aceKindMask {
+		case acePermit:
+		case aceDeny:
+			kind = "deny"
+		default:
+			continue
+		}
+
+		var names []string
+		for _, r := range aceRights {
+			if rights&r.bit != 0 {
+				names = append(names, r.name)
+			}
+		}
+		entry := fmt.Sprintf("%s %s %s", guidName(ace[:16]), kind, strings.Join(names, ","))
+		if flags&aceInherited != 0 {
+			entry += " (inherited)"
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// guidName names the well-known GUIDs that embed a UID or GID; others are
+// printed as is.
+func guidName(guid []byte) string {
+	id := binary.BigEndian.Uint32(guid[12:])
+	switch {
+	case strings.EqualFold(fmt.Sprintf("%X", guid[:12]), "FFFFEEEEDDDDCCCCBBBBAAAA"):
+		return fmt.Sprintf("user:%d", id)
+	case strings.EqualFold(fmt.Sprintf("%X", guid[:12]), "ABCDEFABCDEFABCDEFABCDEF"):
+		if id == 12 {
+			return "group:everyone"
+		}
+		return fmt.Sprintf("group:%d", id)
+	}
+	return fmt.Sprintf("%X-%X-%X-%X-%X", guid[:4], guid[4:6], guid[6:8], guid[8:10], guid[10:])
+}
@@ -0,0 +1,9 @@
+//go:build !darwin
+
+package collectors
+
+// fileACL returns no entries; access control lists are read only on
+// darwin.
+func fileACL(path string) ([]string, error) {
+	return nil, nil
+}
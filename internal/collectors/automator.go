@@ -0,0 +1,201 @@
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+const systemServicesDir = "/Library/Services"
+
+// wflowDocument is the part of a workflow's Contents/document.wflow that
+// says what it runs.
+type wflowDocument struct {
+	Actions []struct {
+		Action struct {
+			Name             string                 `plist:"ActionName"`
+			BundleIdentifier string                 `plist:"BundleIdentifier"`
+			Parameters       map[string]interface{} `plist:"ActionParameters"`
+		} `plist:"action"`
+	} `plist:"actions"`
+}
+
+// workflowInfo is the part of a workflow's Contents/Info.plist that names
+// the Services menu items it adds.
+type workflowInfo struct {
+	Services []struct {
+		MenuItem struct {
+			Default string `plist:"default"`
+		} `plist:"NSMenuItem"`
+		SendFileTypes []string `plist:"NSSendFileTypes"`
+		SendTypes     []string `plist:"NSSendTypes"`
+	} `plist:"NSServices"`
+}
+
+// AutomatorScanner reports Automator Quick Actions and Services: the
+// .workflow bundles in /Library/Services and each user's ~/Library/Services,
+// with the shell, AppleScript, and JavaScript they embed. They only run
+// when chosen from a Services or Quick Actions menu, but stay there until
+// deleted.
+type AutomatorScanner struct{}
+
+func NewAutomatorScanner() *AutomatorScanner {
+	return &AutomatorScanner{}
+}
+
+func (s *AutomatorScanner) Type() scanner.MechanismType {
+	return scanner.MechanismAutomator
+}
+
+// Info reports the Services directories read.
+func (s *AutomatorScanner) Info() scanner.ScannerInfo {
+	var paths []string
+	for _, dir := range s.dirs() {
+		paths = append(paths, dir.path)
+	}
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Automator Quick Action and Service workflows, with their embedded scripts",
+		Paths:       paths,
+		Privileges:  []string{"root to read every local user's Services; otherwise only the invoking user's"},
+	}
+}
+
+type servicesDir struct {
+	path string
+	user string
+}
+
+func (s *AutomatorScanner) dirs() []servicesDir {
+	dirs := []servicesDir{{path: systemServicesDir}}
+	for _, home := range userHomes() {
+		dirs = append(dirs, servicesDir{path: filepath.Join(home.Dir, "Library", "Services"), user: home.Name})
+	}
+	return dirs
+}
+
+func (s *AutomatorScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	for _, dir := range s.dirs() {
+		entries, err := readDir(dir.path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Warn("reading Services directory", err, "scanner", s.Type(), "path", dir.path)
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() || filepath.Ext(entry.Name()) != ".workflow" {
+				continue
+			}
+			path := filepath.Join(dir.path, entry.Name())
+			item, err := s.scanWorkflow(path)
+			if err != nil {
+				logging.Warn("parsing workflow", err, "scanner", s.Type(), "path", path)
+				continue
+			}
+			item.User = dir.user
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (s *AutomatorScanner) scanWorkflow(path string) (scanner.PersistenceItem, error) {
+	documentPath := filepath.Join(path, "Contents", "document.wflow")
+	data, err := readFile(documentPath)
+	if err != nil {
+		return scanner.PersistenceItem{}, err
+	}
+	var document wflowDocument
+	if _, err := plist.Unmarshal(data, &document); err != nil {
+		return scanner.PersistenceItem{}, err
+	}
+
+	// The item is the document that holds the actions, so it is hashed,
+	// and an edit to it changes the item even when the bundle directory's
+	// modification time does not
+	name := strings.TrimSuffix(filepath.Base(path), ".workflow")
+	item := scanner.PersistenceItem{
+		Mechanism:  scanner.MechanismAutomator,
+		Label:      "Quick Action: " + name,
+		Path:       documentPath,
+		ModifiedAt: getFileModTime(documentPath),
+		RawData:    map[string]interface{}{"bundle": path},
+	}
+	if info, err := os.Stat(sysroot.Path(path)); err == nil {
+		item.CreatedAt = fileBirthTime(info)
+	}
+
+	var menuItems, inputs []string
+	if infoData, err := readFile(filepath.Join(path, "Contents", "Info.plist")); err == nil {
+		var info workflowInfo
+		if _, err := plist.Unmarshal(infoData, &info); err == nil {
+			for _, service := range info.Services {
+				if service.MenuItem.Default != "" {
+					menuItems = append(menuItems, service.MenuItem.Default)
+				}
+				inputs = append(inputs, service.SendFileTypes...)
+				inputs = append(inputs, service.SendTypes...)
+			}
+		}
+	}
+	if len(menuItems) > 0 {
+		item.RawData["menu_items"] = menuItems
+	}
+	if len(inputs) > 0 {
+		item.RawData["inputs"] = inputs
+	}
+
+	var actions []map[string]interface{}
+	var scripts []string
+	for _, a := range document.Actions {
+		action := map[string]interface{}{
+			"name":              a.Action.Name,
+			"bundle_identifier": a.Action.BundleIdentifier,
+		}
+		if language, script := workflowScript(a.Action.BundleIdentifier, a.Action.Parameters); script != "" {
+			action["language"] = language
+			action["script"] = script
+			scripts = append(scripts, script)
+		}
+		actions = append(actions, action)
+	}
+	item.RawData["actions"] = actions
+	item.RawData["script_actions"] = len(scripts)
+	if len(scripts) > 0 {
+		item.RawData["scriptContent"] = strings.Join(scripts, "\n")
+	}
+	item.RawData["description"] = fmt.Sprintf("Automator workflow with %d actions, %d of them scripts", len(actions), len(scripts))
+
+	return item, nil
+}
+
+// workflowScript returns the language and body of a Run Shell Script, Run
+// AppleScript, or Run JavaScript action, or "" for other actions.
+func workflowScript(bundleID string, params map[string]interface{}) (string, string) {
+	if command, ok := params["COMMAND_STRING"].(string); ok {
+		shell, _ := params["shell"].(string)
+		if shell == "" {
+			shell = "/bin/sh"
+		}
+		return shell, command
+	}
+	if source, ok := params["source"].(string); ok {
+		if strings.Contains(bundleID, "JavaScript") {
+			return "JavaScript", source
+		}
+		return "AppleScript", source
+	}
+	return "", ""
+}
@@ -0,0 +1,266 @@
+package collectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// chromiumDataDirs are the user data directories of Chromium browsers,
+// relative to each home directory. Each profile in them keeps its installed
+// extensions in Extensions/<id>/<version>.
+var chromiumDataDirs = []struct {
+	Browser string
+	Dir     string
+}{
+	{"Chrome", "Library/Application Support/Google/Chrome"},
+	{"Chrome Beta", "Library/Application Support/Google/Chrome Beta"},
+	{"Chrome Canary", "Library/Application Support/Google/Chrome Canary"},
+	{"Chromium", "Library/Application Support/Chromium"},
+	{"Edge", "Library/Application Support/Microsoft Edge"},
+	{"Brave", "Library/Application Support/BraveSoftware/Brave-Browser"},
+	{"Vivaldi", "Library/Application Support/Vivaldi"},
+}
+
+// firefoxProfilesDir holds Firefox profiles, relative to each home
+// directory. Each profile lists its add-ons in extensions.json.
+const firefoxProfilesDir = "Library/Application Support/Firefox/Profiles"
+
+// firefoxBuiltinLocations are the add-on locations Firefox itself installs
+// into; their add-ons ship with the browser.
+var firefoxBuiltinLocations = map[string]bool{
+	"app-builtin": true, "app-system-defaults": true, "app-system-addons": true,
+}
+
+// chromiumManifest is the part of an extension's manifest.json reported.
+// Permissions may hold objects as well as strings, so they are decoded
+// loosely.
+type chromiumManifest struct {
+	Name            string        `json:"name"`
+	Version         string        `json:"version"`
+	ManifestVersion int           `json:"manifest_version"`
+	UpdateURL       string        `json:"update_url"`
+	Permissions     []interface{} `json:"permissions"`
+	HostPermissions []string      `json:"host_permissions"`
+}
+
+// firefoxAddons is the part of a Firefox profile's extensions.json
+// reported.
+type firefoxAddons struct {
+	Addons []struct {
+		ID            string `json:"id"`
+		Version       string `json:"version"`
+		Type          string `json:"type"`
+		Location      string `json:"location"`
+		Active        bool   `json:"active"`
+		Path          string `json:"path"`
+		SourceURI     string `json:"sourceURI"`
+		DefaultLocale struct {
+			Name string `json:"name"`
+		} `json:"defaultLocale"`
+		UserPermissions struct {
+			Permissions []string `json:"permissions"`
+			Origins     []string `json:"origins"`
+		} `json:"userPermissions"`
+	} `json:"addons"`
+}
+
+// BrowserExtensionScanner reports the extensions installed in each
+// profile of each local account's Chromium browsers and Firefox. An
+// extension runs whenever the browser does, and with broad permissions can
+// read every page, intercept traffic, or start native messaging hosts.
+type BrowserExtensionScanner struct{}
+
+func NewBrowserExtensionScanner() *BrowserExtensionScanner {
+	return &BrowserExtensionScanner{}
+}
+
+func (s *BrowserExtensionScanner) Type() scanner.MechanismType {
+	return scanner.MechanismBrowserExtension
+}
+
+// Info reports the browser data directories read.
+func (s *BrowserExtensionScanner) Info() scanner.ScannerInfo {
+	var paths []string
+	for _, home := range userHomes() {
+		for _, browser := range chromiumDataDirs {
+			paths = append(paths, filepath.Join(home.Dir, browser.Dir))
+		}
+		paths = append(paths, filepath.Join(home.Dir, firefoxProfilesDir))
+	}
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Extensions installed in each Chrome, Edge, Brave, Chromium, Vivaldi, and Firefox profile",
+		Paths:       paths,
+		Privileges:  []string{"root to read every local user's browser profiles; otherwise only the invoking user's"},
+	}
+}
+
+func (s *BrowserExtensionScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	for _, home := range userHomes() {
+		for _, browser := range chromiumDataDirs {
+			dataDir := filepath.Join(home.Dir, browser.Dir)
+			for _, profile := range s.subdirectories(dataDir) {
+				items = append(items, s.scanChromiumProfile(filepath.Join(dataDir, profile), browser.Browser, profile, home.Name)...)
+			}
+		}
+		profilesDir := filepath.Join(home.Dir, firefoxProfilesDir)
+		for _, profile := range s.subdirectories(profilesDir) {
+			items = append(items, s.scanFirefoxProfile(filepath.Join(profilesDir, profile), profile, home.Name)...)
+		}
+	}
+
+	return items, nil
+}
+
+// subdirectories returns the names of the directories in dir.
+func (s *BrowserExtensionScanner) subdirectories(dir string) []string {
+	entries, err := readDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading browser data directory", err, "scanner", s.Type(), "path", dir)
+		}
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// scanChromiumProfile reports each installed version of each extension in
+// a Chromium profile.
+func (s *BrowserExtensionScanner) scanChromiumProfile(profileDir, browser, profile, user string) []scanner.PersistenceItem {
+	extensionsDir := filepath.Join(profileDir, "Extensions")
+
+	var items []scanner.PersistenceItem
+	for _, id := range s.subdirectories(extensionsDir) {
+		for _, version := range s.subdirectories(filepath.Join(extensionsDir, id)) {
+			path := filepath.Join(extensionsDir, id, version, "manifest.json")
+			data, err := readFile(path)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					logging.Warn("reading extension manifest", err, "scanner", s.Type(), "path", path)
+				}
+				continue
+			}
+
+			item := scanner.PersistenceItem{
+				Mechanism:  scanner.MechanismBrowserExtension,
+				Label:      id,
+				Path:       path,
+				User:       user,
+				ModifiedAt: getFileModTime(path),
+				RawData: map[string]interface{}{
+					"browser":      browser,
+					"profile":      profile,
+					"extension_id": id,
+					"version":      version,
+				},
+			}
+
+			var manifest chromiumManifest
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				item.Errors = append(item.Errors, fmt.Sprintf("parsing manifest: %v", err))
+				items = append(items, item)
+				continue
+			}
+
+			var permissions []string
+			for _, p := range manifest.Permissions {
+				if name, ok := p.(string); ok {
+					permissions = append(permissions, name)
+				}
+			}
+			item.RawData["description"] = fmt.Sprintf("%s extension %s in profile %s", browser, id, profile)
+			item.RawData["name"] = manifest.Name
+			if manifest.Version != "" {
+				item.RawData["version"] = manifest.Version
+			}
+			item.RawData["manifest_version"] = manifest.ManifestVersion
+			item.RawData["update_url"] = manifest.UpdateURL
+			item.RawData["permissions"] = permissions
+			item.RawData["host_permissions"] = manifest.HostPermissions
+			// Names starting __MSG_ are looked up in the extension's
+			// locales, so the ID alone identifies those
+			if manifest.Name != "" && !strings.HasPrefix(manifest.Name, "__MSG_") {
+				item.Label = manifest.Name
+			}
+
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// scanFirefoxProfile reports the add-ons listed in a Firefox profile's
+// extensions.json, other than those Firefox ships.
+func (s *BrowserExtensionScanner) scanFirefoxProfile(profileDir, profile, user string) []scanner.PersistenceItem {
+	path := filepath.Join(profileDir, "extensions.json")
+	data, err := readFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading Firefox add-on list", err, "scanner", s.Type(), "path", path)
+		}
+		return nil
+	}
+
+	var list firefoxAddons
+	if err := json.Unmarshal(data, &list); err != nil {
+		logging.Warn("parsing Firefox add-on list", err, "scanner", s.Type(), "path", path)
+		return nil
+	}
+
+	var items []scanner.PersistenceItem
+	for _, addon := range list.Addons {
+		if addon.Type != "extension" || firefoxBuiltinLocations[addon.Location] {
+			continue
+		}
+
+		// The add-on's own file identifies it; extensions.json is shared
+		// by every add-on in the profile
+		addonPath := addon.Path
+		if addonPath == "" {
+			addonPath = path
+		}
+		label := addon.ID
+		if addon.DefaultLocale.Name != "" {
+			label = addon.DefaultLocale.Name
+		}
+		items = append(items, scanner.PersistenceItem{
+			Mechanism:  scanner.MechanismBrowserExtension,
+			Label:      label,
+			Path:       addonPath,
+			User:       user,
+			Disabled:   !addon.Active,
+			ModifiedAt: getFileModTime(addonPath),
+			RawData: map[string]interface{}{
+				"description":      fmt.Sprintf("Firefox extension %s in profile %s", addon.ID, profile),
+				"browser":          "Firefox",
+				"profile":          profile,
+				"extension_id":     addon.ID,
+				"name":             addon.DefaultLocale.Name,
+				"version":          addon.Version,
+				"location":         addon.Location,
+				"source_uri":       addon.SourceURI,
+				"permissions":      addon.UserPermissions.Permissions,
+				"host_permissions": addon.UserPermissions.Origins,
+			},
+		})
+	}
+
+	return items
+}
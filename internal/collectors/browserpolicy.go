@@ -0,0 +1,197 @@
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+const managedPreferences = "/Library/Managed Preferences"
+
+// chromeWebStoreUpdateURL is the update URL of extensions installed from
+// the Chrome Web Store.
+const chromeWebStoreUpdateURL = "https://clients2.google.com/service/update2/crx"
+
+// browserPolicyDomains are the preference domains Chromium-based browsers
+// read policies from.
+var browserPolicyDomains = []struct {
+	Browser string
+	Domain  string
+}{
+	{"Chrome", "com.google.Chrome"},
+	{"Edge", "com.microsoft.Edge"},
+	{"Brave", "com.brave.Browser"},
+	{"Chromium", "org.chromium.Chromium"},
+}
+
+// browserPolicyGroups are the policies reported, grouped into one item
+// each: extensions installed without the user's consent, traffic
+// redirection, and pages opened at startup or on search.
+var browserPolicyGroups = []struct {
+	Name     string
+	Policies []string
+}{
+	{"extensions", []string{"ExtensionInstallForcelist", "ExtensionSettings", "ExtensionInstallSources"}},
+	{"proxy", []string{"ProxyMode", "ProxyServer", "ProxyPacUrl", "ProxyBypassList", "ProxySettings"}},
+	{"startup", []string{"RestoreOnStartup", "RestoreOnStartupURLs", "HomepageLocation", "HomepageIsNewTabPage", "NewTabPageLocation", "DefaultSearchProviderEnabled", "DefaultSearchProviderSearchURL", "DefaultSearchProviderName"}},
+}
+
+// BrowserPolicyScanner reports Chromium browser policies that force
+// extensions, proxies, or startup pages. Adware sets them through a
+// manually installed configuration profile or plain preference files to
+// pose as enterprise management.
+type BrowserPolicyScanner struct{}
+
+func NewBrowserPolicyScanner() *BrowserPolicyScanner {
+	return &BrowserPolicyScanner{}
+}
+
+func (s *BrowserPolicyScanner) Type() scanner.MechanismType {
+	return scanner.MechanismBrowserPolicy
+}
+
+// Info reports the managed and unmanaged preference files read.
+func (s *BrowserPolicyScanner) Info() scanner.ScannerInfo {
+	var paths []string
+	for _, file := range s.policyFiles() {
+		paths = append(paths, file.path)
+	}
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Chrome, Edge, Brave, and Chromium extension, proxy, and startup page policies",
+		Paths:       paths,
+		Privileges:  []string{"root to read every local user's preferences; otherwise only the invoking user's"},
+	}
+}
+
+type browserPolicyFile struct {
+	path    string
+	browser string
+	user    string
+	// managed is set for files written from configuration profiles, as
+	// opposed to preferences anyone with write access can set.
+	managed bool
+}
+
+func (s *BrowserPolicyScanner) policyFiles() []browserPolicyFile {
+	var files []browserPolicyFile
+	homes := userHomes()
+
+	for _, d := range browserPolicyDomains {
+		name := d.Domain + ".plist"
+		files = append(files,
+			browserPolicyFile{path: filepath.Join(managedPreferences, name), browser: d.Browser, managed: true},
+			browserPolicyFile{path: filepath.Join("/Library/Preferences", name), browser: d.Browser})
+		for _, home := range homes {
+			files = append(files,
+				browserPolicyFile{path: filepath.Join(managedPreferences, home.Name, name), browser: d.Browser, user: home.Name, managed: true},
+				browserPolicyFile{path: filepath.Join(home.Dir, "Library", "Preferences", name), browser: d.Browser, user: home.Name})
+		}
+	}
+
+	return files
+}
+
+func (s *BrowserPolicyScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	for _, file := range s.policyFiles() {
+		data, err := readFile(file.path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Warn("reading browser policies", err, "scanner", s.Type(), "path", file.path)
+			}
+			continue
+		}
+
+		var prefs map[string]interface{}
+		if _, err := plist.Unmarshal(data, &prefs); err != nil {
+			logging.Warn("parsing browser policies", err, "scanner", s.Type(), "path", file.path)
+			continue
+		}
+
+		for _, group := range browserPolicyGroups {
+			policies := make(map[string]interface{})
+			for _, name := range group.Policies {
+				if v, ok := prefs[name]; ok {
+					policies[name] = v
+				}
+			}
+			if len(policies) == 0 {
+				continue
+			}
+
+			item := scanner.PersistenceItem{
+				Mechanism:  scanner.MechanismBrowserPolicy,
+				Label:      fmt.Sprintf("%s %s policy", file.browser, group.Name),
+				Path:       file.path,
+				User:       file.user,
+				ModifiedAt: getFileModTime(file.path),
+				RawData: map[string]interface{}{
+					"description": fmt.Sprintf("%s %s policies in %s", file.browser, group.Name, file.path),
+					"browser":     file.browser,
+					"group":       group.Name,
+					"managed":     file.managed,
+					"policies":    policies,
+					"content":     string(data),
+				},
+			}
+			if group.Name == "extensions" {
+				forced, offStore := forcedExtensions(policies)
+				item.RawData["forced_extensions"] = forced
+				item.RawData["off_store_extensions"] = offStore
+			}
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// forcedExtensions returns the IDs of the extensions the policies install
+// without the user's consent, and those among them updated from somewhere
+// other than the Chrome Web Store.
+func forcedExtensions(policies map[string]interface{}) (forced, offStore []string) {
+	add := func(id, updateURL string) {
+		forced = append(forced, id)
+		if updateURL != "" && updateURL != chromeWebStoreUpdateURL {
+			offStore = append(offStore, id)
+		}
+	}
+
+	// ExtensionInstallForcelist entries are "id;update_url"
+	if list, ok := policies["ExtensionInstallForcelist"].([]interface{}); ok {
+		for _, entry := range list {
+			if s, ok := entry.(string); ok {
+				id, updateURL, _ := strings.Cut(s, ";")
+				add(id, updateURL)
+			}
+		}
+	}
+
+	if settings, ok := policies["ExtensionSettings"].(map[string]interface{}); ok {
+		ids := make([]string, 0, len(settings))
+		for id := range settings {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			setting, _ := settings[id].(map[string]interface{})
+			mode, _ := setting["installation_mode"].(string)
+			if id == "*" || mode != "force_installed" && mode != "normal_installed" {
+				continue
+			}
+			updateURL, _ := setting["update_url"].(string)
+			add(id, updateURL)
+		}
+	}
+
+	return forced, offStore
+}
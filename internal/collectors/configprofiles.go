@@ -2,12 +2,12 @@ package collectors
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
 	"howett.net/plist"
 )
@@ -22,21 +22,34 @@ func (s *ConfigProfilesScanner) Type() scanner.MechanismType {
 	return scanner.MechanismConfigProfile
 }
 
+// Info reports the profile stores read and the system_profiler query.
+func (s *ConfigProfilesScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Installed configuration profiles and managed preferences",
+		Paths:       []string{"/Library/Managed Preferences", "/Library/ConfigurationProfiles", "/var/db/ConfigurationProfiles/Store"},
+		Commands:    []string{"system_profiler SPConfigurationProfileDataType -xml (running system only)"},
+		Privileges:  []string{"root to read /var/db/ConfigurationProfiles/Store"},
+	}
+}
+
 func (s *ConfigProfilesScanner) Scan() ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 
-	// Use system_profiler to get installed profiles
-	profileItems, err := s.scanViaSystemProfiler()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning profiles via system_profiler: %v\n", err)
-	} else {
-		items = append(items, profileItems...)
+	// Use system_profiler to get profiles installed on the running system
+	if canExec() {
+		profileItems, err := s.scanViaSystemProfiler()
+		if err != nil {
+			logging.Warn("scanning profiles via system_profiler", err, "scanner", s.Type())
+		} else {
+			items = append(items, profileItems...)
+		}
 	}
 
 	// Also scan the profiles directory directly
 	dirItems, err := s.scanProfilesDirectory()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning profiles directory: %v\n", err)
+		logging.Warn("scanning profiles directory", err, "scanner", s.Type())
 	} else {
 		items = append(items, dirItems...)
 	}
@@ -63,8 +76,7 @@ type profilePayload struct {
 
 func (s *ConfigProfilesScanner) scanViaSystemProfiler() ([]scanner.PersistenceItem, error) {
 	// Run system_profiler to get configuration profiles
-	cmd := exec.Command("system_profiler", "SPConfigurationProfileDataType", "-xml")
-	output, err := cmd.Output()
+	output, err := command.Output("system_profiler", "SPConfigurationProfileDataType", "-xml")
 	if err != nil {
 		return nil, fmt.Errorf("running system_profiler: %w", err)
 	}
@@ -110,6 +122,7 @@ func (s *ConfigProfilesScanner) parseProfileData(data map[string]interface{}) *s
 	identifier, _ := data["spconfigprofile_profile_identifier"].(string)
 	organization, _ := data["spconfigprofile_organization"].(string)
 	description, _ := data["spconfigprofile_description"].(string)
+	installDate, _ := data["spconfigprofile_install_date"].(string)
 	
 	// Check for potentially suspicious payload types
 	suspiciousPayloads := s.checkForSuspiciousPayloads(data)
@@ -118,7 +131,6 @@ func (s *ConfigProfilesScanner) parseProfileData(data map[string]interface{}) *s
 		Mechanism:  scanner.MechanismConfigProfile,
 		Label:      name,
 		Path:       "Configuration Profile",
-		ModifiedAt: time.Now(), // Can't get exact install time from this format
 		RawData: map[string]interface{}{
 			"description":        fmt.Sprintf("Profile: %s (ID: %s, Org: %s)", name, identifier, organization),
 			"ProfileName":        name,
@@ -129,6 +141,9 @@ func (s *ConfigProfilesScanner) parseProfileData(data map[string]interface{}) *s
 			"FullData":          data,
 		},
 	}
+	if installed, err := time.Parse("2006-01-02 15:04:05 -0700", installDate); err == nil {
+		item.InstalledAt = installed
+	}
 
 	// Try to determine if this profile contains persistence mechanisms
 	if len(suspiciousPayloads) > 0 {
@@ -195,7 +210,7 @@ func (s *ConfigProfilesScanner) scanProfilesDirectory() ([]scanner.PersistenceIt
 func (s *ConfigProfilesScanner) scanDirectory(dir string) ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 
-	entries, err := os.ReadDir(dir)
+	entries, err := readDir(dir)
 	if err != nil {
 		return nil, err
 	}
@@ -209,7 +224,7 @@ func (s *ConfigProfilesScanner) scanDirectory(dir string) ([]scanner.Persistence
 		if strings.HasSuffix(entry.Name(), ".plist") || strings.HasSuffix(entry.Name(), ".mobileconfig") {
 			path := filepath.Join(dir, entry.Name())
 			
-			data, err := os.ReadFile(path)
+			data, err := readFile(path)
 			if err != nil {
 				continue
 			}
@@ -222,8 +237,9 @@ func (s *ConfigProfilesScanner) scanDirectory(dir string) ([]scanner.Persistence
 			}
 
 			info, _ := entry.Info()
-			modTime := time.Now()
+			var createdAt, modTime time.Time
 			if info != nil {
+				createdAt = fileBirthTime(info)
 				modTime = info.ModTime()
 			}
 
@@ -231,11 +247,15 @@ func (s *ConfigProfilesScanner) scanDirectory(dir string) ([]scanner.Persistence
 				Mechanism:  scanner.MechanismConfigProfile,
 				Label:      entry.Name(),
 				Path:       path,
+				CreatedAt:  createdAt,
 				ModifiedAt: modTime,
 				RawData:    profileContent,
 			}
 			item.RawData["description"] = fmt.Sprintf("Configuration profile: %s", entry.Name())
 			item.RawData["content"] = string(data)
+			if installed, ok := profileContent["ProfileInstallDate"].(time.Time); ok {
+				item.InstalledAt = installed
+			}
 
 			// Extract profile name if available
 			if name, ok := profileContent["PayloadDisplayName"].(string); ok && name != "" {
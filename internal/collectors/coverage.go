@@ -0,0 +1,79 @@
+package collectors
+
+import (
+	"os"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Coverage reports how completely each location the scanners read was
+// examined in result. Call it after the scan, while the permission denials
+// it recorded are still available.
+func Coverage(scanners []scanner.Scanner, result *scanner.ScanResult) []scanner.CoverageEntry {
+	failed := make(map[scanner.MechanismType]string)
+	for _, e := range result.Errors {
+		failed[e.Mechanism] = e.Error
+	}
+	denied := PermissionIssues()
+
+	var entries []scanner.CoverageEntry
+	for _, s := range scanners {
+		info := scanner.ScannerInfo{Mechanism: s.Type()}
+		if d, ok := s.(scanner.Describer); ok {
+			info = d.Info()
+		}
+
+		for _, path := range info.Paths {
+			entry := scanner.CoverageEntry{Mechanism: s.Type(), Location: path, Status: scanner.CoverageFull}
+			switch {
+			case failed[s.Type()] != "":
+				entry.Status = scanner.CoverageFailed
+				entry.Detail = failed[s.Type()]
+			case !exists(path):
+				entry.Status = scanner.CoverageSkipped
+				entry.Detail = "not present"
+			default:
+				if deniedUnder(denied, path) {
+					entry.Status = scanner.CoveragePartial
+					entry.Detail = "permission denied"
+				}
+			}
+			entries = append(entries, entry)
+		}
+
+		for _, cmd := range info.Commands {
+			entry := scanner.CoverageEntry{Mechanism: s.Type(), Location: cmd, Status: scanner.CoverageFull}
+			switch {
+			case failed[s.Type()] != "":
+				entry.Status = scanner.CoverageFailed
+				entry.Detail = failed[s.Type()]
+			case sysroot.Offline() && strings.Contains(cmd, "running system only"):
+				entry.Status = scanner.CoverageSkipped
+				entry.Detail = "offline root"
+			case NoExec:
+				entry.Status = scanner.CoverageSkipped
+				entry.Detail = "external commands disabled"
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(sysroot.Path(path))
+	return err == nil || os.IsPermission(err)
+}
+
+// deniedUnder reports whether any denied path is at or below location.
+func deniedUnder(denied []string, location string) bool {
+	for _, path := range denied {
+		if path == location || strings.HasPrefix(path, strings.TrimSuffix(location, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -4,11 +4,12 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
 )
 
@@ -22,13 +23,24 @@ func (s *CronScanner) Type() scanner.MechanismType {
 	return scanner.MechanismCronJob
 }
 
+// Info reports the crontabs read.
+func (s *CronScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "System, per-user, and cron.d crontabs",
+		Paths:       []string{"/etc/crontab", "/etc/cron.d", "/usr/lib/cron/tabs", "/var/cron/tabs", "/var/spool/cron/crontabs"},
+		Commands:    []string{"crontab -l -u <user> for every local account as root, otherwise crontab -l (running system only)"},
+		Privileges:  []string{"root to read the per-user crontab directories and every account's live crontab; otherwise only the invoking user's crontab is seen"},
+	}
+}
+
 func (s *CronScanner) Scan() ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 
 	// Scan system crontab
 	systemItems, err := s.scanSystemCrontab()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning system crontab: %v\n", err)
+		logging.Warn("scanning system crontab", err, "scanner", s.Type(), "path", "/etc/crontab")
 	} else {
 		items = append(items, systemItems...)
 	}
@@ -36,7 +48,7 @@ func (s *CronScanner) Scan() ([]scanner.PersistenceItem, error) {
 	// Scan user crontabs
 	userItems, err := s.scanUserCrontabs()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning user crontabs: %v\n", err)
+		logging.Warn("scanning user crontabs", err, "scanner", s.Type())
 	} else {
 		items = append(items, userItems...)
 	}
@@ -44,7 +56,7 @@ func (s *CronScanner) Scan() ([]scanner.PersistenceItem, error) {
 	// Scan cron.d directory
 	cronDItems, err := s.scanCronD()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning cron.d: %v\n", err)
+		logging.Warn("scanning cron.d", err, "scanner", s.Type(), "path", "/etc/cron.d")
 	} else {
 		items = append(items, cronDItems...)
 	}
@@ -57,7 +69,7 @@ func (s *CronScanner) scanSystemCrontab() ([]scanner.PersistenceItem, error) {
 
 	crontabPath := "/etc/crontab"
 	
-	data, err := os.ReadFile(crontabPath)
+	data, err := readFile(crontabPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return items, nil // No system crontab
@@ -98,7 +110,7 @@ func (s *CronScanner) scanUserCrontabs() ([]scanner.PersistenceItem, error) {
 	}
 
 	for _, dir := range crontabDirs {
-		entries, err := os.ReadDir(dir)
+		entries, err := readDir(dir)
 		if err != nil {
 			continue // Directory doesn't exist or no permission
 		}
@@ -111,7 +123,7 @@ func (s *CronScanner) scanUserCrontabs() ([]scanner.PersistenceItem, error) {
 			username := entry.Name()
 			path := filepath.Join(dir, username)
 			
-			data, err := os.ReadFile(path)
+			data, err := readFile(path)
 			if err != nil {
 				continue
 			}
@@ -120,8 +132,9 @@ func (s *CronScanner) scanUserCrontabs() ([]scanner.PersistenceItem, error) {
 			
 			if len(cronEntries) > 0 {
 				info, _ := entry.Info()
-				modTime := time.Now()
+				var createdAt, modTime time.Time
 				if info != nil {
+					createdAt = fileBirthTime(info)
 					modTime = info.ModTime()
 				}
 
@@ -130,6 +143,7 @@ func (s *CronScanner) scanUserCrontabs() ([]scanner.PersistenceItem, error) {
 					Label:      fmt.Sprintf("User Crontab: %s", username),
 					Path:       path,
 					User:       username,
+					CreatedAt:  createdAt,
 					ModifiedAt: modTime,
 					RawData: map[string]interface{}{
 						"description": fmt.Sprintf("Crontab for user %s with %d entries", username, len(cronEntries)),
@@ -143,51 +157,80 @@ func (s *CronScanner) scanUserCrontabs() ([]scanner.PersistenceItem, error) {
 		}
 	}
 
-	// Also check current user's crontab via crontab command
-	currentUserItems, err := s.scanCurrentUserCrontab()
-	if err == nil {
-		items = append(items, currentUserItems...)
+	// Also ask crontab for the live crontabs: as root for every local
+	// account, since spool file names don't always map to accounts,
+	// otherwise for the invoking user
+	if canExec() {
+		spooled := make(map[string]bool)
+		for _, item := range items {
+			spooled[item.User+"\x00"+crontabSignature(item.RawData["entries"].([]cronEntry))] = true
+		}
+
+		var liveItems []scanner.PersistenceItem
+		if os.Geteuid() == 0 {
+			for _, home := range userHomes() {
+				liveItems = append(liveItems, s.scanLiveCrontab(home.Name, "crontab", "-l", "-u", home.Name)...)
+			}
+		} else {
+			currentUser := os.Getenv("USER")
+			if currentUser == "" {
+				currentUser = "current"
+			}
+			liveItems = s.scanLiveCrontab(currentUser, "crontab", "-l")
+		}
+
+		// Skip live crontabs already read from the user's spool file
+		for _, item := range liveItems {
+			if !spooled[item.User+"\x00"+crontabSignature(item.RawData["entries"].([]cronEntry))] {
+				items = append(items, item)
+			}
+		}
 	}
 
 	return items, nil
 }
 
-func (s *CronScanner) scanCurrentUserCrontab() ([]scanner.PersistenceItem, error) {
+// scanLiveCrontab reports user's crontab as printed by the given crontab
+// command line.
+func (s *CronScanner) scanLiveCrontab(user string, args ...string) []scanner.PersistenceItem {
 	var items []scanner.PersistenceItem
 
-	// Get current user's crontab
-	cmd := exec.Command("crontab", "-l")
-	output, err := cmd.Output()
+	output, err := command.Output(args[0], args[1:]...)
 	if err != nil {
 		// No crontab or error
-		return items, nil
+		return items
 	}
 
-	currentUser := os.Getenv("USER")
-	if currentUser == "" {
-		currentUser = "current"
-	}
-
-	entries := s.parseCrontab(string(output), currentUser)
+	entries := s.parseCrontab(string(output), user)
 	
 	if len(entries) > 0 {
 		item := scanner.PersistenceItem{
 			Mechanism:  scanner.MechanismCronJob,
-			Label:      fmt.Sprintf("User Crontab: %s", currentUser),
-			Path:       "crontab -l",
-			User:       currentUser,
-			ModifiedAt: time.Now(),
+			Label:      fmt.Sprintf("User Crontab: %s", user),
+			Path:       strings.Join(args, " "),
+			User:       user,
 			RawData: map[string]interface{}{
-				"description": fmt.Sprintf("Active crontab for user %s with %d entries", currentUser, len(entries)),
+				"description": fmt.Sprintf("Active crontab for user %s with %d entries", user, len(entries)),
 				"entries": entries,
-				"user":    currentUser,
+				"user":    user,
 				"content": string(output),
 			},
 		}
 		items = append(items, item)
 	}
 
-	return items, nil
+	return items
+}
+
+// crontabSignature identifies a crontab by its schedules and commands, so
+// the same crontab read from a spool file and from crontab -l compares
+// equal.
+func crontabSignature(entries []cronEntry) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "%s\x00%s\n", entry.Schedule, entry.Command)
+	}
+	return b.String()
 }
 
 func (s *CronScanner) scanCronD() ([]scanner.PersistenceItem, error) {
@@ -196,7 +239,7 @@ func (s *CronScanner) scanCronD() ([]scanner.PersistenceItem, error) {
 	// Check /etc/cron.d directory
 	cronDDir := "/etc/cron.d"
 	
-	entries, err := os.ReadDir(cronDDir)
+	entries, err := readDir(cronDDir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return items, nil
@@ -210,7 +253,7 @@ func (s *CronScanner) scanCronD() ([]scanner.PersistenceItem, error) {
 		}
 
 		path := filepath.Join(cronDDir, entry.Name())
-		data, err := os.ReadFile(path)
+		data, err := readFile(path)
 		if err != nil {
 			continue
 		}
@@ -220,8 +263,9 @@ func (s *CronScanner) scanCronD() ([]scanner.PersistenceItem, error) {
 		
 		if len(cronEntries) > 0 {
 			info, _ := entry.Info()
-			modTime := time.Now()
+			var createdAt, modTime time.Time
 			if info != nil {
+				createdAt = fileBirthTime(info)
 				modTime = info.ModTime()
 			}
 
@@ -229,6 +273,7 @@ func (s *CronScanner) scanCronD() ([]scanner.PersistenceItem, error) {
 				Mechanism:  scanner.MechanismCronJob,
 				Label:      fmt.Sprintf("Cron.d: %s", entry.Name()),
 				Path:       path,
+				CreatedAt:  createdAt,
 				ModifiedAt: modTime,
 				RawData: map[string]interface{}{
 					"description": fmt.Sprintf("Cron configuration %s with %d entries", entry.Name(), len(cronEntries)),
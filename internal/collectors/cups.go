@@ -0,0 +1,377 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+const (
+	cupsBackendDir = "/usr/libexec/cups/backend"
+	cupsConfigDir  = "/etc/cups"
+	cupsPPDDir     = "/etc/cups/ppd"
+	// printerDriverDir holds third-party printer drivers: filters, PDEs,
+	// utilities, and the helpers their installers add.
+	printerDriverDir = "/Library/Printers"
+)
+
+// appleCUPSBackends are the backends macOS ships.
+var appleCUPSBackends = map[string]bool{
+	"dnssd": true, "http": true, "https": true, "ipp": true, "ipps": true, "lpd": true,
+	"mdns": true, "pap": true, "smb": true, "snmp": true, "socket": true, "usb": true,
+}
+
+// cupsDirectives are the configuration directives that change what cupsd
+// runs or the environment and privileges it runs it with.
+var cupsDirectives = map[string]bool{
+	"ServerBin": true, "DataDir": true, "SetEnv": true, "PassEnv": true,
+	"FileDevice": true, "Sandboxing": true, "User": true, "Group": true,
+	"SystemGroup": true, "DeviceURI": true,
+}
+
+// CUPSScanner reports what the printing system runs: CUPS backends, which
+// cupsd runs as root unless they are readable and executable by everyone,
+// the configuration directives that change what it runs and how, filter
+// programs named by absolute path in installed printers' PPDs, and setuid
+// or setgid helpers installed with printer drivers.
+type CUPSScanner struct{}
+
+func NewCUPSScanner() *CUPSScanner {
+	return &CUPSScanner{}
+}
+
+func (s *CUPSScanner) Type() scanner.MechanismType {
+	return scanner.MechanismCUPS
+}
+
+// Info reports the CUPS and printer driver locations read.
+func (s *CUPSScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "CUPS backends, configuration, PPD filters, and printer driver helpers",
+		Paths:       []string{cupsBackendDir, cupsConfigDir, cupsPPDDir, printerDriverDir},
+		Privileges:  []string{"root to read cups-files.conf and printers.conf"},
+	}
+}
+
+func (s *CUPSScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+	for _, part := range []struct {
+		name string
+		scan func() ([]scanner.PersistenceItem, error)
+		path string
+	}{
+		{"scanning CUPS backends", s.scanBackends, cupsBackendDir},
+		{"scanning CUPS configuration", s.scanConfig, cupsConfigDir},
+		{"scanning PPD filters", s.scanPPDs, cupsPPDDir},
+		{"scanning printer drivers", s.scanDrivers, printerDriverDir},
+	} {
+		found, err := part.scan()
+		if err != nil {
+			logging.Warn(part.name, err, "scanner", s.Type(), "path", part.path)
+			continue
+		}
+		items = append(items, found...)
+	}
+	return items, nil
+}
+
+func (s *CUPSScanner) scanBackends() ([]scanner.PersistenceItem, error) {
+	entries, err := readDir(cupsBackendDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var items []scanner.PersistenceItem
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(cupsBackendDir, name)
+		info, err := os.Stat(sysroot.Path(path))
+		if err != nil {
+			continue
+		}
+
+		// cupsd runs a backend as root unless everyone may read and
+		// execute it
+		asRoot := info.Mode().Perm()&0005 != 0005
+		item := scanner.PersistenceItem{
+			Mechanism:  scanner.MechanismCUPS,
+			Label:      "CUPS backend: " + name,
+			Path:       path,
+			Program:    path,
+			User:       "lp",
+			CreatedAt:  fileBirthTime(info),
+			ModifiedAt: info.ModTime(),
+			RawData: map[string]interface{}{
+				"description":  fmt.Sprintf("CUPS backend for %s:// device URIs", name),
+				"kind":         "backend",
+				"backend":      name,
+				"apple":        appleCUPSBackends[name],
+				"runs_as_root": asRoot,
+				"permissions":  fmt.Sprintf("%04o", info.Mode().Perm()),
+			},
+		}
+		if asRoot {
+			item.User = "root"
+		}
+		if target, err := os.Readlink(sysroot.Path(path)); err == nil {
+			item.RawData["symlink_target"] = target
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *CUPSScanner) scanConfig() ([]scanner.PersistenceItem, error) {
+	entries, err := readDir(cupsConfigDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var items []scanner.PersistenceItem
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".conf" {
+			continue
+		}
+		path := filepath.Join(cupsConfigDir, name)
+		data, err := readFile(path)
+		if err != nil {
+			continue
+		}
+
+		directives, deviceURIs := parseCUPSConfig(data)
+		item := scanner.PersistenceItem{
+			Mechanism:  scanner.MechanismCUPS,
+			Label:      "CUPS configuration: " + name,
+			Path:       path,
+			ModifiedAt: getFileModTime(path),
+			RawData: map[string]interface{}{
+				"description": fmt.Sprintf("CUPS configuration file (%d directives that change what cupsd runs)", len(directives)),
+				"kind":        "config",
+				"directives":  directives,
+				"content":     string(data),
+			},
+		}
+		if len(deviceURIs) > 0 {
+			item.RawData["device_uris"] = deviceURIs
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseCUPSConfig returns the values of the directives in cupsDirectives
+// found in a cupsd.conf-style file, and the device URI of each printer it
+// defines.
+func parseCUPSConfig(data []byte) (map[string][]string, map[string]string) {
+	directives := make(map[string][]string)
+	deviceURIs := make(map[string]string)
+
+	var printer string
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "<") {
+			// Sections such as <Printer name> and <DefaultPrinter name>
+			section := strings.Fields(strings.Trim(line, "<>"))
+			printer = ""
+			if len(section) == 2 && strings.HasSuffix(section[0], "Printer") {
+				printer = section[1]
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		if !cupsDirectives[key] {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if key == "DeviceURI" && printer != "" {
+			deviceURIs[printer] = value
+		}
+		directives[key] = append(directives[key], value)
+	}
+	return directives, deviceURIs
+}
+
+// scanPPDs reports each filter program an installed printer's PPD names by
+// absolute path. cupsd runs it for every job sent to the printer.
+func (s *CUPSScanner) scanPPDs() ([]scanner.PersistenceItem, error) {
+	entries, err := readDir(cupsPPDDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var items []scanner.PersistenceItem
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".ppd" {
+			continue
+		}
+		path := filepath.Join(cupsPPDDir, name)
+		data, err := readFile(path)
+		if err != nil {
+			continue
+		}
+
+		queue := strings.TrimSuffix(name, ".ppd")
+		for _, filter := range ppdFilters(data) {
+			items = append(items, scanner.PersistenceItem{
+				Mechanism:  scanner.MechanismCUPS,
+				Label:      fmt.Sprintf("CUPS filter: %s %s", queue, filepath.Base(filter.program)),
+				Path:       path,
+				Program:    filter.program,
+				User:       "lp",
+				ModifiedAt: getFileModTime(path),
+				RawData: map[string]interface{}{
+					"description": fmt.Sprintf("Filter run by cupsd for each job printed to %s", queue),
+					"kind":        "filter",
+					"printer":     queue,
+					"keyword":     filter.keyword,
+					"filter":      filter.line,
+				},
+			})
+		}
+	}
+	return items, nil
+}
+
+type ppdFilter struct {
+	keyword string
+	line    string
+	program string
+}
+
+// ppdFilters returns the *cupsFilter, *cupsFilter2, and *cupsPreFilter
+// entries of a PPD whose program is an absolute path. Programs named
+// relative to the CUPS filter directory ship with macOS.
+func ppdFilters(data []byte) []ppdFilter {
+	var filters []ppdFilter
+	seen := make(map[string]bool)
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		keyword, value, ok := strings.Cut(line, ":")
+		switch keyword {
+		case "*cupsFilter", "*cupsFilter2", "*cupsPreFilter":
+		default:
+			continue
+		}
+		if !ok {
+			continue
+		}
+		// "source/type [destination/type] cost program"
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		fields := strings.Fields(value)
+		if len(fields) < 3 {
+			continue
+		}
+		program := fields[len(fields)-1]
+		if !filepath.IsAbs(program) || seen[program] {
+			continue
+		}
+		seen[program] = true
+		filters = append(filters, ppdFilter{keyword: strings.TrimPrefix(keyword, "*"), line: value, program: program})
+	}
+	return filters
+}
+
+// scanDrivers reports setuid and setgid files installed with printer
+// drivers, which run with their owner's or group's privileges whoever
+// starts them.
+func (s *CUPSScanner) scanDrivers() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+	var paths []string
+	err := walkPrinterDrivers(printerDriverDir, 8, func(path string, info os.FileInfo) {
+		if info.Mode()&(os.ModeSetuid|os.ModeSetgid) != 0 {
+			paths = append(paths, path)
+		}
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		info, err := os.Stat(sysroot.Path(path))
+		if err != nil {
+			continue
+		}
+		uid, gid, _ := fileOwner(info)
+		var user string
+		if info.Mode()&os.ModeSetuid != 0 && uid == 0 {
+			user = "root"
+		}
+		items = append(items, scanner.PersistenceItem{
+			Mechanism:  scanner.MechanismCUPS,
+			Label:      "Printer driver helper: " + filepath.Base(path),
+			Path:       path,
+			Program:    path,
+			User:       user,
+			CreatedAt:  fileBirthTime(info),
+			ModifiedAt: info.ModTime(),
+			RawData: map[string]interface{}{
+				"description": "Setuid or setgid helper installed with a printer driver",
+				"kind":        "driver_helper",
+				"setuid":      info.Mode()&os.ModeSetuid != 0,
+				"setgid":      info.Mode()&os.ModeSetgid != 0,
+				"owner_uid":   uid,
+				"owner_gid":   gid,
+				"permissions": fmt.Sprintf("%04o", unixMode(info.Mode())),
+			},
+		})
+	}
+	return items, nil
+}
+
+// walkPrinterDrivers calls fn for every regular file below dir, descending
+// at most depth levels and never following symbolic links.
+func walkPrinterDrivers(dir string, depth int, fn func(path string, info os.FileInfo)) error {
+	entries, err := readDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if depth > 1 {
+				walkPrinterDrivers(path, depth-1, fn)
+			}
+			continue
+		}
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			fn(path, info)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package collectors
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+// dockTile is one entry of the persistent-apps array of com.apple.dock.plist,
+// which holds the applications on the left of the Dock, or of
+// persistent-others, which holds the files, folders, and links on the
+// right.
+type dockTile struct {
+	TileType string `plist:"tile-type"`
+	TileData struct {
+		Label string `plist:"file-label"`
+		// URLLabel labels url-tile links
+		URLLabel         string `plist:"label"`
+		BundleIdentifier string `plist:"bundle-identifier"`
+		FileData         struct {
+			URL string `plist:"_CFURLString"`
+		} `plist:"file-data"`
+		URL struct {
+			URL string `plist:"_CFURLString"`
+		} `plist:"url"`
+	} `plist:"tile-data"`
+}
+
+// DockScanner reports the applications and files each user keeps in the
+// Dock. Malware that plants a tile for a copy of itself, or for a look-alike
+// of a familiar app, is relaunched by the user after the original is
+// removed.
+type DockScanner struct{}
+
+func NewDockScanner() *DockScanner {
+	return &DockScanner{}
+}
+
+func (s *DockScanner) Type() scanner.MechanismType {
+	return scanner.MechanismDock
+}
+
+// Info reports the per-user Dock preferences read.
+func (s *DockScanner) Info() scanner.ScannerInfo {
+	var paths []string
+	for _, home := range userHomes() {
+		paths = append(paths, dockPlist(home.Dir))
+	}
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Applications, files, and folders kept in each user's Dock",
+		Paths:       paths,
+		Privileges:  []string{"root to read every local user's Dock; otherwise only the invoking user's"},
+	}
+}
+
+func dockPlist(home string) string {
+	return filepath.Join(home, "Library", "Preferences", "com.apple.dock.plist")
+}
+
+func (s *DockScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	for _, home := range userHomes() {
+		path := dockPlist(home.Dir)
+		data, err := readFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Warn("reading Dock preferences", err, "scanner", s.Type(), "path", path, "user", home.Name)
+			}
+			continue
+		}
+
+		var dock struct {
+			Apps   []dockTile `plist:"persistent-apps"`
+			Others []dockTile `plist:"persistent-others"`
+		}
+		if _, err := plist.Unmarshal(data, &dock); err != nil {
+			logging.Warn("parsing Dock preferences", err, "scanner", s.Type(), "path", path, "user", home.Name)
+			continue
+		}
+
+		modified := getFileModTime(path)
+		for _, section := range []struct {
+			name  string
+			tiles []dockTile
+		}{
+			{"persistent-apps", dock.Apps},
+			{"persistent-others", dock.Others},
+		} {
+			for position, tile := range section.tiles {
+				if item, ok := dockItem(tile, section.name, position); ok {
+					item.Path = path
+					item.User = home.Name
+					item.ModifiedAt = modified
+					items = append(items, item)
+				}
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// dockItem describes one tile, or reports false for spacers and tiles
+// with no target.
+func dockItem(tile dockTile, section string, position int) (scanner.PersistenceItem, bool) {
+	target := tile.TileData.FileData.URL
+	if target == "" {
+		target = tile.TileData.URL.URL
+	}
+	if target == "" {
+		return scanner.PersistenceItem{}, false
+	}
+
+	label := tile.TileData.Label
+	item := scanner.PersistenceItem{
+		Mechanism: scanner.MechanismDock,
+		RawData: map[string]interface{}{
+			"section":   section,
+			"position":  position,
+			"tile_type": tile.TileType,
+			"url":       target,
+		},
+	}
+	if tile.TileData.BundleIdentifier != "" {
+		item.RawData["bundle_identifier"] = tile.TileData.BundleIdentifier
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "file" {
+		// A link to a web page or another URL scheme
+		if label == "" {
+			label = tile.TileData.URLLabel
+		}
+		if label == "" {
+			label = target
+		}
+		item.Label = "Dock link: " + label
+		item.RawData["description"] = fmt.Sprintf("Dock tile opening %s", target)
+		return item, true
+	}
+
+	file := strings.TrimSuffix(u.Path, "/")
+	if label == "" {
+		label = strings.TrimSuffix(filepath.Base(file), ".app")
+	}
+	item.Label = "Dock: " + label
+	item.RawData["target"] = file
+	if tile.TileType != "directory-tile" {
+		item.Program = file
+	}
+	if _, err := os.Lstat(sysroot.Path(file)); os.IsNotExist(err) {
+		item.RawData["missing"] = true
+	}
+	item.RawData["description"] = fmt.Sprintf("Dock tile for %s", file)
+	return item, true
+}
@@ -0,0 +1,132 @@
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+// launchdConfFiles are the system-wide files launchd once ran launchctl
+// subcommands from at boot and at each login.
+var launchdConfFiles = []string{"/etc/launchd.conf", "/etc/launchd-user.conf"}
+
+// EnvironmentScanner reports environment variables that launchd or
+// loginwindow apply to every process in a session: the legacy
+// ~/.MacOSX/environment.plist and the setenv commands in launchd.conf
+// files. Both are ignored by current macOS releases, but they still appear
+// as injection attempts and some older systems honor them.
+type EnvironmentScanner struct{}
+
+func NewEnvironmentScanner() *EnvironmentScanner {
+	return &EnvironmentScanner{}
+}
+
+func (s *EnvironmentScanner) Type() scanner.MechanismType {
+	return scanner.MechanismEnvironment
+}
+
+// Info reports the environment files read.
+func (s *EnvironmentScanner) Info() scanner.ScannerInfo {
+	paths := append([]string{}, launchdConfFiles...)
+	for _, home := range userHomes() {
+		paths = append(paths,
+			filepath.Join(home.Dir, ".MacOSX", "environment.plist"),
+			filepath.Join(home.Dir, ".launchd.conf"))
+	}
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Session environment variables from environment.plist and launchd.conf setenv commands",
+		Paths:       paths,
+		Privileges:  []string{"root to read every local user's environment files; otherwise only the invoking user's"},
+	}
+}
+
+func (s *EnvironmentScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	for _, path := range launchdConfFiles {
+		items = append(items, s.scanLaunchdConf(path, "")...)
+	}
+
+	for _, home := range userHomes() {
+		items = append(items, s.scanEnvironmentPlist(filepath.Join(home.Dir, ".MacOSX", "environment.plist"), home.Name)...)
+		items = append(items, s.scanLaunchdConf(filepath.Join(home.Dir, ".launchd.conf"), home.Name)...)
+	}
+
+	return items, nil
+}
+
+func (s *EnvironmentScanner) scanEnvironmentPlist(path, user string) []scanner.PersistenceItem {
+	data, err := readFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading environment.plist", err, "scanner", s.Type(), "path", path)
+		}
+		return nil
+	}
+
+	var variables map[string]interface{}
+	if _, err := plist.Unmarshal(data, &variables); err != nil {
+		logging.Warn("parsing environment.plist", err, "scanner", s.Type(), "path", path)
+		return nil
+	}
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var items []scanner.PersistenceItem
+	for _, name := range names {
+		items = append(items, s.variableItem(path, user, "environment.plist", name, fmt.Sprint(variables[name]), string(data)))
+	}
+	return items
+}
+
+// scanLaunchdConf reads the setenv commands in a launchd.conf file, which
+// holds one launchctl subcommand per line.
+func (s *EnvironmentScanner) scanLaunchdConf(path, user string) []scanner.PersistenceItem {
+	data, err := readFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading launchd.conf", err, "scanner", s.Type(), "path", path)
+		}
+		return nil
+	}
+
+	var items []scanner.PersistenceItem
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "setenv" {
+			continue
+		}
+		value := strings.Join(fields[2:], " ")
+		items = append(items, s.variableItem(path, user, "launchd.conf", fields[1], value, string(data)))
+	}
+	return items
+}
+
+func (s *EnvironmentScanner) variableItem(path, user, source, name, value, content string) scanner.PersistenceItem {
+	return scanner.PersistenceItem{
+		Mechanism:  scanner.MechanismEnvironment,
+		Label:      name,
+		Path:       path,
+		User:       user,
+		ModifiedAt: getFileModTime(path),
+		RawData: map[string]interface{}{
+			"description": fmt.Sprintf("Session environment variable %s set in %s", name, source),
+			"variable":    name,
+			"value":       value,
+			"source":      source,
+			"content":     content,
+		},
+	}
+}
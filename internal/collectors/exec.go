@@ -0,0 +1,15 @@
+package collectors
+
+import "github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+
+// NoExec stops collectors from running external tools such as crontab,
+// osascript, dscl, and system_profiler, leaving only what can be read from
+// files. Exec-based collection fails in sandboxed and some MDM contexts and
+// is itself noisy to EDR. Set it before scanning.
+var NoExec bool
+
+// canExec reports whether collectors may query the running system through
+// external commands.
+func canExec() bool {
+	return !NoExec && !sysroot.Offline()
+}
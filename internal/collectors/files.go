@@ -0,0 +1,101 @@
+package collectors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// FileStage runs DescribeFiles as a scanner.Stage.
+var FileStage = scanner.NewStage("files", DescribeFiles)
+
+// DescribeFiles records the SHA-256, size, ownership, permissions,
+// provenance attributes, and Mach-O architectures of each item's config
+// file and program, so outputs can be matched against EDR telemetry and
+// threat intelligence. Paths that are not regular files, such as app
+// bundles or the "crontab -l" pseudo-path, are left empty. A file shared by
+// several items is hashed once.
+func DescribeFiles(items []scanner.PersistenceItem) {
+	seen := make(map[string]*scanner.FileMetadata)
+	describe := func(path string) *scanner.FileMetadata {
+		if !filepath.IsAbs(path) {
+			return nil
+		}
+		if meta, ok := seen[path]; ok {
+			return meta
+		}
+		meta, err := fileMetadata(path)
+		if err != nil && !os.IsNotExist(err) {
+			logging.Warn("hashing file", err, "path", path)
+		}
+		seen[path] = meta
+		return meta
+	}
+
+	for i := range items {
+		items[i].ConfigFile = describe(items[i].Path)
+		items[i].ProgramFile = describe(items[i].Program)
+
+		// Not every collector records the config file's mode itself
+		if items[i].FileMode == "" && filepath.IsAbs(items[i].Path) {
+			if info, err := os.Stat(sysroot.Path(items[i].Path)); err == nil {
+				items[i].FileMode = info.Mode().String()
+			}
+		}
+	}
+}
+
+func fileMetadata(path string) (*scanner.FileMetadata, error) {
+	file, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, err
+	}
+	meta := &scanner.FileMetadata{
+		Path:      path,
+		SHA256:    hex.EncodeToString(h.Sum(nil)),
+		Size:      info.Size(),
+		Mode:      fmt.Sprintf("%04o", unixMode(info.Mode())),
+		CreatedAt: fileBirthTime(info),
+		XAttrs:    extendedAttributes(path),
+		Binary:    binaryInfo(file),
+	}
+	meta.UID, meta.GID, _ = fileOwner(info)
+	if meta.ACL, err = fileACL(sysroot.Path(path)); err != nil {
+		logging.Warn("reading access control list", err, "path", path)
+	}
+	return meta, nil
+}
+
+// unixMode converts Go's file mode bits back to the st_mode permission
+// bits.
+func unixMode(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return bits
+}
@@ -0,0 +1,19 @@
+//go:build darwin
+
+package collectors
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileBirthTime returns when the file was created, or the zero time if the
+// filesystem does not record it.
+func fileBirthTime(info os.FileInfo) time.Time {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Birthtimespec.Sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(stat.Birthtimespec.Unix())
+}
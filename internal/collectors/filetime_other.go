@@ -0,0 +1,14 @@
+//go:build !darwin
+
+package collectors
+
+import (
+	"os"
+	"time"
+)
+
+// fileBirthTime returns the zero time; only darwin exposes creation times
+// through os.FileInfo.
+func fileBirthTime(info os.FileInfo) time.Time {
+	return time.Time{}
+}
@@ -0,0 +1,12 @@
+//go:build darwin
+
+package collectors
+
+import "golang.org/x/sys/unix"
+
+// AppleSilicon reports whether the running Mac has an Apple silicon CPU,
+// including when this process runs under Rosetta.
+func AppleSilicon() bool {
+	arm64, err := unix.SysctlUint32("hw.optional.arm64")
+	return err == nil && arm64 == 1
+}
@@ -0,0 +1,9 @@
+//go:build !darwin
+
+package collectors
+
+// AppleSilicon reports whether the running Mac has an Apple silicon CPU.
+// Other systems are never Macs.
+func AppleSilicon() bool {
+	return false
+}
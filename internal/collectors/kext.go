@@ -0,0 +1,205 @@
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+// kextDirs are the third-party kernel extension locations. Kexts approved
+// by the user are copied into StagedExtensions, which mirrors the paths
+// they were installed at, before they can load.
+var kextDirs = []string{"/Library/Extensions", "/Library/StagedExtensions"}
+
+// loadedKextPattern matches a kext in kmutil showloaded or kextstat output:
+// its bundle identifier followed by its version in parentheses.
+var loadedKextPattern = regexp.MustCompile(`^\s*\d+\s+\d+\s+0x[0-9a-fA-F]+\s+0x[0-9a-fA-F]+\s+0x[0-9a-fA-F]+\s+(\S+) \(([^)]*)\)`)
+
+// KextScanner reports third-party kernel extensions on disk and compares
+// them with the kexts loaded in the running kernel. Loaded code with no
+// bundle on disk, and kexts staged but not loaded, are marked so the
+// heuristics can flag them.
+type KextScanner struct{}
+
+func NewKextScanner() *KextScanner {
+	return &KextScanner{}
+}
+
+func (s *KextScanner) Type() scanner.MechanismType {
+	return scanner.MechanismKernelExtension
+}
+
+// Info reports the kext directories read and the loaded-kext queries.
+func (s *KextScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Third-party kernel extensions on disk and loaded in the kernel",
+		Paths:       kextDirs,
+		Commands:    []string{"kmutil showloaded (running system only)", "kextstat (running system only, if kmutil fails)"},
+	}
+}
+
+type kextBundle struct {
+	ID         string `plist:"CFBundleIdentifier"`
+	Version    string `plist:"CFBundleVersion"`
+	Executable string `plist:"CFBundleExecutable"`
+}
+
+func (s *KextScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	// loaded is nil when the running kernel could not be queried
+	var loaded map[string]string
+	if canExec() {
+		var err error
+		if loaded, err = loadedKexts(); err != nil {
+			logging.Warn("listing loaded kexts", err, "scanner", s.Type())
+		}
+	}
+
+	onDisk := make(map[string]bool)
+	for _, dir := range kextDirs {
+		for _, path := range findBundles(dir, ".kext", 6) {
+			item, id := s.kextItem(path, dir == "/Library/StagedExtensions")
+			if loaded != nil && id != "" {
+				_, isLoaded := loaded[id]
+				item.RawData["loaded"] = isLoaded
+			}
+			onDisk[id] = true
+			items = append(items, item)
+
+			// Plugin kexts load under their own bundle IDs
+			for _, plugin := range findBundles(filepath.Join(path, "Contents", "PlugIns"), ".kext", 1) {
+				if id := kextBundleID(plugin); id != "" {
+					onDisk[id] = true
+				}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(loaded))
+	for id := range loaded {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if onDisk[id] || strings.HasPrefix(id, "com.apple.") {
+			continue
+		}
+		items = append(items, scanner.PersistenceItem{
+			Mechanism: scanner.MechanismKernelExtension,
+			Label:     id,
+			Path:      "kmutil showloaded",
+			RawData: map[string]interface{}{
+				"description": fmt.Sprintf("Loaded kernel extension %s with no bundle in %s", id, strings.Join(kextDirs, " or ")),
+				"bundle_id":   id,
+				"version":     loaded[id],
+				"loaded":      true,
+				"on_disk":     false,
+			},
+		})
+	}
+
+	return items, nil
+}
+
+func (s *KextScanner) kextItem(path string, staged bool) (scanner.PersistenceItem, string) {
+	item := scanner.PersistenceItem{
+		Mechanism:  scanner.MechanismKernelExtension,
+		Label:      filepath.Base(path),
+		Path:       path,
+		ModifiedAt: getFileModTime(path),
+		RawData: map[string]interface{}{
+			"description": fmt.Sprintf("Kernel extension %s", filepath.Base(path)),
+			"staged":      staged,
+			"on_disk":     true,
+		},
+	}
+
+	infoPath := filepath.Join(path, "Contents", "Info.plist")
+	data, err := readFile(infoPath)
+	if err != nil {
+		item.Errors = append(item.Errors, fmt.Sprintf("reading Info.plist: %v", err))
+		return item, ""
+	}
+	var bundle kextBundle
+	if _, err := plist.Unmarshal(data, &bundle); err != nil {
+		item.Errors = append(item.Errors, fmt.Sprintf("parsing Info.plist: %v", err))
+		return item, ""
+	}
+
+	item.Label = bundle.ID
+	item.RawData["bundle_id"] = bundle.ID
+	item.RawData["version"] = bundle.Version
+	if bundle.Executable != "" {
+		item.Program = filepath.Join(path, "Contents", "MacOS", bundle.Executable)
+	}
+	return item, bundle.ID
+}
+
+// kextBundleID returns the bundle identifier in the Info.plist of the kext
+// at path, or "" if it cannot be read.
+func kextBundleID(path string) string {
+	data, err := readFile(filepath.Join(path, "Contents", "Info.plist"))
+	if err != nil {
+		return ""
+	}
+	var bundle kextBundle
+	if _, err := plist.Unmarshal(data, &bundle); err != nil {
+		return ""
+	}
+	return bundle.ID
+}
+
+// loadedKexts returns the bundle identifiers and versions of the kexts
+// loaded in the running kernel.
+func loadedKexts() (map[string]string, error) {
+	output, err := command.Output("kmutil", "showloaded")
+	if err != nil {
+		if output, err = command.Output("kextstat"); err != nil {
+			return nil, fmt.Errorf("running kmutil and kextstat: %w", err)
+		}
+	}
+
+	loaded := make(map[string]string)
+	for _, line := range strings.Split(string(output), "\n") {
+		if m := loadedKextPattern.FindStringSubmatch(line); m != nil {
+			loaded[m[1]] = m[2]
+		}
+	}
+	return loaded, nil
+}
+
+// findBundles returns the directories under dir, up to depth levels down,
+// whose names end in ext. Bundles are not searched for nested bundles.
+func findBundles(dir, ext string, depth int) []string {
+	entries, err := readDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading bundle directory", err, "path", dir)
+		}
+		return nil
+	}
+
+	var bundles []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasSuffix(entry.Name(), ext) {
+			bundles = append(bundles, path)
+		} else if depth > 1 {
+			bundles = append(bundles, findBundles(path, ext, depth-1)...)
+		}
+	}
+	return bundles
+}
@@ -1,11 +1,14 @@
 package collectors
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
 	"howett.net/plist"
 )
@@ -13,6 +16,9 @@ import (
 type LaunchdScanner struct {
 	paths        []string
 	mechanismType scanner.MechanismType
+	// owners maps per-user directories in paths to the account they
+	// belong to.
+	owners       map[string]string
 }
 
 type LaunchdPlist struct {
@@ -28,19 +34,40 @@ type LaunchdPlist struct {
 	StartCalendarInterval interface{}         `plist:"StartCalendarInterval"`
 	WatchPaths         []string               `plist:"WatchPaths"`
 	QueueDirectories   []string               `plist:"QueueDirectories"`
+	StartOnMount       bool                   `plist:"StartOnMount"`
+	Sockets            map[string]interface{} `plist:"Sockets"`
+	MachServices       map[string]interface{} `plist:"MachServices"`
+	InetdCompatibility map[string]interface{} `plist:"inetdCompatibility"`
 	StandardInPath     string                 `plist:"StandardInPath"`
 	StandardOutPath    string                 `plist:"StandardOutPath"`
 	StandardErrorPath  string                 `plist:"StandardErrorPath"`
+	// LimitLoadToSessionType is a string or an array of strings
+	LimitLoadToSessionType interface{}        `plist:"LimitLoadToSessionType"`
+	ProcessType        string                 `plist:"ProcessType"`
+	AbandonProcessGroup bool                  `plist:"AbandonProcessGroup"`
+	LaunchOnlyOnce     bool                   `plist:"LaunchOnlyOnce"`
+	ThrottleInterval   int                    `plist:"ThrottleInterval"`
+	LegacyTimers       bool                   `plist:"LegacyTimers"`
+	// LaunchEvents maps event streams to named matching dictionaries
+	LaunchEvents       map[string]interface{} `plist:"LaunchEvents"`
 }
 
 func NewLaunchAgentScanner() *LaunchdScanner {
+	paths := []string{
+		"/Library/LaunchAgents",
+		"/System/Library/LaunchAgents",
+	}
+	owners := make(map[string]string)
+	for _, home := range userHomes() {
+		dir := filepath.Join(home.Dir, "Library/LaunchAgents")
+		paths = append(paths, dir)
+		owners[dir] = home.Name
+	}
+
 	return &LaunchdScanner{
-		paths: []string{
-			"/Library/LaunchAgents",
-			"/System/Library/LaunchAgents",
-			filepath.Join(os.Getenv("HOME"), "Library/LaunchAgents"),
-		},
+		paths:         paths,
 		mechanismType: scanner.MechanismLaunchAgent,
+		owners:        owners,
 	}
 }
 
@@ -58,42 +85,114 @@ func (s *LaunchdScanner) Type() scanner.MechanismType {
 	return s.mechanismType
 }
 
+// Info reports the directories walked for launchd job definitions.
+func (s *LaunchdScanner) Info() scanner.ScannerInfo {
+	info := scanner.ScannerInfo{
+		Mechanism:   s.mechanismType,
+		Description: "launchd job definitions (*.plist)",
+		Paths:       s.paths,
+	}
+	if s.mechanismType == scanner.MechanismLaunchAgent {
+		info.Privileges = []string{"root to read every local user's ~/Library/LaunchAgents; otherwise only the invoking user's"}
+	}
+	return info
+}
+
 func (s *LaunchdScanner) Scan() ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 	
-	for _, basePath := range s.paths {
-		if _, err := os.Stat(basePath); os.IsNotExist(err) {
-			continue
+	results, errs := scanEach(s.paths, s.scanDirectory)
+	for i, basePath := range s.paths {
+		items = append(items, results[i]...)
+		if errs[i] != nil {
+			return items, fmt.Errorf("error walking %s: %w", basePath, errs[i])
+		}
+	}
+	
+	return items, nil
+}
+
+func (s *LaunchdScanner) scanDirectory(basePath string) ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+	
+	if _, err := os.Stat(sysroot.Path(basePath)); os.IsNotExist(err) {
+		return nil, nil
+	}
+	
+	err := filepath.Walk(sysroot.Path(basePath), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Record permission errors but continue
+			noteDenied(sysroot.Trim(path), err)
+			return nil
 		}
 		
-		err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				// Log permission errors but continue
-				if os.IsPermission(err) {
-					return nil
-				}
-				return nil
+		if strings.HasSuffix(path, ".plist") && !info.IsDir() {
+			item, err := s.parsePlist(sysroot.Trim(path), info)
+			if errors.Is(err, errMalformedPlist) {
+				item, err = s.unparseableItem(sysroot.Trim(path), info, err), nil
 			}
-			
-			if strings.HasSuffix(path, ".plist") && !info.IsDir() {
-				item, err := s.parsePlist(path, info)
-				if err == nil && item != nil {
-					items = append(items, *item)
+			if err == nil && item != nil {
+				if item.User == "" {
+					item.User = s.owners[basePath]
 				}
+				items = append(items, *item)
 			}
-			return nil
-		})
-		
-		if err != nil && !os.IsPermission(err) {
-			return items, fmt.Errorf("error walking %s: %w", basePath, err)
 		}
+		return nil
+	})
+	
+	if err != nil && !os.IsPermission(err) {
+		return items, err
 	}
 	
 	return items, nil
 }
 
+// ParseLaunchdPlist reads a single launchd job definition outside of a
+// scan, treating it as a job of the given mechanism.
+func ParseLaunchdPlist(path string, mechanism scanner.MechanismType) (*scanner.PersistenceItem, error) {
+	info, err := os.Stat(sysroot.Path(path))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &LaunchdScanner{mechanismType: mechanism}
+	item, err := s.parsePlist(path, info)
+	if err != nil {
+		return nil, err
+	}
+	if item.Label == "" && item.Program == "" {
+		return nil, fmt.Errorf("%s is not a launchd job definition", path)
+	}
+	return item, nil
+}
+
+// errMalformedPlist is wrapped by parsePlist's error when the file was read
+// but does not decode as a job definition.
+var errMalformedPlist = errors.New("malformed plist")
+
+// unparseableItem reports a job definition that could not be decoded. A
+// corrupted or truncated plist in a launchd directory may be an attempt to
+// hide a job from tools like this one, so it is reported rather than
+// skipped; the file stage still records its hash.
+func (s *LaunchdScanner) unparseableItem(path string, info os.FileInfo, err error) *scanner.PersistenceItem {
+	return &scanner.PersistenceItem{
+		Mechanism:  s.mechanismType,
+		Label:      strings.TrimSuffix(filepath.Base(path), ".plist"),
+		Path:       path,
+		CreatedAt:  fileBirthTime(info),
+		ModifiedAt: info.ModTime(),
+		FileMode:   info.Mode().String(),
+		RawData: map[string]interface{}{
+			"unparseable": true,
+			"size":        info.Size(),
+		},
+		Errors: []string{err.Error()},
+	}
+}
+
 func (s *LaunchdScanner) parsePlist(path string, info os.FileInfo) (*scanner.PersistenceItem, error) {
-	file, err := os.Open(path)
+	file, err := openFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +201,7 @@ func (s *LaunchdScanner) parsePlist(path string, info os.FileInfo) (*scanner.Per
 	var launchdPlist LaunchdPlist
 	decoder := plist.NewDecoder(file)
 	if err := decoder.Decode(&launchdPlist); err != nil {
-		return nil, fmt.Errorf("error decoding plist %s: %w", path, err)
+		return nil, fmt.Errorf("%w %s: %v", errMalformedPlist, path, err)
 	}
 	
 	// Extract program path
@@ -121,7 +220,6 @@ func (s *LaunchdScanner) parsePlist(path string, info os.FileInfo) (*scanner.Per
 	}
 	
 	item := &scanner.PersistenceItem{
-		ID:          filepath.Base(path),
 		Mechanism:   s.mechanismType,
 		Label:       launchdPlist.Label,
 		Path:        path,
@@ -131,6 +229,7 @@ func (s *LaunchdScanner) parsePlist(path string, info os.FileInfo) (*scanner.Per
 		RunAtLoad:   launchdPlist.RunAtLoad,
 		KeepAlive:   keepAlive,
 		Disabled:    launchdPlist.Disabled,
+		CreatedAt:   fileBirthTime(info),
 		ModifiedAt:  info.ModTime(),
 		FileMode:    info.Mode().String(),
 		RawData:     make(map[string]interface{}),
@@ -149,6 +248,142 @@ func (s *LaunchdScanner) parsePlist(path string, info os.FileInfo) (*scanner.Per
 	if len(launchdPlist.QueueDirectories) > 0 {
 		item.RawData["QueueDirectories"] = launchdPlist.QueueDirectories
 	}
+	if launchdPlist.StartOnMount {
+		item.RawData["StartOnMount"] = true
+	}
+	if len(launchdPlist.Sockets) > 0 {
+		item.RawData["Sockets"] = launchdPlist.Sockets
+	}
+	if len(launchdPlist.MachServices) > 0 {
+		services := make([]string, 0, len(launchdPlist.MachServices))
+		for name := range launchdPlist.MachServices {
+			services = append(services, name)
+		}
+		sort.Strings(services)
+		item.RawData["MachServices"] = services
+	}
+	if launchdPlist.InetdCompatibility != nil {
+		item.RawData["inetdCompatibility"] = launchdPlist.InetdCompatibility
+	}
+	if sessions := sessionTypes(launchdPlist.LimitLoadToSessionType); len(sessions) > 0 {
+		item.RawData["LimitLoadToSessionType"] = sessions
+	}
+	if launchdPlist.ProcessType != "" {
+		item.RawData["ProcessType"] = launchdPlist.ProcessType
+	}
+	if launchdPlist.AbandonProcessGroup {
+		item.RawData["AbandonProcessGroup"] = true
+	}
+	if launchdPlist.LaunchOnlyOnce {
+		item.RawData["LaunchOnlyOnce"] = true
+	}
+	if launchdPlist.ThrottleInterval > 0 {
+		item.RawData["ThrottleInterval"] = launchdPlist.ThrottleInterval
+	}
+	if launchdPlist.LegacyTimers {
+		item.RawData["LegacyTimers"] = true
+	}
+	if launchdPlist.StandardInPath != "" {
+		item.RawData["StandardInPath"] = launchdPlist.StandardInPath
+	}
+	if launchdPlist.StandardOutPath != "" {
+		item.RawData["StandardOutPath"] = launchdPlist.StandardOutPath
+	}
+	if launchdPlist.StandardErrorPath != "" {
+		item.RawData["StandardErrorPath"] = launchdPlist.StandardErrorPath
+	}
+	item.Exposure = launchdExposure(&launchdPlist)
+	item.LaunchEvents = launchEvents(launchdPlist.LaunchEvents)
 	
 	return item, nil
+}
+
+// sessionTypes normalises LimitLoadToSessionType, which may be a single
+// session name or an array of them.
+func sessionTypes(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var sessions []string
+		for _, session := range v {
+			if s, ok := session.(string); ok {
+				sessions = append(sessions, s)
+			}
+		}
+		return sessions
+	}
+	return nil
+}
+
+// launchEvents flattens the LaunchEvents dictionary into one entry per
+// event, sorted by stream and name.
+func launchEvents(streams map[string]interface{}) []scanner.LaunchEvent {
+	var events []scanner.LaunchEvent
+	for stream, v := range streams {
+		named, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for name, descriptor := range named {
+			event := scanner.LaunchEvent{Stream: stream, Name: name}
+			event.Matching, _ = descriptor.(map[string]interface{})
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Stream != events[j].Stream {
+			return events[i].Stream < events[j].Stream
+		}
+		return events[i].Name < events[j].Name
+	})
+	return events
+}
+
+// launchdExposure collects the Mach services and sockets a job registers,
+// or nil if it registers none.
+func launchdExposure(p *LaunchdPlist) *scanner.LaunchdExposure {
+	if len(p.MachServices) == 0 && len(p.Sockets) == 0 && p.InetdCompatibility == nil {
+		return nil
+	}
+
+	exposure := &scanner.LaunchdExposure{Inetd: p.InetdCompatibility != nil}
+	for name := range p.MachServices {
+		exposure.MachServices = append(exposure.MachServices, name)
+	}
+	sort.Strings(exposure.MachServices)
+
+	for name, spec := range p.Sockets {
+		// A socket entry is a dictionary or an array of dictionaries
+		var listeners []map[string]interface{}
+		switch v := spec.(type) {
+		case map[string]interface{}:
+			listeners = append(listeners, v)
+		case []interface{}:
+			for _, entry := range v {
+				if m, ok := entry.(map[string]interface{}); ok {
+					listeners = append(listeners, m)
+				}
+			}
+		}
+
+		for _, listener := range listeners {
+			socket := scanner.LaunchdSocket{Name: name, Type: "stream", Passive: true}
+			if v, ok := listener["SockType"].(string); ok {
+				socket.Type = v
+			}
+			if v, ok := listener["SockPassive"].(bool); ok {
+				socket.Passive = v
+			}
+			socket.Node, _ = listener["SockNodeName"].(string)
+			socket.Path, _ = listener["SockPathName"].(string)
+			if service, ok := listener["SockServiceName"]; ok {
+				socket.Service = fmt.Sprint(service)
+			}
+			exposure.Sockets = append(exposure.Sockets, socket)
+		}
+	}
+	sort.SliceStable(exposure.Sockets, func(i, j int) bool { return exposure.Sockets[i].Name < exposure.Sockets[j].Name })
+
+	return exposure
 }
\ No newline at end of file
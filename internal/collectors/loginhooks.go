@@ -3,11 +3,9 @@ package collectors
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
-	"time"
 
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
 	"howett.net/plist"
 )
@@ -22,13 +20,33 @@ func (s *LoginHooksScanner) Type() scanner.MechanismType {
 	return scanner.MechanismLoginHook
 }
 
+// Info reports the loginwindow preferences read. Hooks set with "sudo
+// defaults write com.apple.loginwindow" live in root's preferences under
+// /var/root, which are read with every other account's.
+func (s *LoginHooksScanner) Info() scanner.ScannerInfo {
+	paths := []string{"/Library/Preferences/com.apple.loginwindow.plist"}
+	for _, home := range userHomes() {
+		paths = append(paths, filepath.Join(home.Dir, "Library", "Preferences", "com.apple.loginwindow.plist"))
+	}
+	paths = append(paths,
+		"/Library/Managed Preferences/com.apple.loginwindow.plist",
+		"/Library/Managed Preferences/root/com.apple.loginwindow.plist")
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Login and logout hooks set in loginwindow preferences",
+		Paths:       paths,
+		Privileges: []string{"root to read every local user's loginwindow preferences; otherwise only the invoking user's"},
+	}
+}
+
 func (s *LoginHooksScanner) Scan() ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 
 	// Scan system login window preferences
 	systemItems, err := s.scanSystemLoginWindow()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning system login window: %v\n", err)
+		logging.Warn("scanning system login window", err, "scanner", s.Type(), "path", "/Library/Preferences/com.apple.loginwindow.plist")
 	} else {
 		items = append(items, systemItems...)
 	}
@@ -36,7 +54,7 @@ func (s *LoginHooksScanner) Scan() ([]scanner.PersistenceItem, error) {
 	// Scan user login window preferences
 	userItems, err := s.scanUserLoginWindow()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning user login window: %v\n", err)
+		logging.Warn("scanning user login window", err, "scanner", s.Type())
 	} else {
 		items = append(items, userItems...)
 	}
@@ -44,19 +62,11 @@ func (s *LoginHooksScanner) Scan() ([]scanner.PersistenceItem, error) {
 	// Check for MDM-deployed hooks
 	mdmItems, err := s.scanMDMHooks()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning MDM hooks: %v\n", err)
+		logging.Warn("scanning MDM hooks", err, "scanner", s.Type())
 	} else {
 		items = append(items, mdmItems...)
 	}
 
-	// Check defaults command for login/logout hooks
-	defaultsItems, err := s.scanViaDefaults()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning via defaults: %v\n", err)
-	} else {
-		items = append(items, defaultsItems...)
-	}
-
 	return items, nil
 }
 
@@ -71,7 +81,7 @@ func (s *LoginHooksScanner) scanSystemLoginWindow() ([]scanner.PersistenceItem,
 	// System login window preferences
 	systemPrefPath := "/Library/Preferences/com.apple.loginwindow.plist"
 	
-	data, err := os.ReadFile(systemPrefPath)
+	data, err := readFile(systemPrefPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return items, nil
@@ -116,7 +126,7 @@ func (s *LoginHooksScanner) scanSystemLoginWindow() ([]scanner.PersistenceItem,
 		}
 		
 		// Read the hook script if it exists
-		if scriptData, err := os.ReadFile(prefs.LoginHook); err == nil {
+		if scriptData, err := readFile(prefs.LoginHook); err == nil {
 			item.RawData["scriptContent"] = string(scriptData)
 		}
 		
@@ -141,7 +151,7 @@ func (s *LoginHooksScanner) scanSystemLoginWindow() ([]scanner.PersistenceItem,
 		}
 		
 		// Read the hook script if it exists
-		if scriptData, err := os.ReadFile(prefs.LogoutHook); err == nil {
+		if scriptData, err := readFile(prefs.LogoutHook); err == nil {
 			item.RawData["scriptContent"] = string(scriptData)
 		}
 		
@@ -154,15 +164,25 @@ func (s *LoginHooksScanner) scanSystemLoginWindow() ([]scanner.PersistenceItem,
 func (s *LoginHooksScanner) scanUserLoginWindow() ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("getting home directory: %w", err)
+	for _, home := range userHomes() {
+		userItems, err := s.scanUserPrefs(home.Dir, home.Name)
+		if err != nil {
+			logging.Warn("scanning user login window preferences", err, "scanner", s.Type(), "path", home.Dir, "user", home.Name)
+			continue
+		}
+		items = append(items, userItems...)
 	}
 
+	return items, nil
+}
+
+func (s *LoginHooksScanner) scanUserPrefs(homeDir, currentUser string) ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
 	// User login window preferences
 	userPrefPath := filepath.Join(homeDir, "Library", "Preferences", "com.apple.loginwindow.plist")
 	
-	data, err := os.ReadFile(userPrefPath)
+	data, err := readFile(userPrefPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return items, nil
@@ -189,11 +209,6 @@ func (s *LoginHooksScanner) scanUserLoginWindow() ([]scanner.PersistenceItem, er
 		}
 	}
 
-	currentUser := os.Getenv("USER")
-	if currentUser == "" {
-		currentUser = "current"
-	}
-
 	// Check for login hook
 	if prefs.LoginHook != "" {
 		item := scanner.PersistenceItem{
@@ -214,7 +229,7 @@ func (s *LoginHooksScanner) scanUserLoginWindow() ([]scanner.PersistenceItem, er
 		}
 		
 		// Read the hook script if it exists
-		if scriptData, err := os.ReadFile(prefs.LoginHook); err == nil {
+		if scriptData, err := readFile(prefs.LoginHook); err == nil {
 			item.RawData["scriptContent"] = string(scriptData)
 		}
 		
@@ -241,7 +256,7 @@ func (s *LoginHooksScanner) scanUserLoginWindow() ([]scanner.PersistenceItem, er
 		}
 		
 		// Read the hook script if it exists
-		if scriptData, err := os.ReadFile(prefs.LogoutHook); err == nil {
+		if scriptData, err := readFile(prefs.LogoutHook); err == nil {
 			item.RawData["scriptContent"] = string(scriptData)
 		}
 		
@@ -261,7 +276,7 @@ func (s *LoginHooksScanner) scanMDMHooks() ([]scanner.PersistenceItem, error) {
 	}
 
 	for _, mdmPath := range mdmPaths {
-		data, err := os.ReadFile(mdmPath)
+		data, err := readFile(mdmPath)
 		if err != nil {
 			continue
 		}
@@ -315,56 +330,3 @@ func (s *LoginHooksScanner) scanMDMHooks() ([]scanner.PersistenceItem, error) {
 
 	return items, nil
 }
-
-func (s *LoginHooksScanner) scanViaDefaults() ([]scanner.PersistenceItem, error) {
-	var items []scanner.PersistenceItem
-
-	// Check system level hooks via defaults command
-	loginCmd := exec.Command("defaults", "read", "com.apple.loginwindow", "LoginHook")
-	loginOutput, err := loginCmd.Output()
-	if err == nil {
-		loginHook := strings.TrimSpace(string(loginOutput))
-		if loginHook != "" && loginHook != "0" {
-			item := scanner.PersistenceItem{
-				Mechanism:  scanner.MechanismLoginHook,
-				Label:      "Login Hook (defaults)",
-				Path:       "defaults read com.apple.loginwindow",
-				Program:    loginHook,
-				ModifiedAt: time.Now(),
-				RawData: map[string]interface{}{
-					"description": fmt.Sprintf("Login hook detected via defaults: %s", loginHook),
-					"hook":        "login",
-					"scope":       "system",
-					"script":      loginHook,
-					"method":      "defaults",
-				},
-			}
-			items = append(items, item)
-		}
-	}
-
-	logoutCmd := exec.Command("defaults", "read", "com.apple.loginwindow", "LogoutHook")
-	logoutOutput, err := logoutCmd.Output()
-	if err == nil {
-		logoutHook := strings.TrimSpace(string(logoutOutput))
-		if logoutHook != "" && logoutHook != "0" {
-			item := scanner.PersistenceItem{
-				Mechanism:  scanner.MechanismLogoutHook,
-				Label:      "Logout Hook (defaults)",
-				Path:       "defaults read com.apple.loginwindow",
-				Program:    logoutHook,
-				ModifiedAt: time.Now(),
-				RawData: map[string]interface{}{
-					"description": fmt.Sprintf("Logout hook detected via defaults: %s", logoutHook),
-					"hook":        "logout",
-					"scope":       "system",
-					"script":      logoutHook,
-					"method":      "defaults",
-				},
-			}
-			items = append(items, item)
-		}
-	}
-
-	return items, nil
-}
\ No newline at end of file
@@ -3,11 +3,13 @@ package collectors
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
 	"howett.net/plist"
 )
@@ -22,6 +24,31 @@ func (s *LoginItemsScanner) Type() scanner.MechanismType {
 	return scanner.MechanismLoginItem
 }
 
+// Info reports the login item stores read and the System Events query.
+func (s *LoginItemsScanner) Info() scanner.ScannerInfo {
+	var paths []string
+	for _, home := range userHomes() {
+		paths = append(paths,
+			filepath.Join(home.Dir, "Library", "Preferences", "com.apple.loginitems.plist"),
+			filepath.Join(home.Dir, "Library", "Application Support", "com.apple.backgroundtaskmanagementagent", "backgrounditems.btm"))
+	}
+	paths = append(paths, "/Library/Application Support/com.apple.backgroundtaskmanagementagent/backgrounditems.btm")
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Login items from user preferences, background task management, and System Events",
+		Paths:       paths,
+		Commands: []string{
+			`osascript -e 'tell application "System Events" to get the name of every login item' (running system only)`,
+			`osascript -e 'tell application "System Events" to get the path of login item "<name>"' for each login item (running system only)`,
+		},
+		Privileges: []string{
+			"root to read every local user's login items; otherwise only the invoking user's",
+			"Automation permission to control System Events for the osascript query",
+		},
+	}
+}
+
 func (s *LoginItemsScanner) Scan() ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 
@@ -36,18 +63,21 @@ func (s *LoginItemsScanner) Scan() ([]scanner.PersistenceItem, error) {
 	sharedItems, err := s.scanSharedFileList()
 	if err != nil {
 		// Non-fatal error, continue
-		fmt.Fprintf(os.Stderr, "Warning: scanning shared file list: %v\n", err)
+		logging.Warn("scanning shared file list", err, "scanner", s.Type())
 	} else {
 		items = append(items, sharedItems...)
 	}
 
-	// Scan login items via LSSharedFileList
-	lsItems, err := s.scanLSSharedFileList()
-	if err != nil {
-		// Non-fatal error
-		fmt.Fprintf(os.Stderr, "Warning: scanning LSSharedFileList: %v\n", err)
-	} else {
-		items = append(items, lsItems...)
+	// Scan login items via LSSharedFileList, which only reflects the
+	// running system
+	if canExec() {
+		lsItems, err := s.scanLSSharedFileList()
+		if err != nil {
+			// Non-fatal error
+			logging.Warn("scanning LSSharedFileList", err, "scanner", s.Type())
+		} else {
+			items = append(items, lsItems...)
+		}
 	}
 
 	return items, nil
@@ -66,50 +96,50 @@ type loginItemsPlist struct {
 func (s *LoginItemsScanner) scanUserLoginItems() ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 
-	// Check user's login items plist
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("getting home directory: %w", err)
-	}
+	// Check each user's login items plist
+	for _, home := range userHomes() {
+		plistPath := filepath.Join(home.Dir, "Library", "Preferences", "com.apple.loginitems.plist")
 
-	plistPath := filepath.Join(homeDir, "Library", "Preferences", "com.apple.loginitems.plist")
-	
-	data, err := os.ReadFile(plistPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist, return empty list
-			return items, nil
+		data, err := readFile(plistPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// File doesn't exist, nothing to report for this user
+				continue
+			}
+			logging.Warn("reading login items plist", err, "scanner", s.Type(), "path", plistPath, "user", home.Name)
+			continue
 		}
-		return nil, fmt.Errorf("reading login items plist: %w", err)
-	}
 
-	var loginItems loginItemsPlist
-	_, err = plist.Unmarshal(data, &loginItems)
-	if err != nil {
-		return nil, fmt.Errorf("parsing login items plist: %w", err)
-	}
-
-	for _, item := range loginItems.SessionItems.CustomListItems {
-		persistItem := scanner.PersistenceItem{
-			Mechanism:   scanner.MechanismLoginItem,
-			Label:       item.Name,
-			Path:        plistPath,
-			ModifiedAt:  getFileModTime(plistPath),
-			RawData: map[string]interface{}{
-				"description": fmt.Sprintf("Login item: %s", item.Name),
-				"Name": item.Name,
-				"Data": item.Data,
-				"content": string(data),
-			},
+		var loginItems loginItemsPlist
+		_, err = plist.Unmarshal(data, &loginItems)
+		if err != nil {
+			logging.Warn("parsing login items plist", err, "scanner", s.Type(), "path", plistPath, "user", home.Name)
+			continue
 		}
 
-		// Try to resolve the alias to get the actual path
-		if binaryPath := s.resolveAlias(item.Alias); binaryPath != "" {
-			persistItem.Program = binaryPath
-			persistItem.RawData["description"] = fmt.Sprintf("Login item: %s (%s)", item.Name, binaryPath)
-		}
+		for _, item := range loginItems.SessionItems.CustomListItems {
+			persistItem := scanner.PersistenceItem{
+				Mechanism:   scanner.MechanismLoginItem,
+				Label:       item.Name,
+				Path:        plistPath,
+				User:        home.Name,
+				ModifiedAt:  getFileModTime(plistPath),
+				RawData: map[string]interface{}{
+					"description": fmt.Sprintf("Login item: %s", item.Name),
+					"Name": item.Name,
+					"Data": item.Data,
+					"content": string(data),
+				},
+			}
 
-		items = append(items, persistItem)
+			// Try to resolve the alias to get the actual path
+			if binaryPath := s.resolveAlias(item.Alias); binaryPath != "" {
+				persistItem.Program = binaryPath
+				persistItem.RawData["description"] = fmt.Sprintf("Login item: %s (%s)", item.Name, binaryPath)
+			}
+
+			items = append(items, persistItem)
+		}
 	}
 
 	return items, nil
@@ -118,19 +148,15 @@ func (s *LoginItemsScanner) scanUserLoginItems() ([]scanner.PersistenceItem, err
 func (s *LoginItemsScanner) scanSharedFileList() ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("getting home directory: %w", err)
-	}
-
-	// Check both potential locations
-	paths := []string{
-		filepath.Join(homeDir, "Library", "Application Support", "com.apple.backgroundtaskmanagementagent", "backgrounditems.btm"),
-		"/Library/Application Support/com.apple.backgroundtaskmanagementagent/backgrounditems.btm",
+	// Check the per-user and system-wide locations
+	var paths []string
+	for _, home := range userHomes() {
+		paths = append(paths, filepath.Join(home.Dir, "Library", "Application Support", "com.apple.backgroundtaskmanagementagent", "backgrounditems.btm"))
 	}
+	paths = append(paths, "/Library/Application Support/com.apple.backgroundtaskmanagementagent/backgrounditems.btm")
 
 	for _, path := range paths {
-		data, err := os.ReadFile(path)
+		data, err := readFile(path)
 		if err != nil {
 			if os.IsNotExist(err) {
 				continue
@@ -166,8 +192,7 @@ func (s *LoginItemsScanner) scanLSSharedFileList() ([]scanner.PersistenceItem, e
 	var items []scanner.PersistenceItem
 
 	// Use osascript to query login items
-	cmd := exec.Command("osascript", "-e", `tell application "System Events" to get the name of every login item`)
-	output, err := cmd.Output()
+	output, err := command.Output("osascript", "-e", `tell application "System Events" to get the name of every login item`)
 	if err != nil {
 		return nil, fmt.Errorf("querying login items via osascript: %w", err)
 	}
@@ -180,8 +205,7 @@ func (s *LoginItemsScanner) scanLSSharedFileList() ([]scanner.PersistenceItem, e
 		}
 
 		// Get the path for each login item
-		pathCmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "System Events" to get the path of login item "%s"`, name))
-		pathOutput, err := pathCmd.Output()
+		pathOutput, err := command.Output("osascript", "-e", fmt.Sprintf(`tell application "System Events" to get the path of login item "%s"`, name))
 		
 		itemPath := ""
 		if err == nil {
@@ -193,7 +217,6 @@ func (s *LoginItemsScanner) scanLSSharedFileList() ([]scanner.PersistenceItem, e
 			Label:      name,
 			Path:       "System Events Login Items",
 			Program:    itemPath,
-			ModifiedAt: time.Now(), // Can't get actual mod time from System Events
 			RawData: map[string]interface{}{
 				"description": fmt.Sprintf("Login item registered with System Events: %s", name),
 				"Name": name,
@@ -215,9 +238,9 @@ func (s *LoginItemsScanner) resolveAlias(aliasData []byte) string {
 }
 
 func getFileModTime(path string) time.Time {
-	info, err := os.Stat(path)
+	info, err := os.Stat(sysroot.Path(path))
 	if err != nil {
-		return time.Now()
+		return time.Time{}
 	}
 	return info.ModTime()
 }
\ No newline at end of file
@@ -0,0 +1,115 @@
+package collectors
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// cpuSubtypeARM64E is the arm64 subtype of binaries built for the pointer
+// authentication ABI Apple's own code uses.
+const cpuSubtypeARM64E = 2
+
+// Load commands read by binaryInfo that debug/macho does not decode.
+const (
+	lcUUID             = 0x1b
+	lcVersionMinMacOSX = 0x24
+	lcBuildVersion     = 0x32
+	lcLoadDylib        = 0xc
+	lcLazyLoadDylib    = 0x20
+	lcLoadWeakDylib    = 0x80000018
+	lcReexportDylib    = 0x8000001f
+	lcLoadUpwardDylib  = 0x80000023
+)
+
+// binaryInfo describes a Mach-O file, thin or universal, or returns nil if
+// r is not one. The build details of a universal binary come from its
+// first slice.
+func binaryInfo(r io.ReaderAt) *scanner.BinaryInfo {
+	if fat, err := macho.NewFatFile(r); err == nil {
+		defer fat.Close()
+		info := &scanner.BinaryInfo{}
+		for _, arch := range fat.Arches {
+			info.Architectures = append(info.Architectures, archName(arch.Cpu, arch.SubCpu))
+		}
+		if len(fat.Arches) > 0 {
+			readLoadCommands(info, fat.Arches[0].File)
+		}
+		return info
+	}
+
+	file, err := macho.NewFile(r)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	info := &scanner.BinaryInfo{Architectures: []string{archName(file.Cpu, file.SubCpu)}}
+	readLoadCommands(info, file)
+	return info
+}
+
+// readLoadCommands records the deployment target, SDK, UUID, and number of
+// linked libraries from file's load commands.
+func readLoadCommands(info *scanner.BinaryInfo, file *macho.File) {
+	for _, load := range file.Loads {
+		raw := load.Raw()
+		if len(raw) < 8 {
+			continue
+		}
+		switch cmd := file.ByteOrder.Uint32(raw); cmd {
+		case lcBuildVersion:
+			// platform, minos, sdk
+			if len(raw) >= 20 {
+				info.MinOS = machoVersion(file.ByteOrder, raw[12:])
+				info.SDK = machoVersion(file.ByteOrder, raw[16:])
+			}
+		case lcVersionMinMacOSX:
+			// version, sdk
+			if len(raw) >= 16 {
+				info.MinOS = machoVersion(file.ByteOrder, raw[8:])
+				info.SDK = machoVersion(file.ByteOrder, raw[12:])
+			}
+		case lcUUID:
+			if len(raw) >= 24 {
+				u := raw[8:24]
+				info.UUID = fmt.Sprintf("%X-%X-%X-%X-%X", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+			}
+		case lcLoadDylib, lcLazyLoadDylib, lcLoadWeakDylib, lcReexportDylib, lcLoadUpwardDylib:
+			info.LinkedLibraries++
+		}
+	}
+}
+
+// machoVersion decodes a version packed as xxxx.yy.zz in nibbles.
+func machoVersion(order binary.ByteOrder, b []byte) string {
+	v := order.Uint32(b)
+	if v&0xff == 0 {
+		return fmt.Sprintf("%d.%d", v>>16, v>>8&0xff)
+	}
+	return fmt.Sprintf("%d.%d.%d", v>>16, v>>8&0xff, v&0xff)
+}
+
+// archName returns the architecture name lipo and file(1) use.
+func archName(cpu macho.Cpu, subCpu uint32) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "x86_64"
+	case macho.Cpu386:
+		return "i386"
+	case macho.CpuArm64:
+		if subCpu&0x00ffffff == cpuSubtypeARM64E {
+			return "arm64e"
+		}
+		return "arm64"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuPpc:
+		return "ppc"
+	case macho.CpuPpc64:
+		return "ppc64"
+	}
+	return cpu.String()
+}
@@ -0,0 +1,157 @@
+package collectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// nativeMessagingDir is a browser's host manifest directory. System is the
+// machine-wide location and User is relative to each home directory.
+type nativeMessagingDir struct {
+	Browser string
+	System  string
+	User    string
+}
+
+var nativeMessagingDirs = []nativeMessagingDir{
+	{"Chrome", "/Library/Google/Chrome/NativeMessagingHosts", "Library/Application Support/Google/Chrome/NativeMessagingHosts"},
+	{"Chrome Beta", "", "Library/Application Support/Google/Chrome Beta/NativeMessagingHosts"},
+	{"Chrome Canary", "", "Library/Application Support/Google/Chrome Canary/NativeMessagingHosts"},
+	{"Chromium", "/Library/Application Support/Chromium/NativeMessagingHosts", "Library/Application Support/Chromium/NativeMessagingHosts"},
+	{"Edge", "/Library/Microsoft/Edge/NativeMessagingHosts", "Library/Application Support/Microsoft Edge/NativeMessagingHosts"},
+	{"Brave", "/Library/Application Support/BraveSoftware/Brave-Browser/NativeMessagingHosts", "Library/Application Support/BraveSoftware/Brave-Browser/NativeMessagingHosts"},
+	{"Vivaldi", "", "Library/Application Support/Vivaldi/NativeMessagingHosts"},
+	{"Firefox", "/Library/Application Support/Mozilla/NativeMessagingHosts", "Library/Application Support/Mozilla/NativeMessagingHosts"},
+}
+
+// nativeMessagingManifest is a host manifest. Chromium browsers list the
+// extensions allowed to connect in allowed_origins and Firefox in
+// allowed_extensions.
+type nativeMessagingManifest struct {
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Path              string   `json:"path"`
+	Type              string   `json:"type"`
+	AllowedOrigins    []string `json:"allowed_origins"`
+	AllowedExtensions []string `json:"allowed_extensions"`
+}
+
+// NativeMessagingScanner reports browser native messaging hosts: programs a
+// browser starts on behalf of an extension, giving the extension arbitrary
+// code execution outside the browser sandbox.
+type NativeMessagingScanner struct{}
+
+func NewNativeMessagingScanner() *NativeMessagingScanner {
+	return &NativeMessagingScanner{}
+}
+
+func (s *NativeMessagingScanner) Type() scanner.MechanismType {
+	return scanner.MechanismNativeMessaging
+}
+
+// Info reports the host manifest directories read.
+func (s *NativeMessagingScanner) Info() scanner.ScannerInfo {
+	var paths []string
+	for _, dir := range nativeMessagingDirs {
+		if dir.System != "" {
+			paths = append(paths, dir.System)
+		}
+	}
+	for _, home := range userHomes() {
+		for _, dir := range nativeMessagingDirs {
+			paths = append(paths, filepath.Join(home.Dir, dir.User))
+		}
+	}
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Chrome, Edge, Brave, Chromium, Vivaldi, and Firefox native messaging host manifests",
+		Paths:       paths,
+		Privileges:  []string{"root to read every local user's browser profiles; otherwise only the invoking user's"},
+	}
+}
+
+func (s *NativeMessagingScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	for _, dir := range nativeMessagingDirs {
+		if dir.System != "" {
+			items = append(items, s.scanDirectory(dir.System, dir.Browser, "")...)
+		}
+	}
+	for _, home := range userHomes() {
+		for _, dir := range nativeMessagingDirs {
+			items = append(items, s.scanDirectory(filepath.Join(home.Dir, dir.User), dir.Browser, home.Name)...)
+		}
+	}
+
+	return items, nil
+}
+
+func (s *NativeMessagingScanner) scanDirectory(dir, browser, user string) []scanner.PersistenceItem {
+	entries, err := readDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading native messaging hosts", err, "scanner", s.Type(), "path", dir)
+		}
+		return nil
+	}
+
+	var items []scanner.PersistenceItem
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		data, err := readFile(path)
+		if err != nil {
+			logging.Warn("reading native messaging manifest", err, "scanner", s.Type(), "path", path)
+			continue
+		}
+
+		item := scanner.PersistenceItem{
+			Mechanism:  scanner.MechanismNativeMessaging,
+			Label:      strings.TrimSuffix(entry.Name(), ".json"),
+			Path:       path,
+			User:       user,
+			ModifiedAt: getFileModTime(path),
+			RawData: map[string]interface{}{
+				"browser": browser,
+				"content": string(data),
+			},
+		}
+
+		var manifest nativeMessagingManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			item.Errors = append(item.Errors, fmt.Sprintf("parsing manifest: %v", err))
+			items = append(items, item)
+			continue
+		}
+
+		if manifest.Name != "" {
+			item.Label = manifest.Name
+		}
+		// Chromium resolves a relative host path against the manifest's
+		// directory
+		item.Program = manifest.Path
+		if item.Program != "" && !filepath.IsAbs(item.Program) {
+			item.Program = filepath.Join(dir, item.Program)
+		}
+		allowed := append(manifest.AllowedOrigins, manifest.AllowedExtensions...)
+		item.RawData["description"] = fmt.Sprintf("%s native messaging host %s", browser, item.Label)
+		item.RawData["host_description"] = manifest.Description
+		item.RawData["type"] = manifest.Type
+		item.RawData["allowed_extensions"] = allowed
+
+		items = append(items, item)
+	}
+
+	return items
+}
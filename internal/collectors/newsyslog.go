@@ -0,0 +1,152 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+const (
+	newsyslogConf = "/etc/newsyslog.conf"
+	newsyslogDir  = "/etc/newsyslog.d"
+)
+
+// NewsyslogScanner reports newsyslog rotation entries that act on another
+// program when a log is rotated: entries with the R flag, which run the
+// command in the pid_file field as root, and entries that signal the
+// process whose ID is in a pid file. newsyslog runs from launchd every
+// half hour, so either is a scheduled execution channel few tools check.
+type NewsyslogScanner struct{}
+
+func NewNewsyslogScanner() *NewsyslogScanner {
+	return &NewsyslogScanner{}
+}
+
+func (s *NewsyslogScanner) Type() scanner.MechanismType {
+	return scanner.MechanismNewsyslog
+}
+
+// Info reports the newsyslog configuration read.
+func (s *NewsyslogScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "newsyslog rotation entries that run a command or signal a process",
+		Paths:       []string{newsyslogConf, newsyslogDir},
+	}
+}
+
+func (s *NewsyslogScanner) Scan() ([]scanner.PersistenceItem, error) {
+	paths := []string{newsyslogConf}
+	entries, err := readDir(newsyslogDir)
+	if err != nil && !os.IsNotExist(err) {
+		logging.Warn("reading newsyslog directory", err, "scanner", s.Type(), "path", newsyslogDir)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			paths = append(paths, filepath.Join(newsyslogDir, entry.Name()))
+		}
+	}
+
+	var items []scanner.PersistenceItem
+	for _, path := range paths {
+		data, err := readFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Warn("reading newsyslog configuration", err, "scanner", s.Type(), "path", path)
+			}
+			continue
+		}
+		modified := getFileModTime(path)
+
+		for _, entry := range parseNewsyslog(data) {
+			if entry.target == "" {
+				continue
+			}
+			item := scanner.PersistenceItem{
+				Mechanism:  scanner.MechanismNewsyslog,
+				Path:       path,
+				User:       "root",
+				ModifiedAt: modified,
+				RawData: map[string]interface{}{
+					"log":   entry.log,
+					"flags": entry.flags,
+					"entry": entry.line,
+					"line":  entry.number,
+				},
+			}
+			if entry.command {
+				item.Label = fmt.Sprintf("newsyslog command: %s", entry.log)
+				item.Program = entry.target
+				item.RawData["command"] = entry.target
+				item.RawData["description"] = fmt.Sprintf("Runs %s as root after rotating %s", entry.target, entry.log)
+			} else {
+				item.Label = fmt.Sprintf("newsyslog signal: %s", entry.log)
+				item.RawData["pid_file"] = entry.target
+				item.RawData["signal"] = entry.signal
+				item.RawData["description"] = fmt.Sprintf("Signals the process in %s after rotating %s", entry.target, entry.log)
+			}
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+type newsyslogEntry struct {
+	number int
+	line   string
+	log    string
+	flags  string
+	// target is the pid_file field: a pid file, or with the R flag a
+	// command
+	target  string
+	command bool
+	signal  string
+}
+
+// parseNewsyslog reads newsyslog.conf lines of the form
+//
+//	logfile [owner:group] mode count size when [flags] [pid_file] [sig_num]
+func parseNewsyslog(data []byte) []newsyslogEntry {
+	var entries []newsyslogEntry
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "<") {
+			continue
+		}
+		fields := strings.Fields(line)
+		i := 1
+		if i < len(fields) && strings.Contains(fields[i], ":") {
+			i++
+		}
+		// mode, count, size, and when
+		i += 4
+		if i > len(fields) {
+			continue
+		}
+
+		entry := newsyslogEntry{number: n, line: line, log: fields[0]}
+		rest := fields[i:]
+		if len(rest) > 0 && !strings.HasPrefix(rest[0], "/") {
+			entry.flags = rest[0]
+			rest = rest[1:]
+		}
+		if len(rest) > 0 {
+			// Flags are case-insensitive
+			entry.command = strings.ContainsAny(entry.flags, "Rr")
+			entry.target = strings.Trim(rest[0], `"`)
+			if len(rest) > 1 && !entry.command {
+				entry.signal = rest[1]
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,224 @@
+package collectors
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+const (
+	bootPlist             = "/Library/Preferences/SystemConfiguration/com.apple.Boot.plist"
+	libraryValidationPref = "/Library/Preferences/com.apple.security.libraryvalidation.plist"
+)
+
+// appleNVRAMVariables are variable names, or name prefixes ending in "*",
+// that macOS and Apple firmware set routinely. Anything else is reported as
+// a custom variable.
+var appleNVRAMVariables = []string{
+	"SystemAudioVolume*", "StartupMute", "SkipLogo", "LocationServicesEnabled",
+	"prev-lang:kbd", "prev-lang-diags:kbd", "bluetooth*", "fmm-*", "backlight-*",
+	"efi-*", "boot-volume", "boot-image", "boot-note", "auto-boot*", "ota-*",
+	"panicmedic*", "IDInstallerDataV2", "usbcfwflasherResult", "lts-persistance",
+	"good-samaritan-message", "nonce-seeds", "upgrade-*", "recovery-*",
+	"sleep-*", "wake-*", "display-*", "com.apple.System.*", "platform-uuid",
+	"_kdp_ipstr", "manufacturing-*", "allow-root-hash-mismatch", "root-live-fs",
+	"policy-nonce-digests", "failboot-breadcrumbs", "darkboot", "emu",
+	"IOHibernate*", "AAPL,*", "csr-active-config", "boot-args",
+	"EFILoginHiDPI", "ResetNVRam", "MemoryConfig", "ALS_Data",
+}
+
+// NVRAMScanner records boot arguments, System Integrity Protection
+// settings, and custom NVRAM variables, which survive reinstalls of the OS
+// and can weaken code signing enforcement or stash data.
+type NVRAMScanner struct{}
+
+func NewNVRAMScanner() *NVRAMScanner {
+	return &NVRAMScanner{}
+}
+
+func (s *NVRAMScanner) Type() scanner.MechanismType {
+	return scanner.MechanismNVRAM
+}
+
+// Info reports the boot preferences read and the nvram query.
+func (s *NVRAMScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "NVRAM boot-args, csr-active-config, and custom variables; kernel flags and library validation preferences",
+		Paths:       []string{bootPlist, libraryValidationPref},
+		Commands:    []string{"nvram -xp (running system only)"},
+	}
+}
+
+func (s *NVRAMScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	// NVRAM belongs to the running machine, not to a mounted volume
+	if canExec() {
+		nvramItems, err := s.scanNVRAM()
+		if err != nil {
+			logging.Warn("reading NVRAM", err, "scanner", s.Type())
+		} else {
+			items = append(items, nvramItems...)
+		}
+	}
+
+	bootItems, err := s.scanPreferences()
+	if err != nil {
+		logging.Warn("reading boot preferences", err, "scanner", s.Type())
+	} else {
+		items = append(items, bootItems...)
+	}
+
+	return items, nil
+}
+
+func (s *NVRAMScanner) scanNVRAM() ([]scanner.PersistenceItem, error) {
+	output, err := command.Output("nvram", "-xp")
+	if err != nil {
+		return nil, fmt.Errorf("running nvram: %w", err)
+	}
+
+	var variables map[string]interface{}
+	if _, err := plist.Unmarshal(output, &variables); err != nil {
+		return nil, fmt.Errorf("parsing nvram output: %w", err)
+	}
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var items []scanner.PersistenceItem
+	for _, name := range names {
+		custom := !appleNVRAMVariable(name)
+		if !custom && name != "boot-args" && name != "csr-active-config" && name != "allow-root-hash-mismatch" && name != "root-live-fs" {
+			continue
+		}
+
+		value, size := nvramValue(variables[name])
+		item := scanner.PersistenceItem{
+			Mechanism: scanner.MechanismNVRAM,
+			Label:     name,
+			Path:      "nvram " + name,
+			RawData: map[string]interface{}{
+				"description": fmt.Sprintf("NVRAM variable %s", name),
+				"variable":    name,
+				"value":       value,
+				"size":        size,
+				"custom":      custom,
+			},
+		}
+		if name == "csr-active-config" {
+			if data, ok := variables[name].([]byte); ok && len(data) >= 4 {
+				item.RawData["value"] = fmt.Sprintf("0x%x", binary.LittleEndian.Uint32(data))
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// scanPreferences reads settings on disk that have the same effect as boot
+// arguments: kernel flags in com.apple.Boot.plist and the system-wide
+// library validation override.
+func (s *NVRAMScanner) scanPreferences() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	if data, err := readFile(bootPlist); err == nil {
+		var boot struct {
+			KernelFlags string `plist:"Kernel Flags"`
+		}
+		if _, err := plist.Unmarshal(data, &boot); err == nil && strings.TrimSpace(boot.KernelFlags) != "" {
+			items = append(items, scanner.PersistenceItem{
+				Mechanism:  scanner.MechanismNVRAM,
+				Label:      "Kernel Flags",
+				Path:       bootPlist,
+				ModifiedAt: getFileModTime(bootPlist),
+				RawData: map[string]interface{}{
+					"description": "Kernel boot arguments set in com.apple.Boot.plist",
+					"variable":    "boot-args",
+					"value":       boot.KernelFlags,
+					"content":     string(data),
+				},
+			})
+		}
+	} else if !os.IsNotExist(err) {
+		return items, err
+	}
+
+	if data, err := readFile(libraryValidationPref); err == nil {
+		var prefs struct {
+			DisableLibraryValidation bool `plist:"DisableLibraryValidation"`
+		}
+		if _, err := plist.Unmarshal(data, &prefs); err == nil && prefs.DisableLibraryValidation {
+			items = append(items, scanner.PersistenceItem{
+				Mechanism:  scanner.MechanismNVRAM,
+				Label:      "DisableLibraryValidation",
+				Path:       libraryValidationPref,
+				ModifiedAt: getFileModTime(libraryValidationPref),
+				RawData: map[string]interface{}{
+					"description": "Library validation is disabled system-wide",
+					"variable":    "DisableLibraryValidation",
+					"value":       "true",
+					"content":     string(data),
+				},
+			})
+		}
+	} else if !os.IsNotExist(err) {
+		return items, err
+	}
+
+	return items, nil
+}
+
+func appleNVRAMVariable(name string) bool {
+	// Variables outside the Apple namespaces are printed as GUID:name
+	if strings.Contains(name, ":") && len(name) > 37 && name[36] == ':' {
+		return false
+	}
+	for _, known := range appleNVRAMVariables {
+		if prefix, ok := strings.CutSuffix(known, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// nvramValue renders a variable for output: strings as is, data as text
+// when printable and hex otherwise, and reports its size in bytes.
+func nvramValue(v interface{}) (string, int) {
+	switch v := v.(type) {
+	case string:
+		return v, len(v)
+	case []byte:
+		text := strings.TrimRight(string(v), "\x00")
+		printable := true
+		for _, r := range text {
+			if r < 0x20 && r != '\n' && r != '\t' || r == 0xfffd {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			return text, len(v)
+		}
+		return fmt.Sprintf("%x", v), len(v)
+	default:
+		s := fmt.Sprint(v)
+		return s, len(s)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package collectors
+
+import "os"
+
+// fileOwner reports no owner; only Unix systems record numeric ones.
+func fileOwner(info os.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
 )
 
@@ -20,25 +22,38 @@ func (s *PeriodicScanner) Type() scanner.MechanismType {
 	return scanner.MechanismPeriodicScript
 }
 
+// Info reports the periodic script directories and configuration read.
+func (s *PeriodicScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "periodic(8) scripts and configuration",
+		Paths: []string{
+			"/etc/periodic/daily", "/etc/periodic/weekly", "/etc/periodic/monthly",
+			"/etc/periodic.conf", "/etc/defaults/periodic.conf", "/etc/periodic.conf.local",
+			"/usr/local/etc/periodic", "/opt/local/etc/periodic",
+		},
+	}
+}
+
 func (s *PeriodicScanner) Scan() ([]scanner.PersistenceItem, error) {
 	var items []scanner.PersistenceItem
 
 	// Scan standard periodic directories
 	periods := []string{"daily", "weekly", "monthly"}
 	
-	for _, period := range periods {
-		periodItems, err := s.scanPeriodDirectory(period)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: scanning %s periodic scripts: %v\n", period, err)
+	results, errs := scanEach(periods, s.scanPeriodDirectory)
+	for i, period := range periods {
+		if errs[i] != nil {
+			logging.Warn("scanning periodic scripts", errs[i], "scanner", s.Type(), "path", "/etc/periodic/"+period)
 		} else {
-			items = append(items, periodItems...)
+			items = append(items, results[i]...)
 		}
 	}
 
 	// Check periodic.conf for custom configurations
 	confItems, err := s.scanPeriodicConf()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning periodic.conf: %v\n", err)
+		logging.Warn("scanning periodic.conf", err, "scanner", s.Type())
 	} else {
 		items = append(items, confItems...)
 	}
@@ -46,7 +61,7 @@ func (s *PeriodicScanner) Scan() ([]scanner.PersistenceItem, error) {
 	// Check for custom periodic directories
 	customItems, err := s.scanCustomDirectories()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: scanning custom periodic directories: %v\n", err)
+		logging.Warn("scanning custom periodic directories", err, "scanner", s.Type())
 	} else {
 		items = append(items, customItems...)
 	}
@@ -59,7 +74,7 @@ func (s *PeriodicScanner) scanPeriodDirectory(period string) ([]scanner.Persiste
 
 	baseDir := fmt.Sprintf("/etc/periodic/%s", period)
 	
-	entries, err := os.ReadDir(baseDir)
+	entries, err := readDir(baseDir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return items, nil // Directory doesn't exist
@@ -81,13 +96,13 @@ func (s *PeriodicScanner) scanPeriodDirectory(period string) ([]scanner.Persiste
 		path := filepath.Join(baseDir, name)
 		
 		// Check if file is executable
-		info, err := os.Stat(path)
+		info, err := os.Stat(sysroot.Path(path))
 		if err != nil {
 			continue
 		}
 
 		// Read the script content
-		data, err := os.ReadFile(path)
+		data, err := readFile(path)
 		if err != nil {
 			continue
 		}
@@ -99,6 +114,7 @@ func (s *PeriodicScanner) scanPeriodDirectory(period string) ([]scanner.Persiste
 			Mechanism:  scanner.MechanismPeriodicScript,
 			Label:      fmt.Sprintf("%s: %s", strings.Title(period), name),
 			Path:       path,
+			CreatedAt:  fileBirthTime(info),
 			ModifiedAt: info.ModTime(),
 			RawData: map[string]interface{}{
 				"description": fmt.Sprintf("Periodic %s script: %s", period, name),
@@ -130,7 +146,7 @@ func (s *PeriodicScanner) scanPeriodicConf() ([]scanner.PersistenceItem, error)
 	}
 
 	for _, confPath := range confPaths {
-		data, err := os.ReadFile(confPath)
+		data, err := readFile(confPath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				continue
@@ -185,7 +201,7 @@ func (s *PeriodicScanner) scanCustomDirectories() ([]scanner.PersistenceItem, er
 		for _, period := range periods {
 			dir := filepath.Join(baseDir, period)
 			
-			entries, err := os.ReadDir(dir)
+			entries, err := readDir(dir)
 			if err != nil {
 				continue
 			}
@@ -197,14 +213,15 @@ func (s *PeriodicScanner) scanCustomDirectories() ([]scanner.PersistenceItem, er
 
 				path := filepath.Join(dir, entry.Name())
 				
-				data, err := os.ReadFile(path)
+				data, err := readFile(path)
 				if err != nil {
 					continue
 				}
 
 				info, _ := entry.Info()
-				modTime := time.Now()
+				var createdAt, modTime time.Time
 				if info != nil {
+					createdAt = fileBirthTime(info)
 					modTime = info.ModTime()
 				}
 
@@ -214,6 +231,7 @@ func (s *PeriodicScanner) scanCustomDirectories() ([]scanner.PersistenceItem, er
 					Mechanism:  scanner.MechanismPeriodicScript,
 					Label:      fmt.Sprintf("Custom %s: %s", strings.Title(period), entry.Name()),
 					Path:       path,
+					CreatedAt:  createdAt,
 					ModifiedAt: modTime,
 					RawData: map[string]interface{}{
 						"description": fmt.Sprintf("Custom periodic %s script: %s in %s", period, entry.Name(), baseDir),
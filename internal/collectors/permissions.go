@@ -0,0 +1,70 @@
+package collectors
+
+import (
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+)
+
+// denied collects the paths collectors could not read because the process
+// lacks root or the Full Disk Access TCC grant, which both surface as
+// permission errors. Scans are serialized, so one set serves the process.
+var denied = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: make(map[string]bool)}
+
+// noteDenied records path, as seen on the scanned system, if err is a
+// permission error.
+func noteDenied(path string, err error) {
+	if err == nil || !os.IsPermission(err) {
+		return
+	}
+	denied.Lock()
+	defer denied.Unlock()
+	denied.paths[path] = true
+}
+
+// readFile reads path on the scanned system, recording permission denials.
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(sysroot.Path(path))
+	noteDenied(path, err)
+	return data, err
+}
+
+// readDir lists path on the scanned system, recording permission denials.
+func readDir(path string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(sysroot.Path(path))
+	noteDenied(path, err)
+	return entries, err
+}
+
+// openFile opens path on the scanned system, recording permission denials.
+func openFile(path string) (*os.File, error) {
+	file, err := os.Open(sysroot.Path(path))
+	noteDenied(path, err)
+	return file, err
+}
+
+// ResetPermissionIssues forgets the denials recorded by earlier scans.
+func ResetPermissionIssues() {
+	denied.Lock()
+	defer denied.Unlock()
+	denied.paths = make(map[string]bool)
+}
+
+// PermissionIssues returns the paths that could not be read since the last
+// reset, sorted.
+func PermissionIssues() []string {
+	denied.Lock()
+	defer denied.Unlock()
+
+	paths := make([]string, 0, len(denied.paths))
+	for path := range denied.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,44 @@
+package collectors
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Concurrency bounds how many directories a single collector scans at once.
+// Zero uses one worker per CPU.
+var Concurrency = 0
+
+// scanEach calls fn for every input with a bounded number of calls in
+// flight. Results and errors are returned in input order so output stays
+// deterministic.
+func scanEach(inputs []string, fn func(string) ([]scanner.PersistenceItem, error)) ([][]scanner.PersistenceItem, []error) {
+	results := make([][]scanner.PersistenceItem, len(inputs))
+	errs := make([]error, len(inputs))
+
+	workers := Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(inputs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = fn(inputs[i])
+			}
+		}()
+	}
+	for i := range inputs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, errs
+}
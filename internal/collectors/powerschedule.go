@@ -0,0 +1,232 @@
+package collectors
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+// autoWakePrefs is where powerd keeps the repeating and one-time power
+// events pmset schedules.
+const autoWakePrefs = "/Library/Preferences/SystemConfiguration/com.apple.AutoWake.plist"
+
+// weekdayLetters are pmset's day letters, in the order of the bits of a
+// repeating event's weekdays mask.
+const weekdayLetters = "MTWRFSU"
+
+var (
+	pmsetRepeating = regexp.MustCompile(`^\s*(\w+) at (\d{1,2}):(\d{2})(AM|PM) (.+)$`)
+	pmsetScheduled = regexp.MustCompile(`^\s*\[\d+\]\s+(\w+) at (\S+ \S+)(?: by '([^']*)')?`)
+)
+
+// PowerScheduleScanner reports scheduled power events: the repeating
+// power-on, wake, sleep, and shutdown times set with pmset repeat or System
+// Settings, and one-time events scheduled by software other than macOS. A
+// repeating wake lets a job scheduled for the middle of the night run while
+// nobody is at the Mac; the wake stage pairs them with such jobs.
+type PowerScheduleScanner struct{}
+
+func NewPowerScheduleScanner() *PowerScheduleScanner {
+	return &PowerScheduleScanner{}
+}
+
+func (s *PowerScheduleScanner) Type() scanner.MechanismType {
+	return scanner.MechanismPowerSchedule
+}
+
+// Info reports the power management preferences read.
+func (s *PowerScheduleScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Repeating and one-time scheduled power-on, wake, sleep, and shutdown events",
+		Paths:       []string{autoWakePrefs, managedPreferences},
+		Commands:    []string{"pmset -g sched, when the preferences cannot be read (running system only)"},
+		Privileges:  []string{"root to read the scheduled events directly"},
+	}
+}
+
+// powerEvent is one scheduled power event.
+type powerEvent struct {
+	eventType string
+	repeating bool
+	// minutes after midnight and weekdays, for repeating events
+	minutes  int
+	weekdays string
+	// at and scheduledBy, for one-time events
+	at          string
+	scheduledBy string
+}
+
+func (s *PowerScheduleScanner) Scan() ([]scanner.PersistenceItem, error) {
+	path := autoWakePrefs
+	events, err := autoWakeEvents()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading scheduled power events", err, "scanner", s.Type(), "path", autoWakePrefs)
+		}
+		if !canExec() {
+			return nil, nil
+		}
+		output, err := command.Output("pmset", "-g", "sched")
+		if err != nil {
+			return nil, fmt.Errorf("running pmset -g sched: %w", err)
+		}
+		path = "pmset -g sched"
+		events = parsePmsetSched(string(output))
+	}
+	managed := powerScheduleManaged()
+
+	var items []scanner.PersistenceItem
+	for _, event := range events {
+		item := scanner.PersistenceItem{
+			Mechanism:  scanner.MechanismPowerSchedule,
+			Path:       path,
+			User:       "root",
+			ModifiedAt: getFileModTime(autoWakePrefs),
+			RawData: map[string]interface{}{
+				"event_type": event.eventType,
+				"managed":    managed,
+			},
+		}
+		if event.repeating {
+			at := fmt.Sprintf("%02d:%02d", event.minutes/60, event.minutes%60)
+			item.Label = fmt.Sprintf("Repeating %s at %s (%s)", event.eventType, at, event.weekdays)
+			item.RawData["kind"] = "repeating"
+			item.RawData["time"] = at
+			item.RawData["minutes"] = event.minutes
+			item.RawData["weekdays"] = event.weekdays
+			item.RawData["description"] = fmt.Sprintf("%s every %s at %s", event.eventType, event.weekdays, at)
+		} else {
+			// macOS schedules many one-time wakes for its own maintenance
+			if strings.HasPrefix(event.scheduledBy, "com.apple.") {
+				continue
+			}
+			item.Label = fmt.Sprintf("Scheduled %s at %s", event.eventType, event.at)
+			item.RawData["kind"] = "scheduled"
+			item.RawData["time"] = event.at
+			item.RawData["scheduled_by"] = event.scheduledBy
+			item.RawData["description"] = fmt.Sprintf("One-time %s at %s scheduled by %s", event.eventType, event.at, event.scheduledBy)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// autoWakeEvents reads the scheduled events from powerd's preferences:
+// repeating events are dictionaries under RepeatingPowerOn and
+// RepeatingPowerOff, and one-time events arrays of dictionaries under
+// their event type.
+func autoWakeEvents() ([]powerEvent, error) {
+	data, err := readFile(autoWakePrefs)
+	if err != nil {
+		return nil, err
+	}
+	var prefs map[string]interface{}
+	if _, err := plist.Unmarshal(data, &prefs); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(prefs))
+	for key := range prefs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var events []powerEvent
+	for _, key := range keys {
+		switch v := prefs[key].(type) {
+		case map[string]interface{}:
+			if !strings.HasPrefix(key, "Repeating") {
+				continue
+			}
+			event := powerEvent{repeating: true, weekdays: "every day"}
+			event.eventType, _ = v["eventtype"].(string)
+			minutes, _ := scanner.RawInt(v["time"])
+			event.minutes = minutes
+			if mask, ok := scanner.RawInt(v["weekdays"]); ok && mask&0x7f != 0x7f {
+				event.weekdays = weekdayString(mask)
+			}
+			events = append(events, event)
+		case []interface{}:
+			for _, entry := range v {
+				m, ok := entry.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				event := powerEvent{eventType: key}
+				if eventType, ok := m["eventtype"].(string); ok {
+					event.eventType = eventType
+				}
+				event.scheduledBy, _ = m["scheduledby"].(string)
+				if at, ok := m["time"].(time.Time); ok {
+					event.at = at.UTC().Format(time.RFC3339)
+				}
+				events = append(events, event)
+			}
+		}
+	}
+	return events, nil
+}
+
+// parsePmsetSched reads the repeating and scheduled events pmset -g sched
+// lists.
+func parsePmsetSched(output string) []powerEvent {
+	var events []powerEvent
+	for _, line := range strings.Split(output, "\n") {
+		if m := pmsetRepeating.FindStringSubmatch(line); m != nil {
+			hour, _ := strconv.Atoi(m[2])
+			minute, _ := strconv.Atoi(m[3])
+			hour %= 12
+			if m[4] == "PM" {
+				hour += 12
+			}
+			events = append(events, powerEvent{eventType: m[1], repeating: true, minutes: hour*60 + minute, weekdays: m[5]})
+		} else if m := pmsetScheduled.FindStringSubmatch(line); m != nil {
+			events = append(events, powerEvent{eventType: m[1], at: m[2], scheduledBy: m[3]})
+		}
+	}
+	return events
+}
+
+// weekdayString spells out a weekdays mask with pmset's day letters.
+func weekdayString(mask int) string {
+	var days strings.Builder
+	for i := 0; i < len(weekdayLetters); i++ {
+		if mask&(1<<i) != 0 {
+			days.WriteByte(weekdayLetters[i])
+		}
+	}
+	return days.String()
+}
+
+// powerScheduleManaged reports whether a configuration profile sets the
+// Energy Saver schedule.
+func powerScheduleManaged() bool {
+	entries, err := readDir(managedPreferences)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "com.apple.EnergySaver") && name != "com.apple.MCX.plist" {
+			continue
+		}
+		data, err := readFile(filepath.Join(managedPreferences, name))
+		if err == nil && (bytes.Contains(data, []byte("RepeatingPowerOn")) || bytes.Contains(data, []byte("Schedule"))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// rootSSHFiles are the files in root's ~/.ssh that sshd acts on when
+// someone logs in as root: the keys it accepts, a script it runs, and
+// variables it sets.
+var rootSSHFiles = []struct {
+	name        string
+	kind        string
+	description string
+}{
+	{"authorized_keys", "authorized_key", ""},
+	{"authorized_keys2", "authorized_key", ""},
+	{"rc", "ssh_rc", "Script sshd runs for every login as root"},
+	{"environment", "ssh_environment", "Variables sshd sets for logins as root, if PermitUserEnvironment allows"},
+}
+
+// RootAccountScanner reports the root account's own login persistence: the
+// SSH keys, rc script, and environment in /var/root/.ssh, and root's shell
+// startup files. root's LaunchAgents and crontab are reported by the
+// launchd and cron collectors, which include /var/root among the scanned
+// homes whatever $HOME is set to.
+type RootAccountScanner struct{}
+
+func NewRootAccountScanner() *RootAccountScanner {
+	return &RootAccountScanner{}
+}
+
+func (s *RootAccountScanner) Type() scanner.MechanismType {
+	return scanner.MechanismRootAccount
+}
+
+// Info reports the files read in root's home directory.
+func (s *RootAccountScanner) Info() scanner.ScannerInfo {
+	var paths []string
+	for _, file := range rootSSHFiles {
+		paths = append(paths, filepath.Join(rootHome, ".ssh", file.name))
+	}
+	for _, name := range userShellFiles {
+		paths = append(paths, filepath.Join(rootHome, name))
+	}
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "SSH keys, rc script, and environment, and shell startup files of the root account",
+		Paths:       paths,
+		Privileges:  []string{"root to read /var/root"},
+	}
+}
+
+func (s *RootAccountScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	for _, file := range rootSSHFiles {
+		path := filepath.Join(rootHome, ".ssh", file.name)
+		data, err := readFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Warn("reading root SSH file", err, "scanner", s.Type(), "path", path)
+			}
+			continue
+		}
+
+		if file.kind == "authorized_key" {
+			items = append(items, authorizedKeyItems(path, data)...)
+			continue
+		}
+		items = append(items, rootFileItem(path, file.kind, file.description, data))
+	}
+
+	for _, name := range userShellFiles {
+		path := filepath.Join(rootHome, name)
+		data, err := readFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Warn("reading root shell startup file", err, "scanner", s.Type(), "path", path)
+			}
+			continue
+		}
+		items = append(items, rootFileItem(path, "shell_startup", "Shell startup file run for root's shells, including sudo -i and su -", data))
+	}
+
+	return items, nil
+}
+
+// rootFileItem reports a file in root's home directory as a whole.
+func rootFileItem(path, kind, description string, data []byte) scanner.PersistenceItem {
+	return scanner.PersistenceItem{
+		Mechanism:  scanner.MechanismRootAccount,
+		Label:      "root " + strings.TrimPrefix(path, rootHome+"/"),
+		Path:       path,
+		User:       "root",
+		ModifiedAt: getFileModTime(path),
+		RawData: map[string]interface{}{
+			"description": description,
+			"kind":        kind,
+			"content":     string(data),
+		},
+	}
+}
+
+// authorizedKeyItems reports each key in an authorized_keys file.
+func authorizedKeyItems(path string, data []byte) []scanner.PersistenceItem {
+	var items []scanner.PersistenceItem
+	modified := getFileModTime(path)
+
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, ok := parseAuthorizedKey(line)
+		if !ok {
+			continue
+		}
+
+		name := key.comment
+		if name == "" {
+			name = key.fingerprint
+		}
+		item := scanner.PersistenceItem{
+			Mechanism:  scanner.MechanismRootAccount,
+			Label:      "root SSH key: " + name,
+			Path:       path,
+			User:       "root",
+			ModifiedAt: modified,
+			RawData: map[string]interface{}{
+				"description": fmt.Sprintf("%s key allowed to log in as root", key.keyType),
+				"kind":        "authorized_key",
+				"line":        n,
+				"key_type":    key.keyType,
+				"fingerprint": key.fingerprint,
+				"comment":     key.comment,
+			},
+		}
+		if key.options != "" {
+			item.RawData["options"] = key.options
+		}
+		if key.command != "" {
+			item.RawData["command"] = key.command
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+type authorizedKey struct {
+	options     string
+	command     string
+	keyType     string
+	fingerprint string
+	comment     string
+}
+
+// parseAuthorizedKey splits an authorized_keys line into its options, key
+// type, key, and comment, following sshd(8): options come first when the
+// line does not start with a key type, and may contain quoted spaces.
+func parseAuthorizedKey(line string) (authorizedKey, bool) {
+	var key authorizedKey
+	if !isSSHKeyType(strings.Fields(line)[0]) {
+		end := -1
+		quoted := false
+		for i := 0; i < len(line) && end < 0; i++ {
+			switch c := line[i]; {
+			case c == '\\' && quoted:
+				i++
+			case c == '"':
+				quoted = !quoted
+			case (c == ' ' || c == '\t') && !quoted:
+				end = i
+			}
+		}
+		if end < 0 {
+			return key, false
+		}
+		key.options = line[:end]
+		line = strings.TrimSpace(line[end:])
+		key.command = sshOption(key.options, "command")
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !isSSHKeyType(fields[0]) {
+		return key, false
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return key, false
+	}
+	sum := sha256.Sum256(blob)
+	key.keyType = fields[0]
+	key.fingerprint = "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+	key.comment = strings.Join(fields[2:], " ")
+	return key, true
+}
+
+func isSSHKeyType(s string) bool {
+	return strings.HasPrefix(s, "ssh-") || strings.HasPrefix(s, "ecdsa-sha2-") || strings.HasPrefix(s, "sk-")
+}
+
+// sshOption returns the quoted value of name="..." in an authorized_keys
+// option list.
+func sshOption(options, name string) string {
+	i := strings.Index(options, name+`="`)
+	if i < 0 {
+		return ""
+	}
+	value := options[i+len(name)+2:]
+	for j := 0; j < len(value); j++ {
+		switch value[j] {
+		case '\\':
+			j++
+		case '"':
+			return strings.ReplaceAll(value[:j], `\"`, `"`)
+		}
+	}
+	return ""
+}
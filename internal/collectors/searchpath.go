@@ -0,0 +1,327 @@
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+const (
+	etcPaths       = "/etc/paths"
+	etcPathsDir    = "/etc/paths.d"
+	etcManPathsDir = "/etc/manpaths.d"
+)
+
+// systemShellFiles are the shell startup files read for every user.
+var systemShellFiles = []string{
+	"/etc/profile", "/etc/bashrc", "/etc/zshenv", "/etc/zprofile", "/etc/zshrc", "/etc/zlogin",
+}
+
+// userShellFiles are the shell startup files read from each home directory.
+var userShellFiles = []string{
+	".profile", ".bash_profile", ".bash_login", ".bashrc",
+	".zshenv", ".zprofile", ".zshrc", ".zlogin",
+}
+
+// systemPathDirs are the directories that hold the commands scripts expect
+// to find; anything searched before them can shadow those commands.
+var systemPathDirs = map[string]bool{
+	"/usr/bin": true, "/bin": true, "/usr/sbin": true, "/sbin": true,
+}
+
+var (
+	pathAssignment    = regexp.MustCompile(`^\s*(?:export\s+)?(PATH|MANPATH)=(.*)$`)
+	zshPathAssignment = regexp.MustCompile(`^\s*(path|manpath)=\((.*)\)`)
+)
+
+// SearchPathScanner reports the directories added to PATH and MANPATH by
+// path_helper's /etc/paths files and by shell startup files. A directory
+// that non-root users can write, searched ahead of the system directories,
+// lets them shadow commands that other users and scripts run.
+type SearchPathScanner struct{}
+
+func NewSearchPathScanner() *SearchPathScanner {
+	return &SearchPathScanner{}
+}
+
+func (s *SearchPathScanner) Type() scanner.MechanismType {
+	return scanner.MechanismSearchPath
+}
+
+// Info reports the path_helper files and shell startup files read.
+func (s *SearchPathScanner) Info() scanner.ScannerInfo {
+	paths := []string{etcPaths, etcPathsDir, etcManPathsDir}
+	paths = append(paths, systemShellFiles...)
+	for _, home := range userHomes() {
+		for _, name := range userShellFiles {
+			paths = append(paths, filepath.Join(home.Dir, name))
+		}
+	}
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "PATH and MANPATH entries from /etc/paths, /etc/paths.d, /etc/manpaths.d, and shell startup files",
+		Paths:       paths,
+		Privileges:  []string{"root to read every local user's shell startup files; otherwise only the invoking user's"},
+	}
+}
+
+func (s *SearchPathScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	items = append(items, s.scanPathHelper()...)
+
+	for _, path := range systemShellFiles {
+		items = append(items, s.scanShellFile(path, "", "")...)
+	}
+	for _, home := range userHomes() {
+		for _, name := range userShellFiles {
+			items = append(items, s.scanShellFile(filepath.Join(home.Dir, name), home.Name, home.Dir)...)
+		}
+	}
+
+	return items, nil
+}
+
+// scanPathHelper reads the files path_helper builds the login PATH and
+// MANPATH from: /etc/paths first, then each file in /etc/paths.d appended
+// after it.
+func (s *SearchPathScanner) scanPathHelper() []scanner.PersistenceItem {
+	var items []scanner.PersistenceItem
+
+	if item, ok := s.pathHelperItem(etcPaths, "PATH", "system"); ok {
+		items = append(items, item)
+	}
+
+	for _, dir := range []string{etcPathsDir, etcManPathsDir} {
+		variable := "PATH"
+		if dir == etcManPathsDir {
+			variable = "MANPATH"
+		}
+
+		entries, err := readDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Warn("reading path_helper directory", err, "scanner", s.Type(), "path", dir)
+			}
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if item, ok := s.pathHelperItem(filepath.Join(dir, entry.Name()), variable, "appended"); ok {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items
+}
+
+func (s *SearchPathScanner) pathHelperItem(path, variable, position string) (scanner.PersistenceItem, bool) {
+	data, err := readFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading path_helper file", err, "scanner", s.Type(), "path", path)
+		}
+		return scanner.PersistenceItem{}, false
+	}
+
+	var dirs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			dirs = append(dirs, line)
+		}
+	}
+
+	// Entries in /etc/paths before the system directories are searched
+	// ahead of them
+	var prepended []string
+	if position == "system" {
+		prepended = beforeSystemDirs(dirs)
+	}
+
+	item := scanner.PersistenceItem{
+		Mechanism:  scanner.MechanismSearchPath,
+		Label:      fmt.Sprintf("%s (%s)", variable, path),
+		Path:       path,
+		ModifiedAt: getFileModTime(path),
+		RawData: map[string]interface{}{
+			"description": fmt.Sprintf("%s entries from %s", variable, path),
+			"variable":    variable,
+			"scope":       "system",
+			"position":    position,
+			"directories": dirs,
+			"prepended":   prepended,
+			"content":     string(data),
+		},
+	}
+	s.recordWritable(&item, dirs)
+	return item, true
+}
+
+// scanShellFile reports the PATH and MANPATH assignments in a shell startup
+// file, one item for each variable the file changes. Files that leave both
+// unchanged are not reported. user and home are empty for the system-wide
+// files.
+func (s *SearchPathScanner) scanShellFile(path, user, home string) []scanner.PersistenceItem {
+	data, err := readFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading shell startup file", err, "scanner", s.Type(), "path", path)
+		}
+		return nil
+	}
+
+	type assigned struct {
+		dirs, prepended, assignments []string
+	}
+	var variables []string
+	byVariable := make(map[string]*assigned)
+	for _, line := range strings.Split(string(data), "\n") {
+		var entries []string
+		var self string
+		if m := pathAssignment.FindStringSubmatch(line); m != nil {
+			entries = strings.Split(unquote(m[2]), ":")
+			self = m[1]
+		} else if m := zshPathAssignment.FindStringSubmatch(line); m != nil {
+			entries = strings.Fields(m[2])
+			self = m[1]
+		} else {
+			continue
+		}
+		// zsh ties path and manpath to PATH and MANPATH
+		variable := strings.ToUpper(self)
+		a := byVariable[variable]
+		if a == nil {
+			a = &assigned{}
+			byVariable[variable] = a
+			variables = append(variables, variable)
+		}
+		a.assignments = append(a.assignments, strings.TrimSpace(line))
+
+		// Entries before the variable's previous value are searched first;
+		// a complete replacement is judged against the system directories
+		ahead := true
+		var lineDirs []string
+		for _, entry := range entries {
+			entry = strings.Trim(entry, `"'`)
+			if isSelfReference(entry, self) {
+				ahead = false
+				continue
+			}
+			if entry == "" || strings.HasPrefix(entry, "$(") {
+				continue
+			}
+			entry = expandHome(entry, home)
+			lineDirs = append(lineDirs, entry)
+			if ahead && !systemPathDirs[entry] {
+				a.prepended = append(a.prepended, entry)
+			}
+			if systemPathDirs[entry] {
+				ahead = false
+			}
+		}
+		a.dirs = append(a.dirs, lineDirs...)
+	}
+
+	scope := "system"
+	if user != "" {
+		scope = "user"
+	}
+	var items []scanner.PersistenceItem
+	for _, variable := range variables {
+		a := byVariable[variable]
+		item := scanner.PersistenceItem{
+			Mechanism:  scanner.MechanismSearchPath,
+			Label:      fmt.Sprintf("%s (%s)", variable, path),
+			Path:       path,
+			User:       user,
+			ModifiedAt: getFileModTime(path),
+			RawData: map[string]interface{}{
+				"description": fmt.Sprintf("%s assignments in %s", variable, path),
+				"variable":    variable,
+				"scope":       scope,
+				"position":    "prepended",
+				"directories": a.dirs,
+				"prepended":   a.prepended,
+				"assignments": a.assignments,
+				"content":     string(data),
+			},
+		}
+		s.recordWritable(&item, a.dirs)
+		items = append(items, item)
+	}
+	return items
+}
+
+// recordWritable lists the directories among dirs that a non-root user can
+// write, and those anyone can write, in the item's raw data.
+func (s *SearchPathScanner) recordWritable(item *scanner.PersistenceItem, dirs []string) {
+	var writable, worldWritable []string
+	for _, dir := range dirs {
+		info, err := os.Stat(sysroot.Path(dir))
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		mode := info.Mode().Perm()
+		uid, gid, ok := fileOwner(info)
+		switch {
+		case mode&0o002 != 0:
+			worldWritable = append(worldWritable, dir)
+			writable = append(writable, dir)
+		case ok && (uid != 0 || mode&0o020 != 0 && gid != 0):
+			writable = append(writable, dir)
+		}
+	}
+	item.RawData["writable"] = writable
+	item.RawData["world_writable"] = worldWritable
+}
+
+// beforeSystemDirs returns the entries of dirs searched before the first
+// system directory.
+func beforeSystemDirs(dirs []string) []string {
+	var before []string
+	for _, dir := range dirs {
+		if systemPathDirs[dir] {
+			break
+		}
+		before = append(before, dir)
+	}
+	return before
+}
+
+func isSelfReference(entry, variable string) bool {
+	switch entry {
+	case "$" + variable, "${" + variable + "}", "$" + strings.ToLower(variable), "${" + strings.ToLower(variable) + "}", "$" + strings.ToLower(variable) + "[@]":
+		return true
+	}
+	return false
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.Trim(strings.TrimSuffix(value, ";"), `"'`)
+}
+
+func expandHome(dir, home string) string {
+	if home == "" {
+		return dir
+	}
+	for _, prefix := range []string{"~", "$HOME", "${HOME}"} {
+		if dir == prefix || strings.HasPrefix(dir, prefix+"/") {
+			return home + strings.TrimPrefix(dir, prefix)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,79 @@
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// ShellStartupScanner reports the shell startup files in each local
+// account's home directory. Every Terminal window, SSH login, and script
+// run through a login or interactive shell runs them, so a line added to
+// one persists without a launchd job. root's are reported by the root
+// account collector.
+type ShellStartupScanner struct{}
+
+func NewShellStartupScanner() *ShellStartupScanner {
+	return &ShellStartupScanner{}
+}
+
+func (s *ShellStartupScanner) Type() scanner.MechanismType {
+	return scanner.MechanismShellStartup
+}
+
+// Info reports the startup files read in each home directory.
+func (s *ShellStartupScanner) Info() scanner.ScannerInfo {
+	var paths []string
+	for _, home := range userHomes() {
+		if home.Dir == rootHome {
+			continue
+		}
+		for _, name := range userShellFiles {
+			paths = append(paths, filepath.Join(home.Dir, name))
+		}
+	}
+
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Shell startup files in each local account's home directory",
+		Paths:       paths,
+		Privileges:  []string{"root to read every local user's shell startup files; otherwise only the invoking user's"},
+	}
+}
+
+func (s *ShellStartupScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	for _, home := range userHomes() {
+		if home.Dir == rootHome {
+			continue
+		}
+		for _, name := range userShellFiles {
+			path := filepath.Join(home.Dir, name)
+			data, err := readFile(path)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					logging.Warn("reading shell startup file", err, "scanner", s.Type(), "path", path)
+				}
+				continue
+			}
+
+			items = append(items, scanner.PersistenceItem{
+				Mechanism:  scanner.MechanismShellStartup,
+				Label:      fmt.Sprintf("%s (%s)", name, home.Name),
+				Path:       path,
+				User:       home.Name,
+				ModifiedAt: getFileModTime(path),
+				RawData: map[string]interface{}{
+					"description": fmt.Sprintf("Shell startup file run for %s's shells", home.Name),
+					"content":     string(data),
+				},
+			})
+		}
+	}
+
+	return items, nil
+}
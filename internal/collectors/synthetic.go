@@ -0,0 +1,107 @@
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+const (
+	syntheticConf = "/etc/synthetic.conf"
+	syntheticDir  = "/etc/synthetic.d"
+)
+
+// SyntheticScanner reports the top-level directories and symbolic links
+// synthetic.conf creates at boot. A link there can redirect a path that
+// scripts and installers trust, such as /opt, to a user-writable location.
+type SyntheticScanner struct{}
+
+func NewSyntheticScanner() *SyntheticScanner {
+	return &SyntheticScanner{}
+}
+
+func (s *SyntheticScanner) Type() scanner.MechanismType {
+	return scanner.MechanismSynthetic
+}
+
+// Info reports the synthetic.conf files read.
+func (s *SyntheticScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Top-level directories and links created from synthetic.conf",
+		Paths:       []string{syntheticConf, syntheticDir},
+	}
+}
+
+func (s *SyntheticScanner) Scan() ([]scanner.PersistenceItem, error) {
+	paths := []string{syntheticConf}
+	entries, err := readDir(syntheticDir)
+	if err != nil && !os.IsNotExist(err) {
+		logging.Warn("reading synthetic.d", err, "scanner", s.Type(), "path", syntheticDir)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			paths = append(paths, filepath.Join(syntheticDir, entry.Name()))
+		}
+	}
+
+	var items []scanner.PersistenceItem
+	for _, path := range paths {
+		data, err := readFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Warn("reading synthetic.conf", err, "scanner", s.Type(), "path", path)
+			}
+			continue
+		}
+		items = append(items, s.parse(path, string(data))...)
+	}
+
+	return items, nil
+}
+
+// parse reads one entry per line: a name alone creates an empty directory
+// for a mount point, and a name, a tab, and a target create a link. A
+// relative target is resolved against the Data volume, which appears at /.
+func (s *SyntheticScanner) parse(path, content string) []scanner.PersistenceItem {
+	var items []scanner.PersistenceItem
+
+	for i, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		name, target, isLink := strings.Cut(line, "\t")
+		name = strings.TrimSpace(name)
+		target = strings.TrimSpace(target)
+
+		item := scanner.PersistenceItem{
+			Mechanism:  scanner.MechanismSynthetic,
+			Label:      "/" + name,
+			Path:       path,
+			ModifiedAt: getFileModTime(path),
+			RawData: map[string]interface{}{
+				"name":    name,
+				"kind":    "directory",
+				"line":    i + 1,
+				"content": content,
+			},
+		}
+		item.RawData["description"] = fmt.Sprintf("Empty top-level directory /%s", name)
+
+		if isLink && target != "" {
+			if !filepath.IsAbs(target) {
+				target = "/" + target
+			}
+			item.RawData["kind"] = "link"
+			item.RawData["target"] = target
+			item.RawData["description"] = fmt.Sprintf("Top-level link /%s -> %s", name, target)
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
@@ -0,0 +1,164 @@
+package collectors
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+// systemExtensionsDB is sysextd's record of every system extension
+// installed, with its approval state.
+const systemExtensionsDB = "/Library/SystemExtensions/db.plist"
+
+type systemExtensionsDatabase struct {
+	Extensions []struct {
+		Identifier    string   `plist:"identifier"`
+		TeamID        string   `plist:"teamID"`
+		State         string   `plist:"state"`
+		OriginPath    string   `plist:"originPath"`
+		Categories    []string `plist:"categories"`
+		BundleVersion struct {
+			Version string `plist:"CFBundleVersion"`
+			Short   string `plist:"CFBundleShortVersionString"`
+		} `plist:"bundleVersion"`
+		StagedBundleURL struct {
+			Relative string `plist:"relative"`
+		} `plist:"stagedBundleURL"`
+	} `plist:"extensions"`
+}
+
+// SystemExtensionScanner reports the system extensions sysextd has
+// recorded and compares them with what systemextensionsctl reports as
+// running. Extensions awaiting the user's approval, and running extensions
+// sysextd has no record of, are marked so the heuristics can flag them.
+type SystemExtensionScanner struct{}
+
+func NewSystemExtensionScanner() *SystemExtensionScanner {
+	return &SystemExtensionScanner{}
+}
+
+func (s *SystemExtensionScanner) Type() scanner.MechanismType {
+	return scanner.MechanismSystemExtension
+}
+
+// Info reports the sysextd database and the runtime query.
+func (s *SystemExtensionScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "System extensions recorded by sysextd and running on the system",
+		Paths:       []string{systemExtensionsDB},
+		Commands:    []string{"systemextensionsctl list (running system only)"},
+		Privileges:  []string{"root to read " + systemExtensionsDB},
+	}
+}
+
+func (s *SystemExtensionScanner) Scan() ([]scanner.PersistenceItem, error) {
+	var items []scanner.PersistenceItem
+
+	// running is nil when systemextensionsctl could not be queried
+	var running map[string]string
+	if canExec() {
+		var err error
+		if running, err = runningSystemExtensions(); err != nil {
+			logging.Warn("listing system extensions", err, "scanner", s.Type())
+		}
+	}
+
+	// Without the database, which needs root, running extensions cannot
+	// be told apart from recorded ones; a missing database records none
+	recorded := make(map[string]bool)
+	data, err := readFile(systemExtensionsDB)
+	compare := err == nil || os.IsNotExist(err)
+	if err != nil && !os.IsNotExist(err) {
+		logging.Warn("reading system extensions database", err, "scanner", s.Type(), "path", systemExtensionsDB)
+	}
+	if err == nil {
+		var db systemExtensionsDatabase
+		if _, err := plist.Unmarshal(data, &db); err != nil {
+			logging.Warn("parsing system extensions database", err, "scanner", s.Type(), "path", systemExtensionsDB)
+		}
+		for _, ext := range db.Extensions {
+			recorded[ext.Identifier] = true
+
+			item := scanner.PersistenceItem{
+				Mechanism:  scanner.MechanismSystemExtension,
+				Label:      ext.Identifier,
+				Path:       systemExtensionsDB,
+				ModifiedAt: getFileModTime(systemExtensionsDB),
+				RawData: map[string]interface{}{
+					"description": fmt.Sprintf("System extension %s (%s)", ext.Identifier, ext.State),
+					"bundle_id":   ext.Identifier,
+					"team_id":     ext.TeamID,
+					"state":       ext.State,
+					"origin_path": ext.OriginPath,
+					"categories":  ext.Categories,
+					"version":     ext.BundleVersion.Short,
+					"recorded":    true,
+				},
+			}
+			if u, err := url.Parse(ext.StagedBundleURL.Relative); err == nil && u.Scheme == "file" {
+				item.Program = filepath.Clean(u.Path)
+			}
+			if running != nil {
+				_, isRunning := running[ext.Identifier]
+				item.RawData["running"] = isRunning
+			}
+			items = append(items, item)
+		}
+	}
+
+	ids := make([]string, 0, len(running))
+	for id := range running {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if recorded[id] || !compare {
+			continue
+		}
+		items = append(items, scanner.PersistenceItem{
+			Mechanism: scanner.MechanismSystemExtension,
+			Label:     id,
+			Path:      "systemextensionsctl list",
+			RawData: map[string]interface{}{
+				"description": fmt.Sprintf("Running system extension %s with no record in %s", id, systemExtensionsDB),
+				"bundle_id":   id,
+				"state":       running[id],
+				"running":     true,
+				"recorded":    false,
+			},
+		})
+	}
+
+	return items, nil
+}
+
+// runningSystemExtensions returns the state systemextensionsctl reports for
+// each active or enabled system extension, by bundle identifier.
+func runningSystemExtensions() (map[string]string, error) {
+	output, err := command.Output("systemextensionsctl", "list")
+	if err != nil {
+		return nil, fmt.Errorf("running systemextensionsctl: %w", err)
+	}
+
+	// Rows are: enabled, active, team ID, "bundle.id (version)", name,
+	// and "[state]", separated by tabs
+	running := make(map[string]string)
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 6 || fields[0] != "*" && fields[1] != "*" {
+			continue
+		}
+		id, _, _ := strings.Cut(strings.TrimSpace(fields[3]), " ")
+		running[id] = strings.Trim(strings.TrimSpace(fields[len(fields)-1]), "[]")
+	}
+	return running, nil
+}
@@ -0,0 +1,252 @@
+package collectors
+
+import (
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+const (
+	// maxTargetDepth is how many wrapper scripts are followed.
+	maxTargetDepth = 3
+	// maxScriptSize is how much of a wrapper script is parsed.
+	maxScriptSize = 1 << 20
+)
+
+// inlineFlags are the flags with which each interpreter runs code given on
+// the command line rather than from a file.
+var inlineFlags = map[string][]string{
+	"sh": {"-c"}, "bash": {"-c"}, "zsh": {"-c"}, "dash": {"-c"}, "ksh": {"-c"}, "csh": {"-c"}, "tcsh": {"-c"},
+	"python": {"-c", "-m"}, "ruby": {"-e"}, "perl": {"-e", "-E"}, "node": {"-e", "-p"}, "osascript": {"-e"}, "php": {"-r"},
+}
+
+// shells are the interpreters whose inline code is parsed like a script.
+var shells = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "csh": true, "tcsh": true}
+
+// commandPrefixes run the command that follows them.
+var commandPrefixes = map[string]bool{
+	"exec": true, "nohup": true, "sudo": true, "command": true, "nice": true, "time": true,
+	"then": true, "do": true, "else": true, "!": true,
+}
+
+var (
+	commandSeparator = regexp.MustCompile(`\s*(?:&&|\|\||[;|&()` + "`" + `{}]|\$\()\s*`)
+	assignment       = regexp.MustCompile(`^([A-Za-z_]\w*)=(\S*)$`)
+	shellVariable    = regexp.MustCompile(`\$\{?([A-Za-z_]\w*)\}?`)
+	versionSuffix    = regexp.MustCompile(`[\d.]+$`)
+	shellQuotes      = strings.NewReplacer(`"`, "", `'`, "")
+)
+
+// TargetStage runs ResolveTargets as a scanner.Stage.
+var TargetStage = scanner.NewStage("targets", ResolveTargets)
+
+// ResolveTargets follows each item whose program is an interpreter or a
+// shell script to the files it goes on to run: the script an interpreter
+// is given and the binaries and scripts a script runs by absolute path,
+// following scripts up to maxTargetDepth deep. Targets in the system
+// directories SIP protects are skipped; targets are described like
+// programs so signature, path, and indicator checks can judge them instead
+// of only the wrapper.
+func ResolveTargets(items []scanner.PersistenceItem) {
+	r := &targetResolver{meta: make(map[string]*scanner.FileMetadata)}
+	for i := range items {
+		item := &items[i]
+		if !filepath.IsAbs(item.Program) {
+			continue
+		}
+		r.targets = nil
+		r.visited = map[string]bool{item.Program: true}
+
+		args := item.ProgramArgs
+		if len(args) > 0 && filepath.Base(args[0]) == filepath.Base(item.Program) {
+			args = args[1:]
+		}
+		if !r.command(append([]string{item.Program}, args...), item.Program, 1) {
+			r.script(item.Program, 1)
+		}
+		item.ResolvedTargets = r.targets
+	}
+}
+
+type targetResolver struct {
+	meta    map[string]*scanner.FileMetadata
+	targets []scanner.ResolvedTarget
+	visited map[string]bool
+}
+
+// command follows the words of one command run by wrapper, and reports
+// whether it named an interpreter.
+func (r *targetResolver) command(words []string, wrapper string, depth int) bool {
+	for len(words) > 0 && (commandPrefixes[words[0]] || strings.HasPrefix(words[0], "-") || assignment.MatchString(words[0])) {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return false
+	}
+	program, args := words[0], words[1:]
+	name := interpreterName(program)
+
+	// env runs the interpreter named after its own flags and variables
+	if name == "env" {
+		return r.command(args, wrapper, depth)
+	}
+
+	switch {
+	case name == "open":
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				r.add(arg, "command", wrapper, depth)
+				break
+			}
+		}
+	case name == "source" || name == ".":
+		if len(args) > 0 {
+			r.add(args[0], "interpreter argument", wrapper, depth)
+		}
+	case inlineFlags[name] != nil:
+	args:
+		for i, arg := range args {
+			for _, flag := range inlineFlags[name] {
+				if arg == flag {
+					if shells[name] && i+1 < len(args) {
+						r.text(args[i+1], wrapper, depth)
+					}
+					break args
+				}
+			}
+			if !strings.HasPrefix(arg, "-") {
+				r.add(arg, "interpreter argument", wrapper, depth)
+				break
+			}
+		}
+		return true
+	default:
+		if program != wrapper {
+			r.add(program, "command", wrapper, depth)
+		}
+	}
+	return false
+}
+
+// add records path as a target of wrapper and follows it when it is a
+// script.
+func (r *targetResolver) add(path, via, wrapper string, depth int) {
+	if !filepath.IsAbs(path) || strings.Contains(path, "$") {
+		return
+	}
+	path = filepath.Clean(path)
+	if r.visited[path] || systemDirectory(path) {
+		return
+	}
+	r.visited[path] = true
+
+	meta, ok := r.meta[path]
+	if !ok {
+		meta, _ = fileMetadata(path)
+		r.meta[path] = meta
+	}
+	if meta == nil {
+		return
+	}
+	r.targets = append(r.targets, scanner.ResolvedTarget{
+		Path:    path,
+		Via:     via,
+		Depth:   depth,
+		Wrapper: wrapper,
+		File:    meta,
+	})
+	if depth < maxTargetDepth {
+		r.script(path, depth+1)
+	}
+}
+
+// script follows the commands of the shell script at path, if it is one.
+func (r *targetResolver) script(path string, depth int) {
+	file, err := openFile(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(file, magic); err != nil || string(magic) != "#!" {
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxScriptSize))
+	if err != nil {
+		return
+	}
+
+	shebang, body, _ := strings.Cut(string(data), "\n")
+	interpreter := strings.Fields(shebang)
+	if len(interpreter) > 0 {
+		name := interpreterName(interpreter[0])
+		if name == "env" && len(interpreter) > 1 {
+			name = interpreterName(interpreter[1])
+		}
+		// Only shell scripts are parsed for commands
+		if !shells[name] {
+			return
+		}
+	}
+	r.text(body, path, depth)
+}
+
+// text follows the commands of shell code run by wrapper. Variables
+// assigned a literal value are substituted into later commands.
+func (r *targetResolver) text(code, wrapper string, depth int) {
+	variables := make(map[string]string)
+	for _, line := range strings.Split(code, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, segment := range commandSeparator.Split(line, -1) {
+			var words []string
+			for _, word := range strings.Fields(segment) {
+				word = shellQuotes.Replace(word)
+				word = shellVariable.ReplaceAllStringFunc(word, func(v string) string {
+					name := shellVariable.FindStringSubmatch(v)[1]
+					if value, ok := variables[name]; ok {
+						return value
+					}
+					return v
+				})
+				words = append(words, word)
+			}
+			if len(words) == 2 && words[0] == "export" {
+				words = words[1:]
+			}
+			if len(words) == 1 {
+				if m := assignment.FindStringSubmatch(words[0]); m != nil && !strings.Contains(m[2], "$") {
+					variables[m[1]] = m[2]
+					continue
+				}
+			}
+			r.command(words, wrapper, depth)
+		}
+	}
+}
+
+// interpreterName is the name of program without its directory or version,
+// so /usr/bin/python3.11 is python.
+func interpreterName(program string) string {
+	name := filepath.Base(program)
+	if trimmed := versionSuffix.ReplaceAllString(name, ""); trimmed != "" {
+		return trimmed
+	}
+	return name
+}
+
+// systemDirectory reports whether path is in a directory SIP protects,
+// where the interpreters and system commands scripts run live.
+func systemDirectory(path string) bool {
+	for _, dir := range []string{"/bin/", "/sbin/", "/usr/bin/", "/usr/sbin/", "/usr/libexec/", "/System/"} {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"howett.net/plist"
+)
+
+// userHome is a local account whose per-user persistence is scanned.
+type userHome struct {
+	Name string
+	Dir  string
+}
+
+// dslocalUsers is where the local directory node stores one plist per
+// account.
+const dslocalUsers = "/var/db/dslocal/nodes/Default/users"
+
+// rootHome is the root account's home directory.
+const rootHome = "/var/root"
+
+// userHomes returns the accounts whose home directories are scanned. An
+// unprivileged scan only covers the invoking user; running as root, or
+// against an offline root, covers every local account with a home
+// directory.
+func userHomes() []userHome {
+	if !sysroot.Offline() && os.Geteuid() != 0 {
+		return currentUserHome()
+	}
+
+	homes := dslocalHomes()
+	if len(homes) == 0 && canExec() {
+		homes = dsclHomes()
+	}
+	if len(homes) == 0 {
+		homes = usersDirHomes()
+	}
+	if len(homes) == 0 && !sysroot.Offline() {
+		homes = currentUserHome()
+	}
+	// root's home is outside /Users, and under sudo $HOME is still the
+	// invoking user's, so add it explicitly
+	homes = append(homes, userHome{Name: "root", Dir: rootHome})
+
+	// Skip service accounts without a real home. When accounts share a home
+	// (daemon and root both use /var/root), attribute it to the one named
+	// after the directory.
+	byDir := make(map[string]userHome)
+	for _, home := range homes {
+		if !strings.HasPrefix(home.Dir, "/") || home.Dir == "/var/empty" {
+			continue
+		}
+		if existing, ok := byDir[home.Dir]; ok && existing.Name == filepath.Base(home.Dir) {
+			continue
+		}
+		if info, err := os.Stat(sysroot.Path(home.Dir)); err != nil || !info.IsDir() {
+			continue
+		}
+		byDir[home.Dir] = home
+	}
+
+	result := make([]userHome, 0, len(byDir))
+	for _, home := range byDir {
+		result = append(result, home)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Dir < result[j].Dir })
+
+	return result
+}
+
+func currentUserHome() []userHome {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	name := os.Getenv("USER")
+	if name == "" {
+		name = filepath.Base(home)
+	}
+	return []userHome{{Name: name, Dir: home}}
+}
+
+// dslocalHomes reads accounts straight from the local directory node, which
+// works on live systems and mounted images alike.
+func dslocalHomes() []userHome {
+	entries, err := readDir(dslocalUsers)
+	if err != nil {
+		return nil
+	}
+
+	var homes []userHome
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".plist") {
+			continue
+		}
+		data, err := readFile(filepath.Join(dslocalUsers, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		var record struct {
+			Name []string `plist:"name"`
+			Home []string `plist:"home"`
+		}
+		if _, err := plist.Unmarshal(data, &record); err != nil || len(record.Name) == 0 || len(record.Home) == 0 {
+			continue
+		}
+		if strings.HasPrefix(record.Name[0], "_") {
+			continue
+		}
+		homes = append(homes, userHome{Name: record.Name[0], Dir: record.Home[0]})
+	}
+
+	return homes
+}
+
+// dsclHomes asks Directory Services for local accounts.
+func dsclHomes() []userHome {
+	output, err := command.Output("dscl", ".", "-list", "/Users", "NFSHomeDirectory")
+	if err != nil {
+		return nil
+	}
+
+	var homes []userHome
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "_") {
+			continue
+		}
+		homes = append(homes, userHome{Name: fields[0], Dir: strings.Join(fields[1:], " ")})
+	}
+
+	return homes
+}
+
+// usersDirHomes falls back to the directories under /Users.
+func usersDirHomes() []userHome {
+	entries, err := readDir("/Users")
+	if err != nil {
+		return nil
+	}
+
+	var homes []userHome
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "Shared" || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		homes = append(homes, userHome{Name: entry.Name(), Dir: filepath.Join("/Users", entry.Name())})
+	}
+
+	return homes
+}
@@ -0,0 +1,121 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+const etcShells = "/etc/shells"
+
+// noLoginShells refuse logins; service accounts use them.
+var noLoginShells = map[string]bool{
+	"/usr/bin/false": true, "/bin/false": true, "/sbin/nologin": true, "/usr/sbin/nologin": true,
+}
+
+// UserShellScanner reports each local account's login shell, its
+// UserShell attribute in Directory Services. Whatever it names runs at
+// every Terminal window, SSH login, and su, so pointing it at a wrapper
+// script persists without any file the other collectors read.
+type UserShellScanner struct{}
+
+func NewUserShellScanner() *UserShellScanner {
+	return &UserShellScanner{}
+}
+
+func (s *UserShellScanner) Type() scanner.MechanismType {
+	return scanner.MechanismUserShell
+}
+
+// Info reports the account records and shell list read.
+func (s *UserShellScanner) Info() scanner.ScannerInfo {
+	return scanner.ScannerInfo{
+		Mechanism:   s.Type(),
+		Description: "Login shell of each local account, compared with /etc/shells",
+		Paths:       []string{dslocalUsers, etcShells},
+		Commands:    []string{"dscl . -readall /Users, when the account records cannot be read directly (running system only)"},
+		Privileges:  []string{"root to read the account records directly"},
+	}
+}
+
+func (s *UserShellScanner) Scan() ([]scanner.PersistenceItem, error) {
+	accounts := localAccounts()
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+
+	listed := make(map[string]bool)
+	if data, err := readFile(etcShells); err == nil {
+		lines := bufio.NewScanner(bytes.NewReader(data))
+		for lines.Scan() {
+			if line := strings.TrimSpace(lines.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				listed[line] = true
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		logging.Warn("reading shell list", err, "scanner", s.Type(), "path", etcShells)
+	}
+
+	var items []scanner.PersistenceItem
+	for _, account := range accounts {
+		// Service accounts normally cannot log in; report them only when
+		// they can
+		if strings.HasPrefix(account.Name, "_") && (account.Shell == "" || noLoginShells[account.Shell]) {
+			continue
+		}
+		if account.Shell == "" {
+			continue
+		}
+
+		item := scanner.PersistenceItem{
+			Mechanism: scanner.MechanismUserShell,
+			Label:     fmt.Sprintf("Login shell: %s", account.Name),
+			Path:      account.Record,
+			Program:   account.Shell,
+			User:      account.Name,
+			RawData: map[string]interface{}{
+				"description": fmt.Sprintf("Login shell of %s", account.Name),
+				"shell":       account.Shell,
+				"listed":      listed[account.Shell],
+				"no_login":    noLoginShells[account.Shell],
+			},
+		}
+		if item.Path == "" {
+			item.Path = "dscl . -read /Users/" + account.Name
+		} else {
+			item.ModifiedAt = getFileModTime(item.Path)
+		}
+
+		if isScript(account.Shell) {
+			item.RawData["script"] = true
+			if data, err := readFile(account.Shell); err == nil && len(data) >= 2 {
+				interpreter, _, _ := strings.Cut(string(data[2:]), "\n")
+				item.RawData["interpreter"] = strings.TrimSpace(interpreter)
+				item.RawData["content"] = string(data)
+			}
+		} else if _, err := os.Lstat(sysroot.Path(account.Shell)); os.IsNotExist(err) {
+			item.RawData["missing"] = true
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// isScript reports whether the file at path starts with #!.
+func isScript(path string) bool {
+	f, err := os.Open(sysroot.Path(path))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	header := make([]byte, 2)
+	n, _ := f.Read(header)
+	return n == 2 && string(header) == "#!"
+}
@@ -0,0 +1,67 @@
+package collectors
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+// Extended attributes that record where a file came from.
+const (
+	xattrQuarantine = "com.apple.quarantine"
+	xattrProvenance = "com.apple.provenance"
+	xattrWhereFroms = "com.apple.metadata:kMDItemWhereFroms"
+)
+
+// extendedAttributes reads the provenance-related extended attributes of
+// the file at path on the scanned system, or returns nil if it has none.
+func extendedAttributes(path string) *scanner.ExtendedAttributes {
+	var attrs scanner.ExtendedAttributes
+	found := false
+
+	if value, err := getxattr(sysroot.Path(path), xattrQuarantine); err == nil {
+		attrs.Quarantine = parseQuarantine(string(value))
+		found = true
+	}
+	if value, err := getxattr(sysroot.Path(path), xattrProvenance); err == nil {
+		attrs.Provenance = hex.EncodeToString(value)
+		found = true
+	}
+	if value, err := getxattr(sysroot.Path(path), xattrWhereFroms); err == nil {
+		if _, err := plist.Unmarshal(value, &attrs.WhereFroms); err == nil {
+			found = true
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	return &attrs
+}
+
+// parseQuarantine splits a com.apple.quarantine value,
+// "flags;hex-timestamp;agent;event-uuid", keeping the raw value as well.
+func parseQuarantine(value string) *scanner.Quarantine {
+	value = strings.TrimRight(value, "\x00")
+	q := &scanner.Quarantine{Raw: value}
+
+	fields := strings.Split(value, ";")
+	q.Flags = fields[0]
+	if len(fields) > 1 {
+		if seconds, err := strconv.ParseInt(fields[1], 16, 64); err == nil {
+			q.Time = time.Unix(seconds, 0)
+		}
+	}
+	if len(fields) > 2 {
+		q.Agent = fields[2]
+	}
+	if len(fields) > 3 {
+		q.EventID = fields[3]
+	}
+	return q
+}
@@ -0,0 +1,20 @@
+//go:build darwin
+
+package collectors
+
+import "golang.org/x/sys/unix"
+
+// getxattr returns the value of the named extended attribute of the file
+// at path, a path on this host.
+func getxattr(path, name string) ([]byte, error) {
+	size, err := unix.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, size)
+	size, err = unix.Getxattr(path, name, value)
+	if err != nil {
+		return nil, err
+	}
+	return value[:size], nil
+}
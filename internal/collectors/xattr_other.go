@@ -0,0 +1,10 @@
+//go:build !darwin
+
+package collectors
+
+import "errors"
+
+// getxattr always fails; the attributes read are specific to macOS.
+func getxattr(path, name string) ([]byte, error) {
+	return nil, errors.New("extended attributes are only read on darwin")
+}
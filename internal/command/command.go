@@ -0,0 +1,58 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// Timeout bounds every external command run through this package. Tools
+// like system_profiler and osascript can hang for minutes. Zero disables
+// the limit.
+var Timeout = 30 * time.Second
+
+// ErrTimeout is wrapped by the error of a command killed for exceeding
+// Timeout.
+var ErrTimeout = errors.New("command timed out")
+
+// Output runs the command and returns its standard output.
+func Output(name string, args ...string) ([]byte, error) {
+	return run(Timeout, name, args, (*exec.Cmd).Output)
+}
+
+// CombinedOutput runs the command and returns its standard output and
+// standard error.
+func CombinedOutput(name string, args ...string) ([]byte, error) {
+	return run(Timeout, name, args, (*exec.Cmd).CombinedOutput)
+}
+
+// CombinedOutputTimeout is CombinedOutput with its own limit in place of
+// Timeout, for checks run once per file that must not hold up a scan.
+func CombinedOutputTimeout(timeout time.Duration, name string, args ...string) ([]byte, error) {
+	return run(timeout, name, args, (*exec.Cmd).CombinedOutput)
+}
+
+// Run runs the command and waits for it to finish.
+func Run(name string, args ...string) error {
+	_, err := run(Timeout, name, args, func(cmd *exec.Cmd) ([]byte, error) {
+		return nil, cmd.Run()
+	})
+	return err
+}
+
+func run(timeout time.Duration, name string, args []string, fn func(*exec.Cmd) ([]byte, error)) ([]byte, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	output, err := fn(exec.CommandContext(ctx, name, args...))
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("%s: %w after %s", name, ErrTimeout, timeout)
+	}
+	return output, err
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config mirrors the sections of example-config.toml. Command-line flags
+// take precedence over values loaded from a file.
+type Config struct {
+	Scan   ScanConfig   `toml:"scan"`
+	Output OutputConfig `toml:"output"`
+	Risk   RiskConfig   `toml:"risk"`
+	Rules  RulesConfig  `toml:"rules"`
+	Jamf   JamfConfig   `toml:"jamf"`
+}
+
+type ScanConfig struct {
+	Parallel bool `toml:"parallel"`
+	// Concurrency bounds parallel work; zero means one worker per CPU.
+	Concurrency int `toml:"concurrency"`
+	// Timeout, CommandTimeout, and SignatureTimeout are in seconds; zero
+	// disables the limit.
+	Timeout          int `toml:"timeout"`
+	CommandTimeout   int `toml:"command_timeout"`
+	SignatureTimeout int `toml:"signature_timeout"`
+	// TotalTimeout bounds the whole scan, and CollectTimeout and
+	// AssessTimeout its two phases, in seconds; zero disables a limit.
+	TotalTimeout   int `toml:"total_timeout"`
+	CollectTimeout int `toml:"collect_timeout"`
+	AssessTimeout  int `toml:"assess_timeout"`
+	// ScannerTimeouts overrides Timeout per mechanism, e.g.
+	// ConfigurationProfile = 120.
+	ScannerTimeouts map[string]int `toml:"scanner_timeouts"`
+	// SigningCache persists codesign and spctl results between runs.
+	SigningCache string `toml:"signing_cache"`
+	// NoExec collects only from files, without external commands.
+	NoExec bool `toml:"no_exec"`
+	// Offline keeps scans off the network for air-gapped use.
+	Offline bool `toml:"offline"`
+	// ScanCache keeps item assessments between runs so unchanged items
+	// are not re-verified and re-scored.
+	ScanCache string `toml:"scan_cache"`
+	// Suppressions lists known-good items to hide from results.
+	Suppressions string `toml:"suppressions"`
+	// History is the SQLite database every scan is recorded in.
+	History string `toml:"history"`
+}
+
+type OutputConfig struct {
+	Format     string `toml:"format"`
+	PrettyJSON bool   `toml:"pretty_json"`
+	NoContent  bool   `toml:"no_content"`
+	// SignKey is an Ed25519 private key every output file is signed with.
+	SignKey string `toml:"sign_key"`
+	// EncryptTo lists age recipients, or files of them, every output file
+	// is encrypted to.
+	EncryptTo []string `toml:"encrypt_to"`
+}
+
+type RiskConfig struct {
+	Model string `toml:"model"`
+	// DisabledHeuristics names heuristics that are not run, and
+	// HeuristicConfidence overrides the confidence of others by name.
+	DisabledHeuristics  []string           `toml:"disabled_heuristics"`
+	HeuristicConfidence map[string]float64 `toml:"heuristic_confidence"`
+	// Thresholds are the lowest scores of each risk level.
+	Thresholds ThresholdsConfig `toml:"thresholds"`
+}
+
+type ThresholdsConfig struct {
+	Low      float64 `toml:"low"`
+	Medium   float64 `toml:"medium"`
+	High     float64 `toml:"high"`
+	Critical float64 `toml:"critical"`
+}
+
+// RulesConfig says where update-rules fetches rule bundles from and which
+// key they must be signed with.
+type RulesConfig struct {
+	URL string `toml:"url"`
+	// PublicKey is the base64 Ed25519 key bundles are verified with.
+	PublicKey string `toml:"public_key"`
+	// Path is where the installed bundle is kept.
+	Path string `toml:"path"`
+	// PinVersion, if set, installs exactly this bundle version.
+	PinVersion int `toml:"pin_version"`
+}
+
+// JamfConfig names the Jamf Pro server scans cross-check profiles and login
+// items against. Credentials are read from the environment.
+type JamfConfig struct {
+	URL string `toml:"url"`
+}
+
+// Default returns the configuration used when no file is given.
+func Default() *Config {
+	return &Config{
+		Scan: ScanConfig{
+			Parallel:         true,
+			Timeout:          60,
+			CommandTimeout:   30,
+			SignatureTimeout: 10,
+		},
+		Output: OutputConfig{
+			Format:     "table",
+			PrettyJSON: true,
+		},
+		Risk: RiskConfig{
+			Model:      "weighted-average",
+			Thresholds: ThresholdsConfig{Low: 0.2, Medium: 0.4, High: 0.6, Critical: 0.8},
+		},
+	}
+}
+
+// Load reads a TOML configuration file on top of the defaults.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	if _, err := toml.DecodeFile(path, cfg); err != nil {
+		return nil, fmt.Errorf("loading config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
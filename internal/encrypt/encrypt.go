@@ -0,0 +1,75 @@
+// Package encrypt writes result files encrypted with age to X25519
+// recipients, since results can include script contents and usernames
+// that should not sit in plaintext on disk or on a file share. Files can
+// be decrypted with the age tool and the recipient's identity.
+package encrypt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Extension is appended to the name of every encrypted file.
+const Extension = ".age"
+
+// ParseRecipients decodes age X25519 recipients, each given as the
+// recipient itself (age1...) or as a file listing them one per line, as
+// written by age-keygen -y.
+func ParseRecipients(values []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, value := range values {
+		if strings.HasPrefix(value, "age1") {
+			recipient, err := age.ParseX25519Recipient(value)
+			if err != nil {
+				return nil, fmt.Errorf("parsing recipient %s: %w", value, err)
+			}
+			recipients = append(recipients, recipient)
+			continue
+		}
+
+		file, err := os.Open(value)
+		if err != nil {
+			return nil, fmt.Errorf("reading recipients: %w", err)
+		}
+		parsed, err := age.ParseRecipients(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing recipients in %s: %w", value, err)
+		}
+		recipients = append(recipients, parsed...)
+	}
+	return recipients, nil
+}
+
+// Create creates the file at path, readable only by its owner, and returns
+// a writer that encrypts what is written to it to recipients. Closing the
+// writer finishes the encryption and closes the file.
+func Create(path string, recipients []age.Recipient) (io.WriteCloser, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	w, err := age.Encrypt(file, recipients...)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("encrypting %s: %w", path, err)
+	}
+	return &encryptedFile{WriteCloser: w, file: file}, nil
+}
+
+type encryptedFile struct {
+	io.WriteCloser
+	file *os.File
+}
+
+func (f *encryptedFile) Close() error {
+	if err := f.WriteCloser.Close(); err != nil {
+		f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
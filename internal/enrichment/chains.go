@@ -0,0 +1,236 @@
+package enrichment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// maxChainCommand is how much of a dropping command is kept on a link.
+const maxChainCommand = 200
+
+var (
+	// launchPlistPath matches a launchd plist in a home or system Launch
+	// directory.
+	launchPlistPath = regexp.MustCompile(`(?:~|\$HOME|\$\{HOME\}|/Users/[^/\s"']+|/var/root)?/Library/Launch(?:Agents|Daemons)/[^\s"';|&>]+\.plist`)
+	crontabPath     = regexp.MustCompile(`/etc/crontab\b|/usr/lib/cron/tabs/[\w.-]+|/var/at/tabs/[\w.-]+`)
+	// fileWrite matches the commands scripts write a file with.
+	fileWrite      = regexp.MustCompile(`>|\b(?:tee|cp|mv|ditto|install|ln|curl|wget|plutil|PlistBuddy|writeToFile)\b|\bdefaults\s+write\b`)
+	launchctlLoad  = regexp.MustCompile(`\blaunchctl\s+(load|bootstrap)\s+([^|;&)` + "`" + `]*)`)
+	crontabCommand = regexp.MustCompile(`(?:^|[\s|;&(` + "`" + `"'])(?:/usr/bin/)?crontab(?:$|\s([^|;&)` + "`" + `]*))`)
+	loginwindowKey = regexp.MustCompile(`\bdefaults\s+write\s+(?:-currentHost\s+)?["']?\S*com\.apple\.loginwindow(?:\.plist)?["']?\s+["']?(\w+)["']?(?:\s+(\S+))?`)
+)
+
+// loginwindowMechanisms are the mechanisms loginwindow preference keys
+// create.
+var loginwindowMechanisms = map[string]scanner.MechanismType{
+	"LoginHook":                         scanner.MechanismLoginHook,
+	"LogoutHook":                        scanner.MechanismLogoutHook,
+	"AutoLaunchedApplicationDictionary": scanner.MechanismLoginItem,
+}
+
+// ChainStage finds commands in the scripts and arguments of each item that
+// create further persistence: writing or loading launchd plists, installing
+// crontabs, and setting loginwindow hooks or login items. The item running
+// the command is marked as a dropper, the collected items the command
+// created as dropped, and both share a chain ID under Chains.
+var ChainStage = scanner.NewStage("chains", LinkChains)
+
+// chainTarget is persistence a command creates, and how to recognize the
+// items it became.
+type chainTarget struct {
+	target  string
+	command string
+	match   func(item *scanner.PersistenceItem) bool
+}
+
+// LinkChains links droppers and the persistence they create.
+func LinkChains(items []scanner.PersistenceItem) {
+	for i := range items {
+		dropper := &items[i]
+		for _, target := range chainTargets(dropper) {
+			id := chainID(dropper.ID, target.target)
+			var created []string
+			for j := range items {
+				if j == i || target.match == nil || !target.match(&items[j]) {
+					continue
+				}
+				created = append(created, items[j].ID)
+				items[j].Chains = append(items[j].Chains, scanner.ChainLink{
+					ID:      id,
+					Role:    scanner.ChainDropped,
+					Target:  target.target,
+					Command: target.command,
+					Items:   []string{dropper.ID},
+				})
+			}
+			dropper.Chains = append(dropper.Chains, scanner.ChainLink{
+				ID:      id,
+				Role:    scanner.ChainDropper,
+				Target:  target.target,
+				Command: target.command,
+				Items:   created,
+			})
+		}
+	}
+}
+
+// chainTargets returns the persistence the commands of item create, once
+// per target.
+func chainTargets(item *scanner.PersistenceItem) []chainTarget {
+	var sources []string
+	if len(item.ProgramArgs) > 0 {
+		sources = append(sources, strings.Join(item.ProgramArgs, " "))
+	}
+	for _, key := range []string{"content", "scriptContent"} {
+		if text, ok := item.RawData[key].(string); ok && text != "" {
+			sources = append(sources, text)
+		}
+	}
+	// Second stages the payload decoder found
+	if payloads, ok := item.RawData["decodedPayloads"].([]map[string]interface{}); ok {
+		for _, payload := range payloads {
+			if preview, ok := payload["preview"].(string); ok && payload["binary"] == nil {
+				sources = append(sources, preview)
+			}
+		}
+	}
+
+	var targets []chainTarget
+	seen := make(map[string]bool)
+	add := func(target chainTarget) {
+		if !seen[target.target] {
+			seen[target.target] = true
+			targets = append(targets, target)
+		}
+	}
+
+	for _, source := range sources {
+		for _, line := range strings.Split(source, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			command := line
+			if runes := []rune(command); len(runes) > maxChainCommand {
+				command = string(runes[:maxChainCommand-3]) + "..."
+			}
+
+			if write := fileWrite.FindStringIndex(line); write != nil {
+				for _, path := range launchPlistPath.FindAllStringIndex(line, -1) {
+					if path[0] > write[0] {
+						add(pathTarget(line[path[0]:path[1]], command))
+					}
+				}
+				for _, path := range crontabPath.FindAllStringIndex(line, -1) {
+					if path[0] > write[0] {
+						add(pathTarget(line[path[0]:path[1]], command))
+					}
+				}
+			}
+			for _, m := range launchctlLoad.FindAllStringSubmatch(line, -1) {
+				args := strings.Fields(m[2])
+				if m[1] == "bootstrap" && len(args) > 0 {
+					// The domain, such as gui/501
+					args = args[1:]
+				}
+				for _, arg := range args {
+					if !strings.HasPrefix(arg, "-") {
+						add(pathTarget(strings.Trim(arg, `"'`), command))
+						break
+					}
+				}
+			}
+			for _, m := range crontabCommand.FindAllStringSubmatch(line, -1) {
+				if target, ok := crontabTarget(strings.Fields(m[1]), item.User, command); ok {
+					add(target)
+				}
+			}
+			for _, m := range loginwindowKey.FindAllStringSubmatch(line, -1) {
+				add(loginwindowTarget(m[1], strings.Trim(m[2], `"'`), command))
+			}
+		}
+	}
+	return targets
+}
+
+// pathTarget is a file written or loaded at path. Paths in a home directory
+// named through ~ or $HOME match the file in any home; paths built from
+// other variables match nothing.
+func pathTarget(path, command string) chainTarget {
+	target := chainTarget{target: path, command: command}
+	for _, prefix := range []string{"~", "${HOME}", "$HOME"} {
+		if rest := strings.TrimPrefix(path, prefix); rest != path {
+			if !strings.Contains(rest, "$") {
+				target.match = func(item *scanner.PersistenceItem) bool {
+					return strings.HasSuffix(item.Path, rest) && !strings.HasPrefix(item.Path, "/Library/")
+				}
+			}
+			return target
+		}
+	}
+	if strings.HasPrefix(path, "/") && !strings.Contains(path, "$") {
+		target.match = func(item *scanner.PersistenceItem) bool {
+			return item.Path == path
+		}
+	}
+	return target
+}
+
+// crontabTarget is the crontab installed by running crontab with args: the
+// crontab of the user named with -u, or of the user running it. Listing
+// and removing crontabs create nothing.
+func crontabTarget(args []string, user, command string) (chainTarget, bool) {
+	for i := 0; i < len(args); i++ {
+		switch arg := strings.Trim(args[i], `"'`); arg {
+		case "-l", "-r":
+			return chainTarget{}, false
+		case "-u":
+			if i+1 < len(args) {
+				user = strings.Trim(args[i+1], `"'`)
+				i++
+			}
+		}
+	}
+
+	target := chainTarget{target: "crontab", command: command}
+	if user != "" {
+		target.target = fmt.Sprintf("crontab for %s", user)
+	}
+	target.match = func(item *scanner.PersistenceItem) bool {
+		if item.Mechanism != scanner.MechanismCronJob || item.Path == "/etc/crontab" || strings.HasPrefix(item.Path, "/etc/cron.d") {
+			return false
+		}
+		return user == "" || item.User == user
+	}
+	return target, true
+}
+
+// loginwindowTarget is the loginwindow preference key a defaults write
+// sets. Keys that create a mechanism match its items, narrowed to those
+// running value when one has it as its program.
+func loginwindowTarget(key, value, command string) chainTarget {
+	target := chainTarget{target: "com.apple.loginwindow " + key, command: command}
+	mechanism, ok := loginwindowMechanisms[key]
+	if !ok {
+		return target
+	}
+	target.match = func(item *scanner.PersistenceItem) bool {
+		if item.Mechanism != mechanism {
+			return false
+		}
+		return value == "" || !strings.HasPrefix(value, "/") || item.Program == value
+	}
+	return target
+}
+
+// chainID derives a stable ID for the chain from the dropper and what it
+// creates.
+func chainID(dropper, target string) string {
+	sum := sha256.Sum256([]byte(dropper + "\x00" + target))
+	return "chain-" + hex.EncodeToString(sum[:])[:12]
+}
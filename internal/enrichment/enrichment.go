@@ -0,0 +1,91 @@
+package enrichment
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Enricher attaches structured facts to an item after collection and before
+// risk assessment, so heuristics and formatters can use them without
+// re-inspecting the filesystem.
+type Enricher interface {
+	Name() string
+	Enrich(item *scanner.PersistenceItem)
+}
+
+// Concurrency bounds how many external checks, such as codesign, the
+// enrichers run at once. Zero uses one worker per CPU.
+var Concurrency = 0
+
+// Offline skips the checks that reach the network, such as the Gatekeeper
+// assessment, which asks Apple's notarization service about programs.
+var Offline = false
+
+// Prefetcher is implemented by enrichers that check every item's files up
+// front, concurrently, so Enrich only reads the results.
+type Prefetcher interface {
+	Prefetch(items []scanner.PersistenceItem)
+}
+
+// EnrichAll runs every enricher over every item.
+func EnrichAll(enrichers []Enricher, items []scanner.PersistenceItem) {
+	for _, e := range enrichers {
+		if p, ok := e.(Prefetcher); ok {
+			p.Prefetch(items)
+		}
+	}
+	for i := range items {
+		for _, e := range enrichers {
+			e.Enrich(&items[i])
+		}
+	}
+}
+
+// forEach calls fn for every input with at most Concurrency calls in
+// flight.
+func forEach(inputs []string, fn func(string)) {
+	workers := Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(inputs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for input := range jobs {
+				fn(input)
+			}
+		}()
+	}
+	for _, input := range inputs {
+		jobs <- input
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Stage runs enrichers as a scanner.Stage.
+func Stage(enrichers ...Enricher) scanner.Stage {
+	return scanner.NewStage("enrichment", func(items []scanner.PersistenceItem) {
+		EnrichAll(enrichers, items)
+	})
+}
+
+// BundlePath returns the enclosing .app bundle for a program path, if any.
+func BundlePath(program string) string {
+	idx := strings.Index(program, ".app/")
+	if idx < 0 {
+		if strings.HasSuffix(program, ".app") {
+			return program
+		}
+		return ""
+	}
+	return filepath.Clean(program[:idx+len(".app")])
+}
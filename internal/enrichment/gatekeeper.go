@@ -0,0 +1,94 @@
+package enrichment
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/sigcache"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+type GatekeeperEnricher struct {
+	cache map[string]*scanner.GatekeeperAssessment
+}
+
+func NewGatekeeperEnricher() *GatekeeperEnricher {
+	return &GatekeeperEnricher{
+		cache: make(map[string]*scanner.GatekeeperAssessment),
+	}
+}
+
+func (e *GatekeeperEnricher) Name() string {
+	return "gatekeeper"
+}
+
+func (e *GatekeeperEnricher) Enrich(item *scanner.PersistenceItem) {
+	// spctl assesses against the running system's policy, which says
+	// nothing about an offline root, and may query Apple's servers
+	if item.Program == "" || !filepath.IsAbs(item.Program) || sysroot.Offline() || Offline {
+		return
+	}
+
+	// Gatekeeper assesses applications as a whole
+	target := item.Program
+	if bundle := BundlePath(item.Program); bundle != "" {
+		target = bundle
+	}
+
+	if cached, ok := e.cache[target]; ok {
+		item.Gatekeeper = cached
+		return
+	}
+
+	if _, err := os.Stat(target); err != nil {
+		return
+	}
+
+	assessment := e.assess(target)
+	e.cache[target] = assessment
+	item.Gatekeeper = assessment
+}
+
+func (e *GatekeeperEnricher) assess(target string) *scanner.GatekeeperAssessment {
+	assessment := &scanner.GatekeeperAssessment{Target: target}
+
+	// spctl exits non-zero on rejection, so the output is parsed regardless
+	output, err := sigcache.Shared.CombinedOutput(target, "spctl", "--assess", "--type", "execute", "--verbose=2", target)
+
+	verdictFound := false
+	lines := bufio.NewScanner(strings.NewReader(string(output)))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+
+		switch {
+		case strings.HasPrefix(line, target+": "):
+			verdict := strings.TrimPrefix(line, target+": ")
+			if strings.HasPrefix(verdict, "accepted") {
+				assessment.Accepted = true
+				verdictFound = true
+			} else if strings.HasPrefix(verdict, "rejected") {
+				verdictFound = true
+			}
+			if open := strings.Index(verdict, "("); open >= 0 {
+				assessment.Reason = strings.TrimSuffix(verdict[open+1:], ")")
+			}
+		case strings.HasPrefix(line, "source="):
+			assessment.Source = strings.TrimPrefix(line, "source=")
+		case strings.HasPrefix(line, "origin="):
+			assessment.Origin = strings.TrimPrefix(line, "origin=")
+		}
+	}
+
+	if !verdictFound {
+		if err != nil {
+			assessment.Error = err.Error()
+		} else {
+			assessment.Error = "unrecognized spctl output"
+		}
+	}
+
+	return assessment
+}
@@ -0,0 +1,139 @@
+package enrichment
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+var (
+	base64Blob = regexp.MustCompile(`[A-Za-z0-9+/]{40,}={0,2}`)
+	hexBlob    = regexp.MustCompile(`\b(?:[0-9a-fA-F]{2}){24,}\b`)
+)
+
+// PayloadDecoder finds large base64/hex blobs in program arguments and script
+// bodies, decodes them (recursively, up to MaxDepth), and records previews
+// under RawData["decodedPayloads"] so heuristics can inspect second stages.
+type PayloadDecoder struct {
+	MaxDepth    int
+	MaxPayloads int
+	MaxPreview  int
+}
+
+func NewPayloadDecoder() *PayloadDecoder {
+	return &PayloadDecoder{
+		MaxDepth:    3,
+		MaxPayloads: 10,
+		MaxPreview:  4096,
+	}
+}
+
+func (e *PayloadDecoder) Name() string {
+	return "payload_decoder"
+}
+
+func (e *PayloadDecoder) Enrich(item *scanner.PersistenceItem) {
+	var sources []string
+	if len(item.ProgramArgs) > 0 {
+		sources = append(sources, strings.Join(item.ProgramArgs, " "))
+	}
+	for _, key := range []string{"content", "scriptContent"} {
+		if text, ok := item.RawData[key].(string); ok && text != "" {
+			sources = append(sources, text)
+		}
+	}
+
+	var payloads []map[string]interface{}
+	for _, source := range sources {
+		payloads = e.extract(source, 1, payloads)
+	}
+
+	if len(payloads) > 0 {
+		if item.RawData == nil {
+			item.RawData = make(map[string]interface{})
+		}
+		item.RawData["decodedPayloads"] = payloads
+	}
+}
+
+func (e *PayloadDecoder) extract(text string, depth int, payloads []map[string]interface{}) []map[string]interface{} {
+	if depth > e.MaxDepth {
+		return payloads
+	}
+
+	candidates := []struct {
+		encoding string
+		matches  []string
+		decode   func(string) ([]byte, error)
+	}{
+		{"base64", base64Blob.FindAllString(text, -1), decodeBase64},
+		{"hex", hexBlob.FindAllString(text, -1), hex.DecodeString},
+	}
+
+	for _, candidate := range candidates {
+		for _, blob := range candidate.matches {
+			if len(payloads) >= e.MaxPayloads {
+				return payloads
+			}
+
+			decoded, err := candidate.decode(blob)
+			if err != nil || len(decoded) == 0 {
+				continue
+			}
+
+			payload := map[string]interface{}{
+				"encoding": candidate.encoding,
+				"depth":    depth,
+				"length":   len(decoded),
+			}
+
+			if !isMostlyPrintable(decoded) {
+				// Hex digests and random tokens decode to noise; only keep
+				// binary blobs large enough to be an embedded executable
+				if candidate.encoding == "hex" || len(decoded) < 64 {
+					continue
+				}
+				payload["binary"] = true
+				payload["preview"] = hex.EncodeToString(decoded[:32])
+				payloads = append(payloads, payload)
+				continue
+			}
+
+			decodedText := string(decoded)
+			preview := decodedText
+			if len(preview) > e.MaxPreview {
+				preview = preview[:e.MaxPreview]
+			}
+			payload["preview"] = preview
+			payloads = append(payloads, payload)
+
+			payloads = e.extract(decodedText, depth+1, payloads)
+		}
+	}
+
+	return payloads
+}
+
+func decodeBase64(blob string) ([]byte, error) {
+	if decoded, err := base64.StdEncoding.DecodeString(blob); err == nil {
+		return decoded, nil
+	}
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(blob, "="))
+}
+
+func isMostlyPrintable(data []byte) bool {
+	printable := 0
+	for _, r := range string(data) {
+		if r == unicode.ReplacementChar {
+			continue
+		}
+		if unicode.IsPrint(r) || unicode.IsSpace(r) {
+			printable++
+		}
+	}
+	return float64(printable) >= 0.9*float64(len([]rune(string(data))))
+}
@@ -0,0 +1,264 @@
+package enrichment
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	santaRulesDB = "/var/db/santa/rules.db"
+	// santaSyncState holds the client mode a sync server set, which takes
+	// precedence over the configuration profile.
+	santaSyncState = "/var/db/santa/sync-state.plist"
+	santaConfig    = "/Library/Managed Preferences/com.google.santa.plist"
+)
+
+// SantaFiles are the files Santa verdicts are read from: the rules
+// database, with its write-ahead log, and the settings that set the client
+// mode. A change to any of them can change a verdict.
+var SantaFiles = []string{santaRulesDB, santaRulesDB + "-wal", santaSyncState, santaConfig}
+
+// santaEpoch is the reference date Santa's rule timestamps count from.
+var santaEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// santaRuleTypes names Santa's rule types in the order Santa evaluates
+// them. Santa 2022.x renumbered the types; both numberings are listed.
+var santaRuleTypes = []struct {
+	name  string
+	codes []int
+}{
+	{"cdhash", []int{500}},
+	{"binary", []int{1, 1000}},
+	{"signingid", []int{2000}},
+	{"certificate", []int{2, 3000}},
+	{"teamid", []int{4000}},
+}
+
+type santaRule struct {
+	state int
+	added time.Time
+}
+
+// SantaEnricher records the verdict Google Santa, if installed, would
+// reach on each item's program. It runs after the signature enricher, whose
+// CDHash, signing identifier, and Team ID the rules are matched against.
+type SantaEnricher struct {
+	loaded bool
+	mode   string
+	// rules maps each rule type to its rules by identifier
+	rules map[string]map[string]santaRule
+	certs map[string]string
+}
+
+func NewSantaEnricher() *SantaEnricher {
+	return &SantaEnricher{certs: make(map[string]string)}
+}
+
+func (e *SantaEnricher) Name() string {
+	return "santa"
+}
+
+func (e *SantaEnricher) Enrich(item *scanner.PersistenceItem) {
+	if !e.loaded {
+		e.load()
+	}
+	if e.rules == nil || item.Program == "" {
+		return
+	}
+
+	verdict := &scanner.SantaVerdict{Mode: e.mode, Decision: "unknown"}
+	if e.mode != "monitor" {
+		verdict.Decision = "block"
+	}
+	for _, rt := range santaRuleTypes {
+		id := e.identifier(rt.name, item)
+		if id == "" {
+			continue
+		}
+		rule, ok := e.rules[rt.name][id]
+		if !ok {
+			continue
+		}
+		verdict.Rule = rt.name
+		verdict.Identifier = id
+		verdict.RuleAdded = rule.added
+		// Block and silent block; everything else allows, including
+		// compiler and transitive rules
+		if rule.state == 2 || rule.state == 3 {
+			verdict.Decision = "block"
+		} else {
+			verdict.Decision = "allow"
+		}
+		break
+	}
+	item.Santa = verdict
+}
+
+// identifier returns what a rule of the given type would name item's
+// program by, or "" if it cannot be determined.
+func (e *SantaEnricher) identifier(ruleType string, item *scanner.PersistenceItem) string {
+	sig := item.CodeSignature
+	signed := sig != nil && sig.Signed && !sig.AdHoc
+	switch ruleType {
+	case "cdhash":
+		if sig != nil {
+			return sig.CDHash
+		}
+	case "binary":
+		if item.ProgramFile != nil {
+			return item.ProgramFile.SHA256
+		}
+	case "signingid":
+		if !signed || sig.Identifier == "" {
+			return ""
+		}
+		if sig.TeamID != "" {
+			return sig.TeamID + ":" + sig.Identifier
+		}
+		if len(sig.Authorities) > 0 && sig.Authorities[0] == "Software Signing" {
+			return "platform:" + sig.Identifier
+		}
+	case "certificate":
+		if signed && len(e.rules["certificate"]) > 0 {
+			return e.leafCertificate(item.Program)
+		}
+	case "teamid":
+		if signed {
+			return sig.TeamID
+		}
+	}
+	return ""
+}
+
+// leafCertificate returns the SHA-256 of the certificate program is signed
+// with, which is what Santa's certificate rules name.
+func (e *SantaEnricher) leafCertificate(program string) string {
+	if hash, ok := e.certs[program]; ok {
+		return hash
+	}
+	e.certs[program] = ""
+
+	dir, err := os.MkdirTemp("", "macos-persist-scan-certs")
+	if err != nil {
+		return ""
+	}
+	defer os.RemoveAll(dir)
+	prefix := filepath.Join(dir, "cert")
+	if err := command.Run("codesign", "-d", "--extract-certificates="+prefix, sysroot.Path(program)); err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(prefix + "0")
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	e.certs[program] = hex.EncodeToString(sum[:])
+	return e.certs[program]
+}
+
+// load reads Santa's client mode and rules database. A system without the
+// database has no Santa and gets no verdicts.
+func (e *SantaEnricher) load() {
+	e.loaded = true
+
+	path := sysroot.Path(santaRulesDB)
+	if _, err := os.Stat(path); err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("reading Santa rules", err, "path", santaRulesDB)
+		}
+		return
+	}
+
+	// The database is opened read-only so santad's copy is never touched
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		logging.Warn("opening Santa rules", err, "path", santaRulesDB)
+		return
+	}
+	defer db.Close()
+
+	// Santa renamed the shasum column to identifier
+	column := "identifier"
+	if err := db.QueryRow("SELECT identifier FROM rules LIMIT 1").Scan(new(sql.NullString)); err != nil && err != sql.ErrNoRows {
+		column = "shasum"
+	}
+	rows, err := db.Query("SELECT " + column + ", state, type, COALESCE(timestamp, 0) FROM rules")
+	if err != nil {
+		logging.Warn("querying Santa rules", err, "path", santaRulesDB)
+		return
+	}
+	defer rows.Close()
+
+	e.rules = make(map[string]map[string]santaRule)
+	for rows.Next() {
+		var id string
+		var state, kind int
+		var timestamp int64
+		if err := rows.Scan(&id, &state, &kind, &timestamp); err != nil {
+			logging.Warn("reading Santa rule", err, "path", santaRulesDB)
+			continue
+		}
+		for _, rt := range santaRuleTypes {
+			for _, code := range rt.codes {
+				if code != kind {
+					continue
+				}
+				if e.rules[rt.name] == nil {
+					e.rules[rt.name] = make(map[string]santaRule)
+				}
+				rule := santaRule{state: state}
+				if timestamp > 0 {
+					rule.added = santaEpoch.Add(time.Duration(timestamp) * time.Second)
+				}
+				// Hashes are stored lowercase; Team IDs and signing IDs
+				// are case-sensitive
+				if rt.name == "binary" || rt.name == "certificate" || rt.name == "cdhash" {
+					id = strings.ToLower(id)
+				}
+				e.rules[rt.name][id] = rule
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logging.Warn("reading Santa rules", err, "path", santaRulesDB)
+	}
+
+	e.mode = santaMode()
+}
+
+// santaMode returns Santa's client mode, which decides whether binaries
+// no rule covers may run.
+func santaMode() string {
+	for _, path := range []string{santaSyncState, santaConfig} {
+		data, err := os.ReadFile(sysroot.Path(path))
+		if err != nil {
+			continue
+		}
+		var settings struct {
+			ClientMode int `plist:"ClientMode"`
+		}
+		if _, err := plist.Unmarshal(data, &settings); err != nil || settings.ClientMode == 0 {
+			continue
+		}
+		switch settings.ClientMode {
+		case 2:
+			return "lockdown"
+		case 3:
+			return "standalone"
+		}
+		return "monitor"
+	}
+	return "monitor"
+}
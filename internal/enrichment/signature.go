@@ -0,0 +1,147 @@
+package enrichment
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/sigcache"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// SignatureTimeout bounds each codesign check. A binary that takes longer
+// is reported as not checked rather than unsigned. Zero disables the limit.
+var SignatureTimeout = 10 * time.Second
+
+// SignatureEnricher records the code signature of each item's program. It
+// runs after the Gatekeeper enricher so notarization reported by spctl is
+// included. Every distinct program is checked once, up front, by a bounded
+// pool of workers.
+type SignatureEnricher struct {
+	mu    sync.Mutex
+	cache map[string]*scanner.CodeSignature
+}
+
+func NewSignatureEnricher() *SignatureEnricher {
+	return &SignatureEnricher{
+		cache: make(map[string]*scanner.CodeSignature),
+	}
+}
+
+func (e *SignatureEnricher) Name() string {
+	return "code_signature"
+}
+
+func (e *SignatureEnricher) Enrich(item *scanner.PersistenceItem) {
+	if item.Program == "" {
+		return
+	}
+
+	// Binaries a wrapper runs are signed like programs; scripts never are
+	for i := range item.ResolvedTargets {
+		target := &item.ResolvedTargets[i]
+		if target.File != nil && target.File.Binary != nil {
+			copied := *e.signature(target.Path)
+			target.CodeSignature = &copied
+		}
+	}
+
+	copied := *e.signature(item.Program)
+	if item.Gatekeeper != nil && strings.Contains(item.Gatekeeper.Source, "Notarized") {
+		copied.Notarized = true
+	}
+	item.CodeSignature = &copied
+}
+
+// Prefetch checks the programs of every item, and the binaries their
+// wrappers run, concurrently.
+func (e *SignatureEnricher) Prefetch(items []scanner.PersistenceItem) {
+	seen := make(map[string]bool)
+	var programs []string
+	add := func(program string) {
+		if !seen[program] {
+			seen[program] = true
+			programs = append(programs, program)
+		}
+	}
+	for i := range items {
+		if items[i].Program == "" {
+			continue
+		}
+		add(items[i].Program)
+		for _, target := range items[i].ResolvedTargets {
+			if target.File != nil && target.File.Binary != nil {
+				add(target.Path)
+			}
+		}
+	}
+
+	forEach(programs, func(program string) {
+		e.signature(program)
+	})
+}
+
+// signature returns the signature of program, inspecting it once.
+func (e *SignatureEnricher) signature(program string) *scanner.CodeSignature {
+	e.mu.Lock()
+	sig, ok := e.cache[program]
+	e.mu.Unlock()
+	if ok {
+		return sig
+	}
+
+	sig = e.inspect(sysroot.Path(program))
+	e.mu.Lock()
+	e.cache[program] = sig
+	e.mu.Unlock()
+	return sig
+}
+
+func (e *SignatureEnricher) inspect(program string) *scanner.CodeSignature {
+	sig := &scanner.CodeSignature{}
+
+	output, err := sigcache.Shared.CombinedOutputTimeout(SignatureTimeout, program, "codesign", "-dv", "--verbose=4", program)
+	if errors.Is(err, command.ErrTimeout) {
+		sig.TimedOut = true
+		sig.Error = err.Error()
+		return sig
+	}
+	text := string(output)
+	sig.Signed = err == nil
+	sig.AdHoc = strings.Contains(text, "adhoc")
+	sig.Revoked = strings.Contains(text, "REVOKED")
+	if !sig.Signed {
+		sig.Error = strings.TrimSpace(text)
+		if sig.Error == "" {
+			sig.Error = err.Error()
+		}
+	}
+
+	lines := bufio.NewScanner(strings.NewReader(text))
+	for lines.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(lines.Text()), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Identifier":
+			sig.Identifier = value
+		case "TeamIdentifier":
+			if value != "not set" {
+				sig.TeamID = value
+			}
+		case "Authority":
+			sig.Authorities = append(sig.Authorities, value)
+		case "CDHash":
+			sig.CDHash = value
+		case "Notarization Ticket":
+			sig.Notarized = value == "stapled"
+		}
+	}
+
+	return sig
+}
@@ -0,0 +1,87 @@
+package enrichment
+
+import (
+	"fmt"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// wakeWindow is how long after a scheduled wake a job must be scheduled to
+// be paired with it.
+const wakeWindow = 15
+
+// wakeEvents are the power event types that bring the Mac out of sleep or
+// power it on.
+var wakeEvents = map[string]bool{"wake": true, "poweron": true, "wakepoweron": true}
+
+// WakeStage pairs repeating wake and power-on schedules with launchd jobs
+// whose StartCalendarInterval falls within a few minutes after them. The
+// schedule records the jobs under RawData["paired_jobs"] and each job the
+// schedule under RawData["wake_schedule"].
+var WakeStage = scanner.NewStage("wake", PairWakeSchedules)
+
+// PairWakeSchedules links wake schedules and the jobs they make room for.
+func PairWakeSchedules(items []scanner.PersistenceItem) {
+	for i := range items {
+		schedule := &items[i]
+		if schedule.Mechanism != scanner.MechanismPowerSchedule {
+			continue
+		}
+		if kind, _ := schedule.RawData["kind"].(string); kind != "repeating" {
+			continue
+		}
+		if eventType, _ := schedule.RawData["event_type"].(string); !wakeEvents[eventType] {
+			continue
+		}
+		wake, ok := scanner.RawInt(schedule.RawData["minutes"])
+		if !ok {
+			continue
+		}
+
+		var paired []string
+		for j := range items {
+			job := &items[j]
+			if job.Mechanism != scanner.MechanismLaunchAgent && job.Mechanism != scanner.MechanismLaunchDaemon {
+				continue
+			}
+			for _, start := range calendarMinutes(job.RawData["StartCalendarInterval"]) {
+				if after := (start - wake + 24*60) % (24 * 60); after <= wakeWindow {
+					job.RawData["wake_schedule"] = fmt.Sprintf("%s, %d minutes before", schedule.Label, after)
+					paired = append(paired, job.Label)
+					break
+				}
+			}
+		}
+		if len(paired) > 0 {
+			schedule.RawData["paired_jobs"] = paired
+		}
+	}
+}
+
+// calendarMinutes returns the times of day, in minutes after midnight, of
+// the StartCalendarInterval entries that name an hour. The key holds one
+// dictionary or an array of them.
+func calendarMinutes(v interface{}) []int {
+	var entries []map[string]interface{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		entries = append(entries, v)
+	case []interface{}:
+		for _, entry := range v {
+			if m, ok := entry.(map[string]interface{}); ok {
+				entries = append(entries, m)
+			}
+		}
+	}
+
+	var minutes []int
+	for _, entry := range entries {
+		hour, ok := scanner.RawInt(entry["Hour"])
+		if !ok {
+			continue
+		}
+		minute, _ := scanner.RawInt(entry["Minute"])
+		minutes = append(minutes, hour*60+minute)
+	}
+	return minutes
+}
@@ -0,0 +1,318 @@
+// Package explain gathers and renders everything known about one finding:
+// the assessment recorded at scan time plus evidence read from disk now.
+package explain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/haasonsaas/macos-persist-scan/internal/sigcache"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/attack"
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+// Evidence is collected from the files an item refers to when it is
+// explained, so it reflects their current state rather than the scan's.
+type Evidence struct {
+	// Hashes maps each file to its SHA-256, or to the error reading it.
+	Hashes map[string]string
+	// Signature holds the identifying lines of `codesign -dv` for the
+	// program, including its certificate chain as Authority lines.
+	Signature []string
+	// SignatureError is set when the program is unsigned or unreadable.
+	SignatureError string
+	Raw            string
+}
+
+// signatureKeys are the codesign -dv fields worth showing.
+var signatureKeys = []string{"Identifier=", "Format=", "Authority=", "TeamIdentifier=", "Timestamp=", "Signed Time=", "CDHash=", "Runtime Version="}
+
+// Gather reads the evidence for item.
+func Gather(item *scanner.PersistenceItem) *Evidence {
+	ev := &Evidence{Hashes: make(map[string]string), Raw: RawData(item)}
+
+	for _, path := range []string{item.Path, item.Program} {
+		if !filepath.IsAbs(path) {
+			continue
+		}
+		if _, done := ev.Hashes[path]; done {
+			continue
+		}
+		ev.Hashes[path] = hashFile(sysroot.Path(path))
+	}
+
+	if filepath.IsAbs(item.Program) {
+		program := sysroot.Path(item.Program)
+		out, err := sigcache.Shared.CombinedOutput(program, "codesign", "-dv", "--verbose=4", program)
+		if err != nil {
+			ev.SignatureError = strings.TrimSpace(string(out))
+			if ev.SignatureError == "" {
+				ev.SignatureError = err.Error()
+			}
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			for _, key := range signatureKeys {
+				if strings.HasPrefix(line, key) {
+					ev.Signature = append(ev.Signature, line)
+				}
+			}
+		}
+	}
+
+	return ev
+}
+
+func hashFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Sprintf("unreadable: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RawData returns the item's plist as XML when it can be read, otherwise
+// the data the collector recorded.
+func RawData(item *scanner.PersistenceItem) string {
+	if strings.HasSuffix(item.Path, ".plist") {
+		if data, err := os.ReadFile(sysroot.Path(item.Path)); err == nil {
+			var decoded interface{}
+			if _, err := plist.Unmarshal(data, &decoded); err == nil {
+				if xml, err := plist.MarshalIndent(decoded, plist.XMLFormat, "  "); err == nil {
+					return string(xml)
+				}
+			}
+		}
+	}
+
+	if len(item.RawData) == 0 {
+		return ""
+	}
+	data, err := json.MarshalIndent(item.RawData, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Render writes a human-readable report on item to w.
+func Render(w io.Writer, item *scanner.PersistenceItem, ev *Evidence) {
+	heading := color.New(color.Bold)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "  %-12s %s\n", name+":", value)
+		}
+	}
+
+	heading.Fprintln(w, "Item")
+	field("ID", item.ID)
+	field("Label", item.Label)
+	field("Mechanism", string(item.Mechanism))
+	field("Path", item.Path)
+	field("Program", item.Program)
+	field("Arguments", strings.Join(item.ProgramArgs, " "))
+	field("User", item.User)
+	if !item.ModifiedAt.IsZero() {
+		field("Modified", item.ModifiedAt.Format("2006-01-02 15:04:05"))
+	}
+	var techniques []string
+	for _, id := range item.ATTACKTechniques {
+		techniques = append(techniques, fmt.Sprintf("%s %s", id, attack.Lookup(id).Name))
+	}
+	field("ATT&CK", strings.Join(techniques, ", "))
+	for _, link := range item.Chains {
+		if link.Role == scanner.ChainDropper {
+			field("Creates", fmt.Sprintf("%s (%s)", link.Target, link.ID))
+		} else {
+			field("Created by", fmt.Sprintf("%s (%s)", strings.Join(link.Items, ", "), link.ID))
+		}
+	}
+	if exposure := item.Exposure; exposure != nil {
+		field("Mach services", strings.Join(exposure.MachServices, ", "))
+		var sockets []string
+		for _, socket := range exposure.Sockets {
+			endpoint := socket.Path
+			if endpoint == "" {
+				endpoint = socket.Node + ":" + socket.Service
+			}
+			sockets = append(sockets, fmt.Sprintf("%s (%s %s)", socket.Name, socket.Type, endpoint))
+		}
+		field("Sockets", strings.Join(sockets, ", "))
+		if exposure.Inetd {
+			field("Inetd", "yes")
+		}
+	}
+
+	fmt.Fprintln(w)
+	heading.Fprintf(w, "Risk: %s (score %.2f, confidence %.2f)\n", item.Risk.Level, item.Risk.Score, item.Risk.Confidence)
+	for _, h := range item.Risk.Heuristics {
+		marker := " "
+		if h.Triggered {
+			marker = "!"
+		}
+		fmt.Fprintf(w, "  %s %-24s score %.2f  confidence %.2f\n", marker, h.Name, h.Score, h.Confidence)
+		if h.Details != "" {
+			fmt.Fprintf(w, "      %s\n", h.Details)
+		}
+	}
+
+	if len(item.Errors) > 0 {
+		fmt.Fprintln(w)
+		heading.Fprintln(w, "Collection errors")
+		for _, err := range item.Errors {
+			fmt.Fprintf(w, "  %s\n", err)
+		}
+	}
+
+	if len(ev.Hashes) > 0 {
+		fmt.Fprintln(w)
+		heading.Fprintln(w, "SHA-256")
+		seen := make(map[string]bool)
+		for _, path := range []string{item.Path, item.Program} {
+			if hash, ok := ev.Hashes[path]; ok && !seen[path] {
+				seen[path] = true
+				fmt.Fprintf(w, "  %s  %s\n", hash, path)
+			}
+		}
+	}
+
+	var provenance []string
+	for _, meta := range []*scanner.FileMetadata{item.ConfigFile, item.ProgramFile} {
+		if meta == nil || meta.XAttrs == nil {
+			continue
+		}
+		if q := meta.XAttrs.Quarantine; q != nil {
+			line := fmt.Sprintf("%s: quarantined by %s", meta.Path, q.Agent)
+			if !q.Time.IsZero() {
+				line += " on " + q.Time.Format("2006-01-02 15:04:05")
+			}
+			provenance = append(provenance, line)
+		}
+		for _, url := range meta.XAttrs.WhereFroms {
+			provenance = append(provenance, fmt.Sprintf("%s: downloaded from %s", meta.Path, url))
+		}
+		if meta.XAttrs.Provenance != "" {
+			provenance = append(provenance, fmt.Sprintf("%s: com.apple.provenance %s", meta.Path, meta.XAttrs.Provenance))
+		}
+	}
+	if len(provenance) > 0 {
+		fmt.Fprintln(w)
+		heading.Fprintln(w, "Provenance")
+		for _, line := range provenance {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	if len(item.ResolvedTargets) > 0 {
+		fmt.Fprintln(w)
+		heading.Fprintln(w, "Resolved targets")
+		for _, target := range item.ResolvedTargets {
+			fmt.Fprintf(w, "  %s (%s of %s)\n", target.Path, target.Via, target.Wrapper)
+			if target.File != nil {
+				fmt.Fprintf(w, "    SHA-256 %s\n", target.File.SHA256)
+			}
+			if sig := target.CodeSignature; sig != nil {
+				switch {
+				case sig.TimedOut:
+					fmt.Fprintf(w, "    Signature check timed out\n")
+				case !sig.Signed:
+					fmt.Fprintf(w, "    Unsigned\n")
+				case sig.TeamID != "":
+					fmt.Fprintf(w, "    Signed by %s (%s)\n", sig.Identifier, sig.TeamID)
+				default:
+					fmt.Fprintf(w, "    Signed by %s\n", sig.Identifier)
+				}
+			}
+		}
+	}
+
+	if item.ProgramFile != nil && item.ProgramFile.Binary != nil {
+		bin := item.ProgramFile.Binary
+		fmt.Fprintln(w)
+		heading.Fprintln(w, "Binary")
+		fmt.Fprintf(w, "  Architectures: %s\n", strings.Join(bin.Architectures, ", "))
+		if bin.MinOS != "" {
+			fmt.Fprintf(w, "  Minimum macOS: %s (SDK %s)\n", bin.MinOS, bin.SDK)
+		}
+		fmt.Fprintf(w, "  Linked libraries: %d\n", bin.LinkedLibraries)
+		if bin.UUID != "" {
+			fmt.Fprintf(w, "  UUID: %s\n", bin.UUID)
+		} else {
+			fmt.Fprintf(w, "  UUID: none (LC_UUID missing)\n")
+		}
+	}
+
+	if item.Program != "" && (len(ev.Signature) > 0 || ev.SignatureError != "") {
+		fmt.Fprintln(w)
+		heading.Fprintln(w, "Code signature")
+		if ev.SignatureError != "" {
+			fmt.Fprintf(w, "  %s\n", ev.SignatureError)
+		}
+		for _, line := range ev.Signature {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	if santa := item.Santa; santa != nil {
+		fmt.Fprintln(w)
+		heading.Fprintln(w, "Santa")
+		fmt.Fprintf(w, "  Decision: %s (%s mode)\n", santa.Decision, santa.Mode)
+		if santa.Rule != "" {
+			fmt.Fprintf(w, "  Rule: %s %s", santa.Rule, santa.Identifier)
+			if !santa.RuleAdded.IsZero() {
+				fmt.Fprintf(w, ", added %s", santa.RuleAdded.Format("2006-01-02 15:04:05"))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	if steps := nextSteps(item); len(steps) > 0 {
+		fmt.Fprintln(w)
+		heading.Fprintln(w, "Suggested next steps")
+		for _, step := range steps {
+			fmt.Fprintf(w, "  - %s\n", step)
+		}
+	}
+
+	if ev.Raw != "" {
+		fmt.Fprintln(w)
+		heading.Fprintln(w, "Raw data")
+		fmt.Fprintln(w, strings.TrimRight(ev.Raw, "\n"))
+	}
+}
+
+// nextSteps lists the investigation guidance for each triggered heuristic
+// and how to respond to the item.
+func nextSteps(item *scanner.PersistenceItem) []string {
+	var steps []string
+	for _, h := range item.Risk.Heuristics {
+		if !h.Triggered {
+			continue
+		}
+		if help := output.RuleHelp(h.Name); help != "" {
+			steps = append(steps, strings.ReplaceAll(help, "`", ""))
+		}
+	}
+
+	if item.Risk.Level.Rank() >= scanner.RiskMedium.Rank() {
+		action := "quarantine"
+		if item.Mechanism == scanner.MechanismLaunchAgent || item.Mechanism == scanner.MechanismLaunchDaemon {
+			action = "unload"
+		}
+		steps = append(steps, fmt.Sprintf("If it is malicious, respond with: macos-persist-scan remediate %s --action %s", item.ID, action))
+	} else {
+		steps = append(steps, "If it is known-good, hide it from future scans with the tui command's suppress key")
+	}
+
+	return steps
+}
@@ -0,0 +1,104 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// builtinAccounts are the accounts macOS creates without a leading
+// underscore.
+var builtinAccounts = map[string]bool{"root": true, "daemon": true, "nobody": true}
+
+// AccountHeuristic flags accounts set up for persistent access: a second
+// account with UID 0, hidden accounts that can log in, interactive accounts
+// with UIDs in the range macOS reserves for the system, and admins created
+// in the last month.
+type AccountHeuristic struct{}
+
+func NewAccountHeuristic() *AccountHeuristic {
+	return &AccountHeuristic{}
+}
+
+func (h *AccountHeuristic) Name() string {
+	return "account_anomaly"
+}
+
+func (h *AccountHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.85,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismLocalAccount || item.RawData == nil {
+		return result
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	name := item.User
+	uid, ok := scanner.RawInt(item.RawData["uid"])
+	if !ok {
+		uid = -1
+	}
+	admin, _ := item.RawData["admin"].(bool)
+	noLogin, _ := item.RawData["no_login"].(bool)
+
+	if uid == 0 && name != "root" {
+		flag(0.9, fmt.Sprintf("Account %s has UID 0, the same privileges as root", name))
+	}
+	if builtinAccounts[name] {
+		return result
+	}
+
+	if hiddenBy := stringList(item.RawData["hidden_by"]); len(hiddenBy) > 0 && !noLogin {
+		score := 0.6
+		if admin {
+			score = 0.7
+		}
+		flag(score, fmt.Sprintf("Account %s can log in but is hidden (%s)", name, strings.Join(hiddenBy, ", ")))
+	}
+	if uid >= 0 && uid < 500 && !noLogin {
+		flag(0.6, fmt.Sprintf("Account %s can log in with system-range UID %d", name, uid))
+	}
+
+	if admin && !item.CreatedAt.IsZero() {
+		switch age := time.Since(item.CreatedAt); {
+		case age < 7*24*time.Hour:
+			flag(0.7, fmt.Sprintf("Admin account %s was created %s", name, item.CreatedAt.Format("2006-01-02")))
+		case age < 30*24*time.Hour:
+			flag(0.5, fmt.Sprintf("Admin account %s was created %s", name, item.CreatedAt.Format("2006-01-02")))
+		}
+	}
+
+	return result
+}
+
+// stringList reads a string slice from RawData, in either of the shapes it
+// takes before and after a JSON round trip.
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		var strs []string
+		for _, s := range list {
+			if str, ok := s.(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		return strs
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// userWritablePrefixes are locations any user can place a binary in.
+var userWritablePrefixes = []string{"/Users/", "/tmp/", "/private/tmp/", "/var/tmp/", "/private/var/tmp/", "/var/folders/", "/private/var/folders/"}
+
+// ArchitectureHeuristic flags programs whose Mach-O architectures do not
+// fit the item: unsigned Intel-only binaries that depend on Rosetta on an
+// Apple silicon Mac, which is common in commodity malware, and arm64e
+// binaries, an ABI otherwise reserved for Apple's code, outside the system
+// locations.
+type ArchitectureHeuristic struct {
+	appleSilicon bool
+}
+
+// NewArchitectureHeuristic returns the heuristic for a scan of a Mac with
+// an Apple silicon CPU if appleSilicon is set. The Rosetta check is skipped
+// otherwise.
+func NewArchitectureHeuristic(appleSilicon bool) *ArchitectureHeuristic {
+	return &ArchitectureHeuristic{appleSilicon: appleSilicon}
+}
+
+func (h *ArchitectureHeuristic) Name() string {
+	return "architecture_mismatch"
+}
+
+func (h *ArchitectureHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.7,
+		Details:    "",
+	}
+
+	if item.ProgramFile == nil || item.ProgramFile.Binary == nil {
+		return result
+	}
+	archs := item.ProgramFile.Binary.Architectures
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	var intel, arm, arm64e, runnable bool
+	for _, arch := range archs {
+		switch arch {
+		case "x86_64", "x86_64h":
+			intel, runnable = true, true
+		case "arm64", "arm64e":
+			arm, runnable = true, true
+			arm64e = arm64e || arch == "arm64e"
+		}
+	}
+
+	signed := item.CodeSignature != nil && item.CodeSignature.Signed && !item.CodeSignature.AdHoc
+	unchecked := item.CodeSignature != nil && item.CodeSignature.TimedOut
+	if h.appleSilicon && intel && !arm && !signed {
+		if unchecked {
+			flag(0.4, "Intel-only binary whose signature could not be checked runs under Rosetta on Apple silicon")
+		} else {
+			flag(0.6, "Unsigned Intel-only binary runs under Rosetta on Apple silicon")
+		}
+	}
+
+	if arm64e {
+		program := item.Program
+		for _, prefix := range userWritablePrefixes {
+			if strings.HasPrefix(program, prefix) {
+				flag(0.6, fmt.Sprintf("arm64e binary, an ABI reserved for Apple's code, in user-writable location %s", prefix))
+			}
+		}
+		if !strings.HasPrefix(program, "/System/") && !strings.HasPrefix(program, "/usr/") && !strings.HasPrefix(program, "/bin/") && !strings.HasPrefix(program, "/sbin/") &&
+			(strings.HasPrefix(item.Label, "com.apple.") || item.CodeSignature != nil && strings.HasPrefix(item.CodeSignature.Identifier, "com.apple.")) {
+			flag(0.8, "arm64e binary outside the system volume claims an Apple identity")
+		}
+	}
+
+	if len(archs) > 0 && !runnable {
+		flag(0.3, fmt.Sprintf("Binary has no slice a current Mac can run (%s)", strings.Join(archs, ", ")))
+	}
+
+	return result
+}
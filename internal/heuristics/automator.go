@@ -0,0 +1,68 @@
+package heuristics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// AutomatorHeuristic flags Automator workflows whose embedded scripts
+// reach the network or evaluate code they build or decode at run time.
+// Each use of the Quick Action runs the payload again, with the user's
+// privileges and whatever files they selected.
+type AutomatorHeuristic struct{}
+
+func NewAutomatorHeuristic() *AutomatorHeuristic {
+	return &AutomatorHeuristic{}
+}
+
+func (h *AutomatorHeuristic) Name() string {
+	return "automator_payload"
+}
+
+var (
+	workflowNetwork = regexp.MustCompile(`(?i)\b(curl|wget|nc|ncat|scp|sftp)\b|/dev/tcp/|https?://|NSURLSession|NSURLRequest|\bssh\s`)
+	workflowEval    = regexp.MustCompile(`(?i)\beval\b|base64\s+(-d|-D|--decode)|\b(python3?|perl|ruby|osascript)\s+-(c|e)\b|\brun script\b|\bFunction\(|\bexec\(|\| *(ba|z)?sh\b`)
+)
+
+func (h *AutomatorHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.7,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismAutomator || item.RawData == nil {
+		return result
+	}
+	script, _ := item.RawData["scriptContent"].(string)
+	if script == "" {
+		return result
+	}
+
+	var found []string
+	network := workflowNetwork.FindString(script)
+	if network != "" {
+		found = append(found, "network access ("+strings.TrimSpace(network)+")")
+		result.Score = 0.6
+	}
+	eval := workflowEval.FindString(script)
+	if eval != "" {
+		found = append(found, "dynamic evaluation ("+strings.TrimSpace(eval)+")")
+		result.Score = 0.6
+	}
+	if network != "" && eval != "" {
+		result.Score = 0.8
+	}
+
+	if len(found) > 0 {
+		result.Triggered = true
+		result.Details = fmt.Sprintf("Workflow script uses %s", strings.Join(found, " and "))
+	}
+
+	return result
+}
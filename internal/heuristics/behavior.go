@@ -1,6 +1,8 @@
 package heuristics
 
 import (
+	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -9,6 +11,22 @@ import (
 
 type BehaviorHeuristic struct{}
 
+var suspiciousArgs = []struct {
+	pattern string
+	score   float64
+	reason  string
+}{
+	{"-e", 0.5, "Contains script execution flag"},
+	{"base64", 0.7, "Contains base64 encoding/decoding"},
+	{"curl", 0.6, "Downloads content from internet"},
+	{"wget", 0.6, "Downloads content from internet"},
+	{"/dev/null", 0.4, "Redirects output to null device"},
+	{"nohup", 0.5, "Runs process immune to hangups"},
+	{"eval", 0.7, "Evaluates dynamic code"},
+	{"http://", 0.8, "Contains HTTP URL"},
+	{"https://", 0.6, "Contains HTTPS URL"},
+}
+
 func NewBehaviorHeuristic() *BehaviorHeuristic {
 	return &BehaviorHeuristic{}
 }
@@ -56,22 +74,6 @@ func (h *BehaviorHeuristic) Analyze(item *scanner.PersistenceItem) scanner.Heuri
 
 	// Check for suspicious program arguments
 	if len(item.ProgramArgs) > 0 {
-		suspiciousArgs := []struct {
-			pattern string
-			score   float64
-			reason  string
-		}{
-			{"-e", 0.5, "Contains script execution flag"},
-			{"base64", 0.7, "Contains base64 encoding/decoding"},
-			{"curl", 0.6, "Downloads content from internet"},
-			{"wget", 0.6, "Downloads content from internet"},
-			{"/dev/null", 0.4, "Redirects output to null device"},
-			{"nohup", 0.5, "Runs process immune to hangups"},
-			{"eval", 0.7, "Evaluates dynamic code"},
-			{"http://", 0.8, "Contains HTTP URL"},
-			{"https://", 0.6, "Contains HTTPS URL"},
-		}
-
 		argsStr := strings.Join(item.ProgramArgs, " ")
 		for _, suspicious := range suspiciousArgs {
 			if strings.Contains(strings.ToLower(argsStr), suspicious.pattern) {
@@ -83,6 +85,20 @@ func (h *BehaviorHeuristic) Analyze(item *scanner.PersistenceItem) scanner.Heuri
 		}
 	}
 
+	// Check decoded base64/hex payloads with the same patterns; hiding them
+	// behind an encoding layer makes them more suspicious, not less
+	for _, preview := range decodedPayloadPreviews(item) {
+		lower := strings.ToLower(preview)
+		for _, suspicious := range suspiciousArgs {
+			if strings.Contains(lower, suspicious.pattern) {
+				result.Triggered = true
+				result.Score = math.Min(suspicious.score+0.1, 1.0)
+				result.Details = "Encoded payload: " + suspicious.reason
+				return result
+			}
+		}
+	}
+
 	// Check for shell script interpreters with inline commands
 	if item.Program != "" {
 		interpreters := []string{"/bin/sh", "/bin/bash", "/bin/zsh", "/usr/bin/python", "/usr/bin/ruby", "/usr/bin/perl"}
@@ -101,6 +117,40 @@ func (h *BehaviorHeuristic) Analyze(item *scanner.PersistenceItem) scanner.Heuri
 		}
 	}
 
+	// Check launchd keys that hide a job's activity or keep it running
+	if item.RawData != nil && !strings.HasPrefix(item.Label, "com.apple.") {
+		for _, key := range []string{"StandardOutPath", "StandardErrorPath"} {
+			if path, ok := item.RawData[key].(string); ok && hiddenOrTemporary(path) {
+				result.Triggered = true
+				result.Score = 0.4
+				result.Details = fmt.Sprintf("Writes job output to a hidden or temporary path: %s (%s)", path, key)
+				return result
+			}
+		}
+		if sessions := stringList(item.RawData["LimitLoadToSessionType"]); len(sessions) > 0 {
+			for _, session := range sessions {
+				if session == "LoginWindow" || session == "PreLogin" {
+					result.Triggered = true
+					result.Score = 0.5
+					result.Details = fmt.Sprintf("Loads in the %s session, before any user logs in", session)
+					return result
+				}
+			}
+		}
+		if abandon, _ := item.RawData["AbandonProcessGroup"].(bool); abandon {
+			result.Triggered = true
+			result.Score = 0.4
+			result.Details = "Child processes survive the job being stopped (AbandonProcessGroup)"
+			return result
+		}
+		if throttle, ok := scanner.RawInt(item.RawData["ThrottleInterval"]); ok && throttle < 5 && item.KeepAlive {
+			result.Triggered = true
+			result.Score = 0.4
+			result.Details = fmt.Sprintf("Relaunched within %d seconds whenever it exits (ThrottleInterval)", throttle)
+			return result
+		}
+	}
+
 	// Check for multiple persistence mechanisms from same binary
 	// (This would require cross-referencing with other items, simplified here)
 	if item.RawData != nil {
@@ -130,7 +180,7 @@ func (h *BehaviorHeuristic) hasUIIndicators(item *scanner.PersistenceItem) bool
 	checkStr := item.Program + " " + item.Label
 	if item.RawData != nil {
 		for k, v := range item.RawData {
-			checkStr += " " + k + " " + string(v.([]byte))
+			checkStr += " " + k + " " + fmt.Sprint(v)
 		}
 	}
 
@@ -141,4 +191,50 @@ func (h *BehaviorHeuristic) hasUIIndicators(item *scanner.PersistenceItem) bool
 	}
 
 	return false
-}
\ No newline at end of file
+}
+
+// decodedPayloadPreviews returns the text previews attached by the payload
+// decoder, tolerating the shape RawData takes after a JSON round trip.
+func decodedPayloadPreviews(item *scanner.PersistenceItem) []string {
+	var previews []string
+
+	collect := func(payload map[string]interface{}) {
+		if binary, _ := payload["binary"].(bool); binary {
+			return
+		}
+		if preview, ok := payload["preview"].(string); ok {
+			previews = append(previews, preview)
+		}
+	}
+
+	switch payloads := item.RawData["decodedPayloads"].(type) {
+	case []map[string]interface{}:
+		for _, payload := range payloads {
+			collect(payload)
+		}
+	case []interface{}:
+		for _, payload := range payloads {
+			if m, ok := payload.(map[string]interface{}); ok {
+				collect(m)
+			}
+		}
+	}
+
+	return previews
+}
+
+// hiddenOrTemporary reports whether path is in a temporary directory or
+// has a dot-prefixed component.
+func hiddenOrTemporary(path string) bool {
+	for _, prefix := range []string{"/tmp/", "/private/tmp/", "/var/tmp/", "/private/var/tmp/", "/Users/Shared/"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	for _, part := range strings.Split(path, "/") {
+		if len(part) > 1 && strings.HasPrefix(part, ".") && part != ".." {
+			return true
+		}
+	}
+	return false
+}
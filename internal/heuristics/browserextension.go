@@ -0,0 +1,99 @@
+package heuristics
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// storeUpdateURLs are the update URLs of extensions installed from the
+// Chrome Web Store and Edge Add-ons.
+var storeUpdateURLs = map[string]bool{
+	"https://clients2.google.com/service/update2/crx":         true,
+	"https://edge.microsoft.com/extensionwebstorebase/v1/crx": true,
+}
+
+// allHosts are the host patterns that match every site.
+var allHosts = map[string]bool{
+	"<all_urls>": true, "*://*/*": true, "http://*/*": true, "https://*/*": true,
+}
+
+// interceptPermissions let an extension with access to every site read or
+// redirect its traffic.
+var interceptPermissions = []string{"webRequestBlocking", "webRequest", "proxy", "cookies"}
+
+// BrowserExtensionHeuristic flags installed browser extensions that can
+// reach outside the browser or read every site: those that start native
+// messaging hosts, attach the debugger, or combine access to every site
+// with traffic interception, and Chromium extensions that update from
+// somewhere other than the browser's store.
+type BrowserExtensionHeuristic struct{}
+
+func NewBrowserExtensionHeuristic() *BrowserExtensionHeuristic {
+	return &BrowserExtensionHeuristic{}
+}
+
+func (h *BrowserExtensionHeuristic) Name() string {
+	return "browser_extension"
+}
+
+func (h *BrowserExtensionHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.6,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismBrowserExtension || item.RawData == nil {
+		return result
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	browser, _ := item.RawData["browser"].(string)
+	id, _ := item.RawData["extension_id"].(string)
+	permissions := stringList(item.RawData["permissions"])
+
+	// Manifest V2 lists host patterns among the permissions
+	everySite := false
+	for _, hosts := range [][]string{stringList(item.RawData["host_permissions"]), permissions} {
+		for _, host := range hosts {
+			if allHosts[host] {
+				everySite = true
+			}
+		}
+	}
+
+	if contains(permissions, "debugger") {
+		flag(0.6, fmt.Sprintf("%s extension %s can attach the debugger to any tab", browser, id))
+	}
+	if contains(permissions, "nativeMessaging") {
+		flag(0.5, fmt.Sprintf("%s extension %s can start native messaging hosts outside the browser sandbox", browser, id))
+	}
+	if everySite {
+		for _, permission := range interceptPermissions {
+			if contains(permissions, permission) {
+				flag(0.5, fmt.Sprintf("%s extension %s has access to every site and the %s permission", browser, id, permission))
+				break
+			}
+		}
+	}
+	if update, _ := item.RawData["update_url"].(string); update != "" && !storeUpdateURLs[update] {
+		host := update
+		if u, err := url.Parse(update); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		flag(0.6, fmt.Sprintf("%s extension %s updates from %s, outside the browser's store", browser, id, host))
+	}
+
+	return result
+}
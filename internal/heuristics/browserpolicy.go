@@ -0,0 +1,75 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// BrowserPolicyHeuristic flags browser policies adware uses: extensions
+// force-installed from outside the Chrome Web Store, proxies, and forced
+// startup, home, or search pages. Policies in plain preference files,
+// rather than managed preferences written from a configuration profile,
+// did not come from MDM and score higher.
+type BrowserPolicyHeuristic struct{}
+
+func NewBrowserPolicyHeuristic() *BrowserPolicyHeuristic {
+	return &BrowserPolicyHeuristic{}
+}
+
+func (h *BrowserPolicyHeuristic) Name() string {
+	return "browser_policy"
+}
+
+func (h *BrowserPolicyHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.7,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismBrowserPolicy || item.RawData == nil {
+		return result
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	browser, _ := item.RawData["browser"].(string)
+	group, _ := item.RawData["group"].(string)
+	managed, _ := item.RawData["managed"].(bool)
+	where := "managed preferences"
+	if !managed {
+		where = "unmanaged preferences, outside MDM"
+	}
+
+	switch group {
+	case "extensions":
+		forced := stringList(item.RawData["forced_extensions"])
+		offStore := stringList(item.RawData["off_store_extensions"])
+		if len(offStore) > 0 {
+			flag(0.7, fmt.Sprintf("%s force-installs extensions from outside the Chrome Web Store (%s) via %s", browser, strings.Join(offStore, ", "), where))
+		}
+		if len(forced) > 0 && !managed {
+			flag(0.6, fmt.Sprintf("%s force-installs extensions (%s) via %s", browser, strings.Join(forced, ", "), where))
+		}
+	case "proxy":
+		flag(0.6, fmt.Sprintf("%s proxy is forced via %s", browser, where))
+	case "startup":
+		score := 0.5
+		if managed {
+			score = 0.4
+		}
+		flag(score, fmt.Sprintf("%s startup, home, or search pages are forced via %s", browser, where))
+	}
+
+	return result
+}
@@ -0,0 +1,127 @@
+package heuristics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/enrichment"
+	"github.com/haasonsaas/macos-persist-scan/internal/sigcache"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// BundleIntegrityHeuristic deep-verifies the app bundle hosting a persistent
+// program and flags bundles whose contents changed after signing, which is
+// how trojanized copies of legitimate apps are usually built.
+type BundleIntegrityHeuristic struct {
+	cache map[string]*bundleVerification
+}
+
+// bundleVerification is what codesign reported about a bundle. A bundle
+// is only considered modified when codesign names a changed file or seal;
+// other failures, such as a timeout or a missing tool, leave it unknown.
+type bundleVerification struct {
+	modified bool
+	problems []string
+	failure  string
+}
+
+func NewBundleIntegrityHeuristic() *BundleIntegrityHeuristic {
+	return &BundleIntegrityHeuristic{
+		cache: make(map[string]*bundleVerification),
+	}
+}
+
+func (h *BundleIntegrityHeuristic) Name() string {
+	return "bundle_integrity"
+}
+
+func (h *BundleIntegrityHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.9,
+		Details:    "",
+	}
+
+	bundle := enrichment.BundlePath(item.Program)
+	if bundle == "" {
+		return result
+	}
+
+	verification, ok := h.cache[bundle]
+	if !ok {
+		if _, err := os.Stat(sysroot.Path(bundle)); err != nil {
+			return result
+		}
+		verification = h.verify(sysroot.Path(bundle))
+		h.cache[bundle] = verification
+	}
+
+	if !verification.modified {
+		if verification.failure != "" {
+			result.Details = fmt.Sprintf("Could not verify app bundle %s: %s", bundle, verification.failure)
+		}
+		return result
+	}
+
+	result.Triggered = true
+	result.Score = 0.8
+	result.Details = fmt.Sprintf("App bundle %s was modified after signing", bundle)
+	if len(verification.problems) > 0 {
+		shown := verification.problems
+		if len(shown) > 3 {
+			shown = shown[:3]
+		}
+		result.Details += ": " + strings.Join(shown, "; ")
+	}
+
+	return result
+}
+
+func (h *BundleIntegrityHeuristic) verify(bundle string) *bundleVerification {
+	output, err := sigcache.Shared.CombinedOutput(bundle, "codesign", "--verify", "--deep", "--strict", "--verbose=2", bundle)
+	verification := &bundleVerification{}
+	if err == nil {
+		return verification
+	}
+	if errors.Is(err, command.ErrTimeout) {
+		verification.failure = err.Error()
+		return verification
+	}
+
+	markers := []string{
+		"file added:",
+		"file modified:",
+		"file missing:",
+		"nested code is modified or invalid",
+		"a sealed resource is missing or invalid",
+		"invalid Info.plist",
+		"resource envelope is obsolete",
+	}
+
+	lines := bufio.NewScanner(strings.NewReader(string(output)))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		for _, marker := range markers {
+			if strings.Contains(line, marker) {
+				verification.modified = true
+				verification.problems = append(verification.problems, strings.TrimPrefix(line, bundle+": "))
+				break
+			}
+		}
+	}
+
+	// Unsigned bundles are the signature heuristic's concern, and other
+	// failures say nothing about the bundle's contents
+	if !verification.modified && !strings.Contains(string(output), "code object is not signed at all") {
+		verification.failure = strings.TrimSpace(err.Error())
+	}
+
+	return verification
+}
@@ -0,0 +1,154 @@
+package heuristics
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/sigcache"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// CertificateAgeHeuristic flags programs signed with recently issued
+// Developer ID certificates, or with certificates no other persistent
+// program on the host shares.
+type CertificateAgeHeuristic struct {
+	maxAge time.Duration
+	now    func() time.Time
+
+	certs      map[string]*signingCert
+	prevalence map[string]int
+}
+
+type signingCert struct {
+	teamID      string
+	fingerprint string
+	notBefore   time.Time
+}
+
+func NewCertificateAgeHeuristic(maxAge time.Duration) *CertificateAgeHeuristic {
+	return &CertificateAgeHeuristic{
+		maxAge:     maxAge,
+		now:        time.Now,
+		certs:      make(map[string]*signingCert),
+		prevalence: make(map[string]int),
+	}
+}
+
+func (h *CertificateAgeHeuristic) Name() string {
+	return "certificate_age"
+}
+
+// Prepare extracts the signing certificate of every distinct program and
+// counts how many programs on the host share each certificate.
+func (h *CertificateAgeHeuristic) Prepare(items []scanner.PersistenceItem) {
+	for i := range items {
+		program := items[i].Program
+		if program == "" {
+			continue
+		}
+		if _, done := h.certs[program]; done {
+			continue
+		}
+
+		cert := h.extractLeaf(program)
+		h.certs[program] = cert
+		if cert != nil {
+			h.prevalence[cert.fingerprint]++
+		}
+	}
+}
+
+func (h *CertificateAgeHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.7,
+		Details:    "",
+	}
+
+	if item.Program == "" {
+		return result
+	}
+
+	cert, ok := h.certs[item.Program]
+	if !ok {
+		// Item was not part of the prepared set
+		cert = h.extractLeaf(item.Program)
+		h.certs[item.Program] = cert
+	}
+	if cert == nil {
+		return result
+	}
+
+	age := h.now().Sub(cert.notBefore)
+	unique := h.prevalence[cert.fingerprint] <= 1
+
+	if age < h.maxAge {
+		days := int(age.Hours() / 24)
+		result.Triggered = true
+		result.Score = 0.6
+		result.Details = fmt.Sprintf("Signed with Developer ID certificate issued %d days ago (Team ID %s)", days, cert.teamID)
+		if unique {
+			result.Score = 0.7
+			result.Details += " and not used by any other persistent program"
+		}
+		return result
+	}
+
+	if unique {
+		result.Triggered = true
+		result.Score = 0.3
+		result.Confidence = 0.5
+		result.Details = fmt.Sprintf("Developer ID certificate (Team ID %s) not used by any other persistent program", cert.teamID)
+	}
+
+	return result
+}
+
+// extractLeaf returns the leaf Developer ID certificate for program, or nil
+// if the program is unsigned or signed by another kind of identity.
+func (h *CertificateAgeHeuristic) extractLeaf(program string) *signingCert {
+	program = sysroot.Path(program)
+	der, err := sigcache.Shared.Do("codesign --extract-certificates", program, func() ([]byte, error) {
+		dir, err := os.MkdirTemp("", "persist-scan-certs")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		prefix := filepath.Join(dir, "cert")
+		if err := command.Run("codesign", "-d", "--extract-certificates="+prefix, program); err != nil {
+			return nil, err
+		}
+		return os.ReadFile(prefix + "0")
+	})
+	if err != nil {
+		return nil
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil
+	}
+
+	if !strings.HasPrefix(leaf.Subject.CommonName, "Developer ID") {
+		return nil
+	}
+
+	cert := &signingCert{
+		fingerprint: fmt.Sprintf("%x", sha256.Sum256(leaf.Raw)),
+		notBefore:   leaf.NotBefore,
+	}
+	if len(leaf.Subject.OrganizationalUnit) > 0 {
+		cert.teamID = leaf.Subject.OrganizationalUnit[0]
+	}
+
+	return cert
+}
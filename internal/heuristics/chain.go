@@ -0,0 +1,61 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// ChainHeuristic flags the items in a persistence chain: items whose
+// scripts create further persistence, more strongly when what they
+// created is on the system, and the items another item's script created.
+type ChainHeuristic struct{}
+
+func NewChainHeuristic() *ChainHeuristic {
+	return &ChainHeuristic{}
+}
+
+func (h *ChainHeuristic) Name() string {
+	return "persistence_chain"
+}
+
+func (h *ChainHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.7,
+		Details:    "",
+	}
+
+	var created, createdBy []string
+	present := false
+	for _, link := range item.Chains {
+		switch link.Role {
+		case scanner.ChainDropper:
+			created = append(created, link.Target)
+			if len(link.Items) > 0 {
+				present = true
+			}
+		case scanner.ChainDropped:
+			createdBy = append(createdBy, link.Command)
+		}
+	}
+
+	switch {
+	case len(created) > 0:
+		result.Triggered = true
+		result.Score = 0.6
+		if present {
+			result.Score = 0.7
+		}
+		result.Details = fmt.Sprintf("Creates further persistence: %s", strings.Join(created, ", "))
+	case len(createdBy) > 0:
+		result.Triggered = true
+		result.Score = 0.5
+		result.Details = fmt.Sprintf("Created by another item's script: %s", strings.Join(createdBy, "; "))
+	}
+
+	return result
+}
@@ -0,0 +1,79 @@
+package heuristics
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// DockHeuristic flags Dock tiles for applications and files in places
+// software is not installed to: temporary directories, the Trash, a
+// Downloads folder, or a mounted disk image. A tile whose target has been
+// deleted is flagged too; clicking it runs whatever is later put in its
+// place.
+type DockHeuristic struct{}
+
+func NewDockHeuristic() *DockHeuristic {
+	return &DockHeuristic{}
+}
+
+func (h *DockHeuristic) Name() string {
+	return "dock_anomaly"
+}
+
+var dockLocations = []struct {
+	pattern *regexp.Regexp
+	score   float64
+	reason  string
+}{
+	{regexp.MustCompile(`^(/private)?/(var/)?tmp/|^(/private)?/var/folders/`), 0.7, "in a temporary directory"},
+	{regexp.MustCompile(`^/Users/[^/]+/\.Trash/`), 0.7, "in the Trash"},
+	{regexp.MustCompile(`^/Users/[^/]+/Downloads/`), 0.6, "in a Downloads folder"},
+	{regexp.MustCompile(`^/Volumes/`), 0.5, "on a mounted volume or disk image"},
+	{regexp.MustCompile(`^/Users/Shared/`), 0.5, "in /Users/Shared"},
+}
+
+func (h *DockHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.7,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismDock || item.RawData == nil {
+		return result
+	}
+	// Folder stacks such as ~/Downloads are in the Dock by default
+	if tileType, _ := item.RawData["tile_type"].(string); tileType == "directory-tile" {
+		return result
+	}
+	target, _ := item.RawData["target"].(string)
+	if target == "" {
+		return result
+	}
+
+	missing, _ := item.RawData["missing"].(bool)
+	for _, location := range dockLocations {
+		if location.pattern.MatchString(target) {
+			result.Triggered = true
+			result.Score = location.score
+			result.Details = fmt.Sprintf("Dock tile points %s: %s", location.reason, target)
+			if missing {
+				result.Score += 0.1
+				result.Details = fmt.Sprintf("Dock tile points at a deleted file %s, which anyone who can write there can replace: %s", location.reason, target)
+			}
+			return result
+		}
+	}
+
+	if missing {
+		result.Triggered = true
+		result.Score = 0.5
+		result.Details = fmt.Sprintf("Dock tile points at a deleted file, which will run whatever is put in its place: %s", target)
+	}
+
+	return result
+}
@@ -0,0 +1,80 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// interpreterVariables make an interpreter load attacker-chosen code when
+// any script using it starts.
+var interpreterVariables = []string{
+	"NODE_OPTIONS", "ELECTRON_RUN_AS_NODE", "PYTHONSTARTUP", "PYTHONPATH",
+	"PERL5OPT", "PERL5LIB", "RUBYOPT", "BASH_ENV", "ENV", "ZDOTDIR",
+}
+
+// EnvironmentHeuristic flags session environment variables that inject
+// code into every process started at login, whether set in
+// environment.plist, in launchd.conf, or by a launchd job that runs
+// "launchctl setenv".
+type EnvironmentHeuristic struct{}
+
+func NewEnvironmentHeuristic() *EnvironmentHeuristic {
+	return &EnvironmentHeuristic{}
+}
+
+func (h *EnvironmentHeuristic) Name() string {
+	return "environment_injection"
+}
+
+func (h *EnvironmentHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.9,
+		Details:    "",
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	check := func(name, value, where string) {
+		switch {
+		case strings.HasPrefix(name, "DYLD_"):
+			flag(0.8, fmt.Sprintf("%s sets %s, injecting libraries into every process in the session", where, name))
+		case contains(interpreterVariables, name):
+			flag(0.6, fmt.Sprintf("%s sets %s, which loads code into every script interpreter started", where, name))
+		case name == "PATH" && (strings.Contains(value, "/tmp/") || strings.Contains(value, "/Users/Shared/")):
+			flag(0.7, fmt.Sprintf("%s sets PATH to search a temporary or shared directory", where))
+		case name == "PATH":
+			flag(0.4, fmt.Sprintf("%s overrides PATH for every process in the session", where))
+		}
+	}
+
+	if item.Mechanism == scanner.MechanismEnvironment {
+		name, _ := item.RawData["variable"].(string)
+		value, _ := item.RawData["value"].(string)
+		source, _ := item.RawData["source"].(string)
+		check(name, value, source)
+		return result
+	}
+
+	// A launchd job can reapply the variables at every login
+	if item.Mechanism == scanner.MechanismLaunchAgent || item.Mechanism == scanner.MechanismLaunchDaemon {
+		fields := strings.Fields(strings.Join(item.ProgramArgs, " "))
+		for i := 0; i+3 < len(fields); i++ {
+			if strings.HasSuffix(fields[i], "launchctl") && fields[i+1] == "setenv" {
+				check(fields[i+2], fields[i+3], "launchctl setenv")
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,73 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// ExtensionStateHeuristic compares the runtime state of kernel and system
+// extensions with their bundles on disk. Loaded code with no approved
+// bundle suggests tampering; bundles staged but awaiting approval are
+// persistence waiting for a user to click through.
+type ExtensionStateHeuristic struct{}
+
+func NewExtensionStateHeuristic() *ExtensionStateHeuristic {
+	return &ExtensionStateHeuristic{}
+}
+
+func (h *ExtensionStateHeuristic) Name() string {
+	return "extension_state"
+}
+
+func (h *ExtensionStateHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.8,
+		Details:    "",
+	}
+
+	if item.RawData == nil {
+		return result
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	switch item.Mechanism {
+	case scanner.MechanismKernelExtension:
+		onDisk, _ := item.RawData["on_disk"].(bool)
+		staged, _ := item.RawData["staged"].(bool)
+		loaded, known := item.RawData["loaded"].(bool)
+		switch {
+		case !onDisk:
+			flag(0.8, "Kernel extension is loaded but has no bundle on disk")
+		case known && !loaded && staged:
+			flag(0.4, "Kernel extension is staged but not loaded, pending approval or a restart")
+		case known && !loaded:
+			flag(0.3, "Kernel extension is installed but not loaded, possibly awaiting approval")
+		}
+	case scanner.MechanismSystemExtension:
+		recorded, _ := item.RawData["recorded"].(bool)
+		state, _ := item.RawData["state"].(string)
+		running, known := item.RawData["running"].(bool)
+		switch {
+		case !recorded:
+			flag(0.8, "System extension is running but sysextd has no record of it")
+		case strings.Contains(state, "waiting_for_user") || strings.Contains(state, "waiting for user"):
+			flag(0.5, fmt.Sprintf("System extension is staged and awaiting user approval (%s)", state))
+		case known && !running && strings.HasPrefix(state, "activated"):
+			flag(0.3, fmt.Sprintf("System extension is recorded as %s but is not running", state))
+		}
+	}
+
+	return result
+}
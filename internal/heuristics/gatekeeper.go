@@ -0,0 +1,51 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+type GatekeeperHeuristic struct{}
+
+func NewGatekeeperHeuristic() *GatekeeperHeuristic {
+	return &GatekeeperHeuristic{}
+}
+
+func (h *GatekeeperHeuristic) Name() string {
+	return "gatekeeper_assessment"
+}
+
+func (h *GatekeeperHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.85,
+		Details:    "",
+	}
+
+	gk := item.Gatekeeper
+	if gk == nil || gk.Error != "" || gk.Accepted {
+		return result
+	}
+
+	// Command-line tools are never "apps" to Gatekeeper; that rejection is expected
+	if strings.Contains(gk.Reason, "does not seem to be an app") {
+		return result
+	}
+
+	result.Triggered = true
+	result.Score = 0.6
+	result.Details = "Rejected by Gatekeeper"
+	if gk.Source != "" {
+		result.Details = fmt.Sprintf("Rejected by Gatekeeper (%s)", gk.Source)
+	}
+
+	if gk.Source == "no usable signature" {
+		result.Score = 0.7
+	}
+
+	return result
+}
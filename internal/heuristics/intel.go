@@ -0,0 +1,44 @@
+package heuristics
+
+import (
+	"fmt"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/rules"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// IntelHeuristic flags items matching an indicator of compromise from the
+// installed rule bundle.
+type IntelHeuristic struct {
+	bundle *rules.Bundle
+}
+
+func NewIntelHeuristic(bundle *rules.Bundle) *IntelHeuristic {
+	return &IntelHeuristic{bundle: bundle}
+}
+
+func (h *IntelHeuristic) Name() string {
+	return "threat_intel"
+}
+
+func (h *IntelHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 1.0,
+		Details:    "",
+	}
+
+	if h.bundle == nil {
+		return result
+	}
+
+	if match := h.bundle.IOCs.Match(item); match != "" {
+		result.Triggered = true
+		result.Score = 1.0
+		result.Details = fmt.Sprintf("Matches indicator of compromise (%s) in rule bundle version %d", match, h.bundle.Version)
+	}
+
+	return result
+}
@@ -0,0 +1,89 @@
+package heuristics
+
+import (
+	"fmt"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/jamf"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// JamfHeuristic flags configuration profiles that Jamf Pro did not deploy
+// to this Mac, and login items that no managed login item rule in its
+// profiles allows. Adware installs profiles that look like enterprise
+// management; on a Mac Jamf Pro manages, anything it does not know about
+// is suspect.
+type JamfHeuristic struct {
+	expected *jamf.Expected
+}
+
+func NewJamfHeuristic(expected *jamf.Expected) *JamfHeuristic {
+	return &JamfHeuristic{expected: expected}
+}
+
+func (h *JamfHeuristic) Name() string {
+	return "jamf_unmanaged"
+}
+
+func (h *JamfHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.8,
+		Details:    "",
+	}
+
+	if h.expected == nil || item.RawData == nil {
+		return result
+	}
+
+	switch item.Mechanism {
+	case scanner.MechanismConfigProfile:
+		identifier := profileIdentifier(item)
+		if identifier == "" {
+			return result
+		}
+		if name, ok := h.expected.Profile(identifier); ok {
+			result.Details = fmt.Sprintf("Deployed by Jamf Pro as %q", name)
+			return result
+		}
+
+		result.Triggered = true
+		result.Score = 0.6
+		if persistence, _ := item.RawData["HasPersistenceMechanisms"].(bool); persistence {
+			result.Score = 0.7
+		}
+		result.Details = fmt.Sprintf("Profile %s is not among the %d profiles Jamf Pro scopes to this Mac (%d policies)",
+			identifier, len(h.expected.Profiles), len(h.expected.Policies))
+
+	case scanner.MechanismLoginItem:
+		// Without managed login item rules, Jamf Pro says nothing about
+		// which login items belong
+		if len(h.expected.LoginItems) == 0 {
+			return result
+		}
+		if rule := h.expected.LoginItem(item); rule != nil {
+			result.Details = fmt.Sprintf("Allowed by the %s %s rule in Jamf Pro profile %q", rule.RuleType, rule.RuleValue, rule.Profile)
+			return result
+		}
+
+		result.Triggered = true
+		result.Score = 0.4
+		result.Details = fmt.Sprintf("Login item matches none of the %d managed login item rules Jamf Pro deploys to this Mac", len(h.expected.LoginItems))
+	}
+
+	return result
+}
+
+// profileIdentifier returns the identifier of an installed profile, or of a
+// profile file found on disk.
+func profileIdentifier(item *scanner.PersistenceItem) string {
+	if id, _ := item.RawData["ProfileIdentifier"].(string); id != "" {
+		return id
+	}
+	if kind, _ := item.RawData["PayloadType"].(string); kind == "Configuration" {
+		id, _ := item.RawData["PayloadIdentifier"].(string)
+		return id
+	}
+	return ""
+}
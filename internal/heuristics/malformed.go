@@ -0,0 +1,43 @@
+package heuristics
+
+import (
+	"fmt"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// MalformedHeuristic flags items whose definition file could not be parsed.
+// launchd ignores a corrupted job, but a deliberately mangled plist can
+// also be a way to hide a job from scanners while a loader repairs it.
+type MalformedHeuristic struct{}
+
+func NewMalformedHeuristic() *MalformedHeuristic {
+	return &MalformedHeuristic{}
+}
+
+func (h *MalformedHeuristic) Name() string {
+	return "malformed_plist"
+}
+
+func (h *MalformedHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.8,
+		Details:    "",
+	}
+
+	if unparseable, _ := item.RawData["unparseable"].(bool); !unparseable {
+		return result
+	}
+
+	result.Triggered = true
+	result.Score = 0.6
+	result.Details = "Definition file is not a valid plist"
+	if len(item.Errors) > 0 {
+		result.Details = fmt.Sprintf("Definition file is not a valid plist: %s", item.Errors[0])
+	}
+
+	return result
+}
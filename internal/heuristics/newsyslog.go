@@ -0,0 +1,74 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// systemCommandDirs hold the programs macOS ships.
+var systemCommandDirs = []string{"/bin/", "/sbin/", "/usr/bin/", "/usr/sbin/", "/usr/libexec/", "/System/"}
+
+// NewsyslogHeuristic flags newsyslog entries that run a command, more
+// strongly when it is outside the system directories or in a user-writable
+// or temporary one, and entries that signal the process named by a pid
+// file anyone could rewrite.
+type NewsyslogHeuristic struct{}
+
+func NewNewsyslogHeuristic() *NewsyslogHeuristic {
+	return &NewsyslogHeuristic{}
+}
+
+func (h *NewsyslogHeuristic) Name() string {
+	return "newsyslog_action"
+}
+
+func (h *NewsyslogHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.75,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismNewsyslog || item.RawData == nil {
+		return result
+	}
+
+	if command, _ := item.RawData["command"].(string); command != "" {
+		result.Triggered = true
+		switch {
+		case strings.HasPrefix(command, "/Users/") || hiddenOrTemporary(command):
+			result.Score = 0.8
+			result.Details = fmt.Sprintf("newsyslog runs %s as root from a user-writable or temporary location", command)
+		case !hasAnyPrefix(command, systemCommandDirs):
+			result.Score = 0.6
+			result.Details = fmt.Sprintf("newsyslog runs %s as root, outside the system directories", command)
+		default:
+			result.Score = 0.4
+			result.Details = fmt.Sprintf("newsyslog runs %s as root on every rotation", command)
+		}
+		return result
+	}
+
+	if pidFile, _ := item.RawData["pid_file"].(string); pidFile != "" {
+		if strings.HasPrefix(pidFile, "/Users/") || hiddenOrTemporary(pidFile) {
+			result.Triggered = true
+			result.Score = 0.5
+			result.Details = fmt.Sprintf("newsyslog signals the process named in %s, which non-root users can rewrite", pidFile)
+		}
+	}
+
+	return result
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
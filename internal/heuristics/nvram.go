@@ -0,0 +1,97 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// weakeningBootArgs are boot arguments that disable code signing
+// enforcement, AMFI, or kernel protections.
+var weakeningBootArgs = []struct {
+	arg    string
+	reason string
+}{
+	{"amfi_get_out_of_my_way", "disables Apple Mobile File Integrity"},
+	{"amfi=", "changes Apple Mobile File Integrity enforcement"},
+	{"amfi_allow_any_signature", "lets AMFI accept any code signature"},
+	{"amfi_unrestrict_task_for_pid", "allows task_for_pid on any process"},
+	{"cs_enforcement_disable", "disables code signing enforcement"},
+	{"cs_allow_invalid", "allows invalidly signed code"},
+	{"cs_debug", "enables code signing debugging"},
+	{"ipc_control_port_options=0", "disables task port protections"},
+	{"-arm64e_preview_abi", "loads third-party arm64e code"},
+	{"rootless=0", "disables System Integrity Protection"},
+	{"kext-dev-mode", "loads unsigned kernel extensions"},
+	{"PE_i_can_has_debugger", "enables kernel debugging"},
+	{"debug=", "enables kernel debugging"},
+}
+
+// NVRAMHeuristic flags boot settings that weaken code signing or System
+// Integrity Protection, and custom NVRAM variables large enough to stash a
+// payload.
+type NVRAMHeuristic struct{}
+
+func NewNVRAMHeuristic() *NVRAMHeuristic {
+	return &NVRAMHeuristic{}
+}
+
+func (h *NVRAMHeuristic) Name() string {
+	return "nvram_security"
+}
+
+func (h *NVRAMHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.9,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismNVRAM || item.RawData == nil {
+		return result
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	variable, _ := item.RawData["variable"].(string)
+	value, _ := item.RawData["value"].(string)
+
+	switch variable {
+	case "boot-args":
+		for _, arg := range strings.Fields(value) {
+			for _, weak := range weakeningBootArgs {
+				if strings.HasPrefix(arg, weak.arg) && !strings.HasSuffix(arg, "=0") || arg == weak.arg {
+					flag(0.9, fmt.Sprintf("Boot argument %s %s", arg, weak.reason))
+				}
+			}
+		}
+	case "csr-active-config":
+		if strings.TrimLeft(strings.TrimPrefix(value, "0x"), "0") != "" {
+			flag(0.7, fmt.Sprintf("System Integrity Protection is partially or fully disabled (csr-active-config %s)", value))
+		}
+	case "allow-root-hash-mismatch", "root-live-fs":
+		flag(0.8, fmt.Sprintf("%s lets the system boot a modified, unsealed system volume", variable))
+	case "DisableLibraryValidation":
+		flag(0.7, "Library validation is disabled, so signed programs load unsigned libraries")
+	}
+
+	if custom, _ := item.RawData["custom"].(bool); custom {
+		size, _ := scanner.RawInt(item.RawData["size"])
+		if size >= 256 {
+			flag(0.6, fmt.Sprintf("Custom NVRAM variable %s holds %d bytes", variable, size))
+		} else {
+			flag(0.3, fmt.Sprintf("Custom NVRAM variable %s", variable))
+		}
+	}
+
+	return result
+}
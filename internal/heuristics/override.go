@@ -0,0 +1,37 @@
+package heuristics
+
+import (
+	"github.com/haasonsaas/macos-persist-scan/pkg/risk"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// ConfidenceOverride reports the results of a heuristic with the
+// confidence an administrator configured in place of its own, for fleets
+// where a check is known to be more or less reliable than its default.
+type ConfidenceOverride struct {
+	heuristic  risk.Heuristic
+	confidence float64
+}
+
+// WithConfidence returns h with the confidence of its results replaced.
+func WithConfidence(h risk.Heuristic, confidence float64) *ConfidenceOverride {
+	return &ConfidenceOverride{heuristic: h, confidence: confidence}
+}
+
+func (h *ConfidenceOverride) Name() string {
+	return h.heuristic.Name()
+}
+
+// Prepare passes the scan's items on to the wrapped heuristic if it needs
+// them.
+func (h *ConfidenceOverride) Prepare(items []scanner.PersistenceItem) {
+	if p, ok := h.heuristic.(risk.Preparer); ok {
+		p.Prepare(items)
+	}
+}
+
+func (h *ConfidenceOverride) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := h.heuristic.Analyze(item)
+	result.Confidence = h.confidence
+	return result
+}
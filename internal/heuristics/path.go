@@ -1,6 +1,7 @@
 package heuristics
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -17,7 +18,54 @@ func (h *PathHeuristic) Name() string {
 	return "suspicious_path"
 }
 
+// suspiciousPatterns are path fragments of locations malware runs from.
+var suspiciousPatterns = []struct {
+	pattern string
+	score   float64
+	reason  string
+}{
+	{"/tmp/", 0.8, "Binary located in temporary directory"},
+	{"/var/tmp/", 0.8, "Binary located in temporary directory"},
+	{"/Users/Shared/", 0.6, "Binary in shared user directory (common malware location)"},
+	{"/.hidden", 0.7, "Binary in hidden directory"},
+	{"/Library/Application Support/", 0.3, "Binary in Application Support (sometimes suspicious)"},
+	{"~/Downloads/", 0.5, "Binary in Downloads folder"},
+	{"/usr/local/bin/", 0.2, "Binary in user local bin (common for legitimate tools)"},
+}
+
 func (h *PathHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := h.analyzeProgram(item)
+
+	// The files a wrapper runs are judged by the same locations
+	for _, target := range item.ResolvedTargets {
+		score, reason := 0.0, ""
+		for _, pattern := range suspiciousPatterns {
+			if strings.Contains(target.Path, pattern.pattern) {
+				score, reason = pattern.score, pattern.reason
+				break
+			}
+		}
+		if score == 0 && systemLevel(item) && strings.HasPrefix(target.Path, "/Users/") && !strings.HasPrefix(target.Path, "/Users/Shared/") {
+			score, reason = 0.7, "System-level persistence runs a file in a user directory"
+		}
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = fmt.Sprintf("Resolved target %s: %s", target.Path, reason)
+		}
+	}
+
+	return result
+}
+
+// systemLevel reports whether item runs for every user, or as root.
+func systemLevel(item *scanner.PersistenceItem) bool {
+	return item.Mechanism == scanner.MechanismLaunchDaemon ||
+		item.Mechanism == scanner.MechanismSynthetic ||
+		strings.HasPrefix(item.Path, "/Library/")
+}
+
+func (h *PathHeuristic) analyzeProgram(item *scanner.PersistenceItem) scanner.HeuristicResult {
 	result := scanner.HeuristicResult{
 		Name:       h.Name(),
 		Triggered:  false,
@@ -26,25 +74,19 @@ func (h *PathHeuristic) Analyze(item *scanner.PersistenceItem) scanner.Heuristic
 		Details:    "",
 	}
 
-	// Check for suspicious path patterns
-	suspiciousPatterns := []struct {
-		pattern string
-		score   float64
-		reason  string
-	}{
-		{"/tmp/", 0.8, "Binary located in temporary directory"},
-		{"/var/tmp/", 0.8, "Binary located in temporary directory"},
-		{"/Users/Shared/", 0.6, "Binary in shared user directory (common malware location)"},
-		{"/.hidden", 0.7, "Binary in hidden directory"},
-		{"/Library/Application Support/", 0.3, "Binary in Application Support (sometimes suspicious)"},
-		{"~/Downloads/", 0.5, "Binary in Downloads folder"},
-		{"/usr/local/bin/", 0.2, "Binary in user local bin (common for legitimate tools)"},
+	// Browsers choose where extensions are installed
+	if item.Mechanism == scanner.MechanismBrowserExtension {
+		return result
 	}
 
 	programPath := item.Program
 	if programPath == "" && item.Path != "" {
 		programPath = item.Path
 	}
+	// A synthetic.conf link is judged by where it points
+	if target, ok := item.RawData["target"].(string); ok && item.Mechanism == scanner.MechanismSynthetic {
+		programPath = target
+	}
 
 	for _, pattern := range suspiciousPatterns {
 		if strings.Contains(programPath, pattern.pattern) {
@@ -56,8 +98,7 @@ func (h *PathHeuristic) Analyze(item *scanner.PersistenceItem) scanner.Heuristic
 	}
 
 	// Check for system-level persistence pointing to user directories
-	if (item.Mechanism == scanner.MechanismLaunchDaemon || 
-	    strings.HasPrefix(item.Path, "/Library/")) &&
+	if systemLevel(item) &&
 	    strings.Contains(programPath, "/Users/") &&
 	    !strings.Contains(programPath, "/Users/Shared/") {
 		result.Triggered = true
@@ -66,6 +107,14 @@ func (h *PathHeuristic) Analyze(item *scanner.PersistenceItem) scanner.Heuristic
 		return result
 	}
 
+	// Browser extensions reach native messaging hosts outside the sandbox
+	if item.Mechanism == scanner.MechanismNativeMessaging && strings.HasPrefix(programPath, "/Users/") {
+		result.Triggered = true
+		result.Score = 0.4
+		result.Details = "Native messaging host runs from a user directory"
+		return result
+	}
+
 	// Check for unusually deep nesting
 	depth := strings.Count(programPath, "/")
 	if depth > 8 {
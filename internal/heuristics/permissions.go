@@ -0,0 +1,75 @@
+package heuristics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// PermissionsHeuristic flags config files and programs that users other
+// than their owner can modify, and root-run daemons whose files are owned by
+// an ordinary user. Either lets an unprivileged process rewrite what runs at
+// boot or login.
+type PermissionsHeuristic struct{}
+
+func NewPermissionsHeuristic() *PermissionsHeuristic {
+	return &PermissionsHeuristic{}
+}
+
+func (h *PermissionsHeuristic) Name() string {
+	return "file_permissions"
+}
+
+func (h *PermissionsHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.9,
+		Details:    "",
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	runsAsRoot := item.Mechanism == scanner.MechanismLaunchDaemon && (item.User == "" || item.User == "root")
+	for _, file := range []struct {
+		meta *scanner.FileMetadata
+		kind string
+	}{
+		{item.ProgramFile, "Program"},
+		{item.ConfigFile, "Config file"},
+	} {
+		if file.meta == nil || file.meta.Mode == "" {
+			continue
+		}
+		mode, err := strconv.ParseUint(file.meta.Mode, 8, 32)
+		if err != nil {
+			continue
+		}
+
+		if mode&0o002 != 0 {
+			flag(0.8, fmt.Sprintf("%s %s is world-writable (mode %s)", file.kind, file.meta.Path, file.meta.Mode))
+		}
+		for _, entry := range file.meta.ACL {
+			if strings.HasPrefix(entry, "group:everyone allow") && strings.Contains(entry, "write") {
+				flag(0.8, fmt.Sprintf("%s %s is writable by everyone through its ACL (%s)", file.kind, file.meta.Path, entry))
+			}
+		}
+		if runsAsRoot && file.meta.UID != 0 {
+			flag(0.6, fmt.Sprintf("%s %s of a root daemon is owned by uid %d, who can replace it", file.kind, file.meta.Path, file.meta.UID))
+		}
+		if runsAsRoot && mode&0o020 != 0 && file.meta.GID != 0 && file.meta.GID != 80 {
+			flag(0.5, fmt.Sprintf("%s %s of a root daemon is writable by group %d", file.kind, file.meta.Path, file.meta.GID))
+		}
+	}
+
+	return result
+}
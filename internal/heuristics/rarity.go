@@ -0,0 +1,70 @@
+package heuristics
+
+import (
+	"fmt"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/fleet"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+type RarityHeuristic struct {
+	db *fleet.Database
+
+	// MinHosts is the smallest fleet size for which prevalence is meaningful.
+	MinHosts int
+	// RareRatio is the fraction of hosts at or below which an item is rare.
+	RareRatio float64
+}
+
+func NewRarityHeuristic(db *fleet.Database) *RarityHeuristic {
+	return &RarityHeuristic{
+		db:        db,
+		MinHosts:  10,
+		RareRatio: 0.02,
+	}
+}
+
+func (h *RarityHeuristic) Name() string {
+	return "fleet_rarity"
+}
+
+func (h *RarityHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.6,
+		Details:    "",
+	}
+
+	if h.db == nil {
+		return result
+	}
+
+	total := h.db.TotalHosts()
+	if total < h.MinHosts {
+		return result
+	}
+
+	// Larger fleets make rarity a stronger signal
+	if total >= 100 {
+		result.Confidence = 0.75
+	}
+
+	count := h.db.Prevalence(item)
+	if count == 0 {
+		result.Triggered = true
+		result.Score = 0.6
+		result.Details = fmt.Sprintf("Not seen on any of %d hosts in the fleet baseline", total)
+		return result
+	}
+
+	if float64(count)/float64(total) <= h.RareRatio {
+		result.Triggered = true
+		result.Score = 0.5
+		result.Details = fmt.Sprintf("Rare in fleet: seen on %d of %d hosts", count, total)
+		return result
+	}
+
+	return result
+}
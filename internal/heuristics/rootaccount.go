@@ -0,0 +1,68 @@
+package heuristics
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// RootAccountHeuristic flags persistence in the root account's home
+// directory. macOS ships with root disabled and no keys, rc script, or
+// environment in /var/root/.ssh, so any of them is unusual; shell startup
+// files are flagged only when they fetch or decode something to run.
+type RootAccountHeuristic struct{}
+
+func NewRootAccountHeuristic() *RootAccountHeuristic {
+	return &RootAccountHeuristic{}
+}
+
+func (h *RootAccountHeuristic) Name() string {
+	return "root_account"
+}
+
+// shellPayload matches shell startup commands that download, decode, or
+// hand a connection to a shell.
+var shellPayload = regexp.MustCompile(`\b(curl|wget|base64|nc|ncat|osascript)\b|/dev/tcp/|\b(python3?|perl|ruby)\s+-(c|e)\b`)
+
+func (h *RootAccountHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.8,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismRootAccount || item.RawData == nil {
+		return result
+	}
+
+	flag := func(score float64, details string) {
+		result.Triggered = true
+		result.Score = score
+		result.Details = details
+	}
+
+	kind, _ := item.RawData["kind"].(string)
+	switch kind {
+	case "authorized_key":
+		fingerprint, _ := item.RawData["fingerprint"].(string)
+		if command, _ := item.RawData["command"].(string); command != "" {
+			flag(0.7, fmt.Sprintf("SSH key %s logs in as root and runs %q", fingerprint, command))
+		} else {
+			flag(0.6, fmt.Sprintf("SSH key %s can log in as root", fingerprint))
+		}
+	case "ssh_rc":
+		flag(0.7, "sshd runs ~root/.ssh/rc on every SSH login as root")
+	case "ssh_environment":
+		flag(0.5, "~root/.ssh/environment sets variables for SSH logins as root")
+	case "shell_startup":
+		content, _ := item.RawData["content"].(string)
+		if match := shellPayload.FindString(content); match != "" {
+			flag(0.6, fmt.Sprintf("root's shell startup file runs %s", match))
+		}
+	}
+
+	return result
+}
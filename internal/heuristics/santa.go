@@ -0,0 +1,52 @@
+package heuristics
+
+import (
+	"fmt"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// SantaHeuristic flags persistence whose program Google Santa blocks. A
+// program on disk since before the rule blocking it may have run, and
+// persisted, before the rule existed, so it scores highest.
+type SantaHeuristic struct{}
+
+func NewSantaHeuristic() *SantaHeuristic {
+	return &SantaHeuristic{}
+}
+
+func (h *SantaHeuristic) Name() string {
+	return "santa_block"
+}
+
+func (h *SantaHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.8,
+		Details:    "",
+	}
+
+	santa := item.Santa
+	if santa == nil || santa.Decision != "block" {
+		return result
+	}
+
+	result.Triggered = true
+	switch {
+	case santa.Rule == "":
+		// Lockdown blocks everything no rule allows
+		result.Score = 0.3
+		result.Details = fmt.Sprintf("No Santa rule allows the program, so Santa in %s mode blocks it", santa.Mode)
+	case item.ProgramFile != nil && !item.ProgramFile.CreatedAt.IsZero() && !santa.RuleAdded.IsZero() && item.ProgramFile.CreatedAt.Before(santa.RuleAdded):
+		result.Score = 0.7
+		result.Details = fmt.Sprintf("Santa blocks the program by %s rule since %s, but it has been on disk since %s and may have run before the rule",
+			santa.Rule, santa.RuleAdded.Format("2006-01-02"), item.ProgramFile.CreatedAt.Format("2006-01-02"))
+	default:
+		result.Score = 0.5
+		result.Details = fmt.Sprintf("Santa blocks the program by %s rule", santa.Rule)
+	}
+
+	return result
+}
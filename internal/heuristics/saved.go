@@ -0,0 +1,33 @@
+package heuristics
+
+import (
+	"github.com/haasonsaas/macos-persist-scan/pkg/risk"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// SavedHeuristic stands in for a heuristic that has to read the scanned
+// system, such as one that runs codesign. It reports the result the
+// wrapped heuristic recorded on the item when it was first assessed, so a
+// saved result can be rescored on another machine.
+type SavedHeuristic struct {
+	name string
+}
+
+// Saved returns a stand-in for h.
+func Saved(h risk.Heuristic) *SavedHeuristic {
+	return &SavedHeuristic{name: h.Name()}
+}
+
+func (h *SavedHeuristic) Name() string {
+	return h.name
+}
+
+func (h *SavedHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	for _, result := range item.Risk.Heuristics {
+		if result.Name == h.name {
+			return result
+		}
+	}
+
+	return scanner.HeuristicResult{Name: h.name}
+}
@@ -0,0 +1,83 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// SearchPathHeuristic flags PATH entries that let another account shadow
+// system commands: directories anyone can write, temporary directories,
+// and directories non-root users can write that are searched ahead of the
+// system directories.
+type SearchPathHeuristic struct{}
+
+func NewSearchPathHeuristic() *SearchPathHeuristic {
+	return &SearchPathHeuristic{}
+}
+
+func (h *SearchPathHeuristic) Name() string {
+	return "path_hijack"
+}
+
+func (h *SearchPathHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.8,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismSearchPath || item.RawData == nil {
+		return result
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	variable, _ := item.RawData["variable"].(string)
+	scope, _ := item.RawData["scope"].(string)
+	directories := stringList(item.RawData["directories"])
+	prepended := stringList(item.RawData["prepended"])
+	writable := stringList(item.RawData["writable"])
+	worldWritable := stringList(item.RawData["world_writable"])
+
+	for _, dir := range worldWritable {
+		flag(0.8, fmt.Sprintf("%s includes world-writable directory %s", variable, dir))
+	}
+	for _, dir := range directories {
+		if strings.HasPrefix(dir, "/tmp/") || strings.HasPrefix(dir, "/private/tmp/") || strings.HasPrefix(dir, "/var/tmp/") || strings.HasPrefix(dir, "/Users/Shared/") {
+			flag(0.8, fmt.Sprintf("%s includes temporary or shared directory %s", variable, dir))
+		}
+	}
+
+	for _, dir := range writable {
+		ahead := contains(prepended, dir)
+		switch {
+		case scope == "system" && ahead:
+			flag(0.7, fmt.Sprintf("System-wide %s searches user-writable %s before the system directories", variable, dir))
+		case scope == "system":
+			flag(0.5, fmt.Sprintf("System-wide %s includes user-writable %s", variable, dir))
+		case ahead:
+			flag(0.3, fmt.Sprintf("Shell profile searches user-writable %s before the system directories", dir))
+		}
+	}
+
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
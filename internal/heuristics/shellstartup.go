@@ -0,0 +1,52 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// ShellStartupHeuristic flags users' shell startup files that download,
+// decode, or open a connection, as the root account heuristic does for
+// root's. Comment lines are ignored, since dotfiles often carry commented
+// out examples.
+type ShellStartupHeuristic struct{}
+
+func NewShellStartupHeuristic() *ShellStartupHeuristic {
+	return &ShellStartupHeuristic{}
+}
+
+func (h *ShellStartupHeuristic) Name() string {
+	return "shell_startup"
+}
+
+func (h *ShellStartupHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.7,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismShellStartup || item.RawData == nil {
+		return result
+	}
+
+	content, _ := item.RawData["content"].(string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match := shellPayload.FindString(line); match != "" {
+			result.Triggered = true
+			result.Score = 0.5
+			result.Details = fmt.Sprintf("%s's shell startup file runs %s", item.User, match)
+			break
+		}
+	}
+
+	return result
+}
@@ -1,7 +1,7 @@
 package heuristics
 
 import (
-	"os/exec"
+	"fmt"
 	"strings"
 
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
@@ -18,6 +18,27 @@ func (h *SignatureHeuristic) Name() string {
 }
 
 func (h *SignatureHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	if item.Program == "" {
+		return h.judge(nil)
+	}
+	result := h.judge(item.CodeSignature)
+
+	// A wrapper script or interpreter is judged by the worst binary it runs
+	for _, target := range item.ResolvedTargets {
+		if target.CodeSignature == nil {
+			continue
+		}
+		if judged := h.judge(target.CodeSignature); judged.Triggered && judged.Score > result.Score {
+			judged.Details = fmt.Sprintf("Resolved target %s: %s", target.Path, judged.Details)
+			result = judged
+		}
+	}
+
+	return result
+}
+
+// judge scores one code signature; a nil signature is not judged.
+func (h *SignatureHeuristic) judge(sig *scanner.CodeSignature) scanner.HeuristicResult {
 	result := scanner.HeuristicResult{
 		Name:       h.Name(),
 		Triggered:  false,
@@ -26,42 +47,45 @@ func (h *SignatureHeuristic) Analyze(item *scanner.PersistenceItem) scanner.Heur
 		Details:    "",
 	}
 
-	if item.Program == "" {
+	if sig == nil {
 		return result
 	}
 
-	// Check code signature using codesign
-	cmd := exec.Command("codesign", "-dv", "--verbose=4", item.Program)
-	output, err := cmd.CombinedOutput()
-	
-	if err != nil {
+	// Nothing is known about a signature codesign did not finish reading,
+	// and a binary can be made to stall codesign, so it is not trusted
+	if sig.TimedOut {
+		result.Triggered = true
+		result.Score = 0.5
+		result.Confidence = 0.5
+		result.Details = "Signature check timed out; the binary could not be verified"
+		return result
+	}
+
+	if !sig.Signed {
 		// Binary is unsigned or invalid signature
 		result.Triggered = true
 		result.Score = 0.7
 		result.Details = "Binary is unsigned or has invalid signature"
 		
-		if strings.Contains(string(output), "code object is not signed") {
+		if strings.Contains(sig.Error, "code object is not signed") {
 			result.Details = "Binary is not code signed"
 			result.Score = 0.6
-		} else if strings.Contains(string(output), "adhoc") {
+		} else if sig.AdHoc {
 			result.Details = "Binary has ad-hoc signature (not from trusted developer)"
 			result.Score = 0.8
 		}
 		return result
 	}
 
-	outputStr := string(output)
-	
 	// Check for Apple signature
-	if strings.Contains(outputStr, "Authority=Apple") || 
-	   strings.Contains(outputStr, "com.apple.") {
+	if h.hasAuthority(sig, "Apple") || strings.Contains(sig.Identifier, "com.apple.") {
 		// Apple-signed binaries are generally trusted
 		result.Triggered = false
 		return result
 	}
 
 	// Check for Developer ID
-	if strings.Contains(outputStr, "Authority=Developer ID") {
+	if h.hasAuthority(sig, "Developer ID") {
 		result.Triggered = true
 		result.Score = 0.2
 		result.Details = "Binary signed with Developer ID certificate"
@@ -70,7 +94,7 @@ func (h *SignatureHeuristic) Analyze(item *scanner.PersistenceItem) scanner.Heur
 	}
 
 	// Check for revoked certificates
-	if strings.Contains(outputStr, "REVOKED") {
+	if sig.Revoked {
 		result.Triggered = true
 		result.Score = 0.9
 		result.Details = "Binary signed with revoked certificate"
@@ -84,4 +108,13 @@ func (h *SignatureHeuristic) Analyze(item *scanner.PersistenceItem) scanner.Heur
 	result.Details = "Binary has unknown signature type"
 	
 	return result
-}
\ No newline at end of file
+}
+
+func (h *SignatureHeuristic) hasAuthority(sig *scanner.CodeSignature, prefix string) bool {
+	for _, authority := range sig.Authorities {
+		if strings.HasPrefix(authority, prefix) {
+			return true
+		}
+	}
+	return false
+}
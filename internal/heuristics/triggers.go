@@ -0,0 +1,152 @@
+package heuristics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// TriggerHeuristic evaluates the launchd keys that start a job in response to
+// filesystem, mount, socket, Mach IPC, device, or notification activity
+// rather than at login.
+type TriggerHeuristic struct{}
+
+func NewTriggerHeuristic() *TriggerHeuristic {
+	return &TriggerHeuristic{}
+}
+
+func (h *TriggerHeuristic) Name() string {
+	return "launchd_triggers"
+}
+
+var watchPathPatterns = []struct {
+	pattern *regexp.Regexp
+	score   float64
+	reason  string
+}{
+	{regexp.MustCompile(`/Library/Launch(Agents|Daemons)`), 0.7, "Watches a launchd directory (can re-install persistence)"},
+	{regexp.MustCompile(`^(/Users/[^/]+|~)/Downloads`), 0.6, "Watches a user Downloads folder"},
+	{regexp.MustCompile(`^(/private)?/(var/)?tmp(/|$)`), 0.6, "Watches a temporary directory"},
+	{regexp.MustCompile(`^/Volumes(/|$)`), 0.5, "Watches mounted volumes"},
+	{regexp.MustCompile(`^(/Users/[^/]+|~)/(Desktop|Documents)`), 0.4, "Watches user documents"},
+}
+
+// deviceClasses names the kind of device an IOKit matching dictionary's
+// IOProviderClass waits for.
+var deviceClasses = map[string]string{
+	"IOUSBDevice":          "USB",
+	"IOUSBHostDevice":      "USB",
+	"IOUSBInterface":       "USB",
+	"IOUSBHostInterface":   "USB",
+	"IOMedia":              "storage",
+	"IOBlockStorageDevice": "storage",
+	"IOThunderboltPort":    "Thunderbolt",
+	"IOThunderboltSwitch":  "Thunderbolt",
+	"IOHIDDevice":          "input",
+	"IOBluetoothDevice":    "Bluetooth",
+	"IOSerialBSDClient":    "serial",
+}
+
+// presenceNotifications are notifications posted when a user logs in,
+// unlocks the screen, or the Mac wakes or changes network, so a job
+// started by one runs whenever someone is at the keyboard.
+var presenceNotifications = []string{
+	"com.apple.screenIsUnlocked",
+	"com.apple.screensaver.didstop",
+	"com.apple.sessionDidBecomeActive",
+	"com.apple.loginwindow",
+	"com.apple.system.loginwindow",
+	"com.apple.system.config.network_change",
+	"com.apple.system.powermanagement",
+}
+
+func (h *TriggerHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.75,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismLaunchAgent && item.Mechanism != scanner.MechanismLaunchDaemon {
+		return result
+	}
+	if item.RawData == nil {
+		return result
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	isApple := strings.HasPrefix(item.Label, "com.apple.")
+
+	for _, key := range []string{"WatchPaths", "QueueDirectories"} {
+		paths := stringList(item.RawData[key])
+		for _, path := range paths {
+			for _, wp := range watchPathPatterns {
+				if wp.pattern.MatchString(path) {
+					flag(wp.score, fmt.Sprintf("%s: %s (%s)", wp.reason, path, key))
+				}
+			}
+		}
+	}
+
+	if startOnMount, _ := item.RawData["StartOnMount"].(bool); startOnMount && !isApple {
+		flag(0.5, "Runs whenever a filesystem is mounted (StartOnMount)")
+	}
+
+	if exposure := item.Exposure; exposure != nil {
+		for _, socket := range exposure.Sockets {
+			if socket.Network() {
+				flag(0.6, fmt.Sprintf("Socket %q listens on all network interfaces (%s %s)", socket.Name, socket.Type, socket.Service))
+			}
+		}
+		if exposure.Inetd && len(exposure.Sockets) > 0 && !isApple {
+			flag(0.5, "Launched per connection as an inetd-compatible service")
+		}
+
+		if !isApple {
+			for _, service := range exposure.MachServices {
+				if strings.HasPrefix(service, "com.apple.") {
+					flag(0.8, fmt.Sprintf("Registers Apple Mach service name %s", service))
+				}
+			}
+		}
+	}
+
+	if !isApple {
+		for _, event := range item.LaunchEvents {
+			switch event.Stream {
+			case scanner.EventStreamIOKit:
+				class, _ := event.Matching["IOProviderClass"].(string)
+				if kind, ok := deviceClasses[class]; ok {
+					flag(0.6, fmt.Sprintf("Runs when a %s device is connected (IOKit matching %s on %s)", kind, event.Name, class))
+				} else {
+					flag(0.5, fmt.Sprintf("Runs when IOKit matches a device (%s)", event.Name))
+				}
+			case scanner.EventStreamNotifyd, scanner.EventStreamDistnoted:
+				notification := event.Notification()
+				if notification == "" {
+					notification = event.Name
+				}
+				score := 0.5
+				for _, prefix := range presenceNotifications {
+					if strings.HasPrefix(notification, prefix) {
+						score = 0.6
+					}
+				}
+				flag(score, fmt.Sprintf("Runs when notification %s is posted (%s)", notification, event.Stream))
+			}
+		}
+	}
+
+	return result
+}
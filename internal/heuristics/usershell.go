@@ -0,0 +1,72 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// standardShells are the shells macOS ships.
+var standardShells = map[string]bool{
+	"/bin/sh": true, "/bin/bash": true, "/bin/zsh": true, "/bin/csh": true,
+	"/bin/tcsh": true, "/bin/ksh": true, "/bin/dash": true,
+	"/usr/bin/false": true, "/bin/false": true, "/sbin/nologin": true, "/usr/sbin/nologin": true,
+}
+
+// UserShellHeuristic flags login shells that are scripts, sit in
+// user-writable or temporary locations, or are neither a shell macOS ships
+// nor listed in /etc/shells. Shells installed by a package manager and
+// added to /etc/shells are not flagged.
+type UserShellHeuristic struct{}
+
+func NewUserShellHeuristic() *UserShellHeuristic {
+	return &UserShellHeuristic{}
+}
+
+func (h *UserShellHeuristic) Name() string {
+	return "login_shell"
+}
+
+func (h *UserShellHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.8,
+		Details:    "",
+	}
+
+	if item.Mechanism != scanner.MechanismUserShell || item.RawData == nil {
+		return result
+	}
+	shell, _ := item.RawData["shell"].(string)
+	if shell == "" || standardShells[shell] {
+		return result
+	}
+
+	flag := func(score float64, details string) {
+		if score > result.Score {
+			result.Triggered = true
+			result.Score = score
+			result.Details = details
+		}
+	}
+
+	listed, _ := item.RawData["listed"].(bool)
+	if !listed {
+		flag(0.6, fmt.Sprintf("Login shell %s is not a standard shell or listed in /etc/shells", shell))
+	}
+	if script, _ := item.RawData["script"].(bool); script {
+		interpreter, _ := item.RawData["interpreter"].(string)
+		flag(0.7, fmt.Sprintf("Login shell %s is a script run by %s", shell, interpreter))
+	}
+	if missing, _ := item.RawData["missing"].(bool); missing {
+		flag(0.4, fmt.Sprintf("Login shell %s does not exist", shell))
+	}
+	if strings.HasPrefix(shell, "/Users/") || hiddenOrTemporary(shell) {
+		flag(0.8, fmt.Sprintf("Login shell %s is in a user-writable or temporary location", shell))
+	}
+
+	return result
+}
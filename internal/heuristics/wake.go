@@ -0,0 +1,81 @@
+package heuristics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// managementSchedulers are the prefixes of management tools that schedule
+// one-time wakes for maintenance windows.
+var managementSchedulers = []string{
+	"com.jamf", "com.jamfsoftware", "com.kandji", "io.kandji", "com.mosyle", "com.addigy",
+	"com.microsoft.intune", "com.github.munki", "com.googlecode.munki", "com.absolute",
+}
+
+// WakeScheduleHeuristic flags power schedules that wake the Mac when no
+// configuration profile set them, most strongly when a third-party launchd
+// job is scheduled just after the wake, and those jobs themselves.
+type WakeScheduleHeuristic struct{}
+
+func NewWakeScheduleHeuristic() *WakeScheduleHeuristic {
+	return &WakeScheduleHeuristic{}
+}
+
+func (h *WakeScheduleHeuristic) Name() string {
+	return "wake_schedule"
+}
+
+func (h *WakeScheduleHeuristic) Analyze(item *scanner.PersistenceItem) scanner.HeuristicResult {
+	result := scanner.HeuristicResult{
+		Name:       h.Name(),
+		Triggered:  false,
+		Score:      0.0,
+		Confidence: 0.6,
+		Details:    "",
+	}
+	if item.RawData == nil {
+		return result
+	}
+
+	switch item.Mechanism {
+	case scanner.MechanismPowerSchedule:
+		if managed, _ := item.RawData["managed"].(bool); managed {
+			return result
+		}
+		kind, _ := item.RawData["kind"].(string)
+		eventType, _ := item.RawData["event_type"].(string)
+		if eventType != "wake" && eventType != "poweron" && eventType != "wakepoweron" {
+			return result
+		}
+
+		if kind == "scheduled" {
+			scheduledBy, _ := item.RawData["scheduled_by"].(string)
+			if !hasAnyPrefix(scheduledBy, managementSchedulers) {
+				result.Triggered = true
+				result.Score = 0.4
+				result.Details = fmt.Sprintf("One-time %s scheduled by %q, not a known management tool", eventType, scheduledBy)
+			}
+			return result
+		}
+
+		result.Triggered = true
+		if jobs := stringList(item.RawData["paired_jobs"]); len(jobs) > 0 {
+			result.Score = 0.7
+			result.Details = fmt.Sprintf("Repeating %s set outside device management is followed by launchd jobs %s", eventType, strings.Join(jobs, ", "))
+		} else {
+			result.Score = 0.3
+			result.Details = fmt.Sprintf("Repeating %s set outside device management", eventType)
+		}
+
+	case scanner.MechanismLaunchAgent, scanner.MechanismLaunchDaemon:
+		if schedule, ok := item.RawData["wake_schedule"].(string); ok && !strings.HasPrefix(item.Label, "com.apple.") {
+			result.Triggered = true
+			result.Score = 0.5
+			result.Details = fmt.Sprintf("Scheduled to run just after a repeating wake (%s)", schedule)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,297 @@
+// Package jamf asks a Jamf Pro server what it manages on this Mac: the
+// configuration profiles and policies scoped to it and the login items its
+// profiles allow. Profiles and login items found on disk can then be told
+// apart from sanctioned management.
+package jamf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"howett.net/plist"
+)
+
+// enrollmentProfilePrefix begins the identifiers of the MDM enrollment and
+// certificate profiles Jamf Pro installs itself, which are not listed among
+// a computer's scoped profiles. The last bytes spell "JAMF".
+const enrollmentProfilePrefix = "00000000-0000-0000-A000-4A414D46"
+
+// Expected is what Jamf Pro manages on one computer.
+type Expected struct {
+	Serial string
+	// Profiles maps the identifiers of the configuration profiles scoped
+	// to the computer to their names.
+	Profiles map[string]string
+	// Policies names the policies scoped to the computer.
+	Policies []string
+	// LoginItems are the managed login item rules in its profiles.
+	LoginItems []LoginItemRule
+}
+
+// Fingerprint identifies everything e says Jamf Pro manages, so results
+// that depend on it can be redone when its scoping changes.
+func (e *Expected) Fingerprint() string {
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoginItemRule is a rule of a Service Management (managed login items)
+// payload.
+type LoginItemRule struct {
+	// RuleType is BundleIdentifier, BundleIdentifierPrefix, Label,
+	// LabelPrefix, or TeamIdentifier.
+	RuleType  string `plist:"RuleType"`
+	RuleValue string `plist:"RuleValue"`
+	// TeamIdentifier, if set, also requires the program's signing team.
+	TeamIdentifier string `plist:"TeamIdentifier"`
+	Comment        string `plist:"Comment"`
+	// Profile is the name of the profile the rule came from.
+	Profile string `plist:"-"`
+}
+
+// Profile returns the name of the profile with the given identifier and
+// whether Jamf Pro deployed it.
+func (e *Expected) Profile(identifier string) (string, bool) {
+	if strings.HasPrefix(strings.ToUpper(identifier), enrollmentProfilePrefix) {
+		return "Jamf Pro MDM enrollment", true
+	}
+	name, ok := e.Profiles[identifier]
+	return name, ok
+}
+
+// LoginItem returns the first managed login item rule item matches, or nil.
+// Bundle identifiers and Team IDs come from the program's code signature.
+func (e *Expected) LoginItem(item *scanner.PersistenceItem) *LoginItemRule {
+	var bundleID, teamID string
+	if sig := item.CodeSignature; sig != nil {
+		bundleID, teamID = sig.Identifier, sig.TeamID
+	}
+
+	for i := range e.LoginItems {
+		rule := &e.LoginItems[i]
+		if rule.TeamIdentifier != "" && rule.TeamIdentifier != teamID {
+			continue
+		}
+		var matched bool
+		switch rule.RuleType {
+		case "BundleIdentifier":
+			matched = bundleID != "" && bundleID == rule.RuleValue
+		case "BundleIdentifierPrefix":
+			matched = bundleID != "" && strings.HasPrefix(bundleID, rule.RuleValue)
+		case "Label":
+			matched = item.Label == rule.RuleValue
+		case "LabelPrefix":
+			matched = strings.HasPrefix(item.Label, rule.RuleValue)
+		case "TeamIdentifier":
+			matched = teamID != "" && teamID == rule.RuleValue
+		}
+		if matched {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Client talks to a Jamf Pro server. It authenticates with an API client
+// when ClientID is set and with a user account otherwise.
+type Client struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	Username     string
+	Password     string
+
+	client *http.Client
+	token  string
+}
+
+// NewClient returns a client for the server at baseURL, with credentials
+// from JAMF_CLIENT_ID and JAMF_CLIENT_SECRET, or JAMF_USER and
+// JAMF_PASSWORD.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		URL:          strings.TrimSuffix(baseURL, "/"),
+		ClientID:     os.Getenv("JAMF_CLIENT_ID"),
+		ClientSecret: os.Getenv("JAMF_CLIENT_SECRET"),
+		Username:     os.Getenv("JAMF_USER"),
+		Password:     os.Getenv("JAMF_PASSWORD"),
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Expected fetches what Jamf Pro manages on the computer with the given
+// serial number.
+func (c *Client) Expected(serial string) (*Expected, error) {
+	if err := c.authenticate(); err != nil {
+		return nil, err
+	}
+
+	var management struct {
+		ComputerManagement struct {
+			Policies []struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"policies"`
+			Profiles []struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"os_x_configuration_profiles"`
+		} `json:"computer_management"`
+	}
+	endpoint := "/JSSResource/computermanagement/serialnumber/" + url.PathEscape(serial) + "/subset/Policies&OSXConfigurationProfiles"
+	if err := c.get(endpoint, &management); err != nil {
+		return nil, err
+	}
+
+	expected := &Expected{
+		Serial:   serial,
+		Profiles: make(map[string]string),
+	}
+	for _, policy := range management.ComputerManagement.Policies {
+		expected.Policies = append(expected.Policies, policy.Name)
+	}
+	for _, p := range management.ComputerManagement.Profiles {
+		if err := c.addProfile(expected, p.ID); err != nil {
+			return nil, fmt.Errorf("fetching profile %q: %w", p.Name, err)
+		}
+	}
+	return expected, nil
+}
+
+// addProfile records the identifier and managed login item rules of the
+// profile with the given Jamf Pro ID.
+func (c *Client) addProfile(expected *Expected, id int) error {
+	var profile struct {
+		Profile struct {
+			General struct {
+				Name     string `json:"name"`
+				UUID     string `json:"uuid"`
+				Payloads string `json:"payloads"`
+			} `json:"general"`
+		} `json:"os_x_configuration_profile"`
+	}
+	if err := c.get(fmt.Sprintf("/JSSResource/osxconfigurationprofiles/id/%d", id), &profile); err != nil {
+		return err
+	}
+	general := profile.Profile.General
+
+	var payloads struct {
+		PayloadIdentifier string `plist:"PayloadIdentifier"`
+		PayloadContent    []struct {
+			PayloadType string          `plist:"PayloadType"`
+			Rules       []LoginItemRule `plist:"Rules"`
+		} `plist:"PayloadContent"`
+	}
+	if _, err := plist.Unmarshal([]byte(general.Payloads), &payloads); err != nil {
+		return fmt.Errorf("parsing payloads: %w", err)
+	}
+
+	// Jamf Pro installs profiles with their UUID as the identifier
+	identifier := payloads.PayloadIdentifier
+	if identifier == "" {
+		identifier = general.UUID
+	}
+	expected.Profiles[identifier] = general.Name
+
+	for _, payload := range payloads.PayloadContent {
+		if payload.PayloadType != "com.apple.servicemanagement" {
+			continue
+		}
+		for _, rule := range payload.Rules {
+			rule.Profile = general.Name
+			expected.LoginItems = append(expected.LoginItems, rule)
+		}
+	}
+	return nil
+}
+
+// authenticate obtains a bearer token for the Jamf Pro API.
+func (c *Client) authenticate() error {
+	var req *http.Request
+	var err error
+	switch {
+	case c.ClientID != "":
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {c.ClientID},
+			"client_secret": {c.ClientSecret},
+		}
+		req, err = http.NewRequest(http.MethodPost, c.URL+"/api/oauth/token", strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	case c.Username != "":
+		req, err = http.NewRequest(http.MethodPost, c.URL+"/api/v1/auth/token", nil)
+		if err == nil {
+			req.SetBasicAuth(c.Username, c.Password)
+		}
+	default:
+		return fmt.Errorf("no Jamf Pro credentials: set JAMF_CLIENT_ID and JAMF_CLIENT_SECRET, or JAMF_USER and JAMF_PASSWORD")
+	}
+	if err != nil {
+		return err
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Token       string `json:"token"`
+	}
+	if err := c.do(req, &token); err != nil {
+		return fmt.Errorf("authenticating to Jamf Pro: %w", err)
+	}
+	c.token = token.AccessToken
+	if c.token == "" {
+		c.token = token.Token
+	}
+	return nil
+}
+
+func (c *Client) get(endpoint string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.URL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	return c.do(req, out)
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s returned %s", req.Method, req.URL.Path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+var serialPattern = regexp.MustCompile(`"IOPlatformSerialNumber" = "([^"]+)"`)
+
+// SerialNumber returns the running Mac's hardware serial number, which
+// Jamf Pro identifies computers by.
+func SerialNumber() (string, error) {
+	output, err := command.Output("ioreg", "-rd1", "-c", "IOPlatformExpertDevice")
+	if err != nil {
+		return "", fmt.Errorf("running ioreg: %w", err)
+	}
+	m := serialPattern.FindSubmatch(output)
+	if m == nil {
+		return "", fmt.Errorf("no serial number in ioreg output")
+	}
+	return string(m[1]), nil
+}
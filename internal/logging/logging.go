@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+)
+
+// Levels and Formats list the accepted --log-level and --log-format values.
+var (
+	Levels  = []string{"debug", "info", "warn", "error"}
+	Formats = []string{"text", "json"}
+)
+
+// Setup makes a logger writing to w at level in format the slog default.
+func Setup(w io.Writer, level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown log level %q (valid: %s)", level, strings.Join(Levels, ", "))
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(w, opts)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, opts)))
+	default:
+		return fmt.Errorf("unknown log format %q (valid: %s)", format, strings.Join(Formats, ", "))
+	}
+	return nil
+}
+
+// Warn logs a non-fatal problem. err and its Class are attached to args,
+// which are the usual slog key-value pairs such as "scanner" and "path".
+func Warn(msg string, err error, args ...any) {
+	args = append(args, "error", err.Error(), "error_class", Class(err))
+	slog.Warn(msg, args...)
+}
+
+// Class buckets err so collected logs can be filtered without parsing
+// messages: permission, not_found, timeout, command_missing,
+// command_failed, or other.
+func Class(err error) string {
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return "permission"
+	case errors.Is(err, command.ErrTimeout):
+		return "timeout"
+	case errors.Is(err, exec.ErrNotFound):
+		return "command_missing"
+	case errors.Is(err, fs.ErrNotExist):
+		return "not_found"
+	case errors.As(err, &exitErr):
+		return "command_failed"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,120 @@
+// Package preflight checks, before a scan, whether the process has the
+// access each collector needs for full coverage.
+package preflight
+
+import (
+	"os"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// tccDatabase is readable only by processes with Full Disk Access, even
+// when running as root.
+const tccDatabase = "/Library/Application Support/com.apple.TCC/TCC.db"
+
+// Full Disk Access states.
+const (
+	AccessGranted = "granted"
+	AccessDenied  = "denied"
+	AccessUnknown = "unknown"
+)
+
+// Report is the outcome of a preflight check.
+type Report struct {
+	Root bool `json:"root"`
+	// FullDiskAccess is one of the Access* states. It is unknown when the
+	// TCC database does not exist, e.g. on another OS or an offline root.
+	FullDiskAccess string              `json:"full_disk_access"`
+	Mechanisms     []MechanismCoverage `json:"mechanisms"`
+}
+
+// MechanismCoverage describes what a collector will miss.
+type MechanismCoverage struct {
+	Mechanism scanner.MechanismType `json:"mechanism"`
+	// Denied lists paths the collector reads that this process cannot.
+	Denied []string `json:"denied,omitempty"`
+	// Missing lists privileges the collector needs that the process lacks.
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Complete reports whether the collector will see everything.
+func (m *MechanismCoverage) Complete() bool {
+	return len(m.Denied) == 0 && len(m.Missing) == 0
+}
+
+// Complete reports whether every collector will see everything.
+func (r *Report) Complete() bool {
+	for i := range r.Mechanisms {
+		if !r.Mechanisms[i].Complete() {
+			return false
+		}
+	}
+	return true
+}
+
+// Run probes every path the scanners read and compares their privilege
+// requirements with the current process.
+func Run(scanners []scanner.Scanner) *Report {
+	report := &Report{
+		Root:           os.Geteuid() == 0,
+		FullDiskAccess: fullDiskAccess(),
+	}
+
+	for _, s := range scanners {
+		coverage := MechanismCoverage{Mechanism: s.Type()}
+		if d, ok := s.(scanner.Describer); ok {
+			info := d.Info()
+			for _, path := range info.Paths {
+				if denied(path) {
+					coverage.Denied = append(coverage.Denied, path)
+				}
+			}
+			if !report.Root && !sysroot.Offline() {
+				for _, privilege := range info.Privileges {
+					if strings.HasPrefix(privilege, "root") {
+						coverage.Missing = append(coverage.Missing, privilege)
+					}
+				}
+			}
+		}
+		report.Mechanisms = append(report.Mechanisms, coverage)
+	}
+
+	return report
+}
+
+func fullDiskAccess() string {
+	if sysroot.Offline() {
+		return AccessUnknown
+	}
+	file, err := os.Open(tccDatabase)
+	switch {
+	case err == nil:
+		file.Close()
+		return AccessGranted
+	case os.IsPermission(err):
+		return AccessDenied
+	default:
+		return AccessUnknown
+	}
+}
+
+// denied reports whether path exists but cannot be read.
+func denied(path string) bool {
+	info, err := os.Stat(sysroot.Path(path))
+	if err != nil {
+		return os.IsPermission(err)
+	}
+
+	if info.IsDir() {
+		_, err = os.ReadDir(sysroot.Path(path))
+	} else {
+		var file *os.File
+		if file, err = os.Open(sysroot.Path(path)); err == nil {
+			file.Close()
+		}
+	}
+	return os.IsPermission(err)
+}
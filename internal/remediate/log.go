@@ -0,0 +1,111 @@
+package remediate
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// LogEntry records one applied remediation and how to undo it.
+type LogEntry struct {
+	ID            string                `json:"id"`
+	Time          time.Time             `json:"time"`
+	Action        Action                `json:"action"`
+	ItemID        string                `json:"item_id,omitempty"`
+	Mechanism     scanner.MechanismType `json:"mechanism"`
+	Label         string                `json:"label,omitempty"`
+	Path          string                `json:"path"`
+	QuarantinedAt string                `json:"quarantined_at,omitempty"`
+	Undo          [][]string            `json:"undo,omitempty"`
+	UndoneAt      *time.Time            `json:"undone_at,omitempty"`
+}
+
+// ReadLog returns every entry in the undo log at path, oldest first. A
+// missing log is empty.
+func ReadLog(path string) ([]LogEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading remediation log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []LogEntry
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		if len(lines.Bytes()) == 0 {
+			continue
+		}
+		var entry LogEntry
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("parsing remediation log %s: %w", path, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, lines.Err()
+}
+
+// AppendLog adds entry to the undo log at path.
+func AppendLog(path string, entry *LogEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("opening remediation log: %w", err)
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// MarkUndone rewrites the log at path with the entry id marked as undone.
+func MarkUndone(path, id string) error {
+	entries, err := ReadLog(path)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	found := false
+	for i := range entries {
+		if entries[i].ID == id {
+			entries[i].UndoneAt = &now
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("remediation %s not found in %s", id, path)
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for i := range entries {
+		if err := encoder.Encode(&entries[i]); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,231 @@
+package remediate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Action is a response that can be applied to a persistence item.
+type Action string
+
+const (
+	// ActionUnload stops a loaded launchd job with launchctl bootout.
+	ActionUnload Action = "unload"
+	// ActionDisable marks a launchd job disabled so it will not load again.
+	ActionDisable Action = "disable"
+	// ActionQuarantine moves the item's file into the quarantine directory.
+	ActionQuarantine Action = "quarantine"
+)
+
+// Actions lists every supported action.
+var Actions = []Action{ActionUnload, ActionDisable, ActionQuarantine}
+
+// Plan is the set of changes an action will make to one item, along with
+// how to reverse them.
+type Plan struct {
+	Item   scanner.PersistenceItem
+	Action Action
+	// Commands are run in order; Undo reverses them.
+	Commands [][]string
+	Undo     [][]string
+	// MoveFrom and MoveTo describe a quarantine move.
+	MoveFrom string
+	MoveTo   string
+}
+
+// NewPlan works out how to apply action to item. Quarantined files are
+// moved under quarantineDir.
+func NewPlan(item scanner.PersistenceItem, action Action, quarantineDir string) (*Plan, error) {
+	plan := &Plan{Item: item, Action: action}
+
+	switch action {
+	case ActionUnload, ActionDisable:
+		if item.Mechanism != scanner.MechanismLaunchAgent && item.Mechanism != scanner.MechanismLaunchDaemon {
+			return nil, fmt.Errorf("%s is only supported for launchd jobs, not %s", action, item.Mechanism)
+		}
+		domain, err := launchdDomain(&item)
+		if err != nil {
+			return nil, err
+		}
+
+		if action == ActionUnload {
+			plan.Commands = [][]string{{"launchctl", "bootout", domain, item.Path}}
+			plan.Undo = [][]string{{"launchctl", "bootstrap", domain, item.Path}}
+		} else {
+			if item.Label == "" {
+				return nil, fmt.Errorf("cannot disable %s: the job has no label", item.Path)
+			}
+			plan.Commands = [][]string{{"launchctl", "disable", domain + "/" + item.Label}}
+			plan.Undo = [][]string{{"launchctl", "enable", domain + "/" + item.Label}}
+		}
+
+	case ActionQuarantine:
+		info, err := os.Stat(item.Path)
+		if err != nil || !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("cannot quarantine %q: not a regular file", item.Path)
+		}
+		plan.MoveFrom = item.Path
+		// Items with the same file name quarantined together keep apart
+		// under their IDs
+		dir := item.ID
+		if dir == "" {
+			dir = newEntryID()
+		}
+		plan.MoveTo = filepath.Join(quarantineDir, time.Now().Format("20060102-150405"), dir, filepath.Base(item.Path))
+
+	default:
+		return nil, fmt.Errorf("unknown action %q", action)
+	}
+
+	return plan, nil
+}
+
+// Describe lists the changes the plan will make, one per line.
+func (p *Plan) Describe() []string {
+	var lines []string
+	for _, cmd := range p.Commands {
+		lines = append(lines, "run: "+strings.Join(cmd, " "))
+	}
+	if p.MoveFrom != "" {
+		lines = append(lines, fmt.Sprintf("move: %s -> %s", p.MoveFrom, p.MoveTo))
+		if p.Item.Mechanism == scanner.MechanismLaunchAgent || p.Item.Mechanism == scanner.MechanismLaunchDaemon {
+			lines = append(lines, "note: a loaded job keeps running until it is unloaded or the system restarts")
+		}
+	}
+	return lines
+}
+
+// Execute applies the plan and returns the log entry that can undo it.
+func (p *Plan) Execute() (*LogEntry, error) {
+	entry := &LogEntry{
+		ID:        newEntryID(),
+		Time:      time.Now(),
+		Action:    p.Action,
+		ItemID:    p.Item.ID,
+		Mechanism: p.Item.Mechanism,
+		Label:     p.Item.Label,
+		Path:      p.Item.Path,
+		Undo:      p.Undo,
+	}
+
+	for _, cmd := range p.Commands {
+		if output, err := command.CombinedOutput(cmd[0], cmd[1:]...); err != nil {
+			return nil, fmt.Errorf("%s: %w: %s", strings.Join(cmd, " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	if p.MoveFrom != "" {
+		if err := move(p.MoveFrom, p.MoveTo); err != nil {
+			return nil, err
+		}
+		entry.QuarantinedAt = p.MoveTo
+	}
+
+	return entry, nil
+}
+
+// Undo reverses a logged remediation.
+func Undo(entry *LogEntry) error {
+	if entry.UndoneAt != nil {
+		return fmt.Errorf("remediation %s was already undone", entry.ID)
+	}
+
+	if entry.QuarantinedAt != "" {
+		if _, err := os.Stat(entry.Path); err == nil {
+			return fmt.Errorf("cannot restore %s: a file already exists there", entry.Path)
+		}
+		if err := move(entry.QuarantinedAt, entry.Path); err != nil {
+			return err
+		}
+	}
+
+	for _, cmd := range entry.Undo {
+		if output, err := command.CombinedOutput(cmd[0], cmd[1:]...); err != nil {
+			return fmt.Errorf("%s: %w: %s", strings.Join(cmd, " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	return nil
+}
+
+// launchdDomain returns the launchctl domain target a job is loaded in.
+// Per-user agents belong to that user's GUI session; agents installed for
+// all users are acted on in the invoking user's session.
+func launchdDomain(item *scanner.PersistenceItem) (string, error) {
+	if item.Mechanism == scanner.MechanismLaunchDaemon {
+		return "system", nil
+	}
+
+	uid := fmt.Sprint(os.Getuid())
+	if sudoUID := os.Getenv("SUDO_UID"); sudoUID != "" {
+		uid = sudoUID
+	}
+	if rest, ok := strings.CutPrefix(item.Path, "/Users/"); ok {
+		name, _, _ := strings.Cut(rest, "/")
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", fmt.Errorf("looking up owner of %s: %w", item.Path, err)
+		}
+		uid = u.Uid
+	}
+
+	return "gui/" + uid, nil
+}
+
+// move renames from to to, copying across filesystems when needed, and
+// preserves the file mode. It never replaces a file already at to.
+func move(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(to); err == nil {
+		return fmt.Errorf("%s already exists", to)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(to)
+		return err
+	}
+
+	return os.Remove(from)
+}
+
+func newEntryID() string {
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,265 @@
+// Package rules loads detection content that is published separately from
+// the binary: indicators of compromise that raise an item's risk and an
+// allowlist of known-good software that is hidden from results, and
+// optionally a newer manifest of stock macOS items. Bundles are signed
+// with Ed25519 and fetched by the update-rules command.
+package rules
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/baseline"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Bundle is one published version of the detection content.
+type Bundle struct {
+	// Version increases with every release. Updates never install an older
+	// version unless it is pinned.
+	Version   int        `json:"version"`
+	Published time.Time  `json:"published"`
+	IOCs      Indicators `json:"iocs"`
+	// Allowlist hides items from results. Only its SHA-256 hashes and
+	// Team IDs are used; see Allowed.
+	Allowlist Indicators `json:"allowlist"`
+	// Baseline, if set, replaces the bundled manifest of stock macOS items
+	// when it is newer.
+	Baseline *baseline.Manifest `json:"baseline,omitempty"`
+}
+
+// Indicators identify persistence items. An item matches if any field
+// matches.
+type Indicators struct {
+	// SHA256 lists hashes of programs or configuration files.
+	SHA256 []string `json:"sha256,omitempty"`
+	// TeamIDs lists Developer ID Team IDs of program signers.
+	TeamIDs []string `json:"team_ids,omitempty"`
+	// Labels lists launchd labels and item names.
+	Labels []string `json:"labels,omitempty"`
+	// Paths lists filepath.Match patterns for configuration files and
+	// programs.
+	Paths []string `json:"paths,omitempty"`
+}
+
+// Match returns a description of the first indicator item matches, or "".
+// The programs a wrapper script runs are matched like the item's own.
+func (in *Indicators) Match(item *scanner.PersistenceItem) string {
+	files := []*scanner.FileMetadata{item.ConfigFile, item.ProgramFile}
+	signatures := []*scanner.CodeSignature{item.CodeSignature}
+	paths := []string{item.Path, item.Program}
+	for _, target := range item.ResolvedTargets {
+		files = append(files, target.File)
+		signatures = append(signatures, target.CodeSignature)
+		paths = append(paths, target.Path)
+	}
+
+	for _, hash := range in.SHA256 {
+		for _, file := range files {
+			if file != nil && strings.EqualFold(file.SHA256, hash) {
+				return "SHA-256 " + file.SHA256
+			}
+		}
+	}
+	for _, sig := range signatures {
+		if sig == nil || sig.TeamID == "" {
+			continue
+		}
+		for _, team := range in.TeamIDs {
+			if team == sig.TeamID {
+				return "Team ID " + team
+			}
+		}
+	}
+	for _, label := range in.Labels {
+		if item.Label != "" && label == item.Label {
+			return "label " + label
+		}
+	}
+	for _, pattern := range in.Paths {
+		for _, path := range paths {
+			if matched, _ := filepath.Match(pattern, path); matched && path != "" {
+				return "path " + path
+			}
+		}
+	}
+	return ""
+}
+
+// Allowed reports whether item is allowlisted: its program has a listed
+// hash or a valid Developer ID signature from a listed team, or, for an
+// item that runs no program, its configuration file has a listed hash. A
+// trusted configuration file alone is not enough, since the program it
+// points to can be replaced. Labels and paths are ignored, since whoever
+// plants an item chooses them, and so are the programs a wrapper script
+// runs, since a script can run any trusted binary.
+func (in *Indicators) Allowed(item *scanner.PersistenceItem) bool {
+	file := item.ProgramFile
+	if item.Program == "" && file == nil {
+		file = item.ConfigFile
+	}
+	for _, hash := range in.SHA256 {
+		if file != nil && file.SHA256 != "" && strings.EqualFold(file.SHA256, hash) {
+			return true
+		}
+	}
+	if item.Program == "" {
+		return false
+	}
+	sig := item.CodeSignature
+	if sig == nil || !sig.Signed || sig.AdHoc || sig.Revoked || sig.TeamID == "" {
+		return false
+	}
+	for _, team := range in.TeamIDs {
+		if team == sig.TeamID {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse decodes a bundle.
+func Parse(data []byte) (*Bundle, error) {
+	bundle := &Bundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, fmt.Errorf("parsing rule bundle: %w", err)
+	}
+	return bundle, nil
+}
+
+// Load reads the installed bundle at path and checks its signature, kept
+// at path.sig by Update, with key, so a bundle edited after it was
+// installed is refused. A missing file is no bundle.
+func Load(path string, key ed25519.PublicKey) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading rule bundle: %w", err)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("rule bundle %s: no public key to verify it with", path)
+	}
+	signature, err := os.ReadFile(path + ".sig")
+	if err != nil {
+		return nil, fmt.Errorf("reading rule bundle signature: %w", err)
+	}
+	if err := Verify(data, signature, key); err != nil {
+		return nil, fmt.Errorf("rule bundle %s: %w", path, err)
+	}
+	return Parse(data)
+}
+
+// ParsePublicKey decodes a base64 Ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d base64-encoded bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Verify checks a base64 Ed25519 signature of data.
+func Verify(data, signature []byte, key ed25519.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("decoding bundle signature: %w", err)
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return fmt.Errorf("bundle signature does not verify with the configured public key")
+	}
+	return nil
+}
+
+// Update fetches the bundle at url and its detached signature at url.sig,
+// verifies them, and installs them at path. If pin is non-zero the bundle
+// must be exactly that version; otherwise it must not be older than the
+// installed one. It returns the installed bundle.
+func Update(url string, key ed25519.PublicKey, pin int, path string) (*Bundle, error) {
+	data, err := fetch(url)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := fetch(url + ".sig")
+	if err != nil {
+		return nil, err
+	}
+	if err := Verify(data, signature, key); err != nil {
+		return nil, err
+	}
+
+	bundle, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if pin != 0 && bundle.Version != pin {
+		return nil, fmt.Errorf("bundle is version %d, pinned to %d", bundle.Version, pin)
+	}
+	if pin == 0 {
+		// A bundle that no longer verifies is replaced, not compared
+		installed, err := Load(path, key)
+		if err != nil {
+			logging.Warn("replacing the installed rule bundle", err, "path", path)
+		}
+		if installed != nil && bundle.Version < installed.Version {
+			return nil, fmt.Errorf("bundle version %d is older than installed version %d", bundle.Version, installed.Version)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("writing rule bundle: %w", err)
+	}
+	if err := os.WriteFile(path+".sig", signature, 0600); err != nil {
+		return nil, fmt.Errorf("writing rule bundle signature: %w", err)
+	}
+
+	return bundle, nil
+}
+
+func fetch(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+}
+
+// Filter removes allowlisted items from result and returns how many were
+// removed. The risk summary is recomputed.
+func (b *Bundle) Filter(result *scanner.ScanResult) int {
+	if len(b.Allowlist.Labels) > 0 || len(b.Allowlist.Paths) > 0 {
+		slog.Warn("ignoring rule bundle allowlist labels and paths; allowlist by SHA-256 or Team ID", "version", b.Version)
+	}
+
+	kept := result.Items[:0]
+	for i := range result.Items {
+		if !b.Allowlist.Allowed(&result.Items[i]) {
+			kept = append(kept, result.Items[i])
+		}
+	}
+	removed := len(result.Items) - len(kept)
+	result.Items = kept
+	result.Summarize()
+
+	return removed
+}
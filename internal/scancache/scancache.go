@@ -0,0 +1,215 @@
+// Package scancache lets repeat scans reuse the assessment of persistence
+// items whose files have not changed since the previous scan, so frequent
+// scheduled scans skip hashing, signature checks, and scoring for them.
+package scancache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// MaxAge is how long an assessment is reused. Some verdicts depend on the
+// time as well as the files, such as a certificate's revocation or age, or
+// an account created recently, so even unchanged items are assessed again
+// once their entry is this old.
+const MaxAge = 24 * time.Hour
+
+// Cache holds the assessed items of the previous scan, keyed by item ID.
+type Cache struct {
+	path string
+	// settings identifies the scoring configuration the entries were
+	// assessed with; entries from another configuration are discarded.
+	settings string
+	entries  map[string]*Entry
+	used     map[string]*Entry
+
+	// Hits and Misses count the items reused and assessed afresh.
+	Hits, Misses int
+}
+
+// Entry is one cached assessment.
+type Entry struct {
+	// Fingerprint identifies the item's config file and program by path,
+	// size, and modification time.
+	Fingerprint string `json:"fingerprint"`
+	// Targets identifies the scripts and binaries the item's program was
+	// found to run the same way. They are only known once the item is
+	// assessed, so they are checked against the cached item's targets.
+	Targets string `json:"targets"`
+	// AssessedAt is when the item was assessed.
+	AssessedAt time.Time               `json:"assessed_at"`
+	Item       scanner.PersistenceItem `json:"item"`
+}
+
+type file struct {
+	Settings string            `json:"settings"`
+	Entries  map[string]*Entry `json:"entries"`
+}
+
+// Load reads the cache at path. Entries assessed under different settings,
+// such as another scoring model or rule bundle, are ignored. A missing file
+// is an empty cache.
+func Load(path, settings string) (*Cache, error) {
+	c := &Cache{
+		path:     path,
+		settings: settings,
+		entries:  make(map[string]*Entry),
+		used:     make(map[string]*Entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading scan cache: %w", err)
+	}
+
+	var saved file
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("parsing scan cache %s: %w", path, err)
+	}
+	if saved.Settings == settings && saved.Entries != nil {
+		c.entries = saved.Entries
+	}
+	return c, nil
+}
+
+// Save writes the entries used by the latest scan back to the cache file.
+func (c *Cache) Save() error {
+	data, err := json.Marshal(file{Settings: c.settings, Entries: c.used})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("writing scan cache: %w", err)
+	}
+	return nil
+}
+
+// Stage wraps the stages that assess items. Items whose files are unchanged
+// are restored from the cache; the rest go through stages, and the result
+// is remembered for the next scan. Stages that prepare with the whole item
+// set, such as the risk engine computing host-wide prevalence, still see
+// every item.
+func (c *Cache) Stage(stages ...scanner.Stage) scanner.Stage {
+	return scanner.NewStage("cache", func(items []scanner.PersistenceItem) {
+		now := time.Now()
+		fingerprints := make([]string, len(items))
+		var missed []int
+		for i := range items {
+			fingerprints[i] = fingerprint(&items[i])
+			if entry, ok := c.entries[items[i].ID]; ok && fingerprints[i] != "" && entry.Fingerprint == fingerprints[i] &&
+				entry.Targets == targetsFingerprint(entry.Item.ResolvedTargets) && now.Sub(entry.AssessedAt) < MaxAge {
+				items[i] = entry.Item
+				c.used[items[i].ID] = entry
+				c.Hits++
+				continue
+			}
+			missed = append(missed, i)
+		}
+		c.Misses += len(missed)
+		if len(missed) == 0 {
+			return
+		}
+
+		fresh := make([]scanner.PersistenceItem, len(missed))
+		for j, i := range missed {
+			fresh[j] = items[i]
+		}
+		for _, stage := range stages {
+			if p, ok := stage.(interface {
+				Prepare(items []scanner.PersistenceItem)
+			}); ok {
+				p.Prepare(items)
+			}
+		}
+		scanner.RunStages(stages, fresh)
+
+		for j, i := range missed {
+			items[i] = fresh[j]
+			// A timed-out signature check is retried on the next scan,
+			// as the signing cache retries it
+			if fingerprints[i] != "" && !timedOut(&items[i]) {
+				c.used[items[i].ID] = &Entry{Fingerprint: fingerprints[i], Targets: targetsFingerprint(items[i].ResolvedTargets), AssessedAt: now, Item: items[i]}
+			}
+		}
+	})
+}
+
+// fingerprint identifies the current state of the files an item depends
+// on by size, modification time, and mode. Items not read from a file,
+// such as the output of crontab -l, have no fingerprint and are never
+// cached. The links to other items attached before the cache, such as a
+// paired wake schedule or the dropper of a chain, are part of it, so an
+// item whose links changed is assessed again.
+func fingerprint(item *scanner.PersistenceItem) string {
+	if !filepath.IsAbs(item.Path) {
+		return ""
+	}
+
+	fp := fmt.Sprintf("%v|%v|%v|", item.RawData["wake_schedule"], item.RawData["paired_jobs"], item.Chains)
+	return fp + fileFingerprint(item.Path, item.Program)
+}
+
+// timedOut reports whether the signature check of item's program or of any
+// of its resolved targets did not finish.
+func timedOut(item *scanner.PersistenceItem) bool {
+	if item.CodeSignature != nil && item.CodeSignature.TimedOut {
+		return true
+	}
+	for _, target := range item.ResolvedTargets {
+		if target.CodeSignature != nil && target.CodeSignature.TimedOut {
+			return true
+		}
+	}
+	return false
+}
+
+// targetsFingerprint identifies the current state of an item's resolved
+// targets: the script behind an interpreter can change while the plist and
+// the interpreter stay the same.
+func targetsFingerprint(targets []scanner.ResolvedTarget) string {
+	paths := make([]string, len(targets))
+	for i, target := range targets {
+		paths[i] = target.Path
+	}
+	return fileFingerprint(paths...)
+}
+
+func fileFingerprint(paths ...string) string {
+	var fp string
+	for _, path := range paths {
+		fp += path + "|"
+		if !filepath.IsAbs(path) {
+			continue
+		}
+		if info, err := os.Stat(sysroot.Path(path)); err == nil {
+			fp += fmt.Sprintf("%d|%d|%o|", info.Size(), info.ModTime().UnixNano(), info.Mode())
+		}
+	}
+	return fp
+}
+
+// Settings builds the settings key from values that change how items are
+// assessed, such as the scoring model. Files among them are identified by
+// path and modification time.
+func Settings(values []string, files []string) string {
+	settings := fmt.Sprint(values)
+	for _, path := range files {
+		settings += "|" + path
+		if info, err := os.Stat(path); err == nil {
+			settings += "@" + info.ModTime().UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return settings
+}
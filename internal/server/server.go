@@ -0,0 +1,229 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// ScanFunc performs one complete, risk-assessed scan.
+type ScanFunc func(ctx context.Context) (*scanner.ScanResult, error)
+
+// Server exposes scanning over a small token-authenticated HTTP API. Only
+// one scan runs at a time; the most recent result is kept in memory.
+type Server struct {
+	scan  ScanFunc
+	token string
+
+	mu       sync.Mutex
+	running  bool
+	latest   *scanner.ScanResult
+	lastErr  string
+	lastScan time.Time
+}
+
+func New(scan ScanFunc, token string) *Server {
+	return &Server{
+		scan:  scan,
+		token: token,
+	}
+}
+
+// Handler returns the API routes:
+//
+//	GET  /healthz               liveness and scan status (no auth)
+//	POST /api/v1/scans          start a scan; ?wait=true blocks and returns it
+//	GET  /api/v1/scans/latest   the most recent scan result
+//	GET  /api/v1/items          items from the latest scan, filtered by query
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.Handle("/api/v1/scans", s.authenticate(http.HandlerFunc(s.handleScans)))
+	mux.Handle("/api/v1/scans/latest", s.authenticate(http.HandlerFunc(s.handleLatest)))
+	mux.Handle("/api/v1/items", s.authenticate(http.HandlerFunc(s.handleItems)))
+	return mux
+}
+
+// RunScan performs a scan unless one is already in progress, in which case
+// it returns false.
+func (s *Server) RunScan(ctx context.Context) (*scanner.ScanResult, bool, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, false, nil
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	result, err := s.scan(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.lastScan = time.Now()
+	if err != nil {
+		s.lastErr = err.Error()
+		return nil, true, err
+	}
+	s.lastErr = ""
+	s.latest = result
+	return result, true, nil
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := map[string]interface{}{
+		"status":   "ok",
+		"scanning": s.running,
+	}
+	if !s.lastScan.IsZero() {
+		status["last_scan"] = s.lastScan
+	}
+	if s.lastErr != "" {
+		status["last_error"] = s.lastErr
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST to start a scan")
+		return
+	}
+
+	if r.URL.Query().Get("wait") == "true" {
+		result, started, err := s.RunScan(r.Context())
+		switch {
+		case !started:
+			writeError(w, http.StatusConflict, "a scan is already running")
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, result)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+	if running {
+		writeError(w, http.StatusConflict, "a scan is already running")
+		return
+	}
+
+	// Detach from the request so the scan outlives the connection
+	go s.RunScan(context.Background())
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+func (s *Server) handleLatest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	latest := s.latestResult()
+	if latest == nil {
+		writeError(w, http.StatusNotFound, "no scan has completed yet")
+		return
+	}
+	writeJSON(w, http.StatusOK, latest)
+}
+
+// handleItems filters the latest scan's items. Supported query parameters:
+// mechanism, min_risk, technique (exact), and q (case-insensitive substring
+// of label, path, or program).
+func (s *Server) handleItems(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	latest := s.latestResult()
+	if latest == nil {
+		writeError(w, http.StatusNotFound, "no scan has completed yet")
+		return
+	}
+
+	query := r.URL.Query()
+	minRank := 0
+	if minRisk := query.Get("min_risk"); minRisk != "" {
+		level, err := scanner.ParseRiskLevel(minRisk)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		minRank = level.Rank()
+	}
+	mechanism := query.Get("mechanism")
+	technique := query.Get("technique")
+	text := strings.ToLower(query.Get("q"))
+
+	items := []scanner.PersistenceItem{}
+	for _, item := range latest.Items {
+		if mechanism != "" && !strings.EqualFold(string(item.Mechanism), mechanism) {
+			continue
+		}
+		if item.Risk.Level.Rank() < minRank {
+			continue
+		}
+		if technique != "" && !contains(item.ATTACKTechniques, technique) {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(item.Label+"\n"+item.Path+"\n"+item.Program), text) {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scan_time": latest.StartTime,
+		"total":     len(items),
+		"items":     items,
+	})
+}
+
+func (s *Server) latestResult() *scanner.ScanResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latest
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package sigcache
+
+import "os"
+
+// inode is unavailable off unix; size and mtime still key the cache.
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
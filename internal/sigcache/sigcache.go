@@ -0,0 +1,160 @@
+package sigcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+)
+
+// Shared is the cache used by the signing heuristics and enrichers. It is
+// in-memory only until Load gives it a file.
+var Shared = New()
+
+// Cache remembers the results of code-signing checks such as codesign and
+// spctl, keyed by the command and the identity of the file it inspected
+// (path, inode, size, and modification time), so a binary referenced by
+// many items is verified once. Results for directories such as app bundles
+// are only cached in memory: a change deep inside a bundle does not alter
+// the directory's own metadata.
+type Cache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*Entry
+	used    map[string]bool
+}
+
+// Entry is one cached check result.
+type Entry struct {
+	Output   []byte    `json:"output"`
+	Err      string    `json:"error,omitempty"`
+	CachedAt time.Time `json:"cached_at"`
+}
+
+func New() *Cache {
+	return &Cache{
+		entries: make(map[string]*Entry),
+		used:    make(map[string]bool),
+	}
+}
+
+// Load reads entries saved by an earlier run from path and makes Save write
+// back to it. A missing file is not an error.
+func (c *Cache) Load(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.path = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading signing cache: %w", err)
+	}
+
+	var entries map[string]*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("parsing signing cache %s: %w", path, err)
+	}
+	for key, entry := range entries {
+		c.entries[key] = entry
+	}
+	return nil
+}
+
+// Save writes the file-backed entries used during this run to the path
+// given to Load. Entries for files that no longer match are dropped.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path == "" {
+		return nil
+	}
+
+	persisted := make(map[string]*Entry)
+	for key := range c.used {
+		persisted[key] = c.entries[key]
+	}
+
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("writing signing cache: %w", err)
+	}
+	return nil
+}
+
+// Do returns the cached result of the check named key against file, running
+// check if there is none. Timed-out checks are not cached.
+func (c *Cache) Do(key, file string, check func() ([]byte, error)) ([]byte, error) {
+	fullKey, persistent := c.key(key, file)
+
+	c.mu.Lock()
+	if entry, ok := c.entries[fullKey]; ok {
+		if persistent {
+			c.used[fullKey] = true
+		}
+		c.mu.Unlock()
+		return entry.result()
+	}
+	c.mu.Unlock()
+
+	output, err := check()
+	if errors.Is(err, command.ErrTimeout) {
+		return output, err
+	}
+
+	entry := &Entry{Output: output, CachedAt: time.Now()}
+	if err != nil {
+		entry.Err = err.Error()
+	}
+
+	c.mu.Lock()
+	c.entries[fullKey] = entry
+	if persistent {
+		c.used[fullKey] = true
+	}
+	c.mu.Unlock()
+
+	return output, err
+}
+
+// CombinedOutput runs an external command through the cache; file is the
+// path whose identity keys the result.
+func (c *Cache) CombinedOutput(file, name string, args ...string) ([]byte, error) {
+	return c.CombinedOutputTimeout(command.Timeout, file, name, args...)
+}
+
+// CombinedOutputTimeout is CombinedOutput with its own time limit for the
+// command. Results are cached under the same key either way.
+func (c *Cache) CombinedOutputTimeout(timeout time.Duration, file, name string, args ...string) ([]byte, error) {
+	key := name + " " + strings.Join(args, " ")
+	return c.Do(key, file, func() ([]byte, error) {
+		return command.CombinedOutputTimeout(timeout, name, args...)
+	})
+}
+
+// key builds the cache key and reports whether the result may be persisted.
+func (c *Cache) key(key, file string) (string, bool) {
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		return "mem|" + key + "|" + file, false
+	}
+	return fmt.Sprintf("%s|%s|%d|%d|%d", key, file, inode(info), info.Size(), info.ModTime().UnixNano()), true
+}
+
+func (e *Entry) result() ([]byte, error) {
+	if e.Err != "" {
+		return e.Output, errors.New(e.Err)
+	}
+	return e.Output, nil
+}
@@ -0,0 +1,143 @@
+// Package signing signs written scan results with an operator's Ed25519
+// key, so results collected on a compromised or customer machine can later
+// be shown not to have been altered since. Signatures are detached, in the
+// style of minisign: a .sig file next to the result holds the signature of
+// the result's SHA-512 digest together with a trusted comment recording
+// when, where, and by which key it was signed.
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	untrustedPrefix = "untrusted comment: "
+	trustedPrefix   = "trusted comment: "
+)
+
+// Signature is a verified signature's trusted comment and key.
+type Signature struct {
+	KeyID   string
+	Comment string
+}
+
+// ParsePrivateKey decodes an Ed25519 private key in PKCS#8 PEM, as written
+// by `openssl genpkey -algorithm ed25519`, or as the base64 of its 32-byte
+// seed or 64-byte key.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing signing key: %w", err)
+		}
+		private, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("signing key is not an Ed25519 key")
+		}
+		return private, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("signing key must be PEM or base64: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("signing key must be a %d-byte seed or %d-byte key", ed25519.SeedSize, ed25519.PrivateKeySize)
+}
+
+// ParsePublicKey decodes an Ed25519 public key in PKIX PEM, as written by
+// `openssl pkey -pubout`, or as the base64 of its 32 bytes.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing public key: %w", err)
+		}
+		public, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is not an Ed25519 key")
+		}
+		return public, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be PEM or %d base64-encoded bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// KeyID is a short fingerprint of key, shown when signing and verifying.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sign returns a detached signature of data carrying comment.
+func Sign(data []byte, key ed25519.PrivateKey, comment string) []byte {
+	comment = strings.ReplaceAll(comment, "\n", " ")
+	signature := ed25519.Sign(key, message(data, comment))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%ssignature from macos-persist-scan key %s\n", untrustedPrefix, KeyID(key.Public().(ed25519.PublicKey)))
+	fmt.Fprintln(&buf, base64.StdEncoding.EncodeToString(signature))
+	fmt.Fprintf(&buf, "%s%s\n", trustedPrefix, comment)
+	return buf.Bytes()
+}
+
+// Verify checks a detached signature of data made by Sign.
+func Verify(data, signature []byte, key ed25519.PublicKey) (*Signature, error) {
+	lines := strings.Split(strings.TrimRight(string(signature), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], untrustedPrefix) || !strings.HasPrefix(lines[2], trustedPrefix) {
+		return nil, fmt.Errorf("not a macos-persist-scan signature file")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("decoding signature: malformed")
+	}
+
+	comment := strings.TrimPrefix(lines[2], trustedPrefix)
+	if !ed25519.Verify(key, message(data, comment), sig) {
+		return nil, fmt.Errorf("signature does not verify with key %s: the file or its trusted comment was altered, or another key signed it", KeyID(key))
+	}
+	return &Signature{KeyID: KeyID(key), Comment: comment}, nil
+}
+
+// message is what is signed: the SHA-512 of the data, then the trusted
+// comment, so neither can be changed without breaking the signature.
+func message(data []byte, comment string) []byte {
+	digest := sha512.Sum512(data)
+	return append(digest[:], comment...)
+}
+
+// SignFile writes a detached signature of the file at path to path.sig,
+// recording the time, the signing host, and the file's name.
+func SignFile(path string, key ed25519.PrivateKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s to sign: %w", path, err)
+	}
+	hostname, _ := os.Hostname()
+	comment := fmt.Sprintf("timestamp:%s host:%s file:%s", time.Now().UTC().Format(time.RFC3339), hostname, filepath.Base(path))
+	if err := os.WriteFile(path+".sig", Sign(data, key, comment), 0600); err != nil {
+		return fmt.Errorf("writing signature of %s: %w", path, err)
+	}
+	return nil
+}
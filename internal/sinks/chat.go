@@ -0,0 +1,95 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// maxChatDetails caps the Critical findings described individually in a
+// chat message; the rest are counted. Slack and Teams reject very large
+// messages.
+const maxChatDetails = 10
+
+// summaryText is the one-line headline of a chat alert.
+func summaryText(alert *scanner.ScanResult) string {
+	host := hostName(alert)
+
+	var counts []string
+	for i := len(scanner.RiskLevels) - 1; i >= 0; i-- {
+		level := scanner.RiskLevels[i]
+		if n := alert.RiskSummary[level]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, level))
+		}
+	}
+
+	noun := "findings"
+	if len(alert.Items) == 1 {
+		noun = "finding"
+	}
+	return fmt.Sprintf("macos-persist-scan found %d persistence %s on %s (%s)", len(alert.Items), noun, host, strings.Join(counts, ", "))
+}
+
+func hostName(alert *scanner.ScanResult) string {
+	if alert.Hostname == "" {
+		return "unknown host"
+	}
+	return alert.Hostname
+}
+
+// criticalItems returns the Critical items to describe individually and
+// how many more were left out.
+func criticalItems(alert *scanner.ScanResult) ([]*scanner.PersistenceItem, int) {
+	var items []*scanner.PersistenceItem
+	more := 0
+	for i := range alert.Items {
+		if alert.Items[i].Risk.Level != scanner.RiskCritical {
+			continue
+		}
+		if len(items) == maxChatDetails {
+			more++
+			continue
+		}
+		items = append(items, &alert.Items[i])
+	}
+	return items, more
+}
+
+// itemFacts are the labelled details shown for one finding.
+func itemFacts(item *scanner.PersistenceItem) [][2]string {
+	facts := [][2]string{
+		{"Mechanism", string(item.Mechanism)},
+		{"Path", item.Path},
+	}
+	if item.Program != "" {
+		facts = append(facts, [2]string{"Program", item.Program})
+	}
+	if len(item.Risk.Reasons) > 0 {
+		facts = append(facts, [2]string{"Reasons", strings.Join(item.Risk.Reasons, "; ")})
+	}
+	facts = append(facts, [2]string{"ID", item.ID})
+	return facts
+}
+
+// postJSON POSTs payload to url and fails on any non-2xx response.
+func postJSON(client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
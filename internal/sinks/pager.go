@@ -0,0 +1,215 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+const (
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieAlertsURL  = "https://api.opsgenie.com/v2/alerts"
+)
+
+// pager is implemented by sinks that open one incident or ticket per
+// finding rather than sending the alert as a whole. Incidents are opened
+// for Critical findings and for findings that are new since the baseline
+// scan, tickets for new High and Critical findings.
+type pager interface {
+	Page(alert *scanner.ScanResult, added map[string]bool) error
+}
+
+// pageItems returns the alerting items worth paging for: Critical ones,
+// and any that are not in the baseline. added is nil without a baseline.
+func pageItems(alert *scanner.ScanResult, added map[string]bool) []*scanner.PersistenceItem {
+	var items []*scanner.PersistenceItem
+	for i := range alert.Items {
+		if alert.Items[i].Risk.Level == scanner.RiskCritical || added[alert.Items[i].ID] {
+			items = append(items, &alert.Items[i])
+		}
+	}
+	return items
+}
+
+// dedupKey identifies a finding across scans, so repeat scans update the
+// open incident instead of paging again.
+func dedupKey(alert *scanner.ScanResult, item *scanner.PersistenceItem) string {
+	return fmt.Sprintf("macos-persist-scan/%s/%s", alert.Hostname, item.ID)
+}
+
+func pageSummary(alert *scanner.ScanResult, item *scanner.PersistenceItem, added map[string]bool) string {
+	what := "persistence"
+	if added[item.ID] {
+		what = "new persistence"
+	}
+	return fmt.Sprintf("%s %s on %s: %s (%s)", item.Risk.Level, what, hostName(alert), item.Label, item.Mechanism)
+}
+
+func pageDetails(item *scanner.PersistenceItem, added map[string]bool) map[string]interface{} {
+	details := map[string]interface{}{
+		"id":         item.ID,
+		"mechanism":  item.Mechanism,
+		"path":       item.Path,
+		"risk_level": item.Risk.Level,
+		"risk_score": item.Risk.Score,
+		"reasons":    item.Risk.Reasons,
+		"new":        added[item.ID],
+	}
+	if item.Program != "" {
+		details["program"] = item.Program
+	}
+	if len(item.ATTACKTechniques) > 0 {
+		details["attack_techniques"] = item.ATTACKTechniques
+	}
+	return details
+}
+
+// PagerDutySink triggers a PagerDuty incident through the Events API v2
+// for each Critical or new finding.
+type PagerDutySink struct {
+	RoutingKey string
+	URL        string
+	client     *http.Client
+}
+
+func NewPagerDutySink(routingKey string) *PagerDutySink {
+	return &PagerDutySink{
+		RoutingKey: routingKey,
+		URL:        pagerDutyEventsURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *PagerDutySink) Name() string {
+	return "pagerduty"
+}
+
+func (s *PagerDutySink) Send(alert *scanner.ScanResult) error {
+	return s.Page(alert, nil)
+}
+
+// pagerDutySeverity maps risk levels onto the Events API severities.
+var pagerDutySeverity = map[scanner.RiskLevel]string{
+	scanner.RiskCritical: "critical",
+	scanner.RiskHigh:     "error",
+	scanner.RiskMedium:   "warning",
+}
+
+func (s *PagerDutySink) Page(alert *scanner.ScanResult, added map[string]bool) error {
+	for _, item := range pageItems(alert, added) {
+		severity, ok := pagerDutySeverity[item.Risk.Level]
+		if !ok {
+			severity = "info"
+		}
+		event := map[string]interface{}{
+			"routing_key":  s.RoutingKey,
+			"event_action": "trigger",
+			"dedup_key":    dedupKey(alert, item),
+			"payload": map[string]interface{}{
+				"summary":        truncate(pageSummary(alert, item, added), 1024),
+				"source":         hostName(alert),
+				"severity":       severity,
+				"component":      string(item.Mechanism),
+				"class":          "persistence",
+				"custom_details": pageDetails(item, added),
+			},
+		}
+		if err := postJSON(s.client, s.URL, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpsgenieSink creates an Opsgenie alert for each Critical or new finding.
+type OpsgenieSink struct {
+	APIKey string
+	URL    string
+	client *http.Client
+}
+
+func NewOpsgenieSink(apiKey string) *OpsgenieSink {
+	return &OpsgenieSink{
+		APIKey: apiKey,
+		URL:    opsgenieAlertsURL,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *OpsgenieSink) Name() string {
+	return "opsgenie"
+}
+
+func (s *OpsgenieSink) Send(alert *scanner.ScanResult) error {
+	return s.Page(alert, nil)
+}
+
+// opsgeniePriority maps risk levels onto Opsgenie priorities.
+var opsgeniePriority = map[scanner.RiskLevel]string{
+	scanner.RiskCritical: "P1",
+	scanner.RiskHigh:     "P2",
+	scanner.RiskMedium:   "P3",
+	scanner.RiskLow:      "P4",
+}
+
+func (s *OpsgenieSink) Page(alert *scanner.ScanResult, added map[string]bool) error {
+	for _, item := range pageItems(alert, added) {
+		priority, ok := opsgeniePriority[item.Risk.Level]
+		if !ok {
+			priority = "P5"
+		}
+
+		// Opsgenie details are string-valued
+		details := make(map[string]string)
+		for k, v := range pageDetails(item, added) {
+			if list, ok := v.([]string); ok {
+				details[k] = strings.Join(list, "; ")
+			} else {
+				details[k] = fmt.Sprint(v)
+			}
+		}
+
+		body, err := json.Marshal(map[string]interface{}{
+			"message":     truncate(pageSummary(alert, item, added), 130),
+			"alias":       dedupKey(alert, item),
+			"description": strings.Join(item.Risk.Reasons, "\n"),
+			"priority":    priority,
+			"source":      "macos-persist-scan",
+			"entity":      hostName(alert),
+			"tags":        []string{"persistence", string(item.Mechanism)},
+			"details":     details,
+		})
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "GenieKey "+s.APIKey)
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s returned %s", s.URL, resp.Status)
+		}
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,158 @@
+// Package sinks delivers alerts about risky findings to systems outside the
+// host, so a scheduled scan can report without anyone reading its output.
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Sink receives the findings of a scan that met the alert threshold.
+type Sink interface {
+	Send(alert *scanner.ScanResult) error
+	Name() string
+}
+
+// keyVariables names the environment variable each sink that
+// authenticates with a key reads it from. Keys are never taken on the
+// command line, where ps and launchd plists show them to every user.
+var keyVariables = map[string]string{
+	"pagerduty": "PAGERDUTY_ROUTING_KEY",
+	"opsgenie":  "OPSGENIE_API_KEY",
+}
+
+// Credentials names the environment variables the sink described by spec
+// reads its credentials from, or returns "" if it needs none.
+func Credentials(spec string) string {
+	kind, _, _ := strings.Cut(spec, "=")
+	switch kind {
+	case "github":
+		return "GITHUB_TOKEN"
+	case "jira":
+		return "JIRA_EMAIL and JIRA_API_TOKEN, or JIRA_TOKEN"
+	}
+	return keyVariables[kind]
+}
+
+// Parse builds a sink from a --sink value: webhook=<url>, slack=<url>,
+// teams=<url>, pagerduty, opsgenie, github=<owner>/<repo>, jira=<site
+// URL>/<project key>, syslog (the local syslog daemon), or
+// syslog=<udp|tcp>://host:port.
+func Parse(spec string) (Sink, error) {
+	kind, target, _ := strings.Cut(spec, "=")
+	switch kind {
+	case "webhook", "slack", "teams":
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%s sink needs an http or https URL, got %q", kind, target)
+		}
+		switch kind {
+		case "slack":
+			return NewSlackSink(target), nil
+		case "teams":
+			return NewTeamsSink(target), nil
+		}
+		return NewWebhookSink(target), nil
+	case "pagerduty", "opsgenie":
+		variable := keyVariables[kind]
+		if target != "" {
+			return nil, fmt.Errorf("%s sink reads its key from %s, not the command line, where other users can see it", kind, variable)
+		}
+		key := os.Getenv(variable)
+		if key == "" {
+			return nil, fmt.Errorf("%s sink needs %s set", kind, variable)
+		}
+		if kind == "pagerduty" {
+			return NewPagerDutySink(key), nil
+		}
+		return NewOpsgenieSink(key), nil
+	case "github":
+		if owner, repo, ok := strings.Cut(target, "/"); !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return nil, fmt.Errorf("github sink needs a repository, as github=<owner>/<repo>, got %q", target)
+		}
+		return NewGitHubSink(target), nil
+	case "jira":
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("jira sink needs a site URL and project key, as jira=https://example.atlassian.net/PROJ, got %q", target)
+		}
+		site, project := path.Split(strings.TrimSuffix(target, "/"))
+		if project == "" || u.Path == "" || u.Path == "/" {
+			return nil, fmt.Errorf("jira sink needs a project key after the site URL, as jira=https://example.atlassian.net/PROJ, got %q", target)
+		}
+		return NewJiraSink(site, project), nil
+	case "syslog":
+		if target == "" {
+			return NewSyslogSink("", ""), nil
+		}
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+			return nil, fmt.Errorf("syslog sink needs a udp:// or tcp:// address, got %q", target)
+		}
+		return NewSyslogSink(u.Scheme, u.Host), nil
+	default:
+		return nil, fmt.Errorf("unknown sink %q (valid: webhook=<url>, slack=<url>, teams=<url>, pagerduty, opsgenie, github=<owner>/<repo>, jira=<url>/<project>, syslog, syslog=<udp|tcp>://host:port)", spec)
+	}
+}
+
+// Alert returns a copy of result holding only the items at or above
+// threshold, or nil when there are none.
+func Alert(result *scanner.ScanResult, threshold scanner.RiskLevel) *scanner.ScanResult {
+	alert := *result
+	alert.Items = nil
+	for _, item := range result.Items {
+		if item.Risk.Level.Rank() >= threshold.Rank() {
+			alert.Items = append(alert.Items, item)
+		}
+	}
+	if len(alert.Items) == 0 {
+		return nil
+	}
+	alert.Summarize()
+	return &alert
+}
+
+// Notify sends the items of result at or above threshold to every sink.
+// Paging sinks are also told which of them are missing from baseline, the
+// previous scan, so new persistence pages even below Critical; baseline
+// may be nil. Each sink is tried even if an earlier one fails.
+func Notify(sinks []Sink, result *scanner.ScanResult, threshold scanner.RiskLevel, baseline *scanner.ScanResult) error {
+	alert := Alert(result, threshold)
+	if alert == nil {
+		return nil
+	}
+
+	var added map[string]bool
+	if baseline != nil {
+		known := make(map[string]bool, len(baseline.Items))
+		for _, item := range baseline.Items {
+			known[item.ID] = true
+		}
+		added = make(map[string]bool)
+		for _, item := range alert.Items {
+			if !known[item.ID] {
+				added[item.ID] = true
+			}
+		}
+	}
+
+	var errs []error
+	for _, sink := range sinks {
+		var err error
+		if p, ok := sink.(pager); ok {
+			err = p.Page(alert, added)
+		} else {
+			err = sink.Send(alert)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s sink: %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
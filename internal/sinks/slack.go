@@ -0,0 +1,67 @@
+package sinks
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// SlackSink posts a summary of the alert, with details of each Critical
+// finding, to a Slack incoming webhook.
+type SlackSink struct {
+	URL    string
+	client *http.Client
+}
+
+func NewSlackSink(url string) *SlackSink {
+	return &SlackSink{
+		URL:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *SlackSink) Name() string {
+	return "slack"
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (s *SlackSink) Send(alert *scanner.ScanResult) error {
+	summary := summaryText(alert)
+	blocks := []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*" + summary + "*"}}}
+
+	items, more := criticalItems(alert)
+	for _, item := range items {
+		var b strings.Builder
+		fmt.Fprintf(&b, ":rotating_light: *%s*", slackEscape(item.Label))
+		for _, fact := range itemFacts(item) {
+			fmt.Fprintf(&b, "\n*%s:* `%s`", fact[0], slackEscape(fact[1]))
+		}
+		blocks = append(blocks, slackBlock{Type: "divider"}, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: b.String()}})
+	}
+	if more > 0 {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: fmt.Sprintf("_…and %d more Critical findings_", more)}})
+	}
+
+	// text is the fallback shown in notifications
+	return postJSON(s.client, s.URL, map[string]interface{}{
+		"text":   summary,
+		"blocks": blocks,
+	})
+}
+
+// slackEscape escapes the characters Slack treats as markup.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "`", "'").Replace(s)
+}
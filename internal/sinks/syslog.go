@@ -0,0 +1,45 @@
+package sinks
+
+import (
+	"bytes"
+	"log/syslog"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// SyslogSink writes one CEF message per alerting item to syslog.
+type SyslogSink struct {
+	// Network and Addr name a remote syslog server; both empty means the
+	// local daemon.
+	Network string
+	Addr    string
+}
+
+func NewSyslogSink(network, addr string) *SyslogSink {
+	return &SyslogSink{Network: network, Addr: addr}
+}
+
+func (s *SyslogSink) Name() string {
+	return "syslog"
+}
+
+func (s *SyslogSink) Send(alert *scanner.ScanResult) error {
+	data, err := (&output.CEFFormatter{}).Format(alert)
+	if err != nil {
+		return err
+	}
+
+	w, err := syslog.Dial(s.Network, s.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "macos-persist-scan")
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		if err := w.Warning(string(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package sinks
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// TeamsSink posts a summary of the alert, with details of each Critical
+// finding, as an Adaptive Card to a Microsoft Teams incoming webhook or
+// Workflows webhook.
+type TeamsSink struct {
+	URL    string
+	client *http.Client
+}
+
+func NewTeamsSink(url string) *TeamsSink {
+	return &TeamsSink{
+		URL:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *TeamsSink) Name() string {
+	return "teams"
+}
+
+func (s *TeamsSink) Send(alert *scanner.ScanResult) error {
+	body := []map[string]interface{}{{
+		"type":   "TextBlock",
+		"text":   summaryText(alert),
+		"weight": "Bolder",
+		"size":   "Medium",
+		"wrap":   true,
+	}}
+
+	items, more := criticalItems(alert)
+	for _, item := range items {
+		var facts []map[string]string
+		for _, fact := range itemFacts(item) {
+			facts = append(facts, map[string]string{"title": fact[0], "value": fact[1]})
+		}
+		body = append(body,
+			map[string]interface{}{"type": "TextBlock", "text": "Critical: " + item.Label, "color": "Attention", "weight": "Bolder", "separator": true, "wrap": true},
+			map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+	if more > 0 {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": fmt.Sprintf("…and %d more Critical findings", more), "isSubtle": true, "wrap": true})
+	}
+
+	return postJSON(s.client, s.URL, map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	})
+}
@@ -0,0 +1,350 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/output"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// ticketItems returns the alerting items that get a ticket: High and
+// Critical findings that are new since the baseline scan, or all of them
+// when there is no baseline (added is nil).
+func ticketItems(alert *scanner.ScanResult, added map[string]bool) []*scanner.PersistenceItem {
+	var items []*scanner.PersistenceItem
+	for i := range alert.Items {
+		if alert.Items[i].Risk.Level.Rank() < scanner.RiskHigh.Rank() {
+			continue
+		}
+		if added == nil || added[alert.Items[i].ID] {
+			items = append(items, &alert.Items[i])
+		}
+	}
+	return items
+}
+
+// ticketTitle names a finding's ticket. The item ID and host in it are
+// what later runs search for to find the ticket again.
+func ticketTitle(alert *scanner.ScanResult, item *scanner.PersistenceItem) string {
+	return fmt.Sprintf("%s persistence: %s [%s %s]", item.Risk.Level, item.Label, hostName(alert), item.ID)
+}
+
+// findingFields lists the details of a finding shown in a ticket body, by
+// name. Empty values are left out.
+func findingFields(alert *scanner.ScanResult, item *scanner.PersistenceItem) [][2]string {
+	var fields [][2]string
+	row := func(name, value string) {
+		if value != "" {
+			fields = append(fields, [2]string{name, value})
+		}
+	}
+	row("Host", hostName(alert))
+	row("Finding ID", item.ID)
+	row("Mechanism", string(item.Mechanism))
+	row("Path", item.Path)
+	row("Program", item.Program)
+	row("Arguments", strings.Join(item.ProgramArgs, " "))
+	row("User", item.User)
+	row("Risk score", fmt.Sprintf("%.2f (confidence %.2f)", item.Risk.Score, item.Risk.Confidence))
+	row("ATT&CK", strings.Join(item.ATTACKTechniques, ", "))
+	if item.ProgramFile != nil {
+		row("Program SHA-256", item.ProgramFile.SHA256)
+	}
+	if item.ConfigFile != nil {
+		row("Config SHA-256", item.ConfigFile.SHA256)
+	}
+	row("Scanned", alert.EndTime.UTC().Format(time.RFC3339))
+	return fields
+}
+
+// findingHelp returns the investigation guidance for the heuristics a
+// finding triggered.
+func findingHelp(item *scanner.PersistenceItem) []string {
+	var help []string
+	for _, h := range item.Risk.Heuristics {
+		if text := output.RuleHelp(h.Name); h.Triggered && text != "" {
+			help = append(help, text)
+		}
+	}
+	return help
+}
+
+// findingMarkdown describes a finding for a GitHub issue body.
+func findingMarkdown(alert *scanner.ScanResult, item *scanner.PersistenceItem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s: %s\n\n", item.Risk.Level, item.Label)
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	for _, field := range findingFields(alert, item) {
+		fmt.Fprintf(&b, "| %s | `%s` |\n", field[0], strings.ReplaceAll(field[1], "|", "\\|"))
+	}
+
+	if len(item.Risk.Reasons) > 0 {
+		b.WriteString("\n### Reasons\n\n")
+		for _, reason := range item.Risk.Reasons {
+			fmt.Fprintf(&b, "- %s\n", reason)
+		}
+	}
+	if help := findingHelp(item); len(help) > 0 {
+		b.WriteString("\n### Investigation\n\n")
+		for _, text := range help {
+			fmt.Fprintf(&b, "- %s\n", text)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n_Reported by macos-persist-scan. Inspect with `macos-persist-scan explain %s`._\n", item.ID)
+	return b.String()
+}
+
+// wikiEscaper escapes the characters Jira wiki markup would read as table
+// cells or macros.
+var wikiEscaper = strings.NewReplacer("|", "\\|", "{", "\\{", "}", "\\}", "[", "\\[", "]", "\\]", "*", "\\*", "_", "\\_")
+
+// findingWiki describes a finding for a Jira issue description, in the
+// wiki markup the REST API v2 takes.
+func findingWiki(alert *scanner.ScanResult, item *scanner.PersistenceItem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "h2. %s: %s\n\n", item.Risk.Level, wikiEscaper.Replace(item.Label))
+	for _, field := range findingFields(alert, item) {
+		fmt.Fprintf(&b, "||%s|%s|\n", field[0], wikiEscaper.Replace(field[1]))
+	}
+
+	if len(item.Risk.Reasons) > 0 {
+		b.WriteString("\nh3. Reasons\n\n")
+		for _, reason := range item.Risk.Reasons {
+			fmt.Fprintf(&b, "* %s\n", wikiEscaper.Replace(reason))
+		}
+	}
+	if help := findingHelp(item); len(help) > 0 {
+		b.WriteString("\nh3. Investigation\n\n")
+		for _, text := range help {
+			fmt.Fprintf(&b, "* %s\n", wikiEscaper.Replace(text))
+		}
+	}
+
+	fmt.Fprintf(&b, "\n_Reported by macos-persist-scan. Inspect with {{macos-persist-scan explain %s}}._\n", item.ID)
+	return b.String()
+}
+
+// doJSON sends a JSON request with the given headers and decodes a JSON
+// response into out, if out is not nil.
+func doJSON(client *http.Client, method, url string, headers map[string]string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s", method, url, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// GitHubSink opens an issue in a GitHub repository for each new High or
+// Critical finding, or updates the open issue already filed for it. A
+// closed issue is left closed, so a finding triaged as expected is not
+// filed again. The token comes from GITHUB_TOKEN.
+type GitHubSink struct {
+	Repo   string
+	Token  string
+	API    string
+	client *http.Client
+}
+
+func NewGitHubSink(repo string) *GitHubSink {
+	return &GitHubSink{
+		Repo:   repo,
+		Token:  os.Getenv("GITHUB_TOKEN"),
+		API:    "https://api.github.com",
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *GitHubSink) Name() string {
+	return "github"
+}
+
+func (s *GitHubSink) Send(alert *scanner.ScanResult) error {
+	return s.Page(alert, nil)
+}
+
+// Page files the findings ticketItems selects.
+func (s *GitHubSink) Page(alert *scanner.ScanResult, added map[string]bool) error {
+	headers := map[string]string{
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+	if s.Token != "" {
+		headers["Authorization"] = "Bearer " + s.Token
+	}
+
+	for _, item := range ticketItems(alert, added) {
+		title := ticketTitle(alert, item)
+		body := findingMarkdown(alert, item)
+
+		var found struct {
+			Items []struct {
+				Number int    `json:"number"`
+				State  string `json:"state"`
+			} `json:"items"`
+		}
+		query := fmt.Sprintf(`repo:%s is:issue in:title "%s %s"`, s.Repo, hostName(alert), item.ID)
+		if err := doJSON(s.client, http.MethodGet, s.API+"/search/issues?q="+url.QueryEscape(query), headers, nil, &found); err != nil {
+			return err
+		}
+
+		if len(found.Items) > 0 {
+			if found.Items[0].State != "open" {
+				continue
+			}
+			endpoint := fmt.Sprintf("%s/repos/%s/issues/%d", s.API, s.Repo, found.Items[0].Number)
+			if err := doJSON(s.client, http.MethodPatch, endpoint, headers, map[string]string{"title": title, "body": body}, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		issue := map[string]interface{}{
+			"title":  title,
+			"body":   body,
+			"labels": []string{"macos-persist-scan", strings.ToLower(string(item.Risk.Level))},
+		}
+		if err := doJSON(s.client, http.MethodPost, s.API+"/repos/"+s.Repo+"/issues", headers, issue, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JiraSink opens a Jira issue for each new High or Critical finding, or
+// updates the unresolved issue already filed for it; a resolved issue is
+// left alone. Findings are matched by a label derived from the hostname and
+// item ID. Jira Cloud credentials come from JIRA_EMAIL and JIRA_API_TOKEN;
+// a Jira Server or Data Center personal access token can be given in
+// JIRA_TOKEN instead.
+type JiraSink struct {
+	// BaseURL is the site, such as https://example.atlassian.net, and
+	// Project the key issues are created in.
+	BaseURL   string
+	Project   string
+	IssueType string
+	client    *http.Client
+}
+
+func NewJiraSink(baseURL, project string) *JiraSink {
+	return &JiraSink{
+		BaseURL:   strings.TrimSuffix(baseURL, "/"),
+		Project:   project,
+		IssueType: "Task",
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *JiraSink) Name() string {
+	return "jira"
+}
+
+func (s *JiraSink) headers() map[string]string {
+	headers := make(map[string]string)
+	if token := os.Getenv("JIRA_TOKEN"); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	} else if email := os.Getenv("JIRA_EMAIL"); email != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(email+":"+os.Getenv("JIRA_API_TOKEN")))
+	}
+	return headers
+}
+
+func (s *JiraSink) Send(alert *scanner.ScanResult) error {
+	return s.Page(alert, nil)
+}
+
+// Page files the findings ticketItems selects.
+func (s *JiraSink) Page(alert *scanner.ScanResult, added map[string]bool) error {
+	headers := s.headers()
+	// Jira Cloud removed the v2 search in favor of search/jql; Server and
+	// Data Center, which take personal access tokens, only have v2
+	searchURL := s.BaseURL + "/rest/api/3/search/jql"
+	if os.Getenv("JIRA_TOKEN") != "" {
+		searchURL = s.BaseURL + "/rest/api/2/search"
+	}
+
+	for _, item := range ticketItems(alert, added) {
+		label := fmt.Sprintf("macos-persist-scan-%s-%s", strings.ReplaceAll(hostName(alert), " ", "_"), item.ID)
+		summary := ticketTitle(alert, item)
+		description := findingWiki(alert, item)
+
+		var found struct {
+			Issues []struct {
+				ID     string `json:"id"`
+				Fields struct {
+					Status struct {
+						StatusCategory struct {
+							Key string `json:"key"`
+						} `json:"statusCategory"`
+					} `json:"status"`
+				} `json:"fields"`
+			} `json:"issues"`
+		}
+		jql := fmt.Sprintf(`project = "%s" AND labels = "%s" ORDER BY created DESC`, s.Project, label)
+		search := map[string]interface{}{"jql": jql, "fields": []string{"status"}, "maxResults": 1}
+		if err := doJSON(s.client, http.MethodPost, searchURL, headers, search, &found); err != nil {
+			return err
+		}
+
+		if len(found.Issues) > 0 {
+			if found.Issues[0].Fields.Status.StatusCategory.Key == "done" {
+				continue
+			}
+			update := map[string]interface{}{"fields": map[string]string{"summary": summary, "description": description}}
+			if err := doJSON(s.client, http.MethodPut, s.BaseURL+"/rest/api/2/issue/"+found.Issues[0].ID, headers, update, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		issue := map[string]interface{}{
+			"fields": map[string]interface{}{
+				"project":     map[string]string{"key": s.Project},
+				"issuetype":   map[string]string{"name": s.IssueType},
+				"summary":     summary,
+				"description": description,
+				"labels":      []string{"macos-persist-scan", label},
+			},
+		}
+		if err := doJSON(s.client, http.MethodPost, s.BaseURL+"/rest/api/2/issue", headers, issue, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package sinks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// WebhookSink POSTs the alerting scan result as JSON.
+type WebhookSink struct {
+	URL    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		URL:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Send(alert *scanner.ScanResult) error {
+	return postJSON(s.client, s.URL, alert)
+}
@@ -0,0 +1,208 @@
+package suppress
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/diff"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Statuses record the triage decision behind an entry.
+const (
+	// StatusExpected marks known-good software.
+	StatusExpected = "expected"
+	// StatusSuppressed marks findings accepted as noise. Entries without
+	// a status are suppressions.
+	StatusSuppressed = "suppressed"
+	// StatusInvestigate marks findings under investigation. They stay in
+	// scan results, but triage does not ask about them again.
+	StatusInvestigate = "investigate"
+)
+
+// Entry records the triage decision on one persistence item. Unless it
+// marks the item for investigation, it hides the item from scan results.
+type Entry struct {
+	// Key identifies the item the same way scan diffs do. Hand-written
+	// entries may give the item's ID instead. An entry with an ID matches
+	// only that ID, which covers the item's program.
+	Key string `json:"key,omitempty"`
+	ID  string `json:"id,omitempty"`
+	// Command, if set, is a hash of the program and arguments the item ran
+	// when it was triaged. An item whose command has changed since is
+	// reported again.
+	Command   string                `json:"command,omitempty"`
+	Mechanism scanner.MechanismType `json:"mechanism"`
+	Label     string                `json:"label,omitempty"`
+	Path      string                `json:"path"`
+	Status    string                `json:"status,omitempty"`
+	Reason    string                `json:"reason,omitempty"`
+	CreatedAt time.Time             `json:"created_at"`
+	// Expires, if set, is when the entry stops applying.
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// Active reports whether the entry applies at now.
+func (e *Entry) Active(now time.Time) bool {
+	return e.Expires == nil || now.Before(*e.Expires)
+}
+
+// statusName returns the entry's status, counting entries without one as
+// suppressions.
+func (e *Entry) statusName() string {
+	if e.Status == "" {
+		return StatusSuppressed
+	}
+	return e.Status
+}
+
+// Hides reports whether the entry removes its item from scan results.
+func (e *Entry) Hides() bool {
+	return e.Status != StatusInvestigate
+}
+
+// List is a suppression file.
+type List struct {
+	Entries []Entry `json:"suppressions"`
+}
+
+// Load reads the suppression file at path. A missing file is an empty list.
+func Load(path string) (*List, error) {
+	list := &List{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading suppressions: %w", err)
+	}
+
+	if err := json.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("parsing suppressions %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// Save writes the list to path.
+func (l *List) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("writing suppressions: %w", err)
+	}
+	return nil
+}
+
+// Add suppresses item, replacing any existing entry for it.
+func (l *List) Add(item *scanner.PersistenceItem, reason string, expires *time.Time) {
+	l.Record(item, StatusSuppressed, reason, expires)
+}
+
+// Record adds an entry with the given triage status for item, replacing
+// any existing entry for it.
+func (l *List) Record(item *scanner.PersistenceItem, status, reason string, expires *time.Time) {
+	entry := Entry{
+		Key:       diff.Key(item),
+		ID:        item.ID,
+		Command:   commandHash(item),
+		Mechanism: item.Mechanism,
+		Label:     item.Label,
+		Path:      item.Path,
+		Status:    status,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+		Expires:   expires,
+	}
+
+	for i := range l.Entries {
+		if l.Entries[i].Key == entry.Key {
+			l.Entries[i] = entry
+			return
+		}
+	}
+	l.Entries = append(l.Entries, entry)
+}
+
+// Match returns the active entry suppressing item, if any. An entry for
+// the item that no longer matches its program or arguments is stale and
+// suppresses nothing.
+func (l *List) Match(item *scanner.PersistenceItem) *Entry {
+	entry, _ := l.match(item)
+	return entry
+}
+
+// match returns the active entry suppressing item, or else the active
+// entry for the same item whose command has changed.
+func (l *List) match(item *scanner.PersistenceItem) (matched, stale *Entry) {
+	key := diff.Key(item)
+	now := time.Now()
+	for i := range l.Entries {
+		entry := &l.Entries[i]
+		if !entry.Active(now) {
+			continue
+		}
+		if entry.ID != "" {
+			if entry.ID != item.ID {
+				// Same plist and label, different program
+				if entry.Key == key {
+					stale = entry
+				}
+				continue
+			}
+		} else if entry.Key != key {
+			continue
+		}
+		if entry.Command != "" && entry.Command != commandHash(item) {
+			stale = entry
+			continue
+		}
+		return entry, nil
+	}
+	return nil, stale
+}
+
+// commandHash identifies the program and arguments item runs.
+func commandHash(item *scanner.PersistenceItem) string {
+	sum := sha256.Sum256([]byte(item.Program + "\x00" + strings.Join(item.ProgramArgs, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Filter removes suppressed items from result and returns how many were
+// removed. Items under investigation are kept. The risk summary is
+// recomputed.
+func (l *List) Filter(result *scanner.ScanResult) int {
+	if len(l.Entries) == 0 {
+		return 0
+	}
+
+	kept := result.Items[:0]
+	for i := range result.Items {
+		item := &result.Items[i]
+		entry, stale := l.match(item)
+		if stale != nil {
+			slog.Warn("suppression is stale: the item's program changed since it was triaged", "id", item.ID, "path", item.Path)
+			item.Risk.Reasons = append(item.Risk.Reasons, fmt.Sprintf("Program changed since it was triaged as %s on %s",
+				stale.statusName(), stale.CreatedAt.Format("2006-01-02")))
+		}
+		if entry == nil || !entry.Hides() {
+			kept = append(kept, *item)
+		}
+	}
+	removed := len(result.Items) - len(kept)
+	result.Items = kept
+	result.Summarize()
+
+	return removed
+}
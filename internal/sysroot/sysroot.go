@@ -0,0 +1,156 @@
+package sysroot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// Root is the directory treated as the scanned system's "/", such as a
+// forensic image mounted at /Volumes/evidence. Results report paths as they
+// appear on the scanned system; Path maps them back to the local disk.
+var Root = ""
+
+// DataVolume is set when Root is an APFS Data volume, such as a local
+// snapshot of /System/Volumes/Data, rather than a whole system. The Data
+// volume keeps /etc, /var, and /tmp under /private, where the System
+// volume's links point, and lacks everything on the System volume.
+var DataVolume = false
+
+// dataVolumeLinks are the system paths that are links into /private, most
+// specific first.
+var dataVolumeLinks = []struct{ path, data string }{
+	{"/usr/lib/cron", "/private/var/at"},
+	{"/etc", "/private/etc"},
+	{"/var", "/private/var"},
+	{"/tmp", "/private/tmp"},
+}
+
+// IsDataVolume reports whether dir holds an APFS Data volume: it has
+// /private but no System volume.
+func IsDataVolume(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, "System/Library/CoreServices")); err == nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, "private"))
+	return err == nil && info.IsDir()
+}
+
+// OnSystemVolume reports whether the absolute path p is on the read-only
+// System volume, which a Data volume does not contain.
+func OnSystemVolume(p string) bool {
+	for _, dir := range []string{"/usr/local", "/usr/lib/cron"} {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return false
+		}
+	}
+	for _, dir := range []string{"/System", "/bin", "/sbin", "/usr"} {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Offline reports whether an alternate root is being scanned, in which case
+// commands that query the running system (defaults, osascript,
+// system_profiler, crontab, spctl) must be skipped.
+func Offline() bool {
+	return Root != "" && filepath.Clean(Root) != "/"
+}
+
+// Path returns where the scanned system's absolute path p lives locally.
+// Relative paths are returned unchanged.
+func Path(p string) string {
+	if !Offline() || !filepath.IsAbs(p) {
+		return p
+	}
+	if DataVolume {
+		for _, link := range dataVolumeLinks {
+			if rest, ok := strings.CutPrefix(p, link.path); ok && (rest == "" || rest[0] == '/') {
+				p = link.data + rest
+				break
+			}
+		}
+	}
+	return filepath.Join(Root, p)
+}
+
+// Trim is the inverse of Path: it turns a local path under Root back into
+// the path on the scanned system.
+func Trim(local string) string {
+	if !Offline() {
+		return local
+	}
+	root := filepath.Clean(Root)
+	if local == root {
+		return "/"
+	}
+	rest, ok := strings.CutPrefix(local, root+string(filepath.Separator))
+	if !ok {
+		return local
+	}
+	p := "/" + rest
+	if DataVolume {
+		for _, link := range dataVolumeLinks {
+			if rest, ok := strings.CutPrefix(p, link.data); ok && (rest == "" || rest[0] == '/') {
+				return link.path + rest
+			}
+		}
+	}
+	return p
+}
+
+// Hostname returns the computer name recorded on the offline root, or ""
+// if it cannot be read.
+func Hostname() string {
+	data, err := os.ReadFile(Path("/Library/Preferences/SystemConfiguration/preferences.plist"))
+	if err != nil {
+		return ""
+	}
+
+	var prefs struct {
+		System struct {
+			System struct {
+				HostName     string `plist:"HostName"`
+				ComputerName string `plist:"ComputerName"`
+			} `plist:"System"`
+			Network struct {
+				HostNames struct {
+					LocalHostName string `plist:"LocalHostName"`
+				} `plist:"HostNames"`
+			} `plist:"Network"`
+		} `plist:"System"`
+	}
+	if _, err := plist.Unmarshal(data, &prefs); err != nil {
+		return ""
+	}
+
+	switch {
+	case prefs.System.System.HostName != "":
+		return prefs.System.System.HostName
+	case prefs.System.Network.HostNames.LocalHostName != "":
+		return prefs.System.Network.HostNames.LocalHostName
+	default:
+		return prefs.System.System.ComputerName
+	}
+}
+
+// OSVersion returns the macOS version of the scanned system, such as
+// "15.1", or "" if it cannot be read.
+func OSVersion() string {
+	data, err := os.ReadFile(Path("/System/Library/CoreServices/SystemVersion.plist"))
+	if err != nil {
+		return ""
+	}
+
+	var version struct {
+		ProductVersion string `plist:"ProductVersion"`
+	}
+	if _, err := plist.Unmarshal(data, &version); err != nil {
+		return ""
+	}
+	return version.ProductVersion
+}
@@ -0,0 +1,87 @@
+package timemachine
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// DataVolume is where an APFS system keeps Library, Users, and private
+// and therefore its persistence locations.
+const DataVolume = "/System/Volumes/Data"
+
+// Snapshot is an APFS local snapshot taken by Time Machine.
+type Snapshot struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+// LocalSnapshots returns the local snapshots of volume, oldest first.
+func LocalSnapshots(volume string) ([]Snapshot, error) {
+	output, err := command.Output("tmutil", "listlocalsnapshots", volume)
+	if err != nil {
+		return nil, fmt.Errorf("listing local snapshots with tmutil: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, line := range strings.Split(string(output), "\n") {
+		name := strings.TrimSpace(line)
+		// com.apple.TimeMachine.2026-01-15-101530.local
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, "com.apple.TimeMachine."), ".local")
+		t, ok := parseTime(stamp)
+		if !ok || stamp == name {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Name: name, Time: t})
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no local snapshots of %s", volume)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// DropSystemVolume removes the items on the System volume from result. A
+// Data volume snapshot or backup lacks them, so they are left out of the
+// scans being compared, including one of the running system, to keep
+// stock jobs from showing up as added.
+func DropSystemVolume(result *scanner.ScanResult) {
+	kept := result.Items[:0]
+	for _, item := range result.Items {
+		if !sysroot.OnSystemVolume(item.Path) {
+			kept = append(kept, item)
+		}
+	}
+	result.Items = kept
+	result.Summarize()
+}
+
+// Mount mounts snapshot of volume read-only in a temporary directory and
+// returns the directory and a function that unmounts it. Mounting requires
+// root.
+func Mount(volume string, snapshot Snapshot) (string, func() error, error) {
+	dir, err := os.MkdirTemp("", "persist-scan-snapshot")
+	if err != nil {
+		return "", nil, err
+	}
+
+	if output, err := command.CombinedOutput("mount_apfs", "-o", "ro,nobrowse", "-s", snapshot.Name, volume, dir); err != nil {
+		os.Remove(dir)
+		return "", nil, fmt.Errorf("mounting snapshot %s: %w: %s", snapshot.Name, err, strings.TrimSpace(string(output)))
+	}
+
+	unmount := func() error {
+		if err := command.Run("umount", dir); err != nil {
+			return fmt.Errorf("unmounting %s: %w", dir, err)
+		}
+		return os.Remove(dir)
+	}
+	return dir, unmount, nil
+}
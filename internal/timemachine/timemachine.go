@@ -0,0 +1,179 @@
+// Package timemachine finds the backups in a Time Machine destination and
+// tracks when persistence items first appeared across them.
+package timemachine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// nameLayout is the timestamp every Time Machine backup directory name
+// starts with, in both the HFS+ (Backups.backupdb) and APFS formats.
+const nameLayout = "2006-01-02-150405"
+
+// Backup is one point-in-time copy of a system.
+type Backup struct {
+	Time time.Time `json:"time"`
+	// Path is the backup directory.
+	Path string `json:"path"`
+	// Root is the backed-up startup volume inside Path, which is scanned
+	// as an offline root.
+	Root string `json:"root"`
+}
+
+// List returns the backups in dest, oldest first. dest may be a machine
+// directory in Backups.backupdb, an APFS backup destination, or a single
+// backup. If dest is empty, the backups tmutil reports for the running
+// system are listed.
+func List(dest string) ([]Backup, error) {
+	var dirs []string
+	if dest == "" {
+		output, err := command.Output("tmutil", "listbackups")
+		if err != nil {
+			return nil, fmt.Errorf("listing backups with tmutil: %w", err)
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				dirs = append(dirs, line)
+			}
+		}
+	} else if _, ok := parseTime(dest); ok {
+		dirs = []string{dest}
+	} else {
+		entries, err := os.ReadDir(dest)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, filepath.Join(dest, entry.Name()))
+			}
+		}
+	}
+
+	var backups []Backup
+	for _, dir := range dirs {
+		t, ok := parseTime(dir)
+		if !ok {
+			continue
+		}
+		root, err := FindRoot(dir)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, Backup{Time: t, Path: dir, Root: root})
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no Time Machine backups found in %s", dest)
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.Before(backups[j].Time) })
+	return backups, nil
+}
+
+// FindRoot returns the backed-up startup volume in a backup directory: the
+// volume holding a Library directory, preferring the "- Data" volume that
+// APFS systems back up. A scan of the Data volume reads /etc and /var
+// under /private and finds nothing from the System volume; see
+// DropSystemVolume.
+func FindRoot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var root string
+	for _, entry := range entries {
+		candidate := filepath.Join(dir, entry.Name())
+		if info, err := os.Stat(filepath.Join(candidate, "Library")); err != nil || !info.IsDir() {
+			continue
+		}
+		if root == "" || strings.HasSuffix(entry.Name(), " - Data") {
+			root = candidate
+		}
+	}
+
+	// An APFS backup may nest the volume in a second timestamped directory
+	if root == "" {
+		for _, entry := range entries {
+			if _, ok := parseTime(entry.Name()); ok && entry.IsDir() {
+				return FindRoot(filepath.Join(dir, entry.Name()))
+			}
+		}
+		return "", fmt.Errorf("no backed-up volume found in %s", dir)
+	}
+	return root, nil
+}
+
+func parseTime(path string) (time.Time, bool) {
+	name := filepath.Base(path)
+	if len(name) < len(nameLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(nameLayout, name[:len(nameLayout)], time.Local)
+	return t, err == nil
+}
+
+// Sighting records the backups a persistence item appears in.
+type Sighting struct {
+	ID        string                `json:"id"`
+	Mechanism scanner.MechanismType `json:"mechanism"`
+	Label     string                `json:"label,omitempty"`
+	Path      string                `json:"path"`
+	Program   string                `json:"program,omitempty"`
+	FirstSeen time.Time             `json:"first_seen"`
+	LastSeen  time.Time             `json:"last_seen"`
+	// Backups is how many backups contain the item.
+	Backups int `json:"backups"`
+	// Gaps is set when the item is missing from a backup between its first
+	// and last sighting, i.e. it was removed and later came back.
+	Gaps bool `json:"gaps,omitempty"`
+}
+
+// Sightings correlates the scans of backups, which must be in the same
+// order, and returns every item found, earliest first.
+func Sightings(backups []Backup, results []*scanner.ScanResult) []Sighting {
+	byID := make(map[string]*Sighting)
+	var order []string
+	lastIndex := make(map[string]int)
+
+	for i, result := range results {
+		for _, item := range result.Items {
+			sighting, ok := byID[item.ID]
+			switch {
+			case !ok:
+				sighting = &Sighting{
+					ID:        item.ID,
+					Mechanism: item.Mechanism,
+					Label:     item.Label,
+					Path:      item.Path,
+					Program:   item.Program,
+					FirstSeen: backups[i].Time,
+				}
+				byID[item.ID] = sighting
+				order = append(order, item.ID)
+			case lastIndex[item.ID] == i:
+				continue
+			case lastIndex[item.ID] != i-1:
+				sighting.Gaps = true
+			}
+			sighting.LastSeen = backups[i].Time
+			sighting.Backups++
+			lastIndex[item.ID] = i
+		}
+	}
+
+	sightings := make([]Sighting, 0, len(order))
+	for _, id := range order {
+		sightings = append(sightings, *byID[id])
+	}
+	sort.SliceStable(sightings, func(i, j int) bool { return sightings[i].FirstSeen.Before(sightings[j].FirstSeen) })
+	return sightings
+}
@@ -0,0 +1,363 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/haasonsaas/macos-persist-scan/internal/explain"
+	"github.com/haasonsaas/macos-persist-scan/internal/suppress"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/rivo/tview"
+)
+
+// Options configures where triage decisions are written.
+type Options struct {
+	// Suppressions receives items suppressed with "s" and is saved to
+	// SuppressionsPath after each change.
+	Suppressions     *suppress.List
+	SuppressionsPath string
+	// ExportDir receives items exported with "e".
+	ExportDir string
+}
+
+// App is an interactive triage view of a scan result: a filterable list of
+// findings beside the details of the selected one.
+type App struct {
+	opts  Options
+	items []scanner.PersistenceItem
+	// visible holds indexes into items that match the filter.
+	visible []int
+	marked  map[int]bool
+	hidden  map[int]bool
+
+	app    *tview.Application
+	pages  *tview.Pages
+	filter *tview.InputField
+	list   *tview.Table
+	detail *tview.TextView
+	status *tview.TextView
+}
+
+var riskColors = map[scanner.RiskLevel]tcell.Color{
+	scanner.RiskCritical: tcell.ColorRed,
+	scanner.RiskHigh:     tcell.ColorOrangeRed,
+	scanner.RiskMedium:   tcell.ColorYellow,
+	scanner.RiskLow:      tcell.ColorDodgerBlue,
+	scanner.RiskInfo:     tcell.ColorGray,
+}
+
+const keyHelp = "[yellow]/[-] filter  [yellow]s[-] suppress  [yellow]m[-] mark for remediation  [yellow]e[-] export  [yellow]q[-] quit"
+
+// New builds the interface for result, highest risk first.
+func New(result *scanner.ScanResult, opts Options) *App {
+	items := append([]scanner.PersistenceItem(nil), result.Items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Risk.Score > items[j].Risk.Score
+	})
+
+	a := &App{
+		opts:   opts,
+		items:  items,
+		marked: make(map[int]bool),
+		hidden: make(map[int]bool),
+		app:    tview.NewApplication(),
+	}
+
+	a.filter = tview.NewInputField().
+		SetLabel("Filter: ").
+		SetPlaceholder("mechanism, label, path, program, or risk level").
+		SetChangedFunc(func(string) { a.refresh() }).
+		SetDoneFunc(func(tcell.Key) { a.app.SetFocus(a.list) })
+
+	a.list = tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0).
+		SetSelectionChangedFunc(func(row, _ int) { a.showDetail(row) })
+	a.list.SetBorder(true).SetTitle(" Findings ")
+	a.list.SetInputCapture(a.handleKey)
+
+	a.detail = tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true)
+	a.detail.SetBorder(true).SetTitle(" Details ")
+
+	a.status = tview.NewTextView().
+		SetDynamicColors(true).
+		SetText(keyHelp)
+
+	body := tview.NewFlex().
+		AddItem(a.list, 0, 1, true).
+		AddItem(a.detail, 0, 1, false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(a.filter, 1, 0, false).
+		AddItem(body, 0, 1, true).
+		AddItem(a.status, 1, 0, false)
+
+	a.pages = tview.NewPages().AddPage("main", layout, true, true)
+	a.app.SetRoot(a.pages, true).SetFocus(a.list)
+	a.refresh()
+
+	return a
+}
+
+// Run shows the interface until the user quits.
+func (a *App) Run() error {
+	return a.app.Run()
+}
+
+// Marked returns the items marked for remediation.
+func (a *App) Marked() []scanner.PersistenceItem {
+	var marked []scanner.PersistenceItem
+	for i := range a.items {
+		if a.marked[i] && !a.hidden[i] {
+			marked = append(marked, a.items[i])
+		}
+	}
+	return marked
+}
+
+func (a *App) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Rune() {
+	case '/':
+		a.app.SetFocus(a.filter)
+		return nil
+	case 'q':
+		a.app.Stop()
+		return nil
+	}
+
+	index, ok := a.selected()
+	if !ok {
+		return event
+	}
+
+	switch event.Rune() {
+	case 's':
+		a.promptSuppress(index)
+		return nil
+	case 'm':
+		a.marked[index] = !a.marked[index]
+		a.refresh()
+		return nil
+	case 'e':
+		path, err := a.export(&a.items[index])
+		if err != nil {
+			a.setStatus("[red]Export failed: %v", err)
+		} else {
+			a.setStatus("Exported to %s", path)
+		}
+		return nil
+	}
+	return event
+}
+
+// refresh rebuilds the list from the current filter, keeping the selected
+// item selected when it is still visible.
+func (a *App) refresh() {
+	previous, hadSelection := a.selected()
+
+	query := strings.ToLower(a.filter.GetText())
+	a.visible = a.visible[:0]
+	for i := range a.items {
+		if !a.hidden[i] && matches(&a.items[i], query) {
+			a.visible = append(a.visible, i)
+		}
+	}
+
+	a.list.Clear()
+	for col, title := range []string{"", "Risk", "Mechanism", "Label", "Path"} {
+		a.list.SetCell(0, col, tview.NewTableCell(title).
+			SetSelectable(false).
+			SetAttributes(tcell.AttrBold))
+	}
+
+	row := 1
+	for r, index := range a.visible {
+		item := &a.items[index]
+		mark := " "
+		if a.marked[index] {
+			mark = "*"
+		}
+		a.list.SetCell(r+1, 0, tview.NewTableCell(mark).SetTextColor(tcell.ColorFuchsia))
+		a.list.SetCell(r+1, 1, tview.NewTableCell(string(item.Risk.Level)).SetTextColor(riskColors[item.Risk.Level]))
+		a.list.SetCell(r+1, 2, tview.NewTableCell(string(item.Mechanism)))
+		a.list.SetCell(r+1, 3, tview.NewTableCell(tview.Escape(item.Label)).SetMaxWidth(40))
+		a.list.SetCell(r+1, 4, tview.NewTableCell(tview.Escape(item.Path)).SetExpansion(1))
+		if hadSelection && index == previous {
+			row = r + 1
+		}
+	}
+
+	a.list.SetTitle(fmt.Sprintf(" Findings (%d/%d) ", len(a.visible), len(a.items)-len(a.hidden)))
+	if len(a.visible) > 0 {
+		a.list.Select(row, 0)
+	}
+	a.showDetail(row)
+}
+
+// selected returns the index into items of the highlighted row.
+func (a *App) selected() (int, bool) {
+	row, _ := a.list.GetSelection()
+	if row < 1 || row > len(a.visible) {
+		return 0, false
+	}
+	return a.visible[row-1], true
+}
+
+func (a *App) showDetail(row int) {
+	a.detail.Clear()
+	if row < 1 || row > len(a.visible) {
+		return
+	}
+	fmt.Fprint(a.detail, describe(&a.items[a.visible[row-1]]))
+	a.detail.ScrollToBeginning()
+}
+
+func (a *App) setStatus(format string, args ...interface{}) {
+	a.status.SetText(fmt.Sprintf(format, args...) + "  |  " + keyHelp)
+}
+
+// promptSuppress asks for a reason and adds the item to the suppression
+// list.
+func (a *App) promptSuppress(index int) {
+	item := &a.items[index]
+	reason := ""
+
+	form := tview.NewForm()
+	form.AddInputField("Reason", "", 50, nil, func(text string) { reason = text }).
+		AddButton("Suppress", func() {
+			a.pages.RemovePage("suppress")
+			a.app.SetFocus(a.list)
+
+			a.opts.Suppressions.Add(item, reason, nil)
+			if err := a.opts.Suppressions.Save(a.opts.SuppressionsPath); err != nil {
+				a.setStatus("[red]Saving suppressions failed: %v", err)
+				return
+			}
+			a.hidden[index] = true
+			a.refresh()
+			a.setStatus("Suppressed %s", tview.Escape(item.Path))
+		}).
+		AddButton("Cancel", func() {
+			a.pages.RemovePage("suppress")
+			a.app.SetFocus(a.list)
+		})
+	form.SetCancelFunc(func() {
+		a.pages.RemovePage("suppress")
+		a.app.SetFocus(a.list)
+	})
+	form.SetBorder(true).SetTitle(" Suppress " + tview.Escape(item.Label) + " ")
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 7, 0, true).
+			AddItem(nil, 0, 1, false), 70, 0, true).
+		AddItem(nil, 0, 1, false)
+	a.pages.AddPage("suppress", modal, true, true)
+	a.app.SetFocus(form)
+}
+
+// export writes item as JSON into the export directory.
+func (a *App) export(item *scanner.PersistenceItem) (string, error) {
+	name := item.ID
+	if name == "" {
+		name = filepath.Base(item.Path)
+	}
+	name = strings.NewReplacer("/", "_", string(filepath.Separator), "_").Replace(name)
+	path := filepath.Join(a.opts.ExportDir, name+".json")
+
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func matches(item *scanner.PersistenceItem, query string) bool {
+	if query == "" {
+		return true
+	}
+	for _, field := range []string{string(item.Mechanism), item.Label, item.Path, item.Program, string(item.Risk.Level)} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe renders the detail pane for item: its properties, every
+// heuristic result, and the raw plist or collected data.
+func describe(item *scanner.PersistenceItem) string {
+	var b strings.Builder
+	line := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "[::b]%s:[::-] %s\n", name, tview.Escape(value))
+		}
+	}
+
+	line("Label", item.Label)
+	line("Mechanism", string(item.Mechanism))
+	line("Path", item.Path)
+	line("Program", item.Program)
+	line("Arguments", strings.Join(item.ProgramArgs, " "))
+	line("User", item.User)
+	line("Flags", flags(item))
+	if !item.ModifiedAt.IsZero() {
+		line("Modified", item.ModifiedAt.Format("2006-01-02 15:04:05"))
+	}
+	line("ATT&CK", strings.Join(item.ATTACKTechniques, ", "))
+
+	fmt.Fprintf(&b, "\n[::b]Risk:[::-] [%s]%s[-] (score %.2f, confidence %.2f)\n",
+		riskColors[item.Risk.Level].String(), item.Risk.Level, item.Risk.Score, item.Risk.Confidence)
+	for _, reason := range item.Risk.Reasons {
+		fmt.Fprintf(&b, "  - %s\n", tview.Escape(reason))
+	}
+
+	b.WriteString("\n[::b]Heuristics[::-]\n")
+	for _, h := range item.Risk.Heuristics {
+		marker := "[gray]  [-]"
+		if h.Triggered {
+			marker = "[red]! [-]"
+		}
+		fmt.Fprintf(&b, "%s%-24s %.2f  %s\n", marker, h.Name, h.Score, tview.Escape(h.Details))
+	}
+
+	if len(item.Errors) > 0 {
+		b.WriteString("\n[::b]Errors[::-]\n")
+		for _, err := range item.Errors {
+			fmt.Fprintf(&b, "  %s\n", tview.Escape(err))
+		}
+	}
+
+	if raw := explain.RawData(item); raw != "" {
+		b.WriteString("\n[::b]Raw[::-]\n")
+		b.WriteString(tview.Escape(raw))
+	}
+
+	return b.String()
+}
+
+func flags(item *scanner.PersistenceItem) string {
+	var set []string
+	if item.RunAtLoad {
+		set = append(set, "RunAtLoad")
+	}
+	if item.KeepAlive {
+		set = append(set, "KeepAlive")
+	}
+	if item.Disabled {
+		set = append(set, "Disabled")
+	}
+	return strings.Join(set, ", ")
+}
@@ -0,0 +1,200 @@
+package attack
+
+import (
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Technique is a MITRE ATT&CK (sub-)technique.
+type Technique struct {
+	ID   string
+	Name string
+}
+
+// TacticPersistence is the ATT&CK tactic every finding belongs to.
+var TacticPersistence = Technique{ID: "TA0003", Name: "Persistence"}
+
+var catalog = map[string]string{
+	"T1027":     "Obfuscated Files or Information",
+	"T1036":     "Masquerading",
+	"T1036.004": "Masquerade Task or Service",
+	"T1036.005": "Match Legitimate Name or Location",
+	"T1037":     "Boot or Logon Initialization Scripts",
+	"T1037.002": "Login Hook",
+	"T1053":     "Scheduled Task/Job",
+	"T1053.003": "Cron",
+	"T1059":     "Command and Scripting Interpreter",
+	"T1059.002": "AppleScript",
+	"T1059.004": "Unix Shell",
+	"T1078":     "Valid Accounts",
+	"T1078.003": "Local Accounts",
+	"T1098":     "Account Manipulation",
+	"T1098.004": "SSH Authorized Keys",
+	"T1543":     "Create or Modify System Process",
+	"T1543.001": "Launch Agent",
+	"T1543.004": "Launch Daemon",
+	"T1546":     "Event Triggered Execution",
+	"T1546.004": "Unix Shell Configuration Modification",
+	"T1547":     "Boot or Logon Autostart Execution",
+	"T1547.006": "Kernel Modules and Extensions",
+	"T1547.015": "Login Items",
+	"T1553":     "Subvert Trust Controls",
+	"T1553.001": "Gatekeeper Bypass",
+	"T1553.002": "Code Signing",
+	"T1554":     "Compromise Host Software Binary",
+	"T1176":     "Browser Extensions",
+	"T1136":     "Create Account",
+	"T1136.001": "Local Account",
+	"T1204":     "User Execution",
+	"T1204.002": "Malicious File",
+	"T1542":     "Pre-OS Boot",
+	"T1562":     "Impair Defenses",
+	"T1564":     "Hide Artifacts",
+	"T1564.002": "Hidden Users",
+	"T1562.001": "Disable or Modify Tools",
+	"T1574":     "Hijack Execution Flow",
+	"T1574.006": "Dynamic Linker Hijacking",
+	"T1574.007": "Path Interception by PATH Environment Variable",
+}
+
+var mechanismTechniques = map[scanner.MechanismType][]string{
+	scanner.MechanismLaunchAgent:      {"T1543.001"},
+	scanner.MechanismLaunchDaemon:     {"T1543.004"},
+	scanner.MechanismLoginItem:        {"T1547.015"},
+	scanner.MechanismCronJob:          {"T1053.003"},
+	scanner.MechanismPeriodicScript:   {"T1053"},
+	scanner.MechanismLoginHook:        {"T1037.002"},
+	scanner.MechanismLogoutHook:       {"T1037.002"},
+	scanner.MechanismNVRAM:            {"T1542"},
+	scanner.MechanismSynthetic:        {"T1574"},
+	scanner.MechanismSearchPath:       {"T1574.007"},
+	scanner.MechanismEnvironment:      {"T1574.006"},
+	scanner.MechanismNativeMessaging:  {"T1176"},
+	scanner.MechanismBrowserPolicy:    {"T1176"},
+	scanner.MechanismKernelExtension:  {"T1547.006"},
+	scanner.MechanismSystemExtension:  {"T1547.006"},
+	scanner.MechanismCUPS:             {"T1546"},
+	scanner.MechanismDock:             {"T1204.002"},
+	scanner.MechanismAutomator:        {"T1204.002"},
+	scanner.MechanismUserShell:        {"T1546.004"},
+	scanner.MechanismLocalAccount:     {"T1136.001"},
+	scanner.MechanismNewsyslog:        {"T1053"},
+	scanner.MechanismPowerSchedule:    {"T1053"},
+	scanner.MechanismShellStartup:     {"T1546.004"},
+	scanner.MechanismBrowserExtension: {"T1176"},
+}
+
+// kindTechniques maps the kinds of item a collector records in
+// RawData["kind"] to techniques, for mechanisms whose items differ.
+var kindTechniques = map[scanner.MechanismType]map[string][]string{
+	scanner.MechanismRootAccount: {
+		"authorized_key":  {"T1098.004"},
+		"ssh_rc":          {"T1546.004"},
+		"ssh_environment": {"T1546.004"},
+		"shell_startup":   {"T1546.004"},
+	},
+}
+
+// heuristicTechniques maps heuristic names to the techniques their findings
+// are evidence of.
+var heuristicTechniques = map[string][]string{
+	"suspicious_path":       {"T1036.005"},
+	"suspicious_behavior":   {"T1059.004"},
+	"name_entropy":          {"T1036.004"},
+	"gatekeeper_assessment": {"T1553.001"},
+	"launchd_triggers":      {"T1546"},
+	"certificate_age":       {"T1553.002"},
+	"bundle_integrity":      {"T1554"},
+	"nvram_security":        {"T1562.001"},
+	"path_hijack":           {"T1574.007"},
+	"environment_injection": {"T1574.006"},
+	"architecture_mismatch": {"T1036"},
+	"browser_policy":        {"T1176"},
+	"dock_anomaly":          {"T1036.005"},
+	"automator_payload":     {"T1059.004", "T1059.002"},
+	"login_shell":           {"T1546.004"},
+	"account_anomaly":       {"T1078.003"},
+	"shell_startup":         {"T1546.004"},
+	"browser_extension":     {"T1176"},
+}
+
+// Lookup returns the catalog entry for id.
+func Lookup(id string) Technique {
+	return Technique{ID: id, Name: catalog[id]}
+}
+
+// ForMechanism returns the technique IDs implemented by a mechanism.
+func ForMechanism(mechanism scanner.MechanismType) []string {
+	return mechanismTechniques[mechanism]
+}
+
+// Parent returns the parent technique ID of a sub-technique, or "" if id is
+// already a top-level technique.
+func Parent(id string) string {
+	if parent, _, found := strings.Cut(id, "."); found {
+		return parent
+	}
+	return ""
+}
+
+// URL returns the ATT&CK reference page for id.
+func URL(id string) string {
+	return "https://attack.mitre.org/techniques/" + strings.Replace(id, ".", "/", 1) + "/"
+}
+
+// ForHeuristic returns the technique IDs a triggered heuristic points to.
+func ForHeuristic(name string) []string {
+	return heuristicTechniques[name]
+}
+
+// ForItem returns the techniques for an item's mechanism followed by those
+// of every triggered heuristic, without duplicates. Heuristic techniques
+// depend on item.Risk, so call it after risk assessment.
+func ForItem(item *scanner.PersistenceItem) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	add := func(list []string) {
+		for _, id := range list {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	add(ForMechanism(item.Mechanism))
+	if kind, ok := item.RawData["kind"].(string); ok {
+		add(kindTechniques[item.Mechanism][kind])
+	}
+	if _, hidden := item.RawData["hidden_by"]; hidden && item.Mechanism == scanner.MechanismLocalAccount {
+		add([]string{"T1564.002"})
+	}
+	for _, h := range item.Risk.Heuristics {
+		if h.Triggered {
+			add(ForHeuristic(h.Name))
+		}
+	}
+	if hasDecodedPayloads(item) {
+		add([]string{"T1027"})
+	}
+
+	return ids
+}
+
+// Stage tags every item with its techniques. Run it after the risk stage.
+var Stage = scanner.NewStage("attack", func(items []scanner.PersistenceItem) {
+	for i := range items {
+		items[i].ATTACKTechniques = ForItem(&items[i])
+	}
+})
+
+func hasDecodedPayloads(item *scanner.PersistenceItem) bool {
+	switch payloads := item.RawData["decodedPayloads"].(type) {
+	case []map[string]interface{}:
+		return len(payloads) > 0
+	case []interface{}:
+		return len(payloads) > 0
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Result describes how the persistence items of two scans differ.
+type Result struct {
+	OldScan time.Time                 `json:"old_scan"`
+	NewScan time.Time                 `json:"new_scan"`
+	Added   []scanner.PersistenceItem `json:"added"`
+	Removed []scanner.PersistenceItem `json:"removed"`
+	Changed []Change                  `json:"changed"`
+	// OldThresholds and NewThresholds are set when the scans graded
+	// scores with different risk thresholds, so risk level changes may
+	// come from configuration rather than from the items.
+	OldThresholds *scanner.RiskThresholds `json:"old_thresholds,omitempty"`
+	NewThresholds *scanner.RiskThresholds `json:"new_thresholds,omitempty"`
+}
+
+// Change is an item present in both scans whose fields differ.
+type Change struct {
+	Old    scanner.PersistenceItem `json:"old"`
+	New    scanner.PersistenceItem `json:"new"`
+	Fields []FieldChange           `json:"fields"`
+}
+
+// FieldChange is a single field that differs between two scans.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Empty reports whether the scans contained the same items.
+func (r *Result) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// field is a compared item attribute rendered as a string.
+type field struct {
+	name  string
+	value func(item *scanner.PersistenceItem) string
+}
+
+var fields = []field{
+	{"program", func(item *scanner.PersistenceItem) string { return item.Program }},
+	{"program_args", func(item *scanner.PersistenceItem) string { return strings.Join(item.ProgramArgs, " ") }},
+	{"user", func(item *scanner.PersistenceItem) string { return item.User }},
+	{"run_at_load", func(item *scanner.PersistenceItem) string { return fmt.Sprint(item.RunAtLoad) }},
+	{"keep_alive", func(item *scanner.PersistenceItem) string { return fmt.Sprint(item.KeepAlive) }},
+	{"disabled", func(item *scanner.PersistenceItem) string { return fmt.Sprint(item.Disabled) }},
+	{"file_mode", func(item *scanner.PersistenceItem) string { return item.FileMode }},
+	{"modified_at", func(item *scanner.PersistenceItem) string {
+		if item.ModifiedAt.IsZero() {
+			return ""
+		}
+		return item.ModifiedAt.UTC().Format(time.RFC3339)
+	}},
+	{"risk.level", func(item *scanner.PersistenceItem) string { return string(item.Risk.Level) }},
+}
+
+// Key identifies an item across scans of the same host. The program is
+// deliberately left out so that a repointed item shows up as changed rather
+// than as a removal plus an addition.
+func Key(item *scanner.PersistenceItem) string {
+	return strings.Join([]string{string(item.Mechanism), item.Path, item.Label}, "|")
+}
+
+// Compare returns the items added, removed, and changed between two scans.
+func Compare(oldResult, newResult *scanner.ScanResult) *Result {
+	result := &Result{
+		OldScan: oldResult.StartTime,
+		NewScan: newResult.StartTime,
+		Added:   []scanner.PersistenceItem{},
+		Removed: []scanner.PersistenceItem{},
+		Changed: []Change{},
+	}
+
+	if oldThresholds, newThresholds := thresholds(oldResult), thresholds(newResult); oldThresholds != newThresholds {
+		result.OldThresholds, result.NewThresholds = &oldThresholds, &newThresholds
+	}
+
+	shared := sharedKeys(oldResult.Items)
+	for key := range sharedKeys(newResult.Items) {
+		shared[key] = true
+	}
+	oldItems := keyed(oldResult.Items, shared)
+	newItems := keyed(newResult.Items, shared)
+
+	for _, key := range sortedKeys(newItems) {
+		newItem := newItems[key]
+		oldItem, ok := oldItems[key]
+		if !ok {
+			result.Added = append(result.Added, *newItem)
+			continue
+		}
+
+		var changes []FieldChange
+		for _, f := range fields {
+			if before, after := f.value(oldItem), f.value(newItem); before != after {
+				changes = append(changes, FieldChange{Field: f.name, Old: before, New: after})
+			}
+		}
+		if len(changes) > 0 {
+			result.Changed = append(result.Changed, Change{Old: *oldItem, New: *newItem, Fields: changes})
+		}
+	}
+
+	for _, key := range sortedKeys(oldItems) {
+		if _, ok := newItems[key]; !ok {
+			result.Removed = append(result.Removed, *oldItems[key])
+		}
+	}
+
+	return result
+}
+
+// sharedKeys returns the keys that more than one of items has.
+func sharedKeys(items []scanner.PersistenceItem) map[string]bool {
+	counts := make(map[string]int)
+	shared := make(map[string]bool)
+	for i := range items {
+		key := Key(&items[i])
+		counts[key]++
+		if counts[key] > 1 {
+			shared[key] = true
+		}
+	}
+	return shared
+}
+
+// keyed maps the items of a scan by Key. Items whose key is shared in
+// either scan, such as several root SSH keys with the same comment, are
+// told apart by the line they were read from, or failing that by their
+// ID, so that none of them overwrites another.
+func keyed(items []scanner.PersistenceItem, shared map[string]bool) map[string]*scanner.PersistenceItem {
+	byKey := make(map[string]*scanner.PersistenceItem, len(items))
+	for i := range items {
+		item := &items[i]
+		key := Key(item)
+		if shared[key] {
+			if line, ok := scanner.RawInt(item.RawData["line"]); ok {
+				key += fmt.Sprintf("|line %d", line)
+			} else {
+				key += "|" + item.ID
+			}
+		}
+		// Items identical in every part of the key are numbered in scan
+		// order
+		for n, unique := 2, key; ; n++ {
+			if _, taken := byKey[unique]; !taken {
+				key = unique
+				break
+			}
+			unique = fmt.Sprintf("%s|%d", key, n)
+		}
+		byKey[key] = item
+	}
+	return byKey
+}
+
+func sortedKeys(items map[string]*scanner.PersistenceItem) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// thresholds returns the risk thresholds result was graded with. Results
+// that do not record them predate configurable thresholds and used the
+// defaults.
+func thresholds(result *scanner.ScanResult) scanner.RiskThresholds {
+	if result.RiskThresholds == nil {
+		return scanner.DefaultRiskThresholds
+	}
+	return *result.RiskThresholds
+}
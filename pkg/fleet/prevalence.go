@@ -0,0 +1,129 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Database records how many hosts in a fleet carry each persistence item.
+type Database struct {
+	Hosts []string          `json:"hosts"`
+	Items map[string]*Entry `json:"items"`
+
+	seen map[string]bool
+}
+
+// Entry is the prevalence record for a single persistence item.
+type Entry struct {
+	Mechanism scanner.MechanismType `json:"mechanism"`
+	Label     string                `json:"label"`
+	Program   string                `json:"program,omitempty"`
+	Hosts     int                   `json:"hosts"`
+}
+
+var userHomePattern = regexp.MustCompile(`^/Users/[^/]+/`)
+
+func NewDatabase() *Database {
+	return &Database{
+		Items: make(map[string]*Entry),
+		seen:  make(map[string]bool),
+	}
+}
+
+// Load reads a prevalence database written by Save.
+func Load(path string) (*Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading prevalence database: %w", err)
+	}
+
+	db := NewDatabase()
+	if err := json.Unmarshal(data, db); err != nil {
+		return nil, fmt.Errorf("parsing prevalence database %s: %w", path, err)
+	}
+	if db.Items == nil {
+		db.Items = make(map[string]*Entry)
+	}
+	for _, host := range db.Hosts {
+		db.seen[host] = true
+	}
+
+	return db, nil
+}
+
+func (db *Database) Save(path string) error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding prevalence database: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing prevalence database: %w", err)
+	}
+	return nil
+}
+
+// Add counts every item in result against host. A host is only counted once,
+// so importing a second scan from the same machine is a no-op.
+func (db *Database) Add(host string, result *scanner.ScanResult) bool {
+	if db.seen[host] {
+		return false
+	}
+	db.seen[host] = true
+	db.Hosts = append(db.Hosts, host)
+
+	counted := make(map[string]bool)
+	for i := range result.Items {
+		item := &result.Items[i]
+		key := Key(item)
+		if counted[key] {
+			continue
+		}
+		counted[key] = true
+
+		entry, ok := db.Items[key]
+		if !ok {
+			entry = &Entry{
+				Mechanism: item.Mechanism,
+				Label:     item.Label,
+				Program:   normalizePath(item.Program),
+			}
+			db.Items[key] = entry
+		}
+		entry.Hosts++
+	}
+
+	return true
+}
+
+// TotalHosts returns the number of hosts that contributed to the database.
+func (db *Database) TotalHosts() int {
+	return len(db.Hosts)
+}
+
+// Prevalence returns the number of hosts on which item was observed.
+func (db *Database) Prevalence(item *scanner.PersistenceItem) int {
+	if entry, ok := db.Items[Key(item)]; ok {
+		return entry.Hosts
+	}
+	return 0
+}
+
+// Key identifies an item across hosts. User home directories are collapsed so
+// the same agent installed for different users compares equal.
+func Key(item *scanner.PersistenceItem) string {
+	return strings.Join([]string{
+		string(item.Mechanism),
+		item.Label,
+		normalizePath(item.Path),
+		normalizePath(item.Program),
+	}, "|")
+}
+
+func normalizePath(path string) string {
+	return userHomePattern.ReplaceAllString(path, "~/")
+}
@@ -0,0 +1,176 @@
+// Package merge consolidates the scan results of many hosts into one
+// report, listing each finding with the hosts that have it and each host
+// with its findings.
+package merge
+
+import (
+	"sort"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/fleet"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Report is the consolidated view of a set of hosts.
+type Report struct {
+	TotalHosts int `json:"total_hosts"`
+	// MinRisk is the lowest risk level listed. Prevalence counts every
+	// item, whatever its risk.
+	MinRisk  scanner.RiskLevel `json:"min_risk"`
+	Findings []Finding         `json:"findings"`
+	Hosts    []Host            `json:"hosts"`
+}
+
+// Finding is one item, identified across hosts as the fleet prevalence
+// database identifies it, so the same agent installed for different users
+// is one finding.
+type Finding struct {
+	Key       string                `json:"key"`
+	Mechanism scanner.MechanismType `json:"mechanism"`
+	Label     string                `json:"label"`
+	Path      string                `json:"path"`
+	Program   string                `json:"program,omitempty"`
+	// Risk and Score are the highest any host assessed the item at.
+	Risk  scanner.RiskLevel `json:"risk"`
+	Score float64           `json:"score"`
+	// Hosts lists the hosts the item is on, by name. Prevalence counts
+	// them out of TotalHosts, as the fleet_rarity heuristic counts them.
+	Hosts      []string `json:"hosts"`
+	Prevalence int      `json:"prevalence"`
+}
+
+// Host is one host's scan and the findings on it.
+type Host struct {
+	Name        string                    `json:"name"`
+	ScanTime    time.Time                 `json:"scan_time"`
+	OSVersion   string                    `json:"os_version,omitempty"`
+	RiskSummary map[scanner.RiskLevel]int `json:"risk_summary"`
+	Findings    []HostFinding             `json:"findings"`
+}
+
+// HostFinding is an item as one host has it.
+type HostFinding struct {
+	Key        string                `json:"key"`
+	ID         string                `json:"id"`
+	Mechanism  scanner.MechanismType `json:"mechanism"`
+	Label      string                `json:"label"`
+	Path       string                `json:"path"`
+	Risk       scanner.RiskLevel     `json:"risk"`
+	Score      float64               `json:"score"`
+	Prevalence int                   `json:"prevalence"`
+}
+
+// Merger collects per-host results.
+type Merger struct {
+	names   []string
+	results map[string]*scanner.ScanResult
+}
+
+func New() *Merger {
+	return &Merger{results: make(map[string]*scanner.ScanResult)}
+}
+
+// Add records the result of host. Like the prevalence database, a host is
+// only counted once, so of several results from the same machine only the
+// newest scan is kept, whatever order they are added in. Add reports
+// whether result is the newest of host's so far.
+func (m *Merger) Add(host string, result *scanner.ScanResult) bool {
+	if kept, ok := m.results[host]; !ok {
+		m.names = append(m.names, host)
+	} else if !result.StartTime.After(kept.StartTime) {
+		return false
+	}
+	m.results[host] = result
+	return true
+}
+
+// Report lists the items at or above minRisk by finding and by host.
+func (m *Merger) Report(minRisk scanner.RiskLevel) *Report {
+	db := fleet.NewDatabase()
+	for _, name := range m.names {
+		db.Add(name, m.results[name])
+	}
+	report := &Report{
+		TotalHosts: db.TotalHosts(),
+		MinRisk:    minRisk,
+		Findings:   []Finding{},
+		Hosts:      []Host{},
+	}
+
+	findings := make(map[string]*Finding)
+	for _, name := range m.names {
+		result := m.results[name]
+		host := Host{
+			Name:        name,
+			ScanTime:    result.StartTime,
+			OSVersion:   result.OSVersion,
+			RiskSummary: result.RiskSummary,
+			Findings:    []HostFinding{},
+		}
+
+		for j := range result.Items {
+			item := &result.Items[j]
+			// Inventory results carry no risk and are listed only in full
+			if minRisk != scanner.RiskInfo && item.Risk.Level.Rank() < minRisk.Rank() {
+				continue
+			}
+			key := fleet.Key(item)
+			prevalence := db.Prevalence(item)
+			host.Findings = append(host.Findings, HostFinding{
+				Key:        key,
+				ID:         item.ID,
+				Mechanism:  item.Mechanism,
+				Label:      item.Label,
+				Path:       item.Path,
+				Risk:       item.Risk.Level,
+				Score:      item.Risk.Score,
+				Prevalence: prevalence,
+			})
+
+			finding, ok := findings[key]
+			if !ok {
+				finding = &Finding{
+					Key:        key,
+					Mechanism:  item.Mechanism,
+					Label:      item.Label,
+					Path:       item.Path,
+					Program:    item.Program,
+					Prevalence: prevalence,
+				}
+				findings[key] = finding
+			}
+			if item.Risk.Level.Rank() > finding.Risk.Rank() {
+				finding.Risk = item.Risk.Level
+			}
+			if item.Risk.Score > finding.Score {
+				finding.Score = item.Risk.Score
+			}
+			if n := len(finding.Hosts); n == 0 || finding.Hosts[n-1] != name {
+				finding.Hosts = append(finding.Hosts, name)
+			}
+		}
+
+		sort.SliceStable(host.Findings, func(a, b int) bool {
+			return host.Findings[a].Risk.Rank() > host.Findings[b].Risk.Rank()
+		})
+		report.Hosts = append(report.Hosts, host)
+	}
+
+	for _, finding := range findings {
+		report.Findings = append(report.Findings, *finding)
+	}
+	// Riskiest first, and the rarest of equal risk
+	sort.Slice(report.Findings, func(a, b int) bool {
+		fa, fb := &report.Findings[a], &report.Findings[b]
+		if fa.Risk.Rank() != fb.Risk.Rank() {
+			return fa.Risk.Rank() > fb.Risk.Rank()
+		}
+		if fa.Prevalence != fb.Prevalence {
+			return fa.Prevalence < fb.Prevalence
+		}
+		return fa.Key < fb.Key
+	})
+	sort.Slice(report.Hosts, func(a, b int) bool { return report.Hosts[a].Name < report.Hosts[b].Name })
+
+	return report
+}
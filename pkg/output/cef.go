@@ -0,0 +1,117 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// CEFFormatter emits one ArcSight Common Event Format line per item.
+type CEFFormatter struct{}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+func (f *CEFFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	return formatBuffered(f, result)
+}
+
+func (f *CEFFormatter) FormatStream(w io.Writer, result *scanner.ScanResult, items ItemIterator) error {
+	buf := bufio.NewWriter(w)
+
+	for {
+		item, err := items.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		label := item.Label
+		if label == "" {
+			label = filepath.Base(item.Path)
+		}
+
+		header := []string{
+			"CEF:0",
+			"haasonsaas",
+			"macos-persist-scan",
+			"1.0.0",
+			f.escapeHeader("persistence:" + string(item.Mechanism)),
+			f.escapeHeader(fmt.Sprintf("%s persistence: %s", item.Mechanism, label)),
+			fmt.Sprintf("%d", f.severity(item.Risk.Level)),
+		}
+
+		ext := [][2]string{
+			{"rt", fmt.Sprintf("%d", result.EndTime.UnixMilli())},
+			{"dvchost", result.Hostname},
+			{"cs1Label", "Mechanism"},
+			{"cs1", string(item.Mechanism)},
+			{"cs2Label", "Label"},
+			{"cs2", item.Label},
+			{"cs3Label", "ConfigPath"},
+			{"cs3", item.Path},
+			{"cs4Label", "RiskLevel"},
+			{"cs4", string(item.Risk.Level)},
+			{"cfp1Label", "RiskScore"},
+			{"cfp1", fmt.Sprintf("%.2f", item.Risk.Score)},
+			{"filePath", item.Program},
+			{"suser", item.User},
+		}
+		if item.Program != "" {
+			ext = append(ext, [2]string{"fname", filepath.Base(item.Program)})
+		}
+		if item.ProgramFile != nil {
+			ext = append(ext, [2]string{"fileHash", item.ProgramFile.SHA256})
+			ext = append(ext, [2]string{"fsize", fmt.Sprintf("%d", item.ProgramFile.Size)})
+		}
+		if !item.ModifiedAt.IsZero() {
+			ext = append(ext, [2]string{"fileModificationTime", fmt.Sprintf("%d", item.ModifiedAt.UnixMilli())})
+		}
+		if len(item.Risk.Reasons) > 0 {
+			ext = append(ext, [2]string{"msg", strings.Join(item.Risk.Reasons, "; ")})
+		}
+
+		var pairs []string
+		for _, kv := range ext {
+			if kv[1] == "" {
+				continue
+			}
+			pairs = append(pairs, kv[0]+"="+cefExtensionEscaper.Replace(kv[1]))
+		}
+
+		buf.WriteString(strings.Join(header, "|"))
+		buf.WriteString("|")
+		buf.WriteString(strings.Join(pairs, " "))
+		buf.WriteString("\n")
+	}
+
+	return buf.Flush()
+}
+
+func (f *CEFFormatter) escapeHeader(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+// severity maps risk levels onto the CEF 0-10 scale.
+func (f *CEFFormatter) severity(level scanner.RiskLevel) int {
+	switch level {
+	case scanner.RiskCritical:
+		return 10
+	case scanner.RiskHigh:
+		return 8
+	case scanner.RiskMedium:
+		return 5
+	case scanner.RiskLow:
+		return 3
+	default:
+		return 1
+	}
+}
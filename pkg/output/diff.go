@@ -0,0 +1,79 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/haasonsaas/macos-persist-scan/pkg/diff"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// DiffFormatter renders the comparison of two scans as colored text or JSON.
+type DiffFormatter struct {
+	JSON bool
+}
+
+func (f *DiffFormatter) Format(result *diff.Result) ([]byte, error) {
+	if f.JSON {
+		return json.MarshalIndent(result, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	table := &TableFormatter{}
+
+	fmt.Fprintf(&buf, "Comparing scan from %s with scan from %s\n\n",
+		result.OldScan.Format("2006-01-02 15:04"), result.NewScan.Format("2006-01-02 15:04"))
+	if result.OldThresholds != nil && result.NewThresholds != nil {
+		color.New(color.FgYellow).Fprintf(&buf, "Risk thresholds differ: %s -> %s; risk levels are not directly comparable\n\n",
+			f.thresholds(result.OldThresholds), f.thresholds(result.NewThresholds))
+	}
+
+	if result.Empty() {
+		buf.WriteString("No changes.\n")
+		return buf.Bytes(), nil
+	}
+
+	added := color.New(color.FgGreen)
+	removed := color.New(color.FgRed)
+	changed := color.New(color.FgYellow)
+
+	for _, item := range result.Added {
+		added.Fprintf(&buf, "+ %s\n", f.describe(&item))
+		fmt.Fprintf(&buf, "    risk: %s\n", table.colorizeRisk(item.Risk.Level))
+	}
+	for _, item := range result.Removed {
+		removed.Fprintf(&buf, "- %s\n", f.describe(&item))
+	}
+	for _, change := range result.Changed {
+		changed.Fprintf(&buf, "~ %s\n", f.describe(&change.New))
+		for _, field := range change.Fields {
+			fmt.Fprintf(&buf, "    %s: %s -> %s\n", field.Field, removed.Sprint(f.orNone(field.Old)), added.Sprint(f.orNone(field.New)))
+		}
+	}
+
+	fmt.Fprintf(&buf, "\n%d added, %d removed, %d changed\n", len(result.Added), len(result.Removed), len(result.Changed))
+
+	return buf.Bytes(), nil
+}
+
+func (f *DiffFormatter) describe(item *scanner.PersistenceItem) string {
+	label := item.Label
+	if label == "" {
+		label = filepath.Base(item.Path)
+	}
+	return fmt.Sprintf("[%s] %s (%s)", item.Mechanism, label, item.Path)
+}
+
+func (f *DiffFormatter) thresholds(t *scanner.RiskThresholds) string {
+	return fmt.Sprintf("low %g, medium %g, high %g, critical %g", t.Low, t.Medium, t.High, t.Critical)
+}
+
+func (f *DiffFormatter) orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,275 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/attack"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// ECSFormatter emits one Elastic Common Schema document per item as NDJSON.
+type ECSFormatter struct{}
+
+const ecsVersion = "8.11.0"
+
+type ECSDocument struct {
+	Timestamp time.Time       `json:"@timestamp"`
+	ECS       ECSVersion      `json:"ecs"`
+	Event     ECSEvent        `json:"event"`
+	Host      ECSHost         `json:"host"`
+	File      *ECSFile        `json:"file,omitempty"`
+	Process   *ECSProcess     `json:"process,omitempty"`
+	User      *ECSUser        `json:"user,omitempty"`
+	Rule      *ECSRule        `json:"rule,omitempty"`
+	Threat    ECSThreat       `json:"threat"`
+	Scanner   ECSScannerField `json:"macos_persist_scan"`
+}
+
+type ECSVersion struct {
+	Version string `json:"version"`
+}
+
+type ECSEvent struct {
+	Kind      string    `json:"kind"`
+	Category  []string  `json:"category"`
+	Type      []string  `json:"type"`
+	Module    string    `json:"module"`
+	Dataset   string    `json:"dataset"`
+	RiskScore float64   `json:"risk_score"`
+	Severity  int       `json:"severity"`
+	Reason    string    `json:"reason,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+type ECSHost struct {
+	Hostname string `json:"hostname,omitempty"`
+	OS       struct {
+		Type string `json:"type"`
+	} `json:"os"`
+}
+
+type ECSFile struct {
+	Path      string     `json:"path"`
+	Name      string     `json:"name"`
+	Directory string     `json:"directory"`
+	Mtime     *time.Time `json:"mtime,omitempty"`
+	Mode      string     `json:"mode,omitempty"`
+	Size      int64      `json:"size,omitempty"`
+	Hash      *ECSHash   `json:"hash,omitempty"`
+}
+
+type ECSHash struct {
+	SHA256 string `json:"sha256"`
+}
+
+type ECSProcess struct {
+	Executable string   `json:"executable"`
+	Name       string   `json:"name"`
+	Args       []string `json:"args,omitempty"`
+	Hash       *ECSHash `json:"hash,omitempty"`
+}
+
+type ECSUser struct {
+	Name string `json:"name"`
+}
+
+type ECSRule struct {
+	Name    []string `json:"name"`
+	Ruleset string   `json:"ruleset"`
+}
+
+type ECSThreat struct {
+	Framework string              `json:"framework"`
+	Tactic    ECSThreatReference  `json:"tactic"`
+	Technique *ECSThreatTechnique `json:"technique,omitempty"`
+}
+
+type ECSThreatReference struct {
+	ID        []string `json:"id"`
+	Name      []string `json:"name"`
+	Reference []string `json:"reference,omitempty"`
+}
+
+type ECSThreatTechnique struct {
+	ECSThreatReference
+	Subtechnique *ECSThreatReference `json:"subtechnique,omitempty"`
+}
+
+type ECSScannerField struct {
+	Mechanism  scanner.MechanismType `json:"mechanism"`
+	Label      string                `json:"label,omitempty"`
+	RiskLevel  scanner.RiskLevel     `json:"risk_level"`
+	Confidence float64               `json:"confidence"`
+	RunAtLoad  bool                  `json:"run_at_load"`
+	KeepAlive  bool                  `json:"keep_alive"`
+	Disabled   bool                  `json:"disabled"`
+}
+
+func (f *ECSFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	return formatBuffered(f, result)
+}
+
+func (f *ECSFormatter) FormatStream(w io.Writer, result *scanner.ScanResult, items ItemIterator) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for {
+		item, err := items.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(f.convert(result, item)); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *ECSFormatter) convert(result *scanner.ScanResult, item *scanner.PersistenceItem) ECSDocument {
+	doc := ECSDocument{
+		Timestamp: result.EndTime,
+		ECS:       ECSVersion{Version: ecsVersion},
+		Event: ECSEvent{
+			Kind:      "event",
+			Category:  []string{"configuration", "file"},
+			Type:      []string{"info"},
+			Module:    "macos_persist_scan",
+			Dataset:   "macos_persist_scan.persistence",
+			RiskScore: item.Risk.Score * 100,
+			Severity:  f.severity(item.Risk.Level),
+			Created:   result.EndTime,
+		},
+		Threat: ECSThreat{
+			Framework: "MITRE ATT&CK",
+			Tactic: ECSThreatReference{
+				ID:        []string{attack.TacticPersistence.ID},
+				Name:      []string{attack.TacticPersistence.Name},
+				Reference: []string{"https://attack.mitre.org/tactics/" + attack.TacticPersistence.ID + "/"},
+			},
+			Technique: f.techniques(itemTechniques(item)),
+		},
+		Scanner: ECSScannerField{
+			Mechanism:  item.Mechanism,
+			Label:      item.Label,
+			RiskLevel:  item.Risk.Level,
+			Confidence: item.Risk.Confidence,
+			RunAtLoad:  item.RunAtLoad,
+			KeepAlive:  item.KeepAlive,
+			Disabled:   item.Disabled,
+		},
+	}
+	doc.Host.Hostname = result.Hostname
+	doc.Host.OS.Type = "macos"
+
+	if item.Risk.Level != scanner.RiskInfo && item.Risk.Level != "" {
+		doc.Event.Kind = "alert"
+	}
+	if len(item.Risk.Reasons) > 0 {
+		doc.Event.Reason = item.Risk.Reasons[0]
+	}
+
+	if filepath.IsAbs(item.Path) {
+		doc.File = &ECSFile{
+			Path:      item.Path,
+			Name:      filepath.Base(item.Path),
+			Directory: filepath.Dir(item.Path),
+			Mode:      item.FileMode,
+		}
+		if !item.ModifiedAt.IsZero() {
+			mtime := item.ModifiedAt
+			doc.File.Mtime = &mtime
+		}
+		if item.ConfigFile != nil {
+			doc.File.Size = item.ConfigFile.Size
+			doc.File.Hash = &ECSHash{SHA256: item.ConfigFile.SHA256}
+		}
+	}
+
+	if item.Program != "" {
+		doc.Process = &ECSProcess{
+			Executable: item.Program,
+			Name:       filepath.Base(item.Program),
+			Args:       item.ProgramArgs,
+		}
+		if item.ProgramFile != nil {
+			doc.Process.Hash = &ECSHash{SHA256: item.ProgramFile.SHA256}
+		}
+	}
+
+	if item.User != "" {
+		doc.User = &ECSUser{Name: item.User}
+	}
+
+	var triggered []string
+	for _, h := range item.Risk.Heuristics {
+		if h.Triggered {
+			triggered = append(triggered, h.Name)
+		}
+	}
+	if len(triggered) > 0 {
+		doc.Rule = &ECSRule{Name: triggered, Ruleset: "macos-persist-scan"}
+	}
+
+	return doc
+}
+
+// techniques splits technique IDs into ECS technique and subtechnique lists.
+func (f *ECSFormatter) techniques(ids []string) *ECSThreatTechnique {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	technique := &ECSThreatTechnique{}
+	seen := make(map[string]bool)
+
+	addTechnique := func(id string) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		t := attack.Lookup(id)
+		technique.ID = append(technique.ID, t.ID)
+		technique.Name = append(technique.Name, t.Name)
+		technique.Reference = append(technique.Reference, attack.URL(t.ID))
+	}
+
+	for _, id := range ids {
+		parent := attack.Parent(id)
+		if parent == "" {
+			addTechnique(id)
+			continue
+		}
+
+		addTechnique(parent)
+		if technique.Subtechnique == nil {
+			technique.Subtechnique = &ECSThreatReference{}
+		}
+		sub := attack.Lookup(id)
+		technique.Subtechnique.ID = append(technique.Subtechnique.ID, sub.ID)
+		technique.Subtechnique.Name = append(technique.Subtechnique.Name, sub.Name)
+		technique.Subtechnique.Reference = append(technique.Subtechnique.Reference, attack.URL(sub.ID))
+	}
+
+	return technique
+}
+
+// severity maps risk levels onto the ECS 0-100 severity scale.
+func (f *ECSFormatter) severity(level scanner.RiskLevel) int {
+	switch level {
+	case scanner.RiskCritical:
+		return 99
+	case scanner.RiskHigh:
+		return 73
+	case scanner.RiskMedium:
+		return 47
+	case scanner.RiskLow:
+		return 21
+	default:
+		return 0
+	}
+}
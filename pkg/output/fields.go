@@ -0,0 +1,93 @@
+package output
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/attack"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// toGeneric converts v into the maps and slices encoding/json would produce,
+// so fields can be addressed by their JSON names.
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// lookupField walks a dotted JSON path such as "risk.level" or
+// "program_args.0" through a value produced by toGeneric.
+func lookupField(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+
+	current := v
+	for _, part := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// selectFields returns the values at the given dotted JSON paths of item,
+// keyed by path. Fields the item lacks are null.
+func selectFields(item *scanner.PersistenceItem, fields []string) (map[string]interface{}, error) {
+	generic, err := toGeneric(item)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]interface{}, len(fields))
+	for _, path := range fields {
+		selected[path], _ = lookupField(generic, path)
+	}
+	return selected, nil
+}
+
+// itemTechniques returns the ATT&CK techniques recorded on an item, falling
+// back to its mechanism's techniques for results produced without them.
+func itemTechniques(item *scanner.PersistenceItem) []string {
+	if len(item.ATTACKTechniques) > 0 {
+		return item.ATTACKTechniques
+	}
+	return attack.ForMechanism(item.Mechanism)
+}
+
+// truncate shortens s to at most n characters, marking the cut with "...".
+// A negative n is treated as zero.
+func truncate(n int, s string) string {
+	if n < 0 {
+		n = 0
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
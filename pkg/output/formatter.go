@@ -11,20 +11,56 @@ type Formatter interface {
 type FormatterType string
 
 const (
-	FormatterTable FormatterType = "table"
-	FormatterJSON  FormatterType = "json"
-	FormatterSARIF FormatterType = "sarif"
+	FormatterTable   FormatterType = "table"
+	FormatterJSON    FormatterType = "json"
+	FormatterSARIF   FormatterType = "sarif"
+	FormatterCEF     FormatterType = "cef"
+	FormatterECS     FormatterType = "ecs"
+	FormatterSummary FormatterType = "summary"
+
+	// Streaming formats written one item at a time
+	FormatterNDJSON FormatterType = "ndjson"
+	FormatterCSV    FormatterType = "csv"
+
+	// Timeline formats for DFIR tooling
+	FormatterBodyfile   FormatterType = "bodyfile"
+	FormatterTimesketch FormatterType = "timesketch"
+
+	// FormatterTemplate requires a template file and is constructed with
+	// NewTemplateFormatter rather than GetFormatter.
+	FormatterTemplate FormatterType = "template"
 )
 
+// Formats lists every output format, in the order help text gives them.
+var Formats = []FormatterType{
+	FormatterTable, FormatterJSON, FormatterSARIF, FormatterCEF, FormatterECS,
+	FormatterNDJSON, FormatterCSV, FormatterSummary, FormatterBodyfile,
+	FormatterTimesketch, FormatterTemplate,
+}
+
 func GetFormatter(formatType FormatterType) Formatter {
 	switch formatType {
 	case FormatterJSON:
 		return &JSONFormatter{}
 	case FormatterSARIF:
 		return &SARIFFormatter{}
+	case FormatterCEF:
+		return &CEFFormatter{}
+	case FormatterECS:
+		return &ECSFormatter{}
+	case FormatterSummary:
+		return &SummaryFormatter{}
+	case FormatterNDJSON:
+		return &NDJSONFormatter{}
+	case FormatterCSV:
+		return &CSVFormatter{}
+	case FormatterBodyfile:
+		return &TimelineFormatter{Bodyfile: true}
+	case FormatterTimesketch:
+		return &TimelineFormatter{}
 	case FormatterTable:
 		fallthrough
 	default:
 		return &TableFormatter{}
 	}
-}
\ No newline at end of file
+}
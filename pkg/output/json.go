@@ -8,11 +8,30 @@ import (
 
 type JSONFormatter struct {
 	Pretty bool
+	// Fields, if set, replaces each item with just these dotted JSON
+	// paths, such as "id" or "risk.level", keyed by path.
+	Fields []string
 }
 
 func (f *JSONFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	var v interface{} = result
+	if len(f.Fields) > 0 {
+		generic, err := toGeneric(result)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]interface{}, len(result.Items))
+		for i := range result.Items {
+			if items[i], err = selectFields(&result.Items[i], f.Fields); err != nil {
+				return nil, err
+			}
+		}
+		generic.(map[string]interface{})["items"] = items
+		v = generic
+	}
+
 	if f.Pretty {
-		return json.MarshalIndent(result, "", "  ")
+		return json.MarshalIndent(v, "", "  ")
 	}
-	return json.Marshal(result)
-}
\ No newline at end of file
+	return json.Marshal(v)
+}
@@ -0,0 +1,80 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/haasonsaas/macos-persist-scan/pkg/merge"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// maxListedHosts is how many hosts a finding names in text output.
+const maxListedHosts = 10
+
+// MergeFormatter renders a multi-host report as colored text or JSON.
+type MergeFormatter struct {
+	JSON bool
+}
+
+func (f *MergeFormatter) Format(report *merge.Report) ([]byte, error) {
+	if f.JSON {
+		return json.MarshalIndent(report, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	table := &TableFormatter{}
+	heading := color.New(color.Bold)
+
+	fmt.Fprintf(&buf, "Merged %d hosts; listing findings at %s risk and above\n\n", report.TotalHosts, report.MinRisk)
+
+	heading.Fprintf(&buf, "By finding (%d)\n", len(report.Findings))
+	for _, finding := range report.Findings {
+		fmt.Fprintf(&buf, "  %s  %d/%d hosts  %s\n", table.colorizeRisk(finding.Risk), finding.Prevalence, report.TotalHosts,
+			f.describe(finding.Mechanism, finding.Label, finding.Path))
+		hosts := finding.Hosts
+		if len(hosts) > maxListedHosts {
+			hosts = append(hosts[:maxListedHosts:maxListedHosts], fmt.Sprintf("and %d more", len(finding.Hosts)-maxListedHosts))
+		}
+		fmt.Fprintf(&buf, "      on %s\n", strings.Join(hosts, ", "))
+	}
+
+	fmt.Fprintln(&buf)
+	heading.Fprintf(&buf, "By host (%d)\n", len(report.Hosts))
+	for _, host := range report.Hosts {
+		var counts []string
+		for i := len(scanner.RiskLevels) - 1; i >= 0; i-- {
+			level := scanner.RiskLevels[i]
+			if n := host.RiskSummary[level]; n > 0 {
+				counts = append(counts, fmt.Sprintf("%d %s", n, strings.ToLower(string(level))))
+			}
+		}
+		line := host.Name
+		if !host.ScanTime.IsZero() {
+			line += ", scanned " + host.ScanTime.Format("2006-01-02 15:04")
+		}
+		if host.OSVersion != "" {
+			line += ", macOS " + host.OSVersion
+		}
+		if len(counts) > 0 {
+			line += ": " + strings.Join(counts, ", ")
+		}
+		fmt.Fprintf(&buf, "  %s\n", line)
+		for _, finding := range host.Findings {
+			fmt.Fprintf(&buf, "    %s  %d/%d hosts  %s\n", table.colorizeRisk(finding.Risk), finding.Prevalence, report.TotalHosts,
+				f.describe(finding.Mechanism, finding.Label, finding.Path))
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (f *MergeFormatter) describe(mechanism scanner.MechanismType, label, path string) string {
+	if label == "" {
+		label = filepath.Base(path)
+	}
+	return fmt.Sprintf("[%s] %s (%s)", mechanism, label, path)
+}
@@ -1,9 +1,15 @@
 package output
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/haasonsaas/macos-persist-scan/pkg/attack"
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
 )
 
@@ -16,8 +22,10 @@ type SARIF struct {
 }
 
 type SARIFRun struct {
-	Tool    SARIFTool     `json:"tool"`
-	Results []SARIFResult `json:"results"`
+	Tool        SARIFTool         `json:"tool"`
+	Invocations []SARIFInvocation `json:"invocations,omitempty"`
+	Taxonomies  []SARIFTaxonomy   `json:"taxonomies,omitempty"`
+	Results     []SARIFResult     `json:"results"`
 }
 
 type SARIFTool struct {
@@ -25,29 +33,61 @@ type SARIFTool struct {
 }
 
 type SARIFDriver struct {
-	Name            string      `json:"name"`
-	Version         string      `json:"version"`
-	InformationURI  string      `json:"informationUri"`
-	Rules           []SARIFRule `json:"rules"`
+	Name                string                  `json:"name"`
+	Version             string                  `json:"version"`
+	InformationURI      string                  `json:"informationUri"`
+	Rules               []SARIFRule             `json:"rules"`
+	SupportedTaxonomies []SARIFToolComponentRef `json:"supportedTaxonomies,omitempty"`
 }
 
 type SARIFRule struct {
-	ID               string            `json:"id"`
-	Name             string            `json:"name"`
-	ShortDescription SARIFDescription  `json:"shortDescription"`
-	FullDescription  SARIFDescription  `json:"fullDescription"`
-	DefaultLevel     string            `json:"defaultConfiguration"`
+	ID                   string               `json:"id"`
+	Name                 string               `json:"name"`
+	ShortDescription     SARIFDescription     `json:"shortDescription"`
+	FullDescription      SARIFDescription     `json:"fullDescription"`
+	Help                 *SARIFMultiformat    `json:"help,omitempty"`
+	HelpURI              string               `json:"helpUri,omitempty"`
+	DefaultConfiguration SARIFConfiguration   `json:"defaultConfiguration"`
+	Properties           *SARIFRuleProperties `json:"properties,omitempty"`
 }
 
 type SARIFDescription struct {
 	Text string `json:"text"`
 }
 
+type SARIFMultiformat struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+type SARIFRuleProperties struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
 type SARIFResult struct {
-	RuleID    string           `json:"ruleId"`
-	Level     string           `json:"level"`
-	Message   SARIFMessage     `json:"message"`
-	Locations []SARIFLocation  `json:"locations"`
+	RuleID              string                  `json:"ruleId"`
+	RuleIndex           int                     `json:"ruleIndex"`
+	Level               string                  `json:"level"`
+	Message             SARIFMessage            `json:"message"`
+	Locations           []SARIFLocation         `json:"locations"`
+	PartialFingerprints map[string]string       `json:"partialFingerprints,omitempty"`
+	Taxa                []SARIFReportingDescRef `json:"taxa,omitempty"`
+	Properties          *SARIFResultProperties  `json:"properties,omitempty"`
+}
+
+type SARIFResultProperties struct {
+	Mechanism  scanner.MechanismType `json:"mechanism"`
+	Label      string                `json:"label,omitempty"`
+	Program    string                `json:"program,omitempty"`
+	RiskLevel  scanner.RiskLevel     `json:"riskLevel"`
+	RiskScore  float64               `json:"riskScore"`
+	Confidence float64               `json:"confidence"`
+	// CodeSignature is the structured signing information for Program.
+	CodeSignature *scanner.CodeSignature `json:"codeSignature,omitempty"`
 }
 
 type SARIFMessage struct {
@@ -66,7 +106,357 @@ type SARIFArtifactLocation struct {
 	URI string `json:"uri"`
 }
 
+type SARIFInvocation struct {
+	ExecutionSuccessful        bool                       `json:"executionSuccessful"`
+	StartTimeUTC               string                     `json:"startTimeUtc,omitempty"`
+	EndTimeUTC                 string                     `json:"endTimeUtc,omitempty"`
+	Machine                    string                     `json:"machine,omitempty"`
+	ToolExecutionNotifications []SARIFNotification        `json:"toolExecutionNotifications,omitempty"`
+	Properties                 *SARIFInvocationProperties `json:"properties,omitempty"`
+}
+
+// SARIFInvocationProperties records the configuration findings were graded
+// with.
+type SARIFInvocationProperties struct {
+	RiskThresholds *scanner.RiskThresholds `json:"riskThresholds,omitempty"`
+}
+
+type SARIFNotification struct {
+	Level   string       `json:"level"`
+	Message SARIFMessage `json:"message"`
+}
+
+type SARIFTaxonomy struct {
+	Name             string           `json:"name"`
+	GUID             string           `json:"guid"`
+	Organization     string           `json:"organization"`
+	InformationURI   string           `json:"informationUri"`
+	ShortDescription SARIFDescription `json:"shortDescription"`
+	Taxa             []SARIFTaxon     `json:"taxa"`
+}
+
+type SARIFTaxon struct {
+	ID               string           `json:"id"`
+	Name             string           `json:"name"`
+	ShortDescription SARIFDescription `json:"shortDescription"`
+	HelpURI          string           `json:"helpUri"`
+}
+
+type SARIFToolComponentRef struct {
+	Name string `json:"name"`
+	GUID string `json:"guid"`
+}
+
+type SARIFReportingDescRef struct {
+	ID            string                `json:"id"`
+	ToolComponent SARIFToolComponentRef `json:"toolComponent"`
+}
+
+const (
+	attackTaxonomyName = "MITRE ATT&CK"
+	// Fixed GUID so consumers can correlate the taxonomy across runs
+	attackTaxonomyGUID = "b7a1f6a0-6c2e-4a53-9d0e-4e6d3c1f7a10"
+)
+
+// sarifRule describes how a heuristic is presented as a SARIF rule.
+type sarifRule struct {
+	heuristic string
+	id        string
+	name      string
+	short     string
+	full      string
+	help      string
+	level     string
+}
+
+var sarifRules = []sarifRule{
+	{
+		heuristic: "signature_verification",
+		id:        "unsigned-binary",
+		name:      "Unsigned Binary",
+		short:     "Binary is not code signed",
+		full:      "The persistence mechanism uses an unsigned binary, which could indicate malicious software",
+		help:      "Verify the binary with `codesign -dv --verbose=4 <path>`. Legitimate software is normally signed with an Apple or Developer ID certificate; unsigned or ad-hoc signed binaries launched at login deserve investigation.",
+		level:     "warning",
+	},
+	{
+		heuristic: "suspicious_path",
+		id:        "suspicious-path",
+		name:      "Suspicious File Path",
+		short:     "Binary located in suspicious directory",
+		full:      "The persistence mechanism references a binary in a temporary or unusual location",
+		help:      "Binaries in temporary, shared, hidden, or deeply nested directories are rarely installed by legitimate software. Identify what created the file and whether it is still needed.",
+		level:     "warning",
+	},
+	{
+		heuristic: "suspicious_behavior",
+		id:        "suspicious-behavior",
+		name:      "Suspicious Behavior Pattern",
+		short:     "Persistence exhibits suspicious behavioral patterns",
+		full:      "The persistence mechanism shows patterns commonly associated with malware",
+		help:      "Review the program arguments and any decoded payloads for downloads, inline interpreters, or dynamic code evaluation.",
+		level:     "warning",
+	},
+	{
+		heuristic: "name_entropy",
+		id:        "high-entropy-name",
+		name:      "High Entropy Name",
+		short:     "Name appears random or obfuscated",
+		full:      "The persistence item has a name with high entropy, suggesting randomness or obfuscation",
+		help:      "Randomised or Apple-lookalike labels are used to blend in. Compare the label with the vendor of the binary it launches.",
+		level:     "note",
+	},
+	{
+		heuristic: "fleet_rarity",
+		id:        "rare-in-fleet",
+		name:      "Rare In Fleet",
+		short:     "Item is rare across the fleet baseline",
+		full:      "The persistence item appears on very few hosts in the imported fleet prevalence database",
+		help:      "Items unique to one or two machines are worth confirming with the machine owner or software inventory.",
+		level:     "note",
+	},
+	{
+		heuristic: "gatekeeper_assessment",
+		id:        "gatekeeper-rejected",
+		name:      "Gatekeeper Rejected",
+		short:     "Program is rejected by Gatekeeper",
+		full:      "The syspolicy assessment rejected the program launched by the persistence mechanism",
+		help:      "Run `spctl --assess --type execute -vv <path>` to see the assessment source. Rejected programs are not notarized or are signed by an untrusted identity.",
+		level:     "warning",
+	},
+	{
+		heuristic: "launchd_triggers",
+		id:        "launchd-trigger-abuse",
+		name:      "Launchd Trigger Abuse",
+		short:     "Launchd job uses a risky trigger key",
+		full:      "The launchd job is triggered by watched paths, mounts, network sockets, Mach service names, device insertion, or notifications in a way commonly abused by malware",
+		help:      "Inspect the WatchPaths, QueueDirectories, StartOnMount, Sockets, MachServices, inetdCompatibility, and LaunchEvents keys of the plist and confirm the trigger matches the software's purpose.",
+		level:     "warning",
+	},
+	{
+		heuristic: "certificate_age",
+		id:        "new-signing-certificate",
+		name:      "New Signing Certificate",
+		short:     "Program signed with a newly issued or unique Developer ID certificate",
+		full:      "The program's Developer ID certificate was issued recently or is not shared by any other persistent program on the host, a pattern common in malware campaigns",
+		help:      "Extract the certificate with `codesign -d --extract-certificates <path>` and check the Team ID against known vendors.",
+		level:     "warning",
+	},
+	{
+		heuristic: "bundle_integrity",
+		id:        "modified-app-bundle",
+		name:      "Modified App Bundle",
+		short:     "App bundle contents were modified after signing",
+		full:      "Deep code signature verification of the hosting app bundle failed, indicating added, modified, or missing resources or helpers",
+		help:      "Run `codesign --verify --deep --strict -vv <bundle>` to list the modified files. A trojanized copy of a legitimate app should be replaced from a trusted source.",
+		level:     "error",
+	},
+	{
+		heuristic: "threat_intel",
+		id:        "known-ioc",
+		name:      "Known Indicator Of Compromise",
+		short:     "Item matches an indicator of compromise from the rule bundle",
+		full:      "The item's file hash, signing Team ID, label, or path matches an indicator of compromise in the installed rule bundle",
+		help:      "Treat the host as compromised: preserve the files for analysis before removing the item, and check the rule bundle for related indicators.",
+		level:     "error",
+	},
+	{
+		heuristic: "malformed_plist",
+		id:        "unparseable-plist",
+		name:      "Unparseable Plist",
+		short:     "Persistence definition file is not a valid plist",
+		full:      "A file in a launchd directory could not be decoded as a property list; corrupted or truncated plists can be used to hide a job from scanners",
+		help:      "Run `plutil -lint <path>` and compare the file's SHA-256 with a known-good copy. Preserve it before removing it.",
+		level:     "warning",
+	},
+	{
+		heuristic: "file_permissions",
+		id:        "weak-file-permissions",
+		name:      "Weak File Permissions",
+		short:     "Persistence file can be modified by other users",
+		full:      "The config file or program is world-writable, writable by everyone through an ACL, or belongs to a root daemon but is owned or group-writable by a non-root account",
+		help:      "Check `ls -le <path>` and restore root:wheel ownership and 644 or 755 permissions, or remove the item if the change was not expected.",
+		level:     "warning",
+	},
+	{
+		heuristic: "nvram_security",
+		id:        "weakened-boot-security",
+		name:      "Weakened Boot Security",
+		short:     "Boot setting weakens code signing or SIP, or NVRAM holds custom data",
+		full:      "A boot argument, csr-active-config, or library validation setting disables security enforcement, or a custom NVRAM variable that survives OS reinstalls is present",
+		help:      "Review `nvram -p` and `csrutil status`. Remove unexpected boot-args with `sudo nvram -d boot-args` and unknown variables with `sudo nvram -d <name>`.",
+		level:     "warning",
+	},
+	{
+		heuristic: "path_hijack",
+		id:        "path-hijack",
+		name:      "PATH Hijack",
+		short:     "Command search path includes a directory other users can write",
+		full:      "PATH or MANPATH, set by /etc/paths, /etc/paths.d, or a shell startup file, includes a world-writable or temporary directory, or searches a directory non-root users can write before /usr/bin and /bin",
+		help:      "Check `ls -ld <directory>` for each flagged entry and compare its contents with the system commands of the same name. Remove the entry or restore root ownership of the directory.",
+		level:     "warning",
+	},
+	{
+		heuristic: "environment_injection",
+		id:        "session-environment-injection",
+		name:      "Session Environment Injection",
+		short:     "Environment variable injects code into every process at login",
+		full:      "environment.plist, a launchd.conf file, or a launchd job running `launchctl setenv` sets a DYLD_ variable, an interpreter startup variable such as NODE_OPTIONS, or PATH for the whole session",
+		help:      "Inspect the file or job that sets the variable and the library or directory it names. Remove the entry, then run `launchctl unsetenv <name>` or log out to clear it from the session.",
+		level:     "warning",
+	},
+	{
+		heuristic: "architecture_mismatch",
+		id:        "architecture-mismatch",
+		name:      "Architecture Mismatch",
+		short:     "Program's CPU architectures are unusual for its role or host",
+		full:      "The program is an unsigned Intel-only binary relying on Rosetta on an Apple silicon Mac, an arm64e binary outside the system locations, or has no slice a current Mac can run",
+		help:      "Run `lipo -archs <program>` and `codesign -dv <program>`. Compare with the vendor's release; Intel-only unsigned binaries and arm64e binaries claiming Apple identifiers warrant collection and analysis.",
+		level:     "note",
+	},
+	{
+		heuristic: "browser_policy",
+		id:        "forced-browser-policy",
+		name:      "Forced Browser Policy",
+		short:     "Browser policy forces extensions, a proxy, or startup pages",
+		full:      "A Chrome, Edge, Brave, or Chromium policy force-installs extensions, especially from outside the Chrome Web Store, forces a proxy, or forces startup, home, or search pages; adware sets these to pose as enterprise management",
+		help:      "Check chrome://policy in the browser and `profiles list` for the profile that set it. Remove an unexpected profile with `sudo profiles remove -identifier <id>` or delete the preference file, then remove the extension.",
+		level:     "warning",
+	},
+	{
+		heuristic: "extension_state",
+		id:        "extension-state-mismatch",
+		name:      "Extension State Mismatch",
+		short:     "Kernel or system extension's runtime state does not match disk",
+		full:      "A kext or system extension is loaded with no corresponding approved bundle on disk, or a bundle is staged but awaiting approval or a restart",
+		help:      "Compare `kmutil showloaded` and `systemextensionsctl list` with /Library/Extensions and /Library/SystemExtensions. Decline or remove unexpected extensions with `systemextensionsctl uninstall <team> <id>` or by deleting the kext and rebuilding the kernel collection.",
+		level:     "warning",
+	},
+	{
+		heuristic: "dock_anomaly",
+		id:        "dock-tile-anomaly",
+		name:      "Dock Tile Anomaly",
+		short:     "Dock tile points at a temporary, downloaded, or deleted location",
+		full:      "A user's Dock keeps an application or file in a temporary directory, the Trash, a Downloads folder, or a mounted volume, or one that has been deleted; a planted tile gets the user to relaunch malware after it was cleaned up",
+		help:      "Check the tile's target against the user's installed applications. Remove an unexpected tile by dragging it out of the Dock, or edit persistent-apps in ~/Library/Preferences/com.apple.dock.plist and run `killall Dock`.",
+		level:     "warning",
+	},
+	{
+		heuristic: "root_account",
+		id:        "root-account-persistence",
+		name:      "Root Account Persistence",
+		short:     "Root's home directory holds SSH keys, an SSH rc script, or a shell startup payload",
+		full:      "An SSH key, ~/.ssh/rc script, or ~/.ssh/environment file in /var/root, or a root shell startup file that downloads or decodes something, gives persistent access as root",
+		help:      "Review /var/root/.ssh and root's shell startup files with `sudo ls -la /var/root/.ssh`. Remove keys and scripts nobody can account for, and confirm sshd_config has PermitRootLogin no.",
+		level:     "error",
+	},
+	{
+		heuristic: "automator_payload",
+		id:        "automator-workflow-payload",
+		name:      "Automator Workflow Payload",
+		short:     "Quick Action or Service script reaches the network or evaluates code",
+		full:      "A Run Shell Script, Run AppleScript, or Run JavaScript action in an Automator workflow in a Services folder downloads, connects out, or evaluates code decoded or built at run time, every time the Quick Action is used",
+		help:      "Open the workflow in Automator, or read Contents/document.wflow, to review its script actions. Delete unexpected workflows from ~/Library/Services or /Library/Services.",
+		level:     "warning",
+	},
+	{
+		heuristic: "login_shell",
+		id:        "modified-login-shell",
+		name:      "Modified Login Shell",
+		short:     "Account's login shell is a script or non-standard program",
+		full:      "A local account's UserShell is a script, a program in a user-writable or temporary location, or a program that is neither a shell macOS ships nor listed in /etc/shells; it runs at every Terminal window, SSH login, and su",
+		help:      "Check the shell with `dscl . -read /Users/<name> UserShell` and reset it with `sudo chsh -s /bin/zsh <name>` if it is not expected.",
+		level:     "warning",
+	},
+	{
+		heuristic: "account_anomaly",
+		id:        "account-anomaly",
+		name:      "Account Anomaly",
+		short:     "Hidden, UID 0, system-range, or newly created admin account",
+		full:      "A local account other than root has UID 0, can log in while hidden from the login window, can log in with a UID below 500, or is an admin created in the last month",
+		help:      "Inspect the account with `dscl . -read /Users/<name>` and its admin membership with `dseditgroup -o checkmember -m <name> admin`. Delete accounts nobody created with `sudo sysadminctl -deleteUser <name>`.",
+		level:     "error",
+	},
+	{
+		heuristic: "newsyslog_action",
+		id:        "newsyslog-command",
+		name:      "Newsyslog Rotation Action",
+		short:     "Log rotation runs a command or signals a process from an unusual location",
+		full:      "A newsyslog.conf entry runs a command as root after each rotation (R flag), or signals the process named in a pid file in a user-writable or temporary location",
+		help:      "Review /etc/newsyslog.conf and /etc/newsyslog.d for the entry. Remove the R flag or the pid file field unless the software that installed it needs them.",
+		level:     "warning",
+	},
+	{
+		heuristic: "wake_schedule",
+		id:        "unmanaged-wake-schedule",
+		name:      "Unmanaged Wake Schedule",
+		short:     "Mac is woken on a schedule no profile set, or a job runs just after such a wake",
+		full:      "A repeating or one-time wake or power-on was scheduled outside device management, or a third-party launchd job is scheduled within minutes after a repeating wake, so it runs while nobody is at the Mac",
+		help:      "List schedules with `pmset -g sched` and clear them with `sudo pmset repeat cancel` or `sudo pmset schedule cancelall`. Review the StartCalendarInterval of the jobs paired with the wake.",
+		level:     "note",
+	},
+	{
+		heuristic: "persistence_chain",
+		id:        "persistence-chain",
+		name:      "Persistence Chain",
+		short:     "Script creates further persistence, or was created by one",
+		full:      "A script or command line run by this item writes or loads a launchd plist, installs a crontab, or sets a loginwindow hook or login item, or this item is what such a command created. The linked items share a chain ID",
+		help:      "Follow the chain ID to the other items in the chain and remove the dropper along with everything it created; removing only the dropped item lets the dropper recreate it.",
+		level:     "warning",
+	},
+	{
+		heuristic: "shell_startup",
+		id:        "shell-startup-payload",
+		name:      "Shell Startup Payload",
+		short:     "A user's shell startup file downloads, decodes, or opens a connection",
+		full:      "A shell startup file in a user's home directory, such as ~/.zshrc or ~/.bash_profile, runs curl, wget, base64, nc, osascript, or an inline interpreter, or uses /dev/tcp, every time the user opens a shell",
+		help:      "Open the file and find the line named in the finding. Remove it if the user did not add it, and check what it downloaded or ran.",
+		level:     "warning",
+	},
+	{
+		heuristic: "browser_extension",
+		id:        "risky-browser-extension",
+		name:      "Risky Browser Extension",
+		short:     "Browser extension can leave the sandbox, debug tabs, or intercept every site",
+		full:      "An installed Chromium or Firefox extension requests nativeMessaging or debugger, combines access to every site with webRequest, proxy, or cookies, or updates from somewhere other than the Chrome Web Store or Edge Add-ons",
+		help:      "Find the extension by its ID on the browser's extensions page and remove it if the user did not install it. Check the native messaging hosts it may call.",
+		level:     "warning",
+	},
+	{
+		heuristic: "jamf_unmanaged",
+		id:        "unmanaged-by-jamf",
+		name:      "Not Managed By Jamf Pro",
+		short:     "Configuration profile or login item not deployed by Jamf Pro",
+		full:      "A configuration profile Jamf Pro does not scope to this Mac, or a login item no managed login item rule allows, was found on a Jamf Pro managed computer",
+		help:      "Compare `profiles list -all` with the computer's Management tab in Jamf Pro. Remove profiles installed outside Jamf Pro with `profiles remove -identifier <id>` after confirming no other management tool deployed them.",
+		level:     "warning",
+	},
+	{
+		heuristic: "santa_block",
+		id:        "santa-blocked-program",
+		name:      "Santa Blocked Program",
+		short:     "Persistence launches a program Santa blocks",
+		full:      "A persistence item's program is blocked by a Google Santa rule, or by lockdown mode. Programs on disk since before the blocking rule may have run before it was added",
+		help:      "Look up the program in `santactl fileinfo <path>` and Santa's event logs for executions before the rule was added. Remove the persistence item and the program if the block was deliberate.",
+		level:     "warning",
+	},
+}
+
+// RuleHelp returns the investigation guidance for a heuristic, or "" if
+// the heuristic has no rule.
+func RuleHelp(heuristic string) string {
+	for _, r := range sarifRules {
+		if r.heuristic == heuristic {
+			return r.help
+		}
+	}
+	return ""
+}
+
 func (f *SARIFFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	rules := f.generateRules()
+	results, techniques := f.convertResults(result.Items)
+
 	sarif := SARIF{
 		Version: "2.1.0",
 		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
@@ -76,10 +466,16 @@ func (f *SARIFFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
 					Name:           "macos-persist-scan",
 					Version:        "1.0.0",
 					InformationURI: "https://github.com/haasonsaas/macos-persist-scan",
-					Rules:          f.generateRules(),
+					Rules:          rules,
+					SupportedTaxonomies: []SARIFToolComponentRef{{
+						Name: attackTaxonomyName,
+						GUID: attackTaxonomyGUID,
+					}},
 				},
 			},
-			Results: f.convertResults(result.Items),
+			Invocations: []SARIFInvocation{f.invocation(result)},
+			Taxonomies:  []SARIFTaxonomy{f.taxonomy(techniques)},
+			Results:     results,
 		}},
 	}
 
@@ -87,76 +483,65 @@ func (f *SARIFFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
 }
 
 func (f *SARIFFormatter) generateRules() []SARIFRule {
-	return []SARIFRule{
-		{
-			ID:   "unsigned-binary",
-			Name: "Unsigned Binary",
-			ShortDescription: SARIFDescription{
-				Text: "Binary is not code signed",
-			},
-			FullDescription: SARIFDescription{
-				Text: "The persistence mechanism uses an unsigned binary, which could indicate malicious software",
-			},
-			DefaultLevel: "warning",
-		},
-		{
-			ID:   "suspicious-path",
-			Name: "Suspicious File Path",
-			ShortDescription: SARIFDescription{
-				Text: "Binary located in suspicious directory",
+	rules := make([]SARIFRule, 0, len(sarifRules))
+	for _, r := range sarifRules {
+		rules = append(rules, SARIFRule{
+			ID:               r.id,
+			Name:             r.name,
+			ShortDescription: SARIFDescription{Text: r.short},
+			FullDescription:  SARIFDescription{Text: r.full},
+			Help: &SARIFMultiformat{
+				Text:     strings.ReplaceAll(r.help, "`", ""),
+				Markdown: r.help,
 			},
-			FullDescription: SARIFDescription{
-				Text: "The persistence mechanism references a binary in a temporary or unusual location",
+			HelpURI:              "https://github.com/haasonsaas/macos-persist-scan#risk-assessment",
+			DefaultConfiguration: SARIFConfiguration{Level: r.level},
+			Properties: &SARIFRuleProperties{
+				Tags: []string{"security", "persistence", "macos"},
 			},
-			DefaultLevel: "warning",
-		},
-		{
-			ID:   "suspicious-behavior",
-			Name: "Suspicious Behavior Pattern",
-			ShortDescription: SARIFDescription{
-				Text: "Persistence exhibits suspicious behavioral patterns",
-			},
-			FullDescription: SARIFDescription{
-				Text: "The persistence mechanism shows patterns commonly associated with malware",
-			},
-			DefaultLevel: "warning",
-		},
-		{
-			ID:   "high-entropy-name",
-			Name: "High Entropy Name",
-			ShortDescription: SARIFDescription{
-				Text: "Name appears random or obfuscated",
-			},
-			FullDescription: SARIFDescription{
-				Text: "The persistence item has a name with high entropy, suggesting randomness or obfuscation",
-			},
-			DefaultLevel: "note",
-		},
+		})
 	}
+	return rules
 }
 
-func (f *SARIFFormatter) convertResults(items []scanner.PersistenceItem) []SARIFResult {
-	var results []SARIFResult
-	
+func (f *SARIFFormatter) convertResults(items []scanner.PersistenceItem) ([]SARIFResult, []string) {
+	results := []SARIFResult{}
+	seenTechniques := make(map[string]bool)
+	var techniques []string
+
 	for _, item := range items {
 		if item.Risk.Level == scanner.RiskInfo {
 			continue // Skip info level items in SARIF
 		}
-		
+
+		var taxa []SARIFReportingDescRef
+		for _, id := range itemTechniques(&item) {
+			taxa = append(taxa, SARIFReportingDescRef{
+				ID:            id,
+				ToolComponent: SARIFToolComponentRef{Name: attackTaxonomyName, GUID: attackTaxonomyGUID},
+			})
+			if !seenTechniques[id] {
+				seenTechniques[id] = true
+				techniques = append(techniques, id)
+			}
+		}
+
 		// Map heuristics to rules
 		for _, heuristic := range item.Risk.Heuristics {
 			if !heuristic.Triggered {
 				continue
 			}
-			
-			ruleID := f.heuristicToRuleID(heuristic.Name)
-			if ruleID == "" {
+
+			ruleIndex := f.heuristicToRuleIndex(heuristic.Name)
+			if ruleIndex < 0 {
 				continue
 			}
-			
+			ruleID := sarifRules[ruleIndex].id
+
 			result := SARIFResult{
-				RuleID: ruleID,
-				Level:  f.riskLevelToSARIF(item.Risk.Level),
+				RuleID:    ruleID,
+				RuleIndex: ruleIndex,
+				Level:     f.riskLevelToSARIF(item.Risk.Level),
 				Message: SARIFMessage{
 					Text: fmt.Sprintf("%s: %s", item.Label, heuristic.Details),
 				},
@@ -167,24 +552,103 @@ func (f *SARIFFormatter) convertResults(items []scanner.PersistenceItem) []SARIF
 						},
 					},
 				}},
+				PartialFingerprints: map[string]string{
+					"persistenceItem/v1": f.fingerprint(&item, ruleID),
+				},
+				Taxa: taxa,
+				Properties: &SARIFResultProperties{
+					Mechanism:     item.Mechanism,
+					Label:         item.Label,
+					Program:       item.Program,
+					RiskLevel:     item.Risk.Level,
+					RiskScore:     item.Risk.Score,
+					Confidence:    item.Risk.Confidence,
+					CodeSignature: item.CodeSignature,
+				},
 			}
-			
+
 			results = append(results, result)
 		}
 	}
-	
-	return results
+
+	sort.Strings(techniques)
+	return results, techniques
+}
+
+// fingerprint is stable across scans as long as the item's identity and the
+// rule it violates are unchanged, letting consumers dedupe repeat findings.
+func (f *SARIFFormatter) fingerprint(item *scanner.PersistenceItem, ruleID string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		string(item.Mechanism),
+		item.Path,
+		item.Label,
+		item.Program,
+		ruleID,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
 }
 
-func (f *SARIFFormatter) heuristicToRuleID(heuristicName string) string {
-	mapping := map[string]string{
-		"signature_verification": "unsigned-binary",
-		"suspicious_path":        "suspicious-path",
-		"suspicious_behavior":    "suspicious-behavior",
-		"name_entropy":          "high-entropy-name",
+func (f *SARIFFormatter) invocation(result *scanner.ScanResult) SARIFInvocation {
+	invocation := SARIFInvocation{
+		ExecutionSuccessful: len(result.Errors) == 0,
+		Machine:             result.Hostname,
+	}
+	if !result.StartTime.IsZero() {
+		invocation.StartTimeUTC = result.StartTime.UTC().Format(time.RFC3339)
 	}
-	
-	return mapping[heuristicName]
+	if !result.EndTime.IsZero() {
+		invocation.EndTimeUTC = result.EndTime.UTC().Format(time.RFC3339)
+	}
+	if result.RiskThresholds != nil {
+		invocation.Properties = &SARIFInvocationProperties{RiskThresholds: result.RiskThresholds}
+	}
+
+	for _, scanErr := range result.Errors {
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, SARIFNotification{
+			Level:   "error",
+			Message: SARIFMessage{Text: scanErr.Message()},
+		})
+	}
+	for _, path := range result.PermissionIssues {
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, SARIFNotification{
+			Level:   "warning",
+			Message: SARIFMessage{Text: "Permission denied: " + path},
+		})
+	}
+
+	return invocation
+}
+
+func (f *SARIFFormatter) taxonomy(techniques []string) SARIFTaxonomy {
+	taxonomy := SARIFTaxonomy{
+		Name:             attackTaxonomyName,
+		GUID:             attackTaxonomyGUID,
+		Organization:     "MITRE",
+		InformationURI:   "https://attack.mitre.org/",
+		ShortDescription: SARIFDescription{Text: "MITRE ATT&CK Enterprise techniques"},
+		Taxa:             []SARIFTaxon{},
+	}
+
+	for _, id := range techniques {
+		t := attack.Lookup(id)
+		taxonomy.Taxa = append(taxonomy.Taxa, SARIFTaxon{
+			ID:               t.ID,
+			Name:             t.Name,
+			ShortDescription: SARIFDescription{Text: t.Name},
+			HelpURI:          attack.URL(t.ID),
+		})
+	}
+
+	return taxonomy
+}
+
+func (f *SARIFFormatter) heuristicToRuleIndex(heuristicName string) int {
+	for i, r := range sarifRules {
+		if r.heuristic == heuristicName {
+			return i
+		}
+	}
+	return -1
 }
 
 func (f *SARIFFormatter) riskLevelToSARIF(level scanner.RiskLevel) string {
@@ -198,4 +662,4 @@ func (f *SARIFFormatter) riskLevelToSARIF(level scanner.RiskLevel) string {
 	default:
 		return "none"
 	}
-}
\ No newline at end of file
+}
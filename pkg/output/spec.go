@@ -0,0 +1,46 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Spec is one requested output: a format and an optional destination file.
+// An empty Path means the primary destination (stdout or --output-file).
+type Spec struct {
+	Format FormatterType
+	Path   string
+}
+
+// FormatterTypes lists every format accepted by ParseSpec.
+var FormatterTypes = []FormatterType{
+	FormatterTable,
+	FormatterJSON,
+	FormatterSARIF,
+	FormatterCEF,
+	FormatterECS,
+	FormatterSummary,
+	FormatterNDJSON,
+	FormatterCSV,
+	FormatterBodyfile,
+	FormatterTimesketch,
+	FormatterTemplate,
+}
+
+// ParseSpec parses "format" or "format=path".
+func ParseSpec(value string) (Spec, error) {
+	format, path, _ := strings.Cut(value, "=")
+
+	spec := Spec{
+		Format: FormatterType(strings.ToLower(strings.TrimSpace(format))),
+		Path:   strings.TrimSpace(path),
+	}
+
+	for _, known := range FormatterTypes {
+		if spec.Format == known {
+			return spec, nil
+		}
+	}
+
+	return spec, fmt.Errorf("unknown output format %q", format)
+}
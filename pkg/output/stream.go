@@ -0,0 +1,172 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// ItemIterator yields persistence items one at a time. Next returns io.EOF
+// after the last item.
+type ItemIterator interface {
+	Next() (*scanner.PersistenceItem, error)
+}
+
+// StreamFormatter is implemented by line-oriented formatters that can write
+// each item as soon as it is produced, without rendering the whole result
+// in memory. result supplies the scan-wide fields, such as the hostname and
+// end time; its Items are ignored in favour of items.
+type StreamFormatter interface {
+	Formatter
+	FormatStream(w io.Writer, result *scanner.ScanResult, items ItemIterator) error
+}
+
+// SliceItems iterates over items in order.
+func SliceItems(items []scanner.PersistenceItem) ItemIterator {
+	return &sliceIterator{items: items}
+}
+
+type sliceIterator struct {
+	items []scanner.PersistenceItem
+	next  int
+}
+
+func (it *sliceIterator) Next() (*scanner.PersistenceItem, error) {
+	if it.next >= len(it.items) {
+		return nil, io.EOF
+	}
+	it.next++
+	return &it.items[it.next-1], nil
+}
+
+// formatBuffered renders a stream formatter's output for result into memory,
+// for callers of the Formatter interface.
+func formatBuffered(f StreamFormatter, result *scanner.ScanResult) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.FormatStream(&buf, result, SliceItems(result.Items)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NDJSONFormatter emits each item as one JSON object per line.
+type NDJSONFormatter struct {
+	// Fields, if set, limits each line to these dotted JSON paths, keyed
+	// by path.
+	Fields []string
+}
+
+func (f *NDJSONFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	return formatBuffered(f, result)
+}
+
+func (f *NDJSONFormatter) FormatStream(w io.Writer, result *scanner.ScanResult, items ItemIterator) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for {
+		item, err := items.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var v interface{} = item
+		if len(f.Fields) > 0 {
+			if v, err = selectFields(item, f.Fields); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+	}
+}
+
+// csvColumns are the columns written by CSVFormatter.
+var csvColumns = []string{
+	"id", "mechanism", "label", "path", "program", "program_args", "user",
+	"run_at_load", "keep_alive", "disabled", "modified_at", "program_sha256",
+	"risk_level", "risk_score", "confidence", "attack_techniques", "reasons",
+}
+
+// CSVFormatter emits one row per item with a header row. Lists such as
+// program arguments and reasons are joined with "; ".
+type CSVFormatter struct{}
+
+func (f *CSVFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	return formatBuffered(f, result)
+}
+
+func (f *CSVFormatter) FormatStream(w io.Writer, result *scanner.ScanResult, items ItemIterator) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+
+	for {
+		item, err := items.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(f.row(item)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (f *CSVFormatter) row(item *scanner.PersistenceItem) []string {
+	var modified, sha256 string
+	if !item.ModifiedAt.IsZero() {
+		modified = item.ModifiedAt.UTC().Format(time.RFC3339)
+	}
+	if item.ProgramFile != nil {
+		sha256 = item.ProgramFile.SHA256
+	}
+
+	row := []string{
+		item.ID,
+		string(item.Mechanism),
+		item.Label,
+		item.Path,
+		item.Program,
+		strings.Join(item.ProgramArgs, "; "),
+		item.User,
+		strconv.FormatBool(item.RunAtLoad),
+		strconv.FormatBool(item.KeepAlive),
+		strconv.FormatBool(item.Disabled),
+		modified,
+		sha256,
+		string(item.Risk.Level),
+		strconv.FormatFloat(item.Risk.Score, 'f', 2, 64),
+		strconv.FormatFloat(item.Risk.Confidence, 'f', 2, 64),
+		strings.Join(itemTechniques(item), "; "),
+		strings.Join(item.Risk.Reasons, "; "),
+	}
+	for i, cell := range row {
+		row[i] = neutralizeFormula(cell)
+	}
+	return row
+}
+
+// neutralizeFormula keeps a spreadsheet from evaluating a cell, such as a
+// label or path chosen by whoever planted the item, as a formula.
+func neutralizeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
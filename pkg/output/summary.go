@@ -0,0 +1,171 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// SummaryFormatter prints risk counts by mechanism without per-item rows.
+// With Quiet set it prints only the one-line summary, for cron jobs and
+// health checks that mostly care about the exit code.
+type SummaryFormatter struct {
+	Quiet bool
+}
+
+var summaryLevels = []scanner.RiskLevel{
+	scanner.RiskCritical,
+	scanner.RiskHigh,
+	scanner.RiskMedium,
+	scanner.RiskLow,
+	scanner.RiskInfo,
+}
+
+func (f *SummaryFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	if f.Quiet {
+		return []byte(SummaryLine(result) + "\n"), nil
+	}
+
+	counts := make(map[scanner.MechanismType]map[scanner.RiskLevel]int)
+	for _, item := range result.Items {
+		if counts[item.Mechanism] == nil {
+			counts[item.Mechanism] = make(map[scanner.RiskLevel]int)
+		}
+		counts[item.Mechanism][item.Risk.Level]++
+	}
+
+	mechanisms := make([]scanner.MechanismType, 0, len(counts))
+	for mechanism := range counts {
+		mechanisms = append(mechanisms, mechanism)
+	}
+	sort.Slice(mechanisms, func(i, j int) bool { return mechanisms[i] < mechanisms[j] })
+
+	var buf bytes.Buffer
+	t := table.NewWriter()
+	t.SetOutputMirror(&buf)
+
+	header := table.Row{"Mechanism"}
+	for _, level := range summaryLevels {
+		header = append(header, level)
+	}
+	t.AppendHeader(append(header, "Total"))
+
+	for _, mechanism := range mechanisms {
+		row := table.Row{mechanism}
+		total := 0
+		for _, level := range summaryLevels {
+			row = append(row, counts[mechanism][level])
+			total += counts[mechanism][level]
+		}
+		t.AppendRow(append(row, total))
+	}
+	t.Render()
+
+	buf.WriteString("\n")
+	buf.WriteString(SummaryLine(result))
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
+
+// SummaryLine condenses a result into a single line of risk counts.
+func SummaryLine(result *scanner.ScanResult) string {
+	var counts []string
+	for _, level := range summaryLevels {
+		counts = append(counts, fmt.Sprintf("%s=%d", strings.ToLower(string(level)), result.RiskSummary[level]))
+	}
+
+	line := fmt.Sprintf("%d items (%s) in %s", result.TotalItems, strings.Join(counts, " "), result.Duration.Round(1e6))
+	if result.Partial {
+		line += ", interrupted (partial results)"
+	}
+	if result.Suppressed > 0 {
+		line += fmt.Sprintf(", %d suppressed", result.Suppressed)
+	}
+	if result.Filtered > 0 {
+		line += fmt.Sprintf(", %d filtered", result.Filtered)
+	}
+	if len(result.Errors) > 0 {
+		line += fmt.Sprintf(", %d scanner errors", len(result.Errors))
+	}
+	if len(result.PermissionIssues) > 0 {
+		line += fmt.Sprintf(", %d permission issues", len(result.PermissionIssues))
+	}
+	if incomplete := incompleteCoverage(result); len(incomplete) > 0 {
+		line += fmt.Sprintf(", %d locations not fully scanned", len(incomplete))
+	}
+	return line
+}
+
+// incompleteCoverage returns the locations that were read only partially
+// or not at all because a scanner failed. Locations that do not exist are
+// not a gap.
+func incompleteCoverage(result *scanner.ScanResult) []scanner.CoverageEntry {
+	var incomplete []scanner.CoverageEntry
+	for _, entry := range result.Coverage {
+		if entry.Status == scanner.CoveragePartial || entry.Status == scanner.CoverageFailed {
+			incomplete = append(incomplete, entry)
+		}
+	}
+	return incomplete
+}
+
+// RunSummary is the small machine-readable record of a scan written by
+// --summary-file, so wrappers can act on the counts and exit code without
+// parsing the full report.
+type RunSummary struct {
+	Hostname        string    `json:"hostname,omitempty"`
+	StartTime       time.Time `json:"start_time"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	TotalItems      int       `json:"total_items"`
+	// Risk counts items by lowercase risk level; every level is present.
+	Risk             map[string]int `json:"risk"`
+	Suppressed       int            `json:"suppressed"`
+	Filtered         int            `json:"filtered"`
+	Partial          bool           `json:"partial"`
+	Errors           []string       `json:"errors"`
+	PermissionIssues int            `json:"permission_issues"`
+	ExitCode         int            `json:"exit_code"`
+	// Error is why the scan failed, when it did not complete.
+	Error string `json:"error,omitempty"`
+}
+
+// NewRunSummary summarizes result, which exited with exitCode. A nil
+// result records a scan that failed with err.
+func NewRunSummary(result *scanner.ScanResult, exitCode int, err error) *RunSummary {
+	summary := &RunSummary{
+		Risk:     make(map[string]int),
+		Errors:   []string{},
+		ExitCode: exitCode,
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	for _, level := range summaryLevels {
+		summary.Risk[strings.ToLower(string(level))] = 0
+	}
+	if result == nil {
+		return summary
+	}
+
+	summary.Hostname = result.Hostname
+	summary.StartTime = result.StartTime
+	summary.DurationSeconds = result.Duration.Seconds()
+	summary.TotalItems = result.TotalItems
+	for _, level := range summaryLevels {
+		summary.Risk[strings.ToLower(string(level))] = result.RiskSummary[level]
+	}
+	summary.Suppressed = result.Suppressed
+	summary.Filtered = result.Filtered
+	summary.Partial = result.Partial
+	for _, e := range result.Errors {
+		summary.Errors = append(summary.Errors, e.Message())
+	}
+	summary.PermissionIssues = len(result.PermissionIssues)
+	return summary
+}
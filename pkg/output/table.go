@@ -3,6 +3,7 @@ package output
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
 	"sort"
 	"strings"
 
@@ -11,56 +12,186 @@ import (
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
 )
 
-type TableFormatter struct{}
+// TableFormatter renders items as a table. The zero value uses the standard
+// layout sorted by risk.
+type TableFormatter struct {
+	// Columns overrides the columns chosen by Layout.
+	Columns []string
+	// Layout is one of TableLayouts; empty means "standard".
+	Layout string
+	// SortBy is one of TableSortKeys; empty means "risk".
+	SortBy string
+	// MaxWidth truncates cells longer than this many characters. Zero
+	// disables truncation.
+	MaxWidth int
+	// GroupBy is "mechanism" to render one section per mechanism, or empty
+	// for a single table.
+	GroupBy string
+	// MaxItems limits the table to this many rows, in sort order, and
+	// notes how many were left out. Zero shows every item.
+	MaxItems int
+	// Page selects which MaxItems rows are shown, counting from 1; zero
+	// is the first page.
+	Page int
+}
+
+// TableColumns lists the column names accepted by TableFormatter.Columns.
+var TableColumns = []string{"risk", "score", "mechanism", "attack", "label", "path", "program", "args", "user", "modified", "notes"}
+
+// TableLayouts maps layout names to their default columns.
+var TableLayouts = map[string][]string{
+	"compact":  {"risk", "mechanism", "label", "program"},
+	"standard": {"risk", "mechanism", "attack", "label", "path", "program", "notes"},
+	"wide":     {"risk", "score", "mechanism", "attack", "label", "path", "program", "args", "user", "modified", "notes"},
+}
+
+// TableSortKeys lists the accepted values for TableFormatter.SortBy.
+var TableSortKeys = []string{"risk", "mechanism", "mtime"}
+
+// compactMaxWidth keeps compact rows within a typical terminal when no
+// explicit MaxWidth is set.
+const compactMaxWidth = 40
+
+var tableHeaders = map[string]string{
+	"risk":      "Risk",
+	"score":     "Score",
+	"mechanism": "Mechanism",
+	"attack":    "ATT&CK",
+	"label":     "Label/Name",
+	"path":      "Path",
+	"program":   "Program",
+	"args":      "Arguments",
+	"user":      "User",
+	"modified":  "Modified",
+	"notes":     "Notes",
+}
+
+// Validate reports unknown columns, layouts, or sort keys, and negative
+// widths or limits.
+func (f *TableFormatter) Validate() error {
+	if _, ok := TableLayouts[f.layout()]; !ok {
+		return fmt.Errorf("unknown table layout %q", f.Layout)
+	}
+	for _, column := range f.Columns {
+		if _, ok := tableHeaders[column]; !ok {
+			return fmt.Errorf("unknown table column %q (valid: %s)", column, strings.Join(TableColumns, ", "))
+		}
+	}
+	if f.GroupBy != "" && f.GroupBy != "mechanism" {
+		return fmt.Errorf("unknown table grouping %q (valid: mechanism)", f.GroupBy)
+	}
+	switch f.SortBy {
+	case "", "risk", "mechanism", "mtime":
+	default:
+		return fmt.Errorf("unknown table sort key %q (valid: %s)", f.SortBy, strings.Join(TableSortKeys, ", "))
+	}
+	if f.MaxWidth < 0 {
+		return fmt.Errorf("invalid cell width %d", f.MaxWidth)
+	}
+	if f.MaxItems < 0 {
+		return fmt.Errorf("invalid item limit %d", f.MaxItems)
+	}
+	if f.Page < 0 || (f.Page > 1 && f.MaxItems == 0) {
+		return fmt.Errorf("invalid page %d: pages need an item limit", f.Page)
+	}
+	return nil
+}
+
+// page returns the rows of items on the selected page, and the number of
+// rows before and after it.
+func (f *TableFormatter) page(items []scanner.PersistenceItem) (shown []scanner.PersistenceItem, before, after int) {
+	if f.MaxItems == 0 {
+		return items, 0, 0
+	}
+	page := f.Page
+	if page == 0 {
+		page = 1
+	}
+	start := (page - 1) * f.MaxItems
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + f.MaxItems
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end], start, len(items) - end
+}
 
 func (f *TableFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
 	var buf bytes.Buffer
 
-	// Create table
-	t := table.NewWriter()
-	t.SetOutputMirror(&buf)
-	t.AppendHeader(table.Row{"Risk", "Mechanism", "Label/Name", "Path", "Program", "Notes"})
+	columns := f.Columns
+	if len(columns) == 0 {
+		columns = TableLayouts[f.layout()]
+	}
+	maxWidth := f.MaxWidth
+	if maxWidth == 0 && f.layout() == "compact" {
+		maxWidth = compactMaxWidth
+	}
 
-	// Sort items by risk level (highest first)
 	items := make([]scanner.PersistenceItem, len(result.Items))
 	copy(items, result.Items)
-	sort.Slice(items, func(i, j int) bool {
-		return f.riskLevelToInt(items[i].Risk.Level) > f.riskLevelToInt(items[j].Risk.Level)
-	})
+	f.sortItems(items)
+	items, before, after := f.page(items)
 
-	// Add rows
-	for _, item := range items {
-		riskCell := f.colorizeRisk(item.Risk.Level)
-		
-		label := item.Label
-		if label == "" {
-			label = strings.TrimSuffix(item.ID, ".plist")
+	if f.GroupBy == "mechanism" {
+		groups := make(map[scanner.MechanismType][]scanner.PersistenceItem)
+		var mechanisms []scanner.MechanismType
+		for _, item := range items {
+			if _, ok := groups[item.Mechanism]; !ok {
+				mechanisms = append(mechanisms, item.Mechanism)
+			}
+			groups[item.Mechanism] = append(groups[item.Mechanism], item)
 		}
-		
-		notes := f.formatNotes(&item)
-		
-		t.AppendRow(table.Row{
-			riskCell,
-			item.Mechanism,
-			label,
-			item.Path,
-			item.Program,
-			notes,
-		})
+		sort.Slice(mechanisms, func(i, j int) bool { return mechanisms[i] < mechanisms[j] })
+
+		for i, mechanism := range mechanisms {
+			if i > 0 {
+				buf.WriteString("\n")
+			}
+			buf.WriteString(f.formatGroupHeading(mechanism, groups[mechanism]))
+			f.renderTable(&buf, groups[mechanism], columns, maxWidth)
+		}
+	} else {
+		f.renderTable(&buf, items, columns, maxWidth)
 	}
 
-	// Render table
-	t.Render()
+	if before+after > 0 {
+		pages := (len(result.Items) + f.MaxItems - 1) / f.MaxItems
+		if len(items) == 0 {
+			buf.WriteString(fmt.Sprintf("\nPage %d is past the last page (%d); %d additional items omitted.\n", f.Page, pages, before))
+		} else {
+			buf.WriteString(fmt.Sprintf("\nShowing items %d-%d of %d (page %d of %d); %d additional items omitted.\n",
+				before+1, before+len(items), len(result.Items), before/f.MaxItems+1, pages, before+after))
+		}
+	}
 
 	// Add summary
 	buf.WriteString("\n")
 	buf.WriteString(f.formatSummary(result))
 
+	if result.Partial {
+		buf.WriteString("\n\nScan interrupted: results are partial.\n")
+	}
+
 	// Add errors if any
 	if len(result.Errors) > 0 {
 		buf.WriteString("\n\nErrors encountered during scan:\n")
 		for _, err := range result.Errors {
-			buf.WriteString(fmt.Sprintf("  - %s: %s\n", err.Mechanism, err.Error))
+			buf.WriteString(fmt.Sprintf("  - %s\n", err.Message()))
+		}
+	}
+
+	// Note locations that could not be examined fully
+	if incomplete := incompleteCoverage(result); len(incomplete) > 0 {
+		buf.WriteString("\n\nIncomplete coverage:\n")
+		for _, entry := range incomplete {
+			buf.WriteString(fmt.Sprintf("  - %s %s: %s (%s)\n", entry.Mechanism, entry.Location, entry.Status, entry.Detail))
 		}
 	}
 
@@ -70,12 +201,126 @@ func (f *TableFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
 		for _, path := range result.PermissionIssues {
 			buf.WriteString(fmt.Sprintf("  - %s\n", path))
 		}
-		buf.WriteString("\nRun with elevated privileges for complete scan.\n")
+		buf.WriteString("\nRun as root with Full Disk Access for a complete scan; see the preflight command.\n")
 	}
 
 	return buf.Bytes(), nil
 }
 
+func (f *TableFormatter) renderTable(buf *bytes.Buffer, items []scanner.PersistenceItem, columns []string, maxWidth int) {
+	t := table.NewWriter()
+	t.SetOutputMirror(buf)
+	header := table.Row{}
+	for _, column := range columns {
+		header = append(header, tableHeaders[column])
+	}
+	t.AppendHeader(header)
+
+	for _, item := range items {
+		row := table.Row{}
+		for _, column := range columns {
+			cell := f.cell(&item, column)
+			if column == "risk" {
+				// Color codes would be counted against the width
+				row = append(row, f.colorizeRisk(item.Risk.Level))
+				continue
+			}
+			if maxWidth > 0 {
+				cell = truncate(maxWidth, cell)
+			}
+			row = append(row, cell)
+		}
+		t.AppendRow(row)
+	}
+
+	t.Render()
+}
+
+// formatGroupHeading introduces a mechanism section with its item count and
+// the count at each risk level present.
+func (f *TableFormatter) formatGroupHeading(mechanism scanner.MechanismType, items []scanner.PersistenceItem) string {
+	counts := make(map[scanner.RiskLevel]int)
+	for _, item := range items {
+		counts[item.Risk.Level]++
+	}
+
+	var parts []string
+	for _, level := range summaryLevels {
+		if counts[level] > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", f.colorizeRisk(level), counts[level]))
+		}
+	}
+
+	noun := "items"
+	if len(items) == 1 {
+		noun = "item"
+	}
+	heading := color.New(color.Bold).Sprintf("%s (%d %s)", mechanism, len(items), noun)
+	return fmt.Sprintf("%s  %s\n", heading, strings.Join(parts, ", "))
+}
+
+func (f *TableFormatter) layout() string {
+	if f.Layout == "" {
+		return "standard"
+	}
+	return f.Layout
+}
+
+func (f *TableFormatter) sortItems(items []scanner.PersistenceItem) {
+	switch f.SortBy {
+	case "mechanism":
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Mechanism != items[j].Mechanism {
+				return items[i].Mechanism < items[j].Mechanism
+			}
+			return f.riskLevelToInt(items[i].Risk.Level) > f.riskLevelToInt(items[j].Risk.Level)
+		})
+	case "mtime":
+		// Most recently modified first
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].ModifiedAt.After(items[j].ModifiedAt)
+		})
+	default:
+		// Sort items by risk level (highest first)
+		sort.SliceStable(items, func(i, j int) bool {
+			return f.riskLevelToInt(items[i].Risk.Level) > f.riskLevelToInt(items[j].Risk.Level)
+		})
+	}
+}
+
+func (f *TableFormatter) cell(item *scanner.PersistenceItem, column string) string {
+	switch column {
+	case "score":
+		return fmt.Sprintf("%.2f", item.Risk.Score)
+	case "mechanism":
+		return string(item.Mechanism)
+	case "attack":
+		return strings.Join(itemTechniques(item), ", ")
+	case "label":
+		if item.Label == "" {
+			return strings.TrimSuffix(filepath.Base(item.Path), ".plist")
+		}
+		return item.Label
+	case "path":
+		return item.Path
+	case "program":
+		return item.Program
+	case "args":
+		return strings.Join(item.ProgramArgs, " ")
+	case "user":
+		return item.User
+	case "modified":
+		if item.ModifiedAt.IsZero() {
+			return ""
+		}
+		return item.ModifiedAt.Format("2006-01-02 15:04")
+	case "notes":
+		return f.formatNotes(item)
+	default:
+		return string(item.Risk.Level)
+	}
+}
+
 func (f *TableFormatter) colorizeRisk(level scanner.RiskLevel) string {
 	switch level {
 	case scanner.RiskCritical:
@@ -133,7 +378,7 @@ func (f *TableFormatter) formatSummary(result *scanner.ScanResult) string {
 	buf.WriteString(fmt.Sprintf("Scan completed in %s\n", result.Duration.Round(1e6)))
 	buf.WriteString(fmt.Sprintf("Total items found: %d\n", result.TotalItems))
 	
-	if result.TotalItems > 0 {
+	if result.TotalItems > 0 && !result.Inventory {
 		buf.WriteString("\nRisk Summary:\n")
 		
 		levels := []scanner.RiskLevel{
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// TemplateFormatter renders a ScanResult through a user-supplied text/template.
+type TemplateFormatter struct {
+	tmpl *template.Template
+}
+
+// NewTemplateFormatter parses the template file at path.
+func NewTemplateFormatter(path string) (*TemplateFormatter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(TemplateFuncs()).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing template %s: %w", path, err)
+	}
+
+	return &TemplateFormatter{tmpl: tmpl}, nil
+}
+
+func (f *TemplateFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.tmpl.Execute(&buf, result); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// TemplateFuncs returns the helper functions available to templates.
+func TemplateFuncs() template.FuncMap {
+	table := &TableFormatter{}
+
+	return template.FuncMap{
+		"colorRisk": func(level scanner.RiskLevel) string {
+			return table.colorizeRisk(level)
+		},
+		"riskAtLeast": func(level scanner.RiskLevel, min string) bool {
+			return table.riskLevelToInt(level) >= table.riskLevelToInt(scanner.RiskLevel(min))
+		},
+		"field": func(v interface{}, path string) (interface{}, error) {
+			generic, err := toGeneric(v)
+			if err != nil {
+				return nil, err
+			}
+			value, _ := lookupField(generic, path)
+			return value, nil
+		},
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"join":  strings.Join,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"truncate": truncate,
+		"default": func(fallback, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return fallback
+			}
+			return value
+		},
+	}
+}
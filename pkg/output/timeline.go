@@ -0,0 +1,213 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// TimelineFormatter emits items as timestamped events for DFIR timelines,
+// either as a Sleuth Kit bodyfile for mactime or as Timesketch JSONL.
+// Timestamps a collector could not determine are left out. Text renders
+// the same events as a chronological listing for reading in a terminal.
+type TimelineFormatter struct {
+	Bodyfile bool
+	Text     bool
+	// Since and Until, when set, limit Timesketch and text events to that
+	// window.
+	Since time.Time
+	Until time.Time
+}
+
+// TimelineEvent is one Timesketch-compatible timeline entry.
+type TimelineEvent struct {
+	Message          string                `json:"message"`
+	Datetime         string                `json:"datetime"`
+	Timestamp        int64                 `json:"timestamp"`
+	TimestampDesc    string                `json:"timestamp_desc"`
+	DataType         string                `json:"data_type"`
+	Hostname         string                `json:"hostname,omitempty"`
+	Mechanism        scanner.MechanismType `json:"mechanism"`
+	Label            string                `json:"label,omitempty"`
+	Path             string                `json:"path"`
+	Program          string                `json:"program,omitempty"`
+	User             string                `json:"user,omitempty"`
+	RiskLevel        scanner.RiskLevel     `json:"risk_level"`
+	RiskScore        float64               `json:"risk_score"`
+	ATTACKTechniques []string              `json:"attack_techniques,omitempty"`
+}
+
+var bodyfileEscaper = strings.NewReplacer("|", "_", "\n", " ")
+
+func (f *TimelineFormatter) Format(result *scanner.ScanResult) ([]byte, error) {
+	if f.Bodyfile {
+		return f.bodyfile(result), nil
+	}
+	if f.Text {
+		return f.text(result), nil
+	}
+	return f.timesketch(result)
+}
+
+// bodyfile writes one mactime 3.x line per item:
+// MD5|name|inode|mode_as_string|UID|GID|size|atime|mtime|ctime|crtime
+func (f *TimelineFormatter) bodyfile(result *scanner.ScanResult) []byte {
+	var buf bytes.Buffer
+
+	for _, item := range result.Items {
+		crtime := item.CreatedAt
+		if crtime.IsZero() {
+			crtime = item.InstalledAt
+		}
+		if crtime.IsZero() && item.ModifiedAt.IsZero() {
+			continue
+		}
+
+		name := fmt.Sprintf("%s (%s: %s)", item.Path, item.Mechanism, f.label(&item))
+		fmt.Fprintf(&buf, "0|%s|0|%s|0|0|0|0|%d|0|%d\n",
+			bodyfileEscaper.Replace(name), item.FileMode, f.unix(item.ModifiedAt), f.unix(crtime))
+	}
+
+	return buf.Bytes()
+}
+
+// TimelineEvents flattens every timestamp of every item, including the
+// quarantine times and birth times of the files items refer to, into
+// events in chronological order.
+func (f *TimelineFormatter) TimelineEvents(result *scanner.ScanResult) []TimelineEvent {
+	var events []TimelineEvent
+
+	for i := range result.Items {
+		item := &result.Items[i]
+		for _, stamp := range f.stamps(item) {
+			if stamp.at.IsZero() || !f.within(stamp.at) {
+				continue
+			}
+			events = append(events, TimelineEvent{
+				Message:          fmt.Sprintf("%s persistence %s: %s", item.Mechanism, f.label(item), item.Path),
+				Datetime:         stamp.at.UTC().Format(time.RFC3339),
+				Timestamp:        stamp.at.UnixMicro(),
+				TimestampDesc:    stamp.desc,
+				DataType:         "macos:persistence:item",
+				Hostname:         result.Hostname,
+				Mechanism:        item.Mechanism,
+				Label:            item.Label,
+				Path:             item.Path,
+				Program:          item.Program,
+				User:             item.User,
+				RiskLevel:        item.Risk.Level,
+				RiskScore:        item.Risk.Score,
+				ATTACKTechniques: itemTechniques(item),
+			})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp < events[j].Timestamp })
+	return events
+}
+
+type timelineStamp struct {
+	desc string
+	at   time.Time
+}
+
+// stamps lists the times known for item. Configuration profiles carry their
+// install date in InstalledAt; a quarantine time records when a file was
+// downloaded, which can predate its creation on disk.
+func (f *TimelineFormatter) stamps(item *scanner.PersistenceItem) []timelineStamp {
+	stamps := []timelineStamp{
+		{"Creation Time", item.CreatedAt},
+		{"Modification Time", item.ModifiedAt},
+		{"Install Time", item.InstalledAt},
+	}
+	if file := item.ConfigFile; file != nil && file.XAttrs != nil && file.XAttrs.Quarantine != nil {
+		stamps = append(stamps, timelineStamp{"Quarantine Time", file.XAttrs.Quarantine.Time})
+	}
+	if file := item.ProgramFile; file != nil {
+		stamps = append(stamps, timelineStamp{"Program Creation Time", file.CreatedAt})
+		if file.XAttrs != nil && file.XAttrs.Quarantine != nil {
+			stamps = append(stamps, timelineStamp{"Program Quarantine Time", file.XAttrs.Quarantine.Time})
+		}
+	}
+	return stamps
+}
+
+func (f *TimelineFormatter) within(t time.Time) bool {
+	if !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && t.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func (f *TimelineFormatter) timesketch(result *scanner.ScanResult) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	for _, event := range f.TimelineEvents(result) {
+		if err := encoder.Encode(event); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// text writes one line per event, oldest first, for reading in a terminal.
+func (f *TimelineFormatter) text(result *scanner.ScanResult) []byte {
+	var buf bytes.Buffer
+	table := &TableFormatter{}
+
+	events := f.TimelineEvents(result)
+	if len(events) == 0 {
+		buf.WriteString("No timestamped items\n")
+		return buf.Bytes()
+	}
+
+	width := 0
+	for _, event := range events {
+		if len(event.TimestampDesc) > width {
+			width = len(event.TimestampDesc)
+		}
+	}
+	levelWidth := 0
+	for _, level := range scanner.RiskLevels {
+		if len(level) > levelWidth {
+			levelWidth = len(level)
+		}
+	}
+
+	for _, event := range events {
+		level := table.colorizeRisk(event.RiskLevel) + strings.Repeat(" ", levelWidth-len(event.RiskLevel))
+		label := event.Label
+		if label == "" {
+			label = filepath.Base(event.Path)
+		}
+		fmt.Fprintf(&buf, "%s  %-*s  %s  [%s] %s (%s)\n", event.Datetime, width, event.TimestampDesc, level,
+			event.Mechanism, label, event.Path)
+	}
+
+	return buf.Bytes()
+}
+
+func (f *TimelineFormatter) label(item *scanner.PersistenceItem) string {
+	if item.Label != "" {
+		return item.Label
+	}
+	return filepath.Base(item.Path)
+}
+
+func (f *TimelineFormatter) unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
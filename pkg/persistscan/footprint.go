@@ -0,0 +1,106 @@
+package persistscan
+
+import (
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Kinds of Access.
+const (
+	AccessFile     = "file"
+	AccessCommand  = "command"
+	AccessDatabase = "database"
+	AccessNetwork  = "network"
+)
+
+// Access is one file, command, database, or network service a scan touches
+// besides the locations the collectors read.
+type Access struct {
+	Kind    string `json:"kind"`
+	Target  string `json:"target"`
+	Purpose string `json:"purpose"`
+	// Write marks files and databases the scan writes.
+	Write bool `json:"write,omitempty"`
+}
+
+// Footprint lists everything a scan would read, run, write, or contact.
+type Footprint struct {
+	Root string `json:"root,omitempty"`
+	// Collectors lists what each collector reads, as seen on the scanned
+	// system.
+	Collectors []scanner.ScannerInfo `json:"collectors"`
+	// Accesses lists everything else touched, for each item found or once
+	// per scan.
+	Accesses []Access `json:"accesses,omitempty"`
+}
+
+// DryRun reports the footprint of a scan with opts without running it. Only
+// the local account list is read, as root or on an offline root, to expand
+// per-user paths; files named in opts are listed, not opened.
+func DryRun(opts Options) *Footprint {
+	scanMu.Lock()
+	defer scanMu.Unlock()
+	sysroot.Root = opts.Root
+
+	footprint := &Footprint{Root: opts.Root}
+	for _, s := range Scanners() {
+		info := scanner.ScannerInfo{Mechanism: s.Type()}
+		if d, ok := s.(scanner.Describer); ok {
+			info = d.Info()
+		}
+		if opts.NoExec {
+			info.Commands = nil
+		}
+		footprint.Collectors = append(footprint.Collectors, info)
+	}
+
+	// Every per-user collector expands its paths over the local accounts
+	if !opts.NoExec && opts.Root == "" {
+		footprint.Accesses = append(footprint.Accesses,
+			Access{AccessCommand, "dscl . -list /Users NFSHomeDirectory", "local accounts whose home directories are scanned, when the account records cannot be read directly", false},
+		)
+	}
+	footprint.Accesses = append(footprint.Accesses,
+		Access{AccessFile, "<config file and program of each item>", "SHA-256, size, ownership, mode, ACL, extended attributes, and Mach-O header", false},
+	)
+	if opts.Suppressions != "" {
+		footprint.Accesses = append(footprint.Accesses, Access{AccessFile, opts.Suppressions, "suppressed items", false})
+	}
+	if opts.Inventory {
+		return footprint
+	}
+
+	footprint.Accesses = append(footprint.Accesses,
+		Access{AccessCommand, "codesign -dv --verbose=4 <program>", "code signature of each item's program", false},
+		Access{AccessCommand, "codesign --verify --deep --strict --verbose=2 <bundle>", "integrity of each program's app bundle", false},
+		Access{AccessCommand, "codesign -d --extract-certificates=<temporary directory> <program>", "signing certificate age, and Santa certificate rules; written to a temporary directory removed afterwards", false},
+		Access{AccessDatabase, "/var/db/santa/rules.db", "Google Santa rules, opened read-only", false},
+		Access{AccessFile, "/var/db/santa/sync-state.plist", "Google Santa client mode", false},
+		Access{AccessFile, "/Library/Managed Preferences/com.google.santa.plist", "Google Santa client mode", false},
+	)
+	if opts.Root == "" {
+		footprint.Accesses = append(footprint.Accesses,
+			Access{AccessCommand, "spctl --assess --type execute --verbose=2 <program>", "Gatekeeper assessment of each item's program", false},
+		)
+	}
+	if opts.FleetDB != "" {
+		footprint.Accesses = append(footprint.Accesses, Access{AccessDatabase, opts.FleetDB, "fleet prevalence for rarity scoring", false})
+	}
+	if opts.Rules != "" {
+		footprint.Accesses = append(footprint.Accesses, Access{AccessFile, opts.Rules, "rule bundle indicators and allowlist", false})
+		footprint.Accesses = append(footprint.Accesses, Access{AccessFile, opts.Rules + ".sig", "rule bundle signature", false})
+	}
+	if opts.Jamf != "" {
+		footprint.Accesses = append(footprint.Accesses,
+			Access{AccessCommand, "ioreg -rd1 -c IOPlatformExpertDevice", "serial number this Mac is known by in Jamf Pro", false},
+			Access{AccessNetwork, opts.Jamf, "Jamf Pro API: profiles, policies, and login item rules scoped to this Mac", false},
+		)
+	}
+	if opts.SigningCache != "" {
+		footprint.Accesses = append(footprint.Accesses, Access{AccessFile, opts.SigningCache, "codesign and spctl results kept between scans", true})
+	}
+	if opts.ScanCache != "" {
+		footprint.Accesses = append(footprint.Accesses, Access{AccessFile, opts.ScanCache, "assessments of unchanged items kept between scans", true})
+	}
+	return footprint
+}
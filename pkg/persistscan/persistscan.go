@@ -0,0 +1,765 @@
+// Package persistscan is the programmatic entry point to the scanner. It
+// runs the same collectors, enrichers, and risk heuristics as the
+// macos-persist-scan CLI and returns the assessed result without printing
+// anything, so other Go programs can embed scanning instead of shelling out:
+//
+//	opts := persistscan.DefaultOptions()
+//	opts.Timeout = 2 * time.Minute
+//	result, err := persistscan.Scan(ctx, opts)
+//	if err != nil {
+//		return err
+//	}
+//	for _, item := range result.Items {
+//		if item.Risk.Level.Rank() >= scanner.RiskHigh.Rank() {
+//			...
+//		}
+//	}
+//
+// Non-fatal problems are logged through log/slog's default logger.
+package persistscan
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/haasonsaas/macos-persist-scan/internal/baseline"
+	"github.com/haasonsaas/macos-persist-scan/internal/collectors"
+	"github.com/haasonsaas/macos-persist-scan/internal/command"
+	"github.com/haasonsaas/macos-persist-scan/internal/enrichment"
+	"github.com/haasonsaas/macos-persist-scan/internal/heuristics"
+	"github.com/haasonsaas/macos-persist-scan/internal/jamf"
+	"github.com/haasonsaas/macos-persist-scan/internal/logging"
+	"github.com/haasonsaas/macos-persist-scan/internal/rules"
+	"github.com/haasonsaas/macos-persist-scan/internal/scancache"
+	"github.com/haasonsaas/macos-persist-scan/internal/sigcache"
+	"github.com/haasonsaas/macos-persist-scan/internal/suppress"
+	"github.com/haasonsaas/macos-persist-scan/internal/sysroot"
+	"github.com/haasonsaas/macos-persist-scan/pkg/attack"
+	"github.com/haasonsaas/macos-persist-scan/pkg/fleet"
+	"github.com/haasonsaas/macos-persist-scan/pkg/risk"
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Result is a completed, risk-assessed scan.
+type Result = scanner.ScanResult
+
+// Options controls a scan. Start from DefaultOptions; the zero value
+// disables parallelism and every timeout.
+type Options struct {
+	// Parallel runs scanners concurrently, at most Concurrency at a time
+	// (zero means one per CPU).
+	Parallel    bool
+	Concurrency int
+
+	// Timeout bounds each scanner and ScannerTimeouts overrides it per
+	// mechanism. CommandTimeout bounds each external command such as
+	// codesign or system_profiler, and SignatureTimeout each codesign
+	// check of a program, which is recorded as timed out rather than
+	// unsigned. Zero disables a limit.
+	Timeout          time.Duration
+	ScannerTimeouts  map[scanner.MechanismType]time.Duration
+	CommandTimeout   time.Duration
+	SignatureTimeout time.Duration
+	// TotalTimeout bounds the whole scan; CollectTimeout and AssessTimeout
+	// bound collection and assessment (hashing, enrichment, and scoring).
+	// Work still running when a limit passes is abandoned and the result
+	// is marked Partial. Zero disables a limit.
+	TotalTimeout   time.Duration
+	CollectTimeout time.Duration
+	AssessTimeout  time.Duration
+
+	// Root scans an offline system mounted at this path instead of the
+	// running one. An APFS Data volume on its own, such as a local
+	// snapshot, is recognized and its /private links followed.
+	Root string
+	// NoExec collects only from files, without running tools such as
+	// crontab, osascript, or system_profiler. Enrichment still runs
+	// codesign and spctl.
+	NoExec bool
+
+	// ScoringModel is one of the risk.Model* aggregation models.
+	ScoringModel string
+	// RiskThresholds are the scores at which items reach each risk level;
+	// the zero value means scanner.DefaultRiskThresholds.
+	RiskThresholds scanner.RiskThresholds
+	// CertificateAge flags Developer ID certificates issued more recently
+	// than this.
+	CertificateAge time.Duration
+	// FleetDB, if set, is a prevalence database used for rarity scoring.
+	FleetDB string
+
+	// SigningCache, if set, persists codesign and spctl results in this
+	// file between scans.
+	SigningCache string
+	// ScanCache, if set, keeps each item's assessment in this file. Later
+	// scans reuse it for items whose config file and program are
+	// unchanged, skipping hashing, signature checks, and scoring.
+	ScanCache string
+	// Suppressions, if set, is a suppression file whose items are dropped
+	// from the result.
+	Suppressions string
+	// Rules, if set, is an installed rule bundle. Items matching its
+	// indicators of compromise are flagged and allowlisted items are
+	// dropped from the result.
+	Rules string
+	// RulesKey is the base64 Ed25519 public key the installed rule
+	// bundle's signature is checked with on every load.
+	RulesKey string
+	// Jamf, if set, is the URL of a Jamf Pro server. Configuration
+	// profiles and login items on a running system are checked against
+	// what it manages on this Mac, with credentials from the environment
+	// (see jamf.NewClient).
+	Jamf string
+	// NoContent replaces file contents and script bodies with hashes and
+	// short excerpts.
+	NoContent bool
+
+	// DisabledHeuristics names heuristics that are not run, and
+	// HeuristicConfidence replaces the confidence of others' results, by
+	// heuristic name.
+	DisabledHeuristics  []string
+	HeuristicConfidence map[string]float64
+	// Offline keeps the scan off the network for air-gapped use: spctl is
+	// not run, and the Gatekeeper and Jamf Pro heuristics are disabled.
+	Offline bool
+	// Inventory only collects items: enrichment, risk scoring, and ATT&CK
+	// tagging are skipped, so items carry no risk assessment.
+	Inventory bool
+}
+
+// DefaultOptions returns the options the CLI uses when no flags are given.
+func DefaultOptions() Options {
+	return Options{
+		Parallel:         true,
+		Timeout:          60 * time.Second,
+		CommandTimeout:   30 * time.Second,
+		SignatureTimeout: 10 * time.Second,
+		ScoringModel:     risk.ModelWeightedAverage,
+		RiskThresholds:   scanner.DefaultRiskThresholds,
+		CertificateAge:   30 * 24 * time.Hour,
+	}
+}
+
+// scanMu serializes scans: collectors, the command runner, and the signing
+// cache are configured through package state shared by the whole process.
+var scanMu sync.Mutex
+
+// Scan collects every persistence item on the system, enriches and scores
+// it, and returns the result. Scans run one at a time; concurrent calls
+// wait for the scan in progress. If ctx is cancelled mid-scan, the items
+// collected so far are still assessed and returned in a result marked
+// Partial. If ctx has a deadline, or opts a timeout, that passes, the
+// result is returned marked Partial with whatever had been completed.
+func Scan(ctx context.Context, opts Options) (*Result, error) {
+	scanMu.Lock()
+	defer scanMu.Unlock()
+
+	// Time spent loading databases and Jamf Pro data below counts against
+	// the budget
+	if opts.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.TotalTimeout)
+		defer cancel()
+	}
+
+	var riskEngine *risk.Engine
+	var cache *scancache.Cache
+	var state engineState
+	if !opts.Inventory {
+		var err error
+		if riskEngine, state, err = newEngine(opts, false); err != nil {
+			return nil, err
+		}
+		if opts.SigningCache != "" {
+			if err := sigcache.Shared.Load(opts.SigningCache); err != nil {
+				return nil, err
+			}
+		}
+		if opts.ScanCache != "" {
+			files := []string{opts.FleetDB, opts.Rules}
+			// Santa verdicts are cached with the items they are on
+			for _, path := range enrichment.SantaFiles {
+				files = append(files, filepath.Join(opts.Root, path))
+			}
+			settings := scancache.Settings(
+				[]string{opts.ScoringModel, opts.CertificateAge.String(), opts.Root, opts.Jamf, fmt.Sprint(baseline.Bundled().Version),
+					fmt.Sprint(opts.DisabledHeuristics), fmt.Sprint(opts.HeuristicConfidence), fmt.Sprint(opts.Offline), fmt.Sprint(riskEngine.Thresholds()),
+					fmt.Sprint(len(state.errors)), state.jamf},
+				files,
+			)
+			if cache, err = scancache.Load(opts.ScanCache, settings); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if opts.Root != "" {
+		if info, err := os.Stat(opts.Root); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("root %s is not a directory", opts.Root)
+		}
+	}
+	sysroot.Root = opts.Root
+	sysroot.DataVolume = opts.Root != "" && sysroot.IsDataVolume(opts.Root)
+
+	// Create orchestrator
+	scanners := Scanners()
+	orchestrator := scanner.NewOrchestrator(scanners, opts.Parallel)
+	orchestrator.SetConcurrency(opts.Concurrency)
+	orchestrator.SetTimeout(opts.Timeout)
+	orchestrator.SetPhaseTimeouts(opts.CollectTimeout, opts.AssessTimeout)
+	for mechanism, timeout := range opts.ScannerTimeouts {
+		orchestrator.SetScannerTimeout(mechanism, timeout)
+	}
+	osVersion := sysroot.OSVersion()
+	stages := []scanner.Stage{collectors.FileStage, collectors.TargetStage}
+	var links []scanner.Stage
+	if !opts.Inventory {
+		stock, err := osBaseline(opts)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, assessStages(riskEngine, baseline.Stage(stock, osVersion))...)
+		links = linkStages()
+	}
+	if cache != nil {
+		stages = []scanner.Stage{cache.Stage(stages...)}
+	}
+	stages = append(links, stages...)
+	for _, stage := range stages {
+		orchestrator.AddStage(stage)
+	}
+	command.Timeout = opts.CommandTimeout
+	collectors.Concurrency = opts.Concurrency
+	collectors.NoExec = opts.NoExec
+	enrichment.Offline = opts.Offline
+	enrichment.Concurrency = opts.Concurrency
+	enrichment.SignatureTimeout = opts.SignatureTimeout
+	if !opts.Parallel {
+		collectors.Concurrency = 1
+		enrichment.Concurrency = 1
+	}
+
+	collectors.ResetPermissionIssues()
+	result, err := orchestrator.RunScan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scan failed: %w", err)
+	}
+	if sysroot.Offline() {
+		result.Root = opts.Root
+		result.Hostname = sysroot.Hostname()
+	}
+	result.OSVersion = osVersion
+	result.Errors = append(result.Errors, state.errors...)
+	if riskEngine != nil {
+		thresholds := riskEngine.Thresholds()
+		result.RiskThresholds = &thresholds
+	}
+	result.PermissionIssues = collectors.PermissionIssues()
+	// Abandoned stages are still updating the engine and the cache
+	assessed := len(orchestrator.AbandonedStages()) == 0
+	if riskEngine != nil && assessed {
+		result.Timings = append(result.Timings, riskEngine.Timings()...)
+	}
+	if cache != nil && assessed {
+		slog.Debug("scan cache", "hits", cache.Hits, "misses", cache.Misses)
+		if err := cache.Save(); err != nil {
+			logging.Warn("saving scan cache", err, "path", opts.ScanCache)
+		}
+	}
+	result.Coverage = collectors.Coverage(scanners, result)
+
+	if opts.Inventory {
+		result.Inventory = true
+	} else if err := applyAllowlist(result, opts); err != nil {
+		return nil, err
+	}
+
+	if opts.Suppressions != "" {
+		if err := ApplySuppressions(result, opts.Suppressions); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := sigcache.Shared.Save(); err != nil {
+		logging.Warn("saving signing cache", err, "path", opts.SigningCache)
+	}
+
+	if opts.NoContent {
+		result.RedactContent()
+	}
+
+	return result, nil
+}
+
+// Scanners returns the collectors a scan runs, configured for the current
+// user's privileges.
+func Scanners() []scanner.Scanner {
+	return []scanner.Scanner{
+		collectors.NewLaunchAgentScanner(),
+		collectors.NewLaunchDaemonScanner(),
+		collectors.NewLoginItemsScanner(),
+		collectors.NewConfigProfilesScanner(),
+		collectors.NewCronScanner(),
+		collectors.NewPeriodicScanner(),
+		collectors.NewLoginHooksScanner(),
+		collectors.NewNVRAMScanner(),
+		collectors.NewSyntheticScanner(),
+		collectors.NewSearchPathScanner(),
+		collectors.NewEnvironmentScanner(),
+		collectors.NewNativeMessagingScanner(),
+		collectors.NewBrowserPolicyScanner(),
+		collectors.NewKextScanner(),
+		collectors.NewSystemExtensionScanner(),
+		collectors.NewCUPSScanner(),
+		collectors.NewDockScanner(),
+		collectors.NewRootAccountScanner(),
+		collectors.NewAutomatorScanner(),
+		collectors.NewUserShellScanner(),
+		collectors.NewAccountScanner(),
+		collectors.NewNewsyslogScanner(),
+		collectors.NewPowerScheduleScanner(),
+		collectors.NewShellStartupScanner(),
+		collectors.NewBrowserExtensionScanner(),
+	}
+}
+
+// Inspect assesses a single file without scanning the system. A launchd
+// job definition is parsed as a LaunchDaemon if it sits in a LaunchDaemons
+// directory and as a LaunchAgent otherwise. Any other file, such as a
+// script or binary, is assessed as the program of a LaunchAgent. The
+// result holds one item.
+func Inspect(path string, opts Options) (*Result, error) {
+	scanMu.Lock()
+	defer scanMu.Unlock()
+
+	riskEngine, state, err := newEngine(opts, false)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SigningCache != "" {
+		if err := sigcache.Shared.Load(opts.SigningCache); err != nil {
+			return nil, err
+		}
+	}
+	sysroot.Root = ""
+	sysroot.DataVolume = false
+	command.Timeout = opts.CommandTimeout
+	enrichment.Offline = opts.Offline
+	enrichment.SignatureTimeout = opts.SignatureTimeout
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	mechanism := scanner.MechanismLaunchAgent
+	if strings.Contains(path, "/LaunchDaemons/") {
+		mechanism = scanner.MechanismLaunchDaemon
+	}
+	item, err := collectors.ParseLaunchdPlist(path, mechanism)
+	if err != nil {
+		item, err = programItem(path, info)
+		if err != nil {
+			return nil, err
+		}
+	}
+	item.ID = item.ComputeID()
+	items := []scanner.PersistenceItem{*item}
+	stock, err := osBaseline(opts)
+	if err != nil {
+		return nil, err
+	}
+	stages := append(linkStages(), collectors.FileStage, collectors.TargetStage)
+	stages = append(stages, assessStages(riskEngine, baseline.Stage(stock, sysroot.OSVersion()))...)
+	scanner.RunStages(stages, items)
+
+	thresholds := riskEngine.Thresholds()
+	result := &Result{
+		StartTime:      time.Now(),
+		Items:          items,
+		RiskThresholds: &thresholds,
+		Errors:         state.errors,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		result.Hostname = hostname
+	}
+	result.Summarize()
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+
+	if err := sigcache.Shared.Save(); err != nil {
+		logging.Warn("saving signing cache", err, "path", opts.SigningCache)
+	}
+	if opts.NoContent {
+		result.RedactContent()
+	}
+
+	return result, nil
+}
+
+// programItem describes a script or binary as the program of a LaunchAgent.
+// Text files are kept as script content so the behavior heuristic and the
+// payload decoder can read them.
+func programItem(path string, info os.FileInfo) (*scanner.PersistenceItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &scanner.PersistenceItem{
+		Mechanism:  scanner.MechanismLaunchAgent,
+		Label:      filepath.Base(path),
+		Path:       path,
+		Program:    path,
+		ModifiedAt: info.ModTime(),
+		FileMode:   info.Mode().String(),
+		RawData: map[string]interface{}{
+			"description": "File inspected on demand",
+		},
+	}
+	if utf8.Valid(data) && !bytes.Contains(data, []byte{0}) {
+		item.RawData["scriptContent"] = string(data)
+	}
+
+	return item, nil
+}
+
+// networkHeuristics are the heuristics whose checks reach the network:
+// Gatekeeper asks Apple's notarization service about programs, and the Jamf
+// Pro cross-check queries the server.
+var networkHeuristics = []string{"gatekeeper_assessment", "jamf_unmanaged"}
+
+// newEngine builds the risk engine with every heuristic opts enables. A
+// rescoring engine never reads the scanned system: heuristics that would
+// are replaced by the results already recorded on each item. Heuristics
+// left out because a server could not be reached are returned as errors to
+// record on the result, rather than failing the scan.
+func newEngine(opts Options, rescore bool) (*risk.Engine, engineState, error) {
+	var state engineState
+	aggregator, err := risk.NewAggregator(opts.ScoringModel)
+	if err != nil {
+		return nil, state, err
+	}
+	thresholds := opts.RiskThresholds
+	if thresholds == (scanner.RiskThresholds{}) {
+		thresholds = scanner.DefaultRiskThresholds
+	}
+	if err := thresholds.Validate(); err != nil {
+		return nil, state, err
+	}
+
+	// Initialize heuristics
+	heuristicsList := []risk.Heuristic{
+		heuristics.NewSignatureHeuristic(),
+		heuristics.NewPathHeuristic(),
+		heuristics.NewBehaviorHeuristic(),
+		heuristics.NewEntropyHeuristic(),
+		heuristics.NewGatekeeperHeuristic(),
+		heuristics.NewTriggerHeuristic(),
+		heuristics.NewCertificateAgeHeuristic(opts.CertificateAge),
+		heuristics.NewBundleIntegrityHeuristic(),
+		heuristics.NewMalformedHeuristic(),
+		heuristics.NewPermissionsHeuristic(),
+		heuristics.NewNVRAMHeuristic(),
+		heuristics.NewSearchPathHeuristic(),
+		heuristics.NewEnvironmentHeuristic(),
+		heuristics.NewBrowserPolicyHeuristic(),
+		heuristics.NewExtensionStateHeuristic(),
+		heuristics.NewDockHeuristic(),
+		heuristics.NewRootAccountHeuristic(),
+		heuristics.NewAutomatorHeuristic(),
+		heuristics.NewUserShellHeuristic(),
+		heuristics.NewAccountHeuristic(),
+		heuristics.NewNewsyslogHeuristic(),
+		heuristics.NewWakeScheduleHeuristic(),
+		heuristics.NewShellStartupHeuristic(),
+		heuristics.NewBrowserExtensionHeuristic(),
+		heuristics.NewChainHeuristic(),
+		heuristics.NewArchitectureHeuristic(opts.Root == "" && collectors.AppleSilicon()),
+		heuristics.NewSantaHeuristic(),
+	}
+	if rescore {
+		for i, h := range heuristicsList {
+			switch h.(type) {
+			case *heuristics.CertificateAgeHeuristic, *heuristics.BundleIntegrityHeuristic, *heuristics.ArchitectureHeuristic:
+				heuristicsList[i] = heuristics.Saved(h)
+			}
+		}
+	}
+
+	disabled, err := disabledHeuristics(opts, heuristicsList)
+	if err != nil {
+		return nil, state, err
+	}
+
+	if opts.FleetDB != "" {
+		db, err := fleet.Load(opts.FleetDB)
+		if err != nil {
+			return nil, state, err
+		}
+		heuristicsList = append(heuristicsList, heuristics.NewRarityHeuristic(db))
+	}
+
+	if opts.Rules != "" {
+		bundle, err := loadRules(opts)
+		if err != nil {
+			return nil, state, err
+		}
+		if bundle != nil {
+			heuristicsList = append(heuristicsList, heuristics.NewIntelHeuristic(bundle))
+		}
+	}
+
+	if opts.Jamf != "" && !disabled["jamf_unmanaged"] {
+		if opts.Root != "" && !rescore {
+			return nil, state, fmt.Errorf("the Jamf Pro cross-check needs the running system, not --root")
+		}
+		h, jamfState, err := jamfHeuristic(opts, rescore)
+		if err != nil {
+			// Laptops off the corporate network still get scanned
+			logging.Warn("skipping the Jamf Pro cross-check", err, "url", opts.Jamf)
+			state.errors = append(state.errors, scanner.ScanError{
+				Error:     fmt.Sprintf("Jamf Pro cross-check skipped: %v", err),
+				Timestamp: time.Now(),
+			})
+		} else {
+			heuristicsList = append(heuristicsList, h)
+			state.jamf = jamfState
+		}
+	}
+
+	var enabled []risk.Heuristic
+	for _, h := range heuristicsList {
+		if disabled[h.Name()] {
+			continue
+		}
+		if confidence, ok := opts.HeuristicConfidence[h.Name()]; ok {
+			h = heuristics.WithConfidence(h, confidence)
+		}
+		enabled = append(enabled, h)
+	}
+
+	riskEngine := risk.NewEngine(enabled)
+	riskEngine.SetAggregator(aggregator)
+	riskEngine.SetThresholds(thresholds)
+	return riskEngine, state, nil
+}
+
+// engineState is what building a risk engine learned beyond the engine.
+type engineState struct {
+	// errors records the heuristics left out because a server could not
+	// be reached.
+	errors []scanner.ScanError
+	// jamf identifies what Jamf Pro manages on this Mac, so cached
+	// assessments are redone when its scoping changes.
+	jamf string
+}
+
+// disabledHeuristics returns the names of the heuristics opts turns off,
+// and checks that every heuristic opts names exists and every confidence
+// override is between 0 and 1. builtin is the list always run; heuristics
+// that depend on other options count as existing too.
+func disabledHeuristics(opts Options, builtin []risk.Heuristic) (map[string]bool, error) {
+	known := make(map[string]bool)
+	for _, h := range append(builtin, &heuristics.RarityHeuristic{}, &heuristics.IntelHeuristic{}, &heuristics.JamfHeuristic{}) {
+		known[h.Name()] = true
+	}
+	check := func(name string) error {
+		if known[name] {
+			return nil
+		}
+		names := make([]string, 0, len(known))
+		for name := range known {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown heuristic %q (known: %s)", name, strings.Join(names, ", "))
+	}
+
+	disabled := make(map[string]bool)
+	for _, name := range opts.DisabledHeuristics {
+		if err := check(name); err != nil {
+			return nil, err
+		}
+		disabled[name] = true
+	}
+	if opts.Offline {
+		for _, name := range networkHeuristics {
+			disabled[name] = true
+		}
+	}
+	for name, confidence := range opts.HeuristicConfidence {
+		if err := check(name); err != nil {
+			return nil, err
+		}
+		if confidence < 0 || confidence > 1 {
+			return nil, fmt.Errorf("confidence for heuristic %s must be between 0 and 1, not %g", name, confidence)
+		}
+	}
+	return disabled, nil
+}
+
+// jamfHeuristic fetches what the Jamf Pro server in opts manages on this
+// Mac. Rescoring engines keep the results recorded at scan time, since the
+// saved result may come from another computer.
+func jamfHeuristic(opts Options, rescore bool) (risk.Heuristic, string, error) {
+	if rescore {
+		return heuristics.Saved(heuristics.NewJamfHeuristic(nil)), "", nil
+	}
+
+	serial, err := jamf.SerialNumber()
+	if err != nil {
+		return nil, "", fmt.Errorf("identifying this Mac to Jamf Pro: %w", err)
+	}
+	expected, err := jamf.NewClient(opts.Jamf).Expected(serial)
+	if err != nil {
+		return nil, "", err
+	}
+	slog.Debug("jamf pro", "serial", serial, "profiles", len(expected.Profiles), "policies", len(expected.Policies), "login_item_rules", len(expected.LoginItems))
+	return heuristics.NewJamfHeuristic(expected), expected.Fingerprint(), nil
+}
+
+// assessStages returns the stages that enrich items, score them with
+// riskEngine, classify stock macOS items with stock, and tag them with
+// ATT&CK techniques, in order.
+func assessStages(riskEngine *risk.Engine, stock scanner.Stage) []scanner.Stage {
+	return []scanner.Stage{
+		// Enrich items with structured facts used by the heuristics
+		enrichment.Stage(
+			enrichment.NewGatekeeperEnricher(),
+			enrichment.NewSignatureEnricher(),
+			enrichment.NewSantaEnricher(),
+		),
+		riskEngine,
+		stock,
+		attack.Stage,
+	}
+}
+
+// linkStages returns the stages that relate items to one another. They run
+// ahead of the scan cache, over every item, since a changed item changes
+// the links of unchanged ones; the cache compares the links it recorded.
+func linkStages() []scanner.Stage {
+	return []scanner.Stage{
+		// Pair wake schedules with the jobs that run after them
+		enrichment.WakeStage,
+		// Link scripts, and the second stages decoded from them, to the
+		// persistence they create
+		enrichment.Stage(enrichment.NewPayloadDecoder()),
+		enrichment.ChainStage,
+	}
+}
+
+// osBaseline returns the manifest of stock macOS items: the bundled one, or
+// the one in the rule bundle opts names when that is newer.
+func osBaseline(opts Options) (*baseline.Manifest, error) {
+	stock := baseline.Bundled()
+	if opts.Rules == "" {
+		return stock, nil
+	}
+	bundle, err := loadRules(opts)
+	if err != nil || bundle == nil {
+		return stock, err
+	}
+	return stock.Newer(bundle.Baseline), nil
+}
+
+// Rescore re-runs the risk heuristics over a saved result without reading
+// the system it was collected from, so results gathered elsewhere can be
+// re-analyzed with a different scoring model, fleet database, or
+// suppression file. Enrichment recorded at scan time is reused, and
+// heuristics that need the original files keep their recorded results.
+// The returned result is a copy; saved is not modified.
+func Rescore(saved *Result, opts Options) (*Result, error) {
+	if saved.Inventory {
+		return nil, fmt.Errorf("inventory results carry no enrichment to score; scan the system instead")
+	}
+
+	scanMu.Lock()
+	defer scanMu.Unlock()
+
+	riskEngine, _, err := newEngine(opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := *saved
+	thresholds := riskEngine.Thresholds()
+	result.RiskThresholds = &thresholds
+	result.Items = make([]scanner.PersistenceItem, len(saved.Items))
+	copy(result.Items, saved.Items)
+	stock, err := osBaseline(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Enrichment recorded at scan time is reused
+	result.Timings = scanner.RunStages([]scanner.Stage{riskEngine, baseline.Stage(stock, saved.OSVersion), attack.Stage}, result.Items)
+	result.Timings = append(result.Timings, riskEngine.Timings()...)
+	result.Summarize()
+	if err := applyAllowlist(&result, opts); err != nil {
+		return nil, err
+	}
+
+	if opts.Suppressions != "" {
+		if err := ApplySuppressions(&result, opts.Suppressions); err != nil {
+			return nil, err
+		}
+	}
+
+	return &result, nil
+}
+
+// applyAllowlist drops the items allowlisted by the rule bundle opts names
+// and counts them as suppressed.
+func applyAllowlist(result *Result, opts Options) error {
+	if opts.Rules == "" {
+		return nil
+	}
+	bundle, err := loadRules(opts)
+	if err != nil || bundle == nil {
+		return err
+	}
+	result.Suppressed += bundle.Filter(result)
+	return nil
+}
+
+// loadRules loads and verifies the rule bundle opts names.
+func loadRules(opts Options) (*rules.Bundle, error) {
+	var key ed25519.PublicKey
+	if opts.RulesKey != "" {
+		var err error
+		if key, err = rules.ParsePublicKey(opts.RulesKey); err != nil {
+			return nil, fmt.Errorf("rules public key: %w", err)
+		}
+	}
+	return rules.Load(opts.Rules, key)
+}
+
+// ApplySuppressions drops the items listed in the suppression file at path
+// from result and records how many were removed. A missing file suppresses
+// nothing.
+func ApplySuppressions(result *Result, path string) error {
+	list, err := suppress.Load(path)
+	if err != nil {
+		return err
+	}
+	result.Suppressed += list.Filter(result)
+	return nil
+}
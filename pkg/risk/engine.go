@@ -1,14 +1,21 @@
 package risk
 
 import (
-	"math"
 	"sort"
+	"time"
 
 	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
 )
 
 type Engine struct {
 	heuristics []Heuristic
+	aggregator Aggregator
+	thresholds scanner.RiskThresholds
+
+	// elapsed accumulates the time spent in each heuristic, by index, over
+	// the analyzed items.
+	elapsed  []time.Duration
+	analyzed int
 }
 
 type Heuristic interface {
@@ -16,12 +23,43 @@ type Heuristic interface {
 	Name() string
 }
 
+// Preparer is implemented by heuristics that need to see every item in a scan
+// before individual items are analyzed, e.g. to compute host-wide prevalence.
+type Preparer interface {
+	Prepare(items []scanner.PersistenceItem)
+}
+
 func NewEngine(heuristics []Heuristic) *Engine {
 	return &Engine{
 		heuristics: heuristics,
+		aggregator: &WeightedAverage{},
+		thresholds: scanner.DefaultRiskThresholds,
+	}
+}
+
+// Prepare hands the full item set to heuristics implementing Preparer.
+func (e *Engine) Prepare(items []scanner.PersistenceItem) {
+	for _, h := range e.heuristics {
+		if p, ok := h.(Preparer); ok {
+			p.Prepare(items)
+		}
+	}
+}
+
+// Process prepares the heuristics and assesses every item, so the engine
+// can run as a scanner.Stage.
+func (e *Engine) Process(items []scanner.PersistenceItem) {
+	e.Prepare(items)
+	for i := range items {
+		items[i].Risk = e.AssessRisk(&items[i])
 	}
 }
 
+// Name identifies the engine as a scanner.Stage.
+func (e *Engine) Name() string {
+	return "risk"
+}
+
 func (e *Engine) AssessRisk(item *scanner.PersistenceItem) scanner.RiskAssessment {
 	assessment := scanner.RiskAssessment{
 		Level:      scanner.RiskInfo,
@@ -31,31 +69,33 @@ func (e *Engine) AssessRisk(item *scanner.PersistenceItem) scanner.RiskAssessmen
 		Heuristics: []scanner.HeuristicResult{},
 	}
 
-	var totalScore float64
-	var totalConfidence float64
-	var triggeredCount int
+	var triggered []scanner.HeuristicResult
+
+	if len(e.elapsed) != len(e.heuristics) {
+		e.elapsed = make([]time.Duration, len(e.heuristics))
+	}
+	e.analyzed++
 
 	// Run all heuristics
-	for _, h := range e.heuristics {
+	for i, h := range e.heuristics {
+		started := time.Now()
 		result := h.Analyze(item)
+		e.elapsed[i] += time.Since(started)
 		assessment.Heuristics = append(assessment.Heuristics, result)
 		
 		if result.Triggered {
-			triggeredCount++
-			totalScore += result.Score * result.Confidence
-			totalConfidence += result.Confidence
+			triggered = append(triggered, result)
 			assessment.Reasons = append(assessment.Reasons, result.Details)
 		}
 	}
 
-	// Calculate weighted average score
-	if triggeredCount > 0 {
-		assessment.Score = totalScore / totalConfidence
-		assessment.Confidence = math.Min(totalConfidence/float64(triggeredCount), 1.0)
+	// Combine triggered heuristics using the configured model
+	if len(triggered) > 0 {
+		assessment.Score, assessment.Confidence = e.aggregator.Aggregate(triggered)
 	}
 
 	// Determine risk level based on score
-	assessment.Level = e.scoreToRiskLevel(assessment.Score)
+	assessment.Level = e.thresholds.Level(assessment.Score)
 
 	// Sort heuristics by score (highest first)
 	sort.Slice(assessment.Heuristics, func(i, j int) bool {
@@ -65,19 +105,32 @@ func (e *Engine) AssessRisk(item *scanner.PersistenceItem) scanner.RiskAssessmen
 	return assessment
 }
 
-func (e *Engine) scoreToRiskLevel(score float64) scanner.RiskLevel {
-	switch {
-	case score >= 0.8:
-		return scanner.RiskCritical
-	case score >= 0.6:
-		return scanner.RiskHigh
-	case score >= 0.4:
-		return scanner.RiskMedium
-	case score >= 0.2:
-		return scanner.RiskLow
-	default:
-		return scanner.RiskInfo
+// SetAggregator replaces the scoring model used to combine heuristic results.
+func (e *Engine) SetAggregator(a Aggregator) {
+	e.aggregator = a
+}
+
+// SetThresholds replaces the scores at which items reach each risk level.
+func (e *Engine) SetThresholds(t scanner.RiskThresholds) {
+	e.thresholds = t
+}
+
+// Thresholds returns the scores at which items reach each risk level.
+func (e *Engine) Thresholds() scanner.RiskThresholds {
+	return e.thresholds
+}
+
+// Timings reports the time spent in each heuristic over every item the
+// engine has assessed.
+func (e *Engine) Timings() []scanner.Timing {
+	var timings []scanner.Timing
+	for i, h := range e.heuristics {
+		if i >= len(e.elapsed) {
+			break
+		}
+		timings = append(timings, scanner.Timing{Kind: scanner.TimingHeuristic, Name: h.Name(), Duration: e.elapsed[i], Items: e.analyzed})
 	}
+	return timings
 }
 
 func (e *Engine) AddHeuristic(h Heuristic) {
@@ -0,0 +1,110 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+)
+
+// Aggregator combines the triggered heuristic results for an item into a
+// single score and confidence.
+type Aggregator interface {
+	Name() string
+	Aggregate(triggered []scanner.HeuristicResult) (score, confidence float64)
+}
+
+const (
+	ModelWeightedAverage = "weighted-average"
+	ModelMaxScore        = "max-score"
+	ModelBayesian        = "bayesian"
+)
+
+// Models lists the names accepted by NewAggregator.
+var Models = []string{ModelWeightedAverage, ModelMaxScore, ModelBayesian}
+
+func NewAggregator(model string) (Aggregator, error) {
+	switch model {
+	case ModelWeightedAverage, "":
+		return &WeightedAverage{}, nil
+	case ModelMaxScore:
+		return &MaxScore{}, nil
+	case ModelBayesian:
+		return &Bayesian{}, nil
+	default:
+		return nil, fmt.Errorf("unknown scoring model %q", model)
+	}
+}
+
+// WeightedAverage averages triggered scores weighted by confidence.
+type WeightedAverage struct{}
+
+func (a *WeightedAverage) Name() string {
+	return ModelWeightedAverage
+}
+
+func (a *WeightedAverage) Aggregate(triggered []scanner.HeuristicResult) (float64, float64) {
+	if len(triggered) == 0 {
+		return 0.0, 1.0
+	}
+
+	var totalScore float64
+	var totalConfidence float64
+	for _, result := range triggered {
+		totalScore += result.Score * result.Confidence
+		totalConfidence += result.Confidence
+	}
+
+	if totalConfidence == 0 {
+		return 0.0, 0.0
+	}
+	return totalScore / totalConfidence, math.Min(totalConfidence/float64(len(triggered)), 1.0)
+}
+
+// MaxScore reports the single strongest signal, so one high-confidence
+// finding is not diluted by weaker ones.
+type MaxScore struct{}
+
+func (a *MaxScore) Name() string {
+	return ModelMaxScore
+}
+
+func (a *MaxScore) Aggregate(triggered []scanner.HeuristicResult) (float64, float64) {
+	if len(triggered) == 0 {
+		return 0.0, 1.0
+	}
+
+	best := triggered[0]
+	for _, result := range triggered[1:] {
+		if result.Score*result.Confidence > best.Score*best.Confidence {
+			best = result
+		}
+	}
+	return best.Score, best.Confidence
+}
+
+// Bayesian treats each heuristic as independent evidence and combines them in
+// log-odds space, weighting each by its confidence. Agreeing signals
+// reinforce each other instead of averaging out.
+type Bayesian struct{}
+
+func (a *Bayesian) Name() string {
+	return ModelBayesian
+}
+
+func (a *Bayesian) Aggregate(triggered []scanner.HeuristicResult) (float64, float64) {
+	if len(triggered) == 0 {
+		return 0.0, 1.0
+	}
+
+	var logOdds float64
+	var totalConfidence float64
+	for _, result := range triggered {
+		p := math.Min(math.Max(result.Score, 0.01), 0.99)
+		logOdds += result.Confidence * math.Log(p/(1-p))
+		totalConfidence += result.Confidence
+	}
+
+	score := 1 / (1 + math.Exp(-logOdds))
+	return score, math.Min(totalConfidence/float64(len(triggered)), 1.0)
+}
@@ -0,0 +1,128 @@
+package scanner
+
+// Clone returns a deep copy of item that shares no maps, slices, or
+// pointers with it, so either can be changed while the other is in use.
+func (item *PersistenceItem) Clone() PersistenceItem {
+	clone := *item
+	clone.ProgramArgs = cloneStrings(item.ProgramArgs)
+	clone.ConfigFile = item.ConfigFile.clone()
+	clone.ProgramFile = item.ProgramFile.clone()
+	if item.ResolvedTargets != nil {
+		clone.ResolvedTargets = make([]ResolvedTarget, len(item.ResolvedTargets))
+		for i, target := range item.ResolvedTargets {
+			target.File = target.File.clone()
+			target.CodeSignature = target.CodeSignature.clone()
+			clone.ResolvedTargets[i] = target
+		}
+	}
+	if item.Gatekeeper != nil {
+		gatekeeper := *item.Gatekeeper
+		clone.Gatekeeper = &gatekeeper
+	}
+	clone.CodeSignature = item.CodeSignature.clone()
+	if item.Santa != nil {
+		santa := *item.Santa
+		clone.Santa = &santa
+	}
+	if item.Exposure != nil {
+		exposure := *item.Exposure
+		exposure.MachServices = cloneStrings(exposure.MachServices)
+		exposure.Sockets = append([]LaunchdSocket(nil), exposure.Sockets...)
+		clone.Exposure = &exposure
+	}
+	if item.LaunchEvents != nil {
+		clone.LaunchEvents = make([]LaunchEvent, len(item.LaunchEvents))
+		for i, event := range item.LaunchEvents {
+			event.Matching = cloneMap(event.Matching)
+			clone.LaunchEvents[i] = event
+		}
+	}
+	if item.Chains != nil {
+		clone.Chains = make([]ChainLink, len(item.Chains))
+		for i, link := range item.Chains {
+			link.Items = cloneStrings(link.Items)
+			clone.Chains[i] = link
+		}
+	}
+	clone.Provenance = append([]Provenance(nil), item.Provenance...)
+	clone.ATTACKTechniques = cloneStrings(item.ATTACKTechniques)
+	clone.Risk.Reasons = cloneStrings(item.Risk.Reasons)
+	clone.Risk.Heuristics = append([]HeuristicResult(nil), item.Risk.Heuristics...)
+	clone.RawData = cloneMap(item.RawData)
+	clone.Errors = cloneStrings(item.Errors)
+	return clone
+}
+
+func (f *FileMetadata) clone() *FileMetadata {
+	if f == nil {
+		return nil
+	}
+	clone := *f
+	clone.ACL = cloneStrings(f.ACL)
+	if f.XAttrs != nil {
+		xattrs := *f.XAttrs
+		if xattrs.Quarantine != nil {
+			quarantine := *xattrs.Quarantine
+			xattrs.Quarantine = &quarantine
+		}
+		xattrs.WhereFroms = cloneStrings(xattrs.WhereFroms)
+		clone.XAttrs = &xattrs
+	}
+	if f.Binary != nil {
+		binary := *f.Binary
+		binary.Architectures = cloneStrings(binary.Architectures)
+		clone.Binary = &binary
+	}
+	return &clone
+}
+
+func (s *CodeSignature) clone() *CodeSignature {
+	if s == nil {
+		return nil
+	}
+	clone := *s
+	clone.Authorities = cloneStrings(s.Authorities)
+	return &clone
+}
+
+func cloneStrings(list []string) []string {
+	if list == nil {
+		return nil
+	}
+	return append([]string(nil), list...)
+}
+
+// cloneMap copies RawData-style values: nested maps and slices are copied,
+// anything else is assumed immutable.
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		clone[key] = cloneValue(value)
+	}
+	return clone
+}
+
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return cloneMap(v)
+	case []interface{}:
+		clone := make([]interface{}, len(v))
+		for i, elem := range v {
+			clone[i] = cloneValue(elem)
+		}
+		return clone
+	case []map[string]interface{}:
+		clone := make([]map[string]interface{}, len(v))
+		for i, elem := range v {
+			clone[i] = cloneMap(elem)
+		}
+		return clone
+	case []string:
+		return cloneStrings(v)
+	}
+	return value
+}
@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Provenance records where an item was found.
+type Provenance struct {
+	Scanner MechanismType `json:"scanner"`
+	Path    string        `json:"path"`
+}
+
+// Deduplicate merges items that describe the same artifact read through
+// different sources, such as a login hook found both in the loginwindow
+// plist and through `defaults read`. Items match when they share a
+// mechanism, program, and arguments and at least one of them was not read
+// from a file (its Path is a description such as "System Events Login
+// Items" rather than an absolute path); two items read from files running
+// the same program are separate artifacts, even from the same file. The file-backed item is kept and the others'
+// provenance is appended to it.
+func Deduplicate(items []PersistenceItem) []PersistenceItem {
+	var merged []PersistenceItem
+	groups := make(map[string][]int)
+
+	for _, item := range items {
+		key := artifactKey(&item)
+		if key == "" {
+			merged = append(merged, item)
+			continue
+		}
+
+		duplicate := false
+		for _, i := range groups[key] {
+			existing := &merged[i]
+			// Two entries in files are separate even in the same file,
+			// such as crontab lines with different schedules
+			if filepath.IsAbs(existing.Path) && filepath.IsAbs(item.Path) {
+				continue
+			}
+			if !filepath.IsAbs(existing.Path) && filepath.IsAbs(item.Path) {
+				item.Provenance = append(item.Provenance, existing.Provenance...)
+				item.Errors = append(item.Errors, existing.Errors...)
+				*existing = item
+			} else {
+				existing.Provenance = append(existing.Provenance, item.Provenance...)
+				existing.Errors = append(existing.Errors, item.Errors...)
+			}
+			duplicate = true
+			break
+		}
+		if !duplicate {
+			groups[key] = append(groups[key], len(merged))
+			merged = append(merged, item)
+		}
+	}
+
+	return merged
+}
+
+func artifactKey(item *PersistenceItem) string {
+	if item.Program == "" {
+		return ""
+	}
+	return strings.Join(append([]string{string(item.Mechanism), item.Program}, item.ProgramArgs...), "\x00")
+}
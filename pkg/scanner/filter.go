@@ -0,0 +1,88 @@
+package scanner
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Filter restricts a result to the items a targeted check is about. An
+// item must match every criterion set; unset criteria match everything.
+type Filter struct {
+	// Mechanisms lists the mechanism types kept, compared without regard
+	// to case.
+	Mechanisms []string
+	// Label matches item labels.
+	Label *regexp.Regexp
+	// Paths lists filepath.Match patterns; an item matches if its Path or
+	// Program matches any of them.
+	Paths []string
+	// Users lists the users whose items are kept.
+	Users []string
+}
+
+// Empty reports whether the filter keeps every item.
+func (f *Filter) Empty() bool {
+	return len(f.Mechanisms) == 0 && f.Label == nil && len(f.Paths) == 0 && len(f.Users) == 0
+}
+
+// Match reports whether item meets every criterion of the filter.
+func (f *Filter) Match(item *PersistenceItem) bool {
+	if len(f.Mechanisms) > 0 && !f.matchMechanism(item.Mechanism) {
+		return false
+	}
+	if f.Label != nil && !f.Label.MatchString(item.Label) {
+		return false
+	}
+	if len(f.Paths) > 0 && !f.matchPath(item) {
+		return false
+	}
+	if len(f.Users) > 0 && !containsString(f.Users, item.User) {
+		return false
+	}
+	return true
+}
+
+func (f *Filter) matchMechanism(mechanism MechanismType) bool {
+	for _, m := range f.Mechanisms {
+		if strings.EqualFold(m, string(mechanism)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) matchPath(item *PersistenceItem) bool {
+	for _, pattern := range f.Paths {
+		for _, path := range []string{item.Path, item.Program} {
+			if matched, _ := filepath.Match(pattern, path); matched && path != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns a copy of r holding only the items f matches, with
+// Filtered counting those left out. r is not modified.
+func (r *ScanResult) Select(f *Filter) *ScanResult {
+	out := *r
+	out.Items = make([]PersistenceItem, 0, len(r.Items))
+	for _, item := range r.Items {
+		if f.Match(&item) {
+			out.Items = append(out.Items, item)
+		}
+	}
+	out.Filtered = len(r.Items) - len(out.Items)
+	out.Summarize()
+	return &out
+}
@@ -2,13 +2,32 @@ package scanner
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 )
 
 type Orchestrator struct {
-	scanners []Scanner
-	parallel bool
+	scanners    []Scanner
+	stages      []Stage
+	parallel    bool
+	concurrency int
+
+	// timeout bounds each scanner unless overridden in scannerTimeouts.
+	// Zero means no limit.
+	timeout         time.Duration
+	scannerTimeouts map[MechanismType]time.Duration
+
+	// collectTimeout and assessTimeout bound the collection and stage
+	// phases of a scan. Zero means no limit.
+	collectTimeout time.Duration
+	assessTimeout  time.Duration
+	// abandoned lists the stages the last scan did not complete.
+	abandoned []string
 }
 
 func NewOrchestrator(scanners []Scanner, parallel bool) *Orchestrator {
@@ -18,27 +37,64 @@ func NewOrchestrator(scanners []Scanner, parallel bool) *Orchestrator {
 	}
 }
 
+// RunScan runs every scanner and then every stage over the collected
+// items. Cancelling ctx abandons the scanners still running; the result
+// then holds the items collected so far and is marked Partial. Those items
+// are still assessed unless ctx's deadline, or the assessment timeout,
+// passes first; the stages not completed are then reported in Errors.
 func (o *Orchestrator) RunScan(ctx context.Context) (*ScanResult, error) {
 	result := &ScanResult{
-		StartTime:   time.Now(),
-		RiskSummary: make(map[RiskLevel]int),
+		StartTime: time.Now(),
+	}
+	o.abandoned = nil
+
+	assessCtx, cancelAssess := assessContext(ctx)
+	defer cancelAssess()
+	if o.collectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.collectTimeout)
+		defer cancel()
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		result.Hostname = hostname
 	}
 
 	var allItems []PersistenceItem
 	var allErrors []ScanError
+	var timings []Timing
 	var mu sync.Mutex
 
-	if o.parallel {
-		var wg sync.WaitGroup
-		for _, scanner := range o.scanners {
-			wg.Add(1)
-			go func(s Scanner) {
-				defer wg.Done()
-				
-				items, err := s.Scan()
+	jobs := make(chan Scanner)
+	var wg sync.WaitGroup
+	for w := 0; w < o.workers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for s := range jobs {
+				if ctx.Err() != nil {
+					// Interrupted: don't start scanners still queued
+					mu.Lock()
+					allErrors = append(allErrors, ScanError{
+						Mechanism: s.Type(),
+						Error:     fmt.Sprintf("not run: %v", ctx.Err()),
+						Timestamp: time.Now(),
+					})
+					mu.Unlock()
+					continue
+				}
+
+				started := time.Now()
+				items, err := o.runScanner(ctx, s)
+				elapsed := time.Since(started)
+				slog.Debug("scanner finished", "scanner", s.Type(), "items", len(items), "duration", elapsed, "failed", err != nil)
+
+				for i := range items {
+					items[i].Provenance = []Provenance{{Scanner: s.Type(), Path: items[i].Path}}
+				}
+
 				mu.Lock()
-				defer mu.Unlock()
-				
+				timings = append(timings, Timing{Kind: TimingScanner, Name: string(s.Type()), Duration: elapsed, Items: len(items)})
 				if err != nil {
 					allErrors = append(allErrors, ScanError{
 						Mechanism: s.Type(),
@@ -48,38 +104,143 @@ func (o *Orchestrator) RunScan(ctx context.Context) (*ScanResult, error) {
 				} else {
 					allItems = append(allItems, items...)
 				}
-			}(scanner)
-		}
-		wg.Wait()
-	} else {
-		for _, scanner := range o.scanners {
-			items, err := scanner.Scan()
-			if err != nil {
-				allErrors = append(allErrors, ScanError{
-					Mechanism: scanner.Type(),
-					Error:     err.Error(),
-					Timestamp: time.Now(),
-				})
-			} else {
-				allItems = append(allItems, items...)
+				mu.Unlock()
 			}
-		}
+		}()
 	}
-
-	// Calculate risk summary
-	for _, item := range allItems {
-		result.RiskSummary[item.Risk.Level]++
+	for _, scanner := range o.scanners {
+		jobs <- scanner
 	}
+	close(jobs)
+	wg.Wait()
+	result.Partial = ctx.Err() != nil
 
+	allItems = Deduplicate(allItems)
+	for i := range allItems {
+		allItems[i].ID = allItems[i].ComputeID()
+	}
+	if o.assessTimeout > 0 {
+		var cancel context.CancelFunc
+		assessCtx, cancel = context.WithTimeout(assessCtx, o.assessTimeout)
+		defer cancel()
+	}
+	stageTimings, abandoned := RunStagesContext(assessCtx, o.stages, allItems)
+	timings = append(timings, stageTimings...)
+	if len(abandoned) > 0 {
+		o.abandoned = abandoned
+		result.Partial = true
+		allErrors = append(allErrors, ScanError{
+			Error:     fmt.Sprintf("assessment stopped: %v; stages not completed: %s", assessCtx.Err(), strings.Join(abandoned, ", ")),
+			Timestamp: time.Now(),
+		})
+	}
 	result.Items = allItems
-	result.TotalItems = len(allItems)
+	result.Timings = timings
 	result.Errors = allErrors
+	result.Summarize()
 	result.EndTime = time.Now()
 	result.Duration = result.EndTime.Sub(result.StartTime)
 
 	return result, nil
 }
 
+// assessContext returns the context stages run under: ctx's deadline
+// applies, but cancelling ctx does not, so an interrupted scan still
+// assesses what it collected.
+func assessContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	}
+	return context.WithoutCancel(ctx), func() {}
+}
+
+// SetPhaseTimeouts limits how long collection and assessment may each
+// take. Scanners still running when collection times out are abandoned as
+// on cancellation; stages are abandoned as RunStagesContext describes.
+func (o *Orchestrator) SetPhaseTimeouts(collect, assess time.Duration) {
+	o.collectTimeout = collect
+	o.assessTimeout = assess
+}
+
+// AbandonedStages lists the stages the last scan gave up on. They may
+// still be running in the background.
+func (o *Orchestrator) AbandonedStages() []string {
+	return o.abandoned
+}
+
+// SetConcurrency bounds how many scanners run at once when scanning in
+// parallel. Zero uses one worker per CPU.
+func (o *Orchestrator) SetConcurrency(n int) {
+	o.concurrency = n
+}
+
+func (o *Orchestrator) workers() int {
+	switch {
+	case !o.parallel:
+		return 1
+	case o.concurrency > 0:
+		return o.concurrency
+	default:
+		return runtime.NumCPU()
+	}
+}
+
+// SetTimeout limits how long any one scanner may run. A scanner that
+// exceeds it is reported in ScanResult.Errors and its items are dropped.
+func (o *Orchestrator) SetTimeout(timeout time.Duration) {
+	o.timeout = timeout
+}
+
+// SetScannerTimeout overrides the timeout for scanners of one mechanism.
+func (o *Orchestrator) SetScannerTimeout(mechanism MechanismType, timeout time.Duration) {
+	if o.scannerTimeouts == nil {
+		o.scannerTimeouts = make(map[MechanismType]time.Duration)
+	}
+	o.scannerTimeouts[mechanism] = timeout
+}
+
+// runScanner runs s, giving up once its timeout passes or ctx is done. A
+// scanner that is given up on keeps running in the background, but its
+// results are discarded.
+func (o *Orchestrator) runScanner(ctx context.Context, s Scanner) ([]PersistenceItem, error) {
+	timeout := o.timeout
+	if override, ok := o.scannerTimeouts[s.Type()]; ok {
+		timeout = override
+	}
+
+	type outcome struct {
+		items []PersistenceItem
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		items, err := s.Scan()
+		done <- outcome{items, err}
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case result := <-done:
+		return result.items, result.err
+	case <-expired:
+		return nil, fmt.Errorf("scanner timed out after %s", timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// AddStage appends a stage run over the collected items before the result
+// is summarized.
+func (o *Orchestrator) AddStage(stage Stage) {
+	o.stages = append(o.stages, stage)
+}
+
 func (o *Orchestrator) AddScanner(scanner Scanner) {
 	o.scanners = append(o.scanners, scanner)
 }
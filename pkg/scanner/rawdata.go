@@ -0,0 +1,17 @@
+package scanner
+
+// RawInt reads an integer from RawData, which holds the integer types a
+// plist decodes to when collected and a float64 after a JSON round trip.
+func RawInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
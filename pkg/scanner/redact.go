@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ContentKeys are the RawData keys collectors use for full file contents,
+// script bodies, and raw tool output.
+var ContentKeys = []string{"content", "scriptContent", "FullData"}
+
+// ExcerptLength is how many characters of redacted content are kept.
+const ExcerptLength = 200
+
+// RedactContent replaces full contents in every item's RawData with a
+// SHA-256 hash, the original length, and a short excerpt, and trims decoded
+// payload previews to the same length. It must run after risk assessment,
+// since heuristics inspect the full contents.
+func (r *ScanResult) RedactContent() {
+	for i := range r.Items {
+		r.Items[i].RedactContent()
+	}
+}
+
+// RedactContent redacts a single item; see ScanResult.RedactContent.
+func (item *PersistenceItem) RedactContent() {
+	for _, key := range ContentKeys {
+		value, ok := item.RawData[key]
+		if !ok {
+			continue
+		}
+		delete(item.RawData, key)
+
+		var data []byte
+		if s, ok := value.(string); ok {
+			data = []byte(s)
+			item.RawData[key+"Excerpt"] = excerpt(s)
+		} else if encoded, err := json.Marshal(value); err == nil {
+			data = encoded
+		}
+
+		sum := sha256.Sum256(data)
+		item.RawData[key+"SHA256"] = hex.EncodeToString(sum[:])
+		item.RawData[key+"Length"] = len(data)
+	}
+
+	switch payloads := item.RawData["decodedPayloads"].(type) {
+	case []map[string]interface{}:
+		for _, payload := range payloads {
+			if preview, ok := payload["preview"].(string); ok {
+				payload["preview"] = excerpt(preview)
+			}
+		}
+	case []interface{}:
+		for _, p := range payloads {
+			if payload, ok := p.(map[string]interface{}); ok {
+				if preview, ok := payload["preview"].(string); ok {
+					payload["preview"] = excerpt(preview)
+				}
+			}
+		}
+	}
+}
+
+func excerpt(s string) string {
+	runes := []rune(s)
+	if len(runes) <= ExcerptLength {
+		return s
+	}
+	return string(runes[:ExcerptLength]) + "..."
+}
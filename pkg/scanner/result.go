@@ -0,0 +1,35 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadResult reads a scan result previously written with the JSON formatter.
+func LoadResult(path string) (*ScanResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scan result: %w", err)
+	}
+
+	var result ScanResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parsing scan result %s: %w", path, err)
+	}
+
+	return &result, nil
+}
+
+// Summarize recomputes TotalItems and RiskSummary from Items. Call it again
+// after risk assessment, since items are collected before they are scored;
+// unscored items are not counted in RiskSummary.
+func (r *ScanResult) Summarize() {
+	r.TotalItems = len(r.Items)
+	r.RiskSummary = make(map[RiskLevel]int)
+	for _, item := range r.Items {
+		if item.Risk.Level != "" {
+			r.RiskSummary[item.Risk.Level]++
+		}
+	}
+}
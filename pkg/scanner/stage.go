@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Stage processes collected items after deduplication, such as hashing
+// files, enriching items, or scoring risk. Stages run in order over the
+// whole item set, so a stage can rely on the facts attached by the stages
+// before it.
+type Stage interface {
+	Name() string
+	Process(items []PersistenceItem)
+}
+
+type funcStage struct {
+	name string
+	fn   func(items []PersistenceItem)
+}
+
+func (s funcStage) Name() string                    { return s.name }
+func (s funcStage) Process(items []PersistenceItem) { s.fn(items) }
+
+// NewStage returns a stage that calls fn.
+func NewStage(name string, fn func(items []PersistenceItem)) Stage {
+	return funcStage{name: name, fn: fn}
+}
+
+// RunStages runs every stage over items in order and reports how long
+// each took.
+func RunStages(stages []Stage, items []PersistenceItem) []Timing {
+	var timings []Timing
+	for _, stage := range stages {
+		started := time.Now()
+		stage.Process(items)
+		elapsed := time.Since(started)
+		slog.Debug("stage finished", "stage", stage.Name(), "items", len(items), "duration", elapsed)
+		timings = append(timings, Timing{Kind: TimingStage, Name: stage.Name(), Duration: elapsed, Items: len(items)})
+	}
+	return timings
+}
+
+// RunStagesContext runs stages like RunStages, but gives up once ctx is
+// done. The stage running then is abandoned: it finishes in the background
+// on a deep copy of items, which shares nothing with items, and its
+// changes are discarded. The names of the stages that did not complete
+// are returned with the timings of those that did.
+func RunStagesContext(ctx context.Context, stages []Stage, items []PersistenceItem) ([]Timing, []string) {
+	var timings []Timing
+	for i, stage := range stages {
+		if ctx.Err() != nil {
+			return timings, stageNames(stages[i:])
+		}
+
+		// A copy that shares maps or pointers with items would let an
+		// abandoned stage write to them while the caller reads them
+		work := make([]PersistenceItem, len(items))
+		for j := range items {
+			work[j] = items[j].Clone()
+		}
+		done := make(chan struct{})
+		started := time.Now()
+		go func() {
+			stage.Process(work)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			slog.Debug("stage abandoned", "stage", stage.Name(), "items", len(items), "duration", time.Since(started))
+			return timings, stageNames(stages[i:])
+		}
+		copy(items, work)
+		elapsed := time.Since(started)
+		slog.Debug("stage finished", "stage", stage.Name(), "items", len(items), "duration", elapsed)
+		timings = append(timings, Timing{Kind: TimingStage, Name: stage.Name(), Duration: elapsed, Items: len(items)})
+	}
+	return timings, nil
+}
+
+func stageNames(stages []Stage) []string {
+	var names []string
+	for _, stage := range stages {
+		names = append(names, stage.Name())
+	}
+	return names
+}
@@ -1,22 +1,55 @@
 package scanner
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"time"
 )
 
 type MechanismType string
 
 const (
-	MechanismLaunchAgent     MechanismType = "LaunchAgent"
-	MechanismLaunchDaemon    MechanismType = "LaunchDaemon"
-	MechanismLoginItem       MechanismType = "LoginItem"
-	MechanismConfigProfile   MechanismType = "ConfigurationProfile"
-	MechanismCronJob         MechanismType = "CronJob"
-	MechanismPeriodicScript  MechanismType = "PeriodicScript"
-	MechanismLoginHook       MechanismType = "LoginHook"
-	MechanismLogoutHook      MechanismType = "LogoutHook"
+	MechanismLaunchAgent      MechanismType = "LaunchAgent"
+	MechanismLaunchDaemon     MechanismType = "LaunchDaemon"
+	MechanismLoginItem        MechanismType = "LoginItem"
+	MechanismConfigProfile    MechanismType = "ConfigurationProfile"
+	MechanismCronJob          MechanismType = "CronJob"
+	MechanismPeriodicScript   MechanismType = "PeriodicScript"
+	MechanismLoginHook        MechanismType = "LoginHook"
+	MechanismLogoutHook       MechanismType = "LogoutHook"
+	MechanismNVRAM            MechanismType = "NVRAM"
+	MechanismSynthetic        MechanismType = "SyntheticLink"
+	MechanismSearchPath       MechanismType = "SearchPath"
+	MechanismEnvironment      MechanismType = "EnvironmentVariable"
+	MechanismNativeMessaging  MechanismType = "NativeMessagingHost"
+	MechanismBrowserPolicy    MechanismType = "BrowserPolicy"
+	MechanismKernelExtension  MechanismType = "KernelExtension"
+	MechanismSystemExtension  MechanismType = "SystemExtension"
+	MechanismCUPS             MechanismType = "CUPS"
+	MechanismDock             MechanismType = "DockItem"
+	MechanismRootAccount      MechanismType = "RootAccount"
+	MechanismAutomator        MechanismType = "AutomatorWorkflow"
+	MechanismUserShell        MechanismType = "UserShell"
+	MechanismLocalAccount     MechanismType = "LocalAccount"
+	MechanismNewsyslog        MechanismType = "Newsyslog"
+	MechanismPowerSchedule    MechanismType = "PowerSchedule"
+	MechanismShellStartup     MechanismType = "ShellStartup"
+	MechanismBrowserExtension MechanismType = "BrowserExtension"
 )
 
+// Mechanisms lists every mechanism items can have. Some scanners report
+// more than one, such as login and logout hooks.
+var Mechanisms = []MechanismType{
+	MechanismLaunchAgent, MechanismLaunchDaemon, MechanismLoginItem, MechanismConfigProfile, MechanismCronJob,
+	MechanismPeriodicScript, MechanismLoginHook, MechanismLogoutHook, MechanismNVRAM, MechanismSynthetic,
+	MechanismSearchPath, MechanismEnvironment, MechanismNativeMessaging, MechanismBrowserPolicy,
+	MechanismKernelExtension, MechanismSystemExtension, MechanismCUPS, MechanismDock, MechanismRootAccount,
+	MechanismAutomator, MechanismUserShell, MechanismLocalAccount, MechanismNewsyslog, MechanismPowerSchedule,
+	MechanismShellStartup, MechanismBrowserExtension,
+}
+
 type RiskLevel string
 
 const (
@@ -27,6 +60,66 @@ const (
 	RiskCritical RiskLevel = "Critical"
 )
 
+// RiskLevels lists the risk levels from lowest to highest.
+var RiskLevels = []RiskLevel{RiskInfo, RiskLow, RiskMedium, RiskHigh, RiskCritical}
+
+// Rank orders risk levels from 1 (Info) to 5 (Critical); unknown levels rank 0.
+func (l RiskLevel) Rank() int {
+	for i, level := range RiskLevels {
+		if l == level {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ParseRiskLevel converts a case-insensitive level name such as "high".
+func ParseRiskLevel(s string) (RiskLevel, error) {
+	for _, level := range RiskLevels {
+		if strings.EqualFold(s, string(level)) {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("unknown risk level %q", s)
+}
+
+// RiskThresholds are the lowest scores at which an item is Low, Medium,
+// High, and Critical; lower scores are Info.
+type RiskThresholds struct {
+	Low      float64 `json:"low"`
+	Medium   float64 `json:"medium"`
+	High     float64 `json:"high"`
+	Critical float64 `json:"critical"`
+}
+
+// DefaultRiskThresholds are the thresholds used unless configured.
+var DefaultRiskThresholds = RiskThresholds{Low: 0.2, Medium: 0.4, High: 0.6, Critical: 0.8}
+
+// Level returns the risk level of score.
+func (t RiskThresholds) Level(score float64) RiskLevel {
+	switch {
+	case score >= t.Critical:
+		return RiskCritical
+	case score >= t.High:
+		return RiskHigh
+	case score >= t.Medium:
+		return RiskMedium
+	case score >= t.Low:
+		return RiskLow
+	default:
+		return RiskInfo
+	}
+}
+
+// Validate checks that the thresholds rise from Low to Critical and lie
+// between 0 and 1.
+func (t RiskThresholds) Validate() error {
+	if t.Low <= 0 || t.Low >= t.Medium || t.Medium >= t.High || t.High >= t.Critical || t.Critical > 1 {
+		return fmt.Errorf("risk thresholds must rise from low to critical between 0 and 1, not low %g, medium %g, high %g, critical %g", t.Low, t.Medium, t.High, t.Critical)
+	}
+	return nil
+}
+
 type PersistenceItem struct {
 	ID            string                 `json:"id"`
 	Mechanism     MechanismType          `json:"mechanism"`
@@ -40,18 +133,257 @@ type PersistenceItem struct {
 	Disabled      bool                   `json:"disabled"`
 	CreatedAt     time.Time              `json:"created_at"`
 	ModifiedAt    time.Time              `json:"modified_at"`
+	InstalledAt   time.Time              `json:"installed_at"`
 	FileMode      string                 `json:"file_mode"`
+	// ConfigFile and ProgramFile describe the file at Path and the program
+	// it launches, when those are files on the scanned system.
+	ConfigFile    *FileMetadata          `json:"config_file,omitempty"`
+	ProgramFile   *FileMetadata          `json:"program_file,omitempty"`
+	// ResolvedTargets are the programs a wrapper script or interpreter
+	// program goes on to run.
+	ResolvedTargets []ResolvedTarget     `json:"resolved_targets,omitempty"`
+	Gatekeeper    *GatekeeperAssessment  `json:"gatekeeper,omitempty"`
+	CodeSignature *CodeSignature         `json:"code_signature,omitempty"`
+	Santa         *SantaVerdict          `json:"santa,omitempty"`
+	Exposure      *LaunchdExposure       `json:"exposure,omitempty"`
+	LaunchEvents  []LaunchEvent          `json:"launch_events,omitempty"`
+	Chains        []ChainLink            `json:"chains,omitempty"`
+	Provenance    []Provenance           `json:"provenance,omitempty"`
+	ATTACKTechniques []string            `json:"attack_techniques,omitempty"`
 	Risk          RiskAssessment         `json:"risk"`
 	RawData       map[string]interface{} `json:"raw_data,omitempty"`
 	Errors        []string               `json:"errors,omitempty"`
 }
 
+// ComputeID derives a stable identifier from the mechanism, path, label,
+// and program, so the same item keeps its ID across scans and hosts.
+func (item *PersistenceItem) ComputeID() string {
+	h := sha256.New()
+	for _, part := range []string{string(item.Mechanism), item.Path, item.Label, item.Program} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// FileMetadata identifies a file an item refers to.
+type FileMetadata struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	// UID and GID are the numeric owner and group, and Mode the permission
+	// bits, including setuid, setgid, and sticky, in octal.
+	UID  uint32 `json:"uid"`
+	GID  uint32 `json:"gid"`
+	Mode string `json:"mode"`
+	// CreatedAt is the file's birth time, where the filesystem records it.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// ACL lists the file's access control entries as ls -le prints them.
+	ACL []string `json:"acl,omitempty"`
+	// XAttrs records where the file came from, when macOS noted it.
+	XAttrs *ExtendedAttributes `json:"xattrs,omitempty"`
+	// Binary describes the file when it is a Mach-O executable or library.
+	Binary *BinaryInfo `json:"binary,omitempty"`
+}
+
+// ResolvedTarget is a file an item's program runs when that program is a
+// shell script or an interpreter: the script an interpreter is given, or a
+// binary or script a script execs. Targets found by following another
+// target have a greater depth.
+type ResolvedTarget struct {
+	Path string `json:"path"`
+	// Via is how the target is run: "interpreter argument" or "command".
+	Via   string `json:"via"`
+	Depth int    `json:"depth"`
+	// Wrapper is the script or program that runs the target.
+	Wrapper       string         `json:"wrapper"`
+	File          *FileMetadata  `json:"file,omitempty"`
+	CodeSignature *CodeSignature `json:"code_signature,omitempty"`
+}
+
+// BinaryInfo describes a Mach-O file.
+type BinaryInfo struct {
+	// Architectures lists the slices of a universal binary, or the one
+	// architecture of a thin one, by name: x86_64, arm64, arm64e, and so on.
+	Architectures []string `json:"architectures"`
+	// MinOS and SDK are the macOS deployment target and the SDK the
+	// binary was built with, from LC_BUILD_VERSION or the older
+	// LC_VERSION_MIN_MACOSX. Universal binaries report their first slice.
+	MinOS string `json:"min_os,omitempty"`
+	SDK   string `json:"sdk,omitempty"`
+	// LinkedLibraries counts the dylibs and frameworks the binary loads.
+	LinkedLibraries int `json:"linked_libraries"`
+	// UUID is the LC_UUID build identifier, empty when the linker omitted
+	// it, as hand-built or packed binaries sometimes do.
+	UUID string `json:"uuid,omitempty"`
+}
+
+// ExtendedAttributes are the provenance-related extended attributes of a
+// file.
+type ExtendedAttributes struct {
+	// Quarantine is com.apple.quarantine, set on files downloaded or
+	// written by a quarantine-aware app.
+	Quarantine *Quarantine `json:"quarantine,omitempty"`
+	// Provenance is the hex-encoded com.apple.provenance value macOS 13
+	// and later attach to files created by quarantined or sandboxed apps.
+	Provenance string `json:"provenance,omitempty"`
+	// WhereFroms is com.apple.metadata:kMDItemWhereFroms, the URLs a
+	// download came from.
+	WhereFroms []string `json:"where_froms,omitempty"`
+}
+
+// Quarantine is a parsed com.apple.quarantine value.
+type Quarantine struct {
+	Flags   string    `json:"flags"`
+	Time    time.Time `json:"time,omitempty"`
+	Agent   string    `json:"agent,omitempty"`
+	EventID string    `json:"event_id,omitempty"`
+	Raw     string    `json:"raw"`
+}
+
+// GatekeeperAssessment is the syspolicy verdict for an item's program.
+type GatekeeperAssessment struct {
+	Target   string `json:"target"`
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Origin   string `json:"origin,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CodeSignature is what codesign reports about an item's program.
+type CodeSignature struct {
+	// Signed is false when the program is unsigned or its signature does
+	// not verify; Error then holds codesign's explanation.
+	Signed     bool   `json:"signed"`
+	AdHoc      bool   `json:"ad_hoc,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	TeamID     string `json:"team_id,omitempty"`
+	// Authorities is the certificate chain, leaf first.
+	Authorities []string `json:"authorities,omitempty"`
+	CDHash      string   `json:"cdhash,omitempty"`
+	// Notarized is set when a notarization ticket is stapled or Gatekeeper
+	// reports a notarized source.
+	Notarized bool   `json:"notarized"`
+	Revoked   bool   `json:"revoked,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// TimedOut is set when codesign did not finish in time, so nothing is
+	// known about the signature.
+	TimedOut bool `json:"timed_out,omitempty"`
+}
+
+// SantaVerdict is what Google Santa decides when an item's program is
+// executed, from the rules in its database.
+type SantaVerdict struct {
+	// Decision is allow, block, or unknown when no rule matches in
+	// Monitor mode.
+	Decision string `json:"decision"`
+	// Rule is the type of the matching rule: cdhash, binary, signingid,
+	// certificate, or teamid. Identifier is the rule's identifier and
+	// RuleAdded when Santa recorded it.
+	Rule       string    `json:"rule,omitempty"`
+	Identifier string    `json:"identifier,omitempty"`
+	RuleAdded  time.Time `json:"rule_added,omitempty"`
+	// Mode is Santa's client mode: monitor, lockdown, or standalone.
+	Mode string `json:"mode"`
+}
+
+// LaunchdExposure is the IPC and network surface a launchd job registers
+// through its MachServices, Sockets, and inetdCompatibility keys.
+type LaunchdExposure struct {
+	MachServices []string        `json:"mach_services,omitempty"`
+	Sockets      []LaunchdSocket `json:"sockets,omitempty"`
+	// Inetd is set when the job is launched per connection, like an inetd
+	// service.
+	Inetd bool `json:"inetd,omitempty"`
+}
+
+// LaunchEvent is one event a launchd job is started by, from the streams
+// of its LaunchEvents dictionary.
+type LaunchEvent struct {
+	// Stream is the event stream, such as com.apple.iokit.matching or
+	// com.apple.notifyd.matching.
+	Stream string `json:"stream"`
+	Name   string `json:"name"`
+	// Matching is the event's descriptor: IOKit matching properties, or
+	// the notification it waits for.
+	Matching map[string]interface{} `json:"matching,omitempty"`
+}
+
+// Launch event streams with their own trigger rules.
+const (
+	EventStreamIOKit     = "com.apple.iokit.matching"
+	EventStreamNotifyd   = "com.apple.notifyd.matching"
+	EventStreamDistnoted = "com.apple.distnoted.matching"
+)
+
+// Notification returns the Darwin or distributed notification the event
+// waits for, or "" for other streams.
+func (e *LaunchEvent) Notification() string {
+	var key string
+	switch e.Stream {
+	case EventStreamNotifyd:
+		key = "Notification"
+	case EventStreamDistnoted:
+		key = "Name"
+	default:
+		return ""
+	}
+	name, _ := e.Matching[key].(string)
+	return name
+}
+
+// ChainLink ties an item to persistence a command in its scripts creates,
+// or to the item whose scripts created it. The dropper and every item it
+// created share the chain ID.
+type ChainLink struct {
+	ID   string `json:"id"`
+	Role string `json:"role"`
+	// Target is what the command creates: a launchd plist, a crontab, or
+	// a loginwindow preference key.
+	Target  string `json:"target"`
+	Command string `json:"command"`
+	// Items are the IDs of the items at the other end of the chain: the
+	// items created, for a dropper, or the dropper.
+	Items []string `json:"items,omitempty"`
+}
+
+// Roles an item plays in a persistence chain.
+const (
+	ChainDropper = "dropper"
+	ChainDropped = "dropped"
+)
+
+// LaunchdSocket is one listener launchd opens on a job's behalf.
+type LaunchdSocket struct {
+	Name string `json:"name"`
+	// Type is stream, dgram, or seqpacket.
+	Type    string `json:"type,omitempty"`
+	Node    string `json:"node,omitempty"`
+	Service string `json:"service,omitempty"`
+	// Path is set for Unix domain sockets.
+	Path    string `json:"path,omitempty"`
+	Passive bool   `json:"passive"`
+}
+
+// Network reports whether the socket accepts connections from the network
+// on every interface.
+func (s *LaunchdSocket) Network() bool {
+	if s.Path != "" || !s.Passive || s.Service == "" {
+		return false
+	}
+	return s.Node == "" || s.Node == "0.0.0.0" || s.Node == "::" || s.Node == "*"
+}
+
 type RiskAssessment struct {
 	Level       RiskLevel              `json:"level"`
 	Score       float64                `json:"score"`
 	Confidence  float64                `json:"confidence"`
 	Reasons     []string               `json:"reasons"`
 	Heuristics  []HeuristicResult      `json:"heuristics"`
+	// Baseline is the macOS release whose stock items this item exactly
+	// matches; such items are Info whatever the heuristics found.
+	Baseline    string                 `json:"baseline,omitempty"`
 }
 
 type HeuristicResult struct {
@@ -63,14 +395,76 @@ type HeuristicResult struct {
 }
 
 type ScanResult struct {
+	Hostname        string            `json:"hostname,omitempty"`
+	Root            string            `json:"root,omitempty"`
+	// OSVersion is the macOS version of the scanned system.
+	OSVersion       string            `json:"os_version,omitempty"`
+	// RiskThresholds are the thresholds item scores were graded with, so
+	// results from differently configured hosts can be told apart.
+	RiskThresholds  *RiskThresholds   `json:"risk_thresholds,omitempty"`
 	StartTime       time.Time         `json:"start_time"`
 	EndTime         time.Time         `json:"end_time"`
 	Duration        time.Duration     `json:"duration"`
 	Items           []PersistenceItem `json:"items"`
 	TotalItems      int               `json:"total_items"`
 	RiskSummary     map[RiskLevel]int `json:"risk_summary"`
+	Suppressed      int               `json:"suppressed,omitempty"`
+	// Filtered counts items left out by the --mechanism, --label, --path,
+	// and --user filters.
+	Filtered        int               `json:"filtered,omitempty"`
+	// Inventory marks a result collected without risk scoring.
+	Inventory       bool              `json:"inventory,omitempty"`
+	// Partial marks a scan that was interrupted; scanners that had not
+	// finished are listed in Errors and their items are missing.
+	Partial         bool              `json:"partial,omitempty"`
 	Errors          []ScanError       `json:"errors,omitempty"`
 	PermissionIssues []string         `json:"permission_issues,omitempty"`
+	// Coverage lists every location the scanners were meant to examine
+	// and how completely they did, so an empty result can be told apart
+	// from one that could not look.
+	Coverage        []CoverageEntry   `json:"coverage,omitempty"`
+	// Timings records how long each scanner, stage, and heuristic took.
+	Timings         []Timing          `json:"timings,omitempty"`
+}
+
+// Kinds of component a Timing can describe.
+const (
+	TimingScanner   = "scanner"
+	TimingStage     = "stage"
+	TimingHeuristic = "heuristic"
+)
+
+// Timing records the wall time one component of a scan took and how many
+// items it produced or processed. A heuristic's duration is the sum over
+// every item it analyzed.
+type Timing struct {
+	Kind     string        `json:"kind"`
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Items    int           `json:"items"`
+}
+
+// CoverageStatus says how completely a location was examined.
+type CoverageStatus string
+
+const (
+	// CoverageFull means the location was read completely.
+	CoverageFull CoverageStatus = "full"
+	// CoveragePartial means some of the location could not be read.
+	CoveragePartial CoverageStatus = "partial"
+	// CoverageSkipped means the location does not exist or the check does
+	// not apply, such as a command run against an offline root.
+	CoverageSkipped CoverageStatus = "skipped"
+	// CoverageFailed means the scanner failed or timed out.
+	CoverageFailed CoverageStatus = "failed"
+)
+
+// CoverageEntry reports how completely one location was examined.
+type CoverageEntry struct {
+	Mechanism MechanismType  `json:"mechanism"`
+	Location  string         `json:"location"`
+	Status    CoverageStatus `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
 }
 
 type ScanError struct {
@@ -80,7 +474,35 @@ type ScanError struct {
 	Timestamp   time.Time     `json:"timestamp"`
 }
 
+// Message describes the error, prefixed by the mechanism it occurred in.
+// Errors outside any one scanner, such as an assessment timeout, have no
+// mechanism.
+func (e ScanError) Message() string {
+	if e.Mechanism == "" {
+		return e.Error
+	}
+	return fmt.Sprintf("%s: %s", e.Mechanism, e.Error)
+}
+
 type Scanner interface {
 	Scan() ([]PersistenceItem, error)
 	Type() MechanismType
-}
\ No newline at end of file
+}
+
+// ScannerInfo documents what a scanner reads, so coverage and the access a
+// deployment needs can be reviewed without reading the source.
+type ScannerInfo struct {
+	Mechanism   MechanismType `json:"mechanism"`
+	Description string        `json:"description"`
+	// Paths are the files and directories read, as seen on the scanned
+	// system.
+	Paths    []string `json:"paths"`
+	Commands []string `json:"commands,omitempty"`
+	// Privileges lists the access needed for full coverage.
+	Privileges []string `json:"privileges,omitempty"`
+}
+
+// Describer is implemented by scanners that can report what they read.
+type Describer interface {
+	Info() ScannerInfo
+}
@@ -0,0 +1,309 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haasonsaas/macos-persist-scan/pkg/scanner"
+	_ "modernc.org/sqlite"
+)
+
+// SchemaVersion is recorded in PRAGMA user_version. Columns are only ever
+// added, so queries written against an older version keep working.
+const SchemaVersion = 1
+
+const schema = `
+CREATE TABLE IF NOT EXISTS scans (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	hostname    TEXT    NOT NULL DEFAULT '',
+	start_time  TEXT    NOT NULL,
+	end_time    TEXT    NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	total_items INTEGER NOT NULL,
+	errors      TEXT    NOT NULL DEFAULT '[]',
+	permission_issues TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE IF NOT EXISTS items (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	scan_id         INTEGER NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+	item_id         TEXT    NOT NULL DEFAULT '',
+	mechanism       TEXT    NOT NULL,
+	label           TEXT    NOT NULL DEFAULT '',
+	path            TEXT    NOT NULL DEFAULT '',
+	program         TEXT    NOT NULL DEFAULT '',
+	user            TEXT    NOT NULL DEFAULT '',
+	run_at_load     INTEGER NOT NULL DEFAULT 0,
+	keep_alive      INTEGER NOT NULL DEFAULT 0,
+	disabled        INTEGER NOT NULL DEFAULT 0,
+	modified_at     TEXT    NOT NULL DEFAULT '',
+	risk_level      TEXT    NOT NULL DEFAULT '',
+	risk_score      REAL    NOT NULL DEFAULT 0,
+	risk_confidence REAL    NOT NULL DEFAULT 0,
+	data            TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS items_scan_id ON items(scan_id);
+CREATE INDEX IF NOT EXISTS items_item_id ON items(item_id);
+
+CREATE TABLE IF NOT EXISTS heuristics (
+	item_rowid INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+	name       TEXT    NOT NULL,
+	triggered  INTEGER NOT NULL,
+	score      REAL    NOT NULL,
+	confidence REAL    NOT NULL,
+	details    TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS heuristics_item ON heuristics(item_rowid);
+`
+
+// Store persists scan results in a local SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+// ScanInfo summarises a stored scan without loading its items.
+type ScanInfo struct {
+	ID         int64
+	Hostname   string
+	StartTime  time.Time
+	Duration   time.Duration
+	TotalItems int
+}
+
+// Open opens or creates the database at path and applies the schema.
+func Open(path string) (*Store, error) {
+	// Pragmas set with Exec apply to one pooled connection; those in the
+	// DSN apply to every connection the pool opens
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, fmt.Errorf("opening results database: %w", err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Store) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("results database schema version %d is newer than supported version %d", version, SchemaVersion)
+	}
+
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("creating schema: %w", err)
+	}
+	if _, err := s.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
+		return fmt.Errorf("recording schema version: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save writes result and returns the new scan's ID.
+func (s *Store) Save(result *scanner.ScanResult) (int64, error) {
+	errorsJSON, err := json.Marshal(nonNil(result.Errors))
+	if err != nil {
+		return 0, err
+	}
+	permissionJSON, err := json.Marshal(nonNil(result.PermissionIssues))
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO scans (hostname, start_time, end_time, duration_ms, total_items, errors, permission_issues)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		result.Hostname,
+		result.StartTime.Format(time.RFC3339Nano),
+		result.EndTime.Format(time.RFC3339Nano),
+		result.Duration.Milliseconds(),
+		result.TotalItems,
+		string(errorsJSON),
+		string(permissionJSON),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("inserting scan: %w", err)
+	}
+	scanID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range result.Items {
+		if err := s.insertItem(tx, scanID, &result.Items[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing scan: %w", err)
+	}
+	return scanID, nil
+}
+
+func (s *Store) insertItem(tx *sql.Tx, scanID int64, item *scanner.PersistenceItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("encoding item %s: %w", item.Label, err)
+	}
+
+	modifiedAt := ""
+	if !item.ModifiedAt.IsZero() {
+		modifiedAt = item.ModifiedAt.Format(time.RFC3339Nano)
+	}
+
+	res, err := tx.Exec(`INSERT INTO items (scan_id, item_id, mechanism, label, path, program, user,
+		run_at_load, keep_alive, disabled, modified_at, risk_level, risk_score, risk_confidence, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		scanID, item.ID, string(item.Mechanism), item.Label, item.Path, item.Program, item.User,
+		item.RunAtLoad, item.KeepAlive, item.Disabled, modifiedAt,
+		string(item.Risk.Level), item.Risk.Score, item.Risk.Confidence, string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting item %s: %w", item.Label, err)
+	}
+	rowID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, h := range item.Risk.Heuristics {
+		_, err := tx.Exec(`INSERT INTO heuristics (item_rowid, name, triggered, score, confidence, details)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			rowID, h.Name, h.Triggered, h.Score, h.Confidence, h.Details)
+		if err != nil {
+			return fmt.Errorf("inserting heuristic %s: %w", h.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// List returns stored scans, newest first.
+func (s *Store) List() ([]ScanInfo, error) {
+	rows, err := s.db.Query(`SELECT id, hostname, start_time, duration_ms, total_items FROM scans ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("listing scans: %w", err)
+	}
+	defer rows.Close()
+
+	var scans []ScanInfo
+	for rows.Next() {
+		var info ScanInfo
+		var start string
+		var durationMS int64
+		if err := rows.Scan(&info.ID, &info.Hostname, &start, &durationMS, &info.TotalItems); err != nil {
+			return nil, err
+		}
+		info.StartTime, _ = time.Parse(time.RFC3339Nano, start)
+		info.Duration = time.Duration(durationMS) * time.Millisecond
+		scans = append(scans, info)
+	}
+
+	return scans, rows.Err()
+}
+
+// ItemScans returns the scans that contain the item with the given ID,
+// oldest first.
+func (s *Store) ItemScans(itemID string) ([]ScanInfo, error) {
+	rows, err := s.db.Query(`SELECT s.id, s.hostname, s.start_time, s.duration_ms, s.total_items
+		FROM scans s JOIN items i ON i.scan_id = s.id
+		WHERE i.item_id = ? ORDER BY s.id`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("listing scans for item %s: %w", itemID, err)
+	}
+	defer rows.Close()
+
+	var scans []ScanInfo
+	for rows.Next() {
+		var info ScanInfo
+		var start string
+		var durationMS int64
+		if err := rows.Scan(&info.ID, &info.Hostname, &start, &durationMS, &info.TotalItems); err != nil {
+			return nil, err
+		}
+		info.StartTime, _ = time.Parse(time.RFC3339Nano, start)
+		info.Duration = time.Duration(durationMS) * time.Millisecond
+		scans = append(scans, info)
+	}
+
+	return scans, rows.Err()
+}
+
+// Load reconstructs the scan with the given ID.
+func (s *Store) Load(scanID int64) (*scanner.ScanResult, error) {
+	result := &scanner.ScanResult{}
+
+	var start, end, errorsJSON, permissionJSON string
+	var durationMS int64
+	err := s.db.QueryRow(`SELECT hostname, start_time, end_time, duration_ms, errors, permission_issues FROM scans WHERE id = ?`, scanID).
+		Scan(&result.Hostname, &start, &end, &durationMS, &errorsJSON, &permissionJSON)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("scan %d not found", scanID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading scan %d: %w", scanID, err)
+	}
+
+	result.StartTime, _ = time.Parse(time.RFC3339Nano, start)
+	result.EndTime, _ = time.Parse(time.RFC3339Nano, end)
+	result.Duration = time.Duration(durationMS) * time.Millisecond
+	if err := json.Unmarshal([]byte(errorsJSON), &result.Errors); err != nil {
+		return nil, fmt.Errorf("decoding scan errors: %w", err)
+	}
+	if err := json.Unmarshal([]byte(permissionJSON), &result.PermissionIssues); err != nil {
+		return nil, fmt.Errorf("decoding permission issues: %w", err)
+	}
+
+	rows, err := s.db.Query(`SELECT data FROM items WHERE scan_id = ? ORDER BY id`, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("loading items for scan %d: %w", scanID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var item scanner.PersistenceItem
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, fmt.Errorf("decoding item: %w", err)
+		}
+		result.Items = append(result.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Summarize()
+	return result, nil
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}